@@ -28,6 +28,13 @@ type Expression interface {
 	expressionNode()
 }
 
+// Pattern - subset of nodes which describe the shape of the value
+// and names it's bound to (used on the left side of let statements)
+type Pattern interface {
+	Node
+	patternNode()
+}
+
 // Program - root node for program AST
 type Program struct {
 	Statements []Statement
@@ -57,10 +64,11 @@ func (p *Program) String() string {
 }
 
 // LetStatement - statement that represents sentences with let
+// let <pattern> = <expression>;
 type LetStatement struct {
-	Token token.Token // token.LET token
-	Name  *Identifier
-	Value Expression
+	Token   token.Token // token.LET token
+	Pattern Pattern
+	Value   Expression
 }
 
 func (ls *LetStatement) statementNode() {}
@@ -75,7 +83,7 @@ func (ls *LetStatement) String() string {
 	var out bytes.Buffer
 
 	out.WriteString(ls.TokenLiteral() + " ")
-	out.WriteString(ls.Name.String())
+	out.WriteString(ls.Pattern.String())
 	out.WriteString(" = ")
 
 	if ls.Value != nil {
@@ -116,9 +124,17 @@ func (rs *ReturnStatement) String() string {
 type Identifier struct {
 	Token token.Token // token.IDENT token
 	Value string
+	// variable the identifier refers to, assigned by the resolver of the
+	// evaluator, nil if the name isn't declared (e.g. builtin function)
+	Location *Location
+	// variable of the enclosing scopes with the same name as the name
+	// bound by the pattern. If it holds the variant without fields, the
+	// pattern compares the value with the variant instead of binding it
+	Shadowed *Location
 }
 
 func (i *Identifier) expressionNode() {}
+func (i *Identifier) patternNode()    {}
 
 // TokenLiteral - returns the literal value of the associated node
 func (i *Identifier) TokenLiteral() string { return i.Token.Literal }
@@ -240,6 +256,23 @@ func (b *Boolean) String() string {
 	return b.Token.Literal
 }
 
+// NullLiteral - represents null value
+type NullLiteral struct {
+	Token token.Token
+}
+
+func (n *NullLiteral) expressionNode() {}
+
+// TokenLiteral - returns the literal value of the associated node
+func (n *NullLiteral) TokenLiteral() string {
+	return n.Token.Literal
+}
+
+// String - returns string representation of the expression
+func (n *NullLiteral) String() string {
+	return n.Token.Literal
+}
+
 // IfExpression - defines conditional expression
 // if (<condition>) <consequence> else <alternative>
 type IfExpression struct {
@@ -273,6 +306,37 @@ func (ie *IfExpression) String() string {
 	return out.String()
 }
 
+// ConditionalExpression - defines ternary conditional expression
+// <condition> ? <consequence> : <alternative>
+type ConditionalExpression struct {
+	Token       token.Token // the `?` token
+	Condition   Expression
+	Consequence Expression
+	Alternative Expression
+}
+
+func (ce *ConditionalExpression) expressionNode() {}
+
+// TokenLiteral - returns the literal value of the associated node
+func (ce *ConditionalExpression) TokenLiteral() string {
+	return ce.Token.Literal
+}
+
+// String - returns string representation of the expression
+func (ce *ConditionalExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(ce.Condition.String())
+	out.WriteString(" ? ")
+	out.WriteString(ce.Consequence.String())
+	out.WriteString(" : ")
+	out.WriteString(ce.Alternative.String())
+	out.WriteString(")")
+
+	return out.String()
+}
+
 // BlockStatement - represents series of statements
 type BlockStatement struct {
 	// the "{" token
@@ -300,12 +364,19 @@ func (bs *BlockStatement) String() string {
 }
 
 // FunctionLiteral - represents functions
+// function(<params>) { <body> } or (<params>) => <body>
 type FunctionLiteral struct {
-	// The 'function' token
+	// The 'function' token or the '=>' token for arrow functions
 	Token token.Token
 
 	Parameters []*Identifier
+	// default values of parameters, keyed by parameter name
+	Defaults map[string]Expression
+	// the rest parameter (...name), nil if function doesn't have it
+	Rest *Identifier
 	Body *BlockStatement
+	// parameters and variables of the function, nil if not resolved
+	Scope *Scope
 }
 
 
@@ -320,22 +391,49 @@ func (fl *FunctionLiteral) TokenLiteral() string {
 func (fl *FunctionLiteral) String() string {
 	var out bytes.Buffer
 
-	params := []string{}
-
-	// collect all parameters of function as strings
-	for _, p := range fl.Parameters {
-		params = append(params, p.String())
+	// arrow functions are printed as (<params>) => <body>
+	if fl.Token.Type == token.ARROW {
+		out.WriteString("(")
+		out.WriteString(ParametersString(fl.Parameters, fl.Defaults, fl.Rest))
+		out.WriteString(") => ")
+		out.WriteString(fl.Body.String())
+		return out.String()
 	}
 
 	out.WriteString(fl.TokenLiteral())
 	out.WriteString("(")
-	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(ParametersString(fl.Parameters, fl.Defaults, fl.Rest))
 	out.WriteString(")")
 	out.WriteString(fl.Body.String())
 
 	return out.String()
 }
 
+// ParametersString - returns comma separated list of function parameters
+// including default values and the rest parameter
+func ParametersString(
+	parameters []*Identifier,
+	defaults map[string]Expression,
+	rest *Identifier,
+) string {
+	params := []string{}
+
+	// collect all parameters of function as strings
+	for _, p := range parameters {
+		if def, ok := defaults[p.Value]; ok {
+			params = append(params, p.String()+" = "+def.String())
+			continue
+		}
+		params = append(params, p.String())
+	}
+
+	if rest != nil {
+		params = append(params, "..."+rest.String())
+	}
+
+	return strings.Join(params, ", ")
+}
+
 // CallExpression - defines calls of expressions
 // <expression>(<comma separated expressions>)
 type CallExpression struct {
@@ -387,4 +485,671 @@ func (sl *StringLiteral) TokenLiteral() string {
 // String - returns string representation of the expression
 func (sl *StringLiteral) String() string {
 	return sl.Token.Literal
-}
\ No newline at end of file
+}
+
+// KeywordArgument - represents argument passed by name in the call
+// <name>: <expression>
+type KeywordArgument struct {
+	// the argument name token
+	Token token.Token
+	Name  *Identifier
+	Value Expression
+}
+
+func (ka *KeywordArgument) expressionNode() {}
+
+// TokenLiteral - returns the literal value of the associated node
+func (ka *KeywordArgument) TokenLiteral() string {
+	return ka.Token.Literal
+}
+
+// String - returns string representation of the expression
+func (ka *KeywordArgument) String() string {
+	return ka.Name.String() + ": " + ka.Value.String()
+}
+
+// ArrayLiteral - represents arrays
+// [<comma separated expressions>]
+type ArrayLiteral struct {
+	// the '[' token
+	Token    token.Token
+	Elements []Expression
+}
+
+func (al *ArrayLiteral) expressionNode() {}
+
+// TokenLiteral - returns the literal value of the associated node
+func (al *ArrayLiteral) TokenLiteral() string {
+	return al.Token.Literal
+}
+
+// String - returns string representation of the expression
+func (al *ArrayLiteral) String() string {
+	var out bytes.Buffer
+
+	elements := []string{}
+	for _, e := range al.Elements {
+		elements = append(elements, e.String())
+	}
+
+	out.WriteString("[")
+	out.WriteString(strings.Join(elements, ", "))
+	out.WriteString("]")
+
+	return out.String()
+}
+
+// HashPair - key-value pair of the hash literal, for spread pairs
+// the key is *SpreadElement and the value is nil
+type HashPair struct {
+	Key   Expression
+	Value Expression
+}
+
+// String - returns string representation of the pair
+func (hp *HashPair) String() string {
+	if hp.Value == nil {
+		return hp.Key.String()
+	}
+	return hp.Key.String() + ": " + hp.Value.String()
+}
+
+// HashLiteral - represents hashes (pairs keep the order from the source)
+// {<key>: <value>, <key>: <value>, ...}
+type HashLiteral struct {
+	// the '{' token
+	Token token.Token
+	Pairs []*HashPair
+}
+
+func (hl *HashLiteral) expressionNode() {}
+
+// TokenLiteral - returns the literal value of the associated node
+func (hl *HashLiteral) TokenLiteral() string {
+	return hl.Token.Literal
+}
+
+// String - returns string representation of the expression
+func (hl *HashLiteral) String() string {
+	var out bytes.Buffer
+
+	pairs := []string{}
+	for _, pair := range hl.Pairs {
+		pairs = append(pairs, pair.String())
+	}
+
+	out.WriteString("{")
+	out.WriteString(strings.Join(pairs, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
+
+// ArrayPattern - destructures arrays
+// [<pattern>, <pattern>, ..., ...<rest>]
+type ArrayPattern struct {
+	// the '[' token
+	Token    token.Token
+	Elements []Pattern
+	// collects the rest of elements, nil if pattern doesn't have it
+	Rest *Identifier
+}
+
+func (ap *ArrayPattern) patternNode() {}
+
+// TokenLiteral - returns the literal value of the associated node
+func (ap *ArrayPattern) TokenLiteral() string {
+	return ap.Token.Literal
+}
+
+// String - returns string representation of the pattern
+func (ap *ArrayPattern) String() string {
+	var out bytes.Buffer
+
+	elements := []string{}
+	for _, e := range ap.Elements {
+		elements = append(elements, e.String())
+	}
+	if ap.Rest != nil {
+		elements = append(elements, "..."+ap.Rest.String())
+	}
+
+	out.WriteString("[")
+	out.WriteString(strings.Join(elements, ", "))
+	out.WriteString("]")
+
+	return out.String()
+}
+
+// HashPatternPair - destructures value stored by the key
+// <key>: <pattern> or <key> as a shorthand for <key>: <key>
+type HashPatternPair struct {
+	Key   *StringLiteral
+	Value Pattern
+}
+
+// HashPattern - destructures hashes
+// {<key>, <key>: <pattern>, ..., ...<rest>}
+type HashPattern struct {
+	// the '{' token
+	Token token.Token
+	Pairs []*HashPatternPair
+	// collects pairs which weren't destructured, nil if pattern doesn't
+	// have it
+	Rest *Identifier
+}
+
+func (hp *HashPattern) patternNode() {}
+
+// TokenLiteral - returns the literal value of the associated node
+func (hp *HashPattern) TokenLiteral() string {
+	return hp.Token.Literal
+}
+
+// String - returns string representation of the pattern
+func (hp *HashPattern) String() string {
+	var out bytes.Buffer
+
+	pairs := []string{}
+	for _, pair := range hp.Pairs {
+		if isShorthandPair(pair) {
+			pairs = append(pairs, pair.Value.String())
+			continue
+		}
+		pairs = append(pairs, pair.Key.String()+": "+pair.Value.String())
+	}
+	if hp.Rest != nil {
+		pairs = append(pairs, "..."+hp.Rest.String())
+	}
+
+	out.WriteString("{")
+	out.WriteString(strings.Join(pairs, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
+
+// isShorthandPair - checks if the pair binds the value to the name
+// matching the key ({name} or {name = <default>})
+func isShorthandPair(pair *HashPatternPair) bool {
+	target := pair.Value
+	if def, ok := target.(*DefaultPattern); ok {
+		target = def.Target
+	}
+	ident, ok := target.(*Identifier)
+	return ok && ident.Value == pair.Key.Value
+}
+
+// DefaultPattern - provides value for the pattern if destructured
+// value is missing
+// <pattern> = <expression>
+type DefaultPattern struct {
+	// the '=' token
+	Token   token.Token
+	Target  Pattern
+	Default Expression
+}
+
+func (dp *DefaultPattern) patternNode() {}
+
+// TokenLiteral - returns the literal value of the associated node
+func (dp *DefaultPattern) TokenLiteral() string {
+	return dp.Token.Literal
+}
+
+// String - returns string representation of the pattern
+func (dp *DefaultPattern) String() string {
+	return dp.Target.String() + " = " + dp.Default.String()
+}
+
+// LiteralPattern - matches values equal to the literal
+// (integer, string, boolean or null)
+type LiteralPattern struct {
+	Token token.Token
+	Value Expression
+}
+
+func (lp *LiteralPattern) patternNode() {}
+
+// TokenLiteral - returns the literal value of the associated node
+func (lp *LiteralPattern) TokenLiteral() string {
+	return lp.Token.Literal
+}
+
+// String - returns string representation of the pattern
+func (lp *LiteralPattern) String() string {
+	return lp.Value.String()
+}
+
+// WildcardPattern - matches any value without binding it
+// _
+type WildcardPattern struct {
+	Token token.Token
+}
+
+func (wp *WildcardPattern) patternNode() {}
+
+// TokenLiteral - returns the literal value of the associated node
+func (wp *WildcardPattern) TokenLiteral() string {
+	return wp.Token.Literal
+}
+
+// String - returns string representation of the pattern
+func (wp *WildcardPattern) String() string {
+	return wp.Token.Literal
+}
+
+// MatchArm - single branch of the match expression
+// <pattern> => <body> or <pattern> if <guard> => <body>
+type MatchArm struct {
+	Pattern Pattern
+	// optional condition which must be truly for the arm to be chosen
+	Guard Expression
+	Body  *BlockStatement
+	// names bound by the arm, nil if not resolved
+	Scope *Scope
+}
+
+// String - returns string representation of the arm
+func (ma *MatchArm) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(ma.Pattern.String())
+	if ma.Guard != nil {
+		out.WriteString(" if ")
+		out.WriteString(ma.Guard.String())
+	}
+	out.WriteString(" => ")
+	out.WriteString(ma.Body.String())
+
+	return out.String()
+}
+
+// MatchExpression - chooses the first arm which pattern matches the value
+// match (<expression>) { <pattern> => <body>, ... }
+type MatchExpression struct {
+	Token   token.Token // the `match` token
+	Subject Expression
+	Arms    []*MatchArm
+}
+
+func (me *MatchExpression) expressionNode() {}
+
+// TokenLiteral - returns the literal value of the associated node
+func (me *MatchExpression) TokenLiteral() string {
+	return me.Token.Literal
+}
+
+// String - returns string representation of the expression
+func (me *MatchExpression) String() string {
+	var out bytes.Buffer
+
+	arms := []string{}
+	for _, arm := range me.Arms {
+		arms = append(arms, arm.String())
+	}
+
+	out.WriteString("match")
+	out.WriteString(me.Subject.String())
+	out.WriteString(" {")
+	out.WriteString(strings.Join(arms, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
+
+// ThrowStatement - statement that raises an error
+// throw <expression>;
+type ThrowStatement struct {
+	Token token.Token // the `throw` token
+	Value Expression
+}
+
+func (ts *ThrowStatement) statementNode() {}
+
+// TokenLiteral - returns the literal value of the associated node
+func (ts *ThrowStatement) TokenLiteral() string {
+	return ts.Token.Literal
+}
+
+// String - returns string representation of the statement
+func (ts *ThrowStatement) String() string {
+	return ts.TokenLiteral() + " " + ts.Value.String() + ";"
+}
+
+// TryExpression - evaluates the block and handles errors raised inside it
+// try { <block> } catch (<parameter>) { <handler> } finally { <finalizer> }
+type TryExpression struct {
+	Token token.Token // the `try` token
+	Block *BlockStatement
+	// name the caught error is bound to, can be nil
+	Parameter *Identifier
+	// error handler, nil if there is no catch clause
+	Catch *BlockStatement
+	// parameter and variables of the catch block, nil if not resolved
+	CatchScope *Scope
+	// always executed block, nil if there is no finally clause
+	Finally *BlockStatement
+}
+
+func (te *TryExpression) expressionNode() {}
+
+// TokenLiteral - returns the literal value of the associated node
+func (te *TryExpression) TokenLiteral() string {
+	return te.Token.Literal
+}
+
+// String - returns string representation of the expression
+func (te *TryExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("try ")
+	out.WriteString(te.Block.String())
+
+	if te.Catch != nil {
+		out.WriteString(" catch")
+		if te.Parameter != nil {
+			out.WriteString("(" + te.Parameter.String() + ")")
+		}
+		out.WriteString(" ")
+		out.WriteString(te.Catch.String())
+	}
+
+	if te.Finally != nil {
+		out.WriteString(" finally ")
+		out.WriteString(te.Finally.String())
+	}
+
+	return out.String()
+}
+
+// StructStatement - declares struct type with named fields
+// struct <name> { <field>, <field>, ... }
+type StructStatement struct {
+	Token  token.Token // the `struct` token
+	Name   *Identifier
+	Fields []*Identifier
+}
+
+func (ss *StructStatement) statementNode() {}
+
+// TokenLiteral - returns the literal value of the associated node
+func (ss *StructStatement) TokenLiteral() string {
+	return ss.Token.Literal
+}
+
+// String - returns string representation of the statement
+func (ss *StructStatement) String() string {
+	var out bytes.Buffer
+
+	fields := []string{}
+	for _, f := range ss.Fields {
+		fields = append(fields, f.String())
+	}
+
+	out.WriteString(ss.TokenLiteral() + " ")
+	out.WriteString(ss.Name.String())
+	out.WriteString(" { ")
+	out.WriteString(strings.Join(fields, ", "))
+	out.WriteString(" }")
+
+	return out.String()
+}
+
+// MemberExpression - access to the field of the value
+// <expression>.<identifier>
+type MemberExpression struct {
+	Token  token.Token // the `.` or `?.` token
+	Object Expression
+	Member *Identifier
+	// optional access (?.) evaluates to null if the object is null
+	Optional bool
+}
+
+func (me *MemberExpression) expressionNode() {}
+
+// TokenLiteral - returns the literal value of the associated node
+func (me *MemberExpression) TokenLiteral() string {
+	return me.Token.Literal
+}
+
+// String - returns string representation of the expression
+func (me *MemberExpression) String() string {
+	if me.Optional {
+		return me.Object.String() + "?." + me.Member.String()
+	}
+	return me.Object.String() + "." + me.Member.String()
+}
+
+// AssignExpression - assigns value to the field
+// <member expression> = <expression>
+type AssignExpression struct {
+	Token  token.Token // the `=` token
+	Target *MemberExpression
+	Value  Expression
+}
+
+func (ae *AssignExpression) expressionNode() {}
+
+// TokenLiteral - returns the literal value of the associated node
+func (ae *AssignExpression) TokenLiteral() string {
+	return ae.Token.Literal
+}
+
+// String - returns string representation of the expression
+func (ae *AssignExpression) String() string {
+	return "(" + ae.Target.String() + " = " + ae.Value.String() + ")"
+}
+
+// MethodDeclaration - method inside impl block, the receiver is
+// passed as the first parameter
+// <name>(<parameters>) <block statement>
+type MethodDeclaration struct {
+	Name     *Identifier
+	Function *FunctionLiteral
+}
+
+// String - returns string representation of the method
+func (md *MethodDeclaration) String() string {
+	return md.Function.String()
+}
+
+// ImplStatement - declares methods of the struct or enum type, optionally
+// as implementation of the trait
+// impl <name> { <method> <method> ... }
+// impl <trait> for <name> { <method> <method> ... }
+type ImplStatement struct {
+	Token   token.Token // the `impl` token
+	// implemented trait, nil if methods don't implement a trait
+	Trait   *Identifier
+	Name    *Identifier
+	Methods []*MethodDeclaration
+}
+
+func (is *ImplStatement) statementNode() {}
+
+// TokenLiteral - returns the literal value of the associated node
+func (is *ImplStatement) TokenLiteral() string {
+	return is.Token.Literal
+}
+
+// String - returns string representation of the statement
+func (is *ImplStatement) String() string {
+	var out bytes.Buffer
+
+	methods := []string{}
+	for _, m := range is.Methods {
+		methods = append(methods, m.String())
+	}
+
+	out.WriteString(is.TokenLiteral() + " ")
+	if is.Trait != nil {
+		out.WriteString(is.Trait.String() + " for ")
+	}
+	out.WriteString(is.Name.String())
+	out.WriteString(" { ")
+	out.WriteString(strings.Join(methods, " "))
+	out.WriteString(" }")
+
+	return out.String()
+}
+
+// EnumVariant - variant of the enum with its fields
+// <name>(<field>, <field>, ...) or <name>
+type EnumVariant struct {
+	Name   *Identifier
+	Fields []*Identifier
+}
+
+// String - returns string representation of the variant
+func (ev *EnumVariant) String() string {
+	if len(ev.Fields) == 0 {
+		return ev.Name.String()
+	}
+
+	fields := []string{}
+	for _, f := range ev.Fields {
+		fields = append(fields, f.String())
+	}
+
+	return ev.Name.String() + "(" + strings.Join(fields, ", ") + ")"
+}
+
+// EnumStatement - declares enum (tagged union) type
+// enum <name> { <variant>, <variant>, ... }
+type EnumStatement struct {
+	Token    token.Token // the `enum` token
+	Name     *Identifier
+	Variants []*EnumVariant
+}
+
+func (es *EnumStatement) statementNode() {}
+
+// TokenLiteral - returns the literal value of the associated node
+func (es *EnumStatement) TokenLiteral() string {
+	return es.Token.Literal
+}
+
+// String - returns string representation of the statement
+func (es *EnumStatement) String() string {
+	var out bytes.Buffer
+
+	variants := []string{}
+	for _, v := range es.Variants {
+		variants = append(variants, v.String())
+	}
+
+	out.WriteString(es.TokenLiteral() + " ")
+	out.WriteString(es.Name.String())
+	out.WriteString(" { ")
+	out.WriteString(strings.Join(variants, ", "))
+	out.WriteString(" }")
+
+	return out.String()
+}
+
+// VariantPattern - matches enum variant and destructures its fields
+// <variant>(<pattern>, <pattern>, ...)
+// <enum>.<variant>(<pattern>, <pattern>, ...)
+// <enum>.<variant>
+type VariantPattern struct {
+	Token token.Token // the first name token
+	// enum of the qualified variant, nil if the variant is looked up by name
+	Enum     *Identifier
+	Name     *Identifier
+	Elements []Pattern // nil for the qualified variant without parentheses
+}
+
+func (vp *VariantPattern) patternNode() {}
+
+// TokenLiteral - returns the literal value of the associated node
+func (vp *VariantPattern) TokenLiteral() string {
+	return vp.Token.Literal
+}
+
+// String - returns string representation of the pattern
+func (vp *VariantPattern) String() string {
+	elements := []string{}
+	for _, e := range vp.Elements {
+		elements = append(elements, e.String())
+	}
+
+	name := vp.Name.String()
+	if vp.Enum != nil {
+		name = vp.Enum.String() + "." + name
+	}
+	if vp.Elements == nil {
+		return name
+	}
+	return name + "(" + strings.Join(elements, ", ") + ")"
+}
+
+// TraitMethod - signature of the method required by the trait
+// <name>(<parameters>)
+type TraitMethod struct {
+	Name       *Identifier
+	Parameters []*Identifier
+}
+
+// String - returns string representation of the method signature
+func (tm *TraitMethod) String() string {
+	params := []string{}
+	for _, p := range tm.Parameters {
+		params = append(params, p.String())
+	}
+
+	return tm.Name.String() + "(" + strings.Join(params, ", ") + ")"
+}
+
+// TraitStatement - declares trait: set of methods required from the type
+// trait <name> { <method signature>; <method signature>; ... }
+type TraitStatement struct {
+	Token   token.Token // the `trait` token
+	Name    *Identifier
+	Methods []*TraitMethod
+}
+
+func (ts *TraitStatement) statementNode() {}
+
+// TokenLiteral - returns the literal value of the associated node
+func (ts *TraitStatement) TokenLiteral() string {
+	return ts.Token.Literal
+}
+
+// String - returns string representation of the statement
+func (ts *TraitStatement) String() string {
+	var out bytes.Buffer
+
+	methods := []string{}
+	for _, m := range ts.Methods {
+		methods = append(methods, m.String())
+	}
+
+	out.WriteString(ts.TokenLiteral() + " ")
+	out.WriteString(ts.Name.String())
+	out.WriteString(" { ")
+	out.WriteString(strings.Join(methods, "; "))
+	out.WriteString(" }")
+
+	return out.String()
+}
+
+// SpreadElement - expands array into elements of the array literal or
+// arguments of the call, and hash into pairs of the hash literal
+// ...<expression>
+type SpreadElement struct {
+	Token token.Token // the `...` token
+	Value Expression
+}
+
+func (se *SpreadElement) expressionNode() {}
+
+// TokenLiteral - returns the literal value of the associated node
+func (se *SpreadElement) TokenLiteral() string {
+	return se.Token.Literal
+}
+
+// String - returns string representation of the expression
+func (se *SpreadElement) String() string {
+	return "..." + se.Value.String()
+}
@@ -0,0 +1,42 @@
+package ast
+
+// Scope - variables of the program, function, match arm or catch block.
+// Each variable gets the slot in the environment of the scope, so the
+// evaluator finds variables by index instead of the name
+type Scope struct {
+	slots map[string]int
+}
+
+// NewScope - creates scope without variables
+func NewScope() *Scope {
+	return &Scope{slots: make(map[string]int)}
+}
+
+// Declare - returns slot of the variable, variables declared for the first
+// time get the next free slot
+func (s *Scope) Declare(name string) int {
+	if slot, ok := s.slots[name]; ok {
+		return slot
+	}
+	slot := len(s.slots)
+	s.slots[name] = slot
+	return slot
+}
+
+// Lookup - returns slot of the variable if it's declared in the scope
+func (s *Scope) Lookup(name string) (int, bool) {
+	slot, ok := s.slots[name]
+	return slot, ok
+}
+
+// Len - returns number of variables in the scope
+func (s *Scope) Len() int {
+	return len(s.slots)
+}
+
+// Location - place of the variable: number of environments to go up from
+// the current one and the slot in that environment
+type Location struct {
+	Depth int
+	Slot  int
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/technoboom/compiler/ast"
+	"github.com/technoboom/compiler/cgen"
+	"github.com/technoboom/compiler/gogen"
+	"github.com/technoboom/compiler/ir"
+	"github.com/technoboom/compiler/jsgen"
+	"github.com/technoboom/compiler/lexer"
+	"github.com/technoboom/compiler/parser"
+)
+
+// build - translates the program from the file with one of the backends:
+//
+//	beaver build [-emit=native|c|go|js|ir] [-o output] [-package name] file
+//
+// `native` builds the executable with the system C compiler,
+// `c` writes the generated C source, `go` writes the Go package,
+// `js` writes JavaScript and its source map next to it,
+// `ir` writes the textual form of the SSA intermediate representation
+func build(args []string, stderr io.Writer) int {
+	flags := flag.NewFlagSet("build", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	emit := flags.String("emit", "native", "output of the build: native, c, go, js or ir")
+	output := flags.String("o", "", "name of the output file")
+	pkg := flags.String("package", "", "name of the Go package (default: name of the file)")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprintln(stderr, "usage: beaver build [-emit=native|c|go|js|ir] [-o output] [-package name] file")
+		return 2
+	}
+
+	file := flags.Arg(0)
+	input, err := ioutil.ReadFile(file)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	p := parser.New(lexer.New(string(input)))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		for _, msg := range p.Errors() {
+			fmt.Fprintf(stderr, "%s: %s\n", file, msg)
+		}
+		return 1
+	}
+
+	base := strings.TrimSuffix(file, filepath.Ext(file))
+
+	switch *emit {
+	case "native", "c":
+		source, err := cgen.Generate(program)
+		if err != nil {
+			fmt.Fprintf(stderr, "%s: %s\n", file, err)
+			return 1
+		}
+
+		if *emit == "c" {
+			if *output == "" {
+				*output = base + ".c"
+			}
+			err = ioutil.WriteFile(*output, []byte(source), 0644)
+		} else {
+			if *output == "" {
+				*output = base
+			}
+			err = cgen.Build(source, *output)
+		}
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+	case "go":
+		if *pkg == "" {
+			*pkg = packageName(filepath.Base(base))
+		}
+		source, err := gogen.Generate(program, *pkg)
+		if err != nil {
+			fmt.Fprintf(stderr, "%s: %s\n", file, err)
+			return 1
+		}
+
+		if *output == "" {
+			*output = base + ".go"
+		}
+		if err := ioutil.WriteFile(*output, []byte(source), 0644); err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+	case "js":
+		if *output == "" {
+			*output = base + ".js"
+		}
+		if err := buildJS(program, file, input, *output); err != nil {
+			fmt.Fprintf(stderr, "%s: %s\n", file, err)
+			return 1
+		}
+	case "ir":
+		p, err := ir.Build(program)
+		if err != nil {
+			fmt.Fprintf(stderr, "%s: %s\n", file, err)
+			return 1
+		}
+
+		if *output == "" {
+			*output = base + ".ir"
+		}
+		if err := ioutil.WriteFile(*output, []byte(p.String()), 0644); err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+	default:
+		fmt.Fprintf(stderr, "unknown output: %s\n", *emit)
+		return 2
+	}
+
+	return 0
+}
+
+// buildJS - writes JavaScript of the program into the output and its
+// source map into `<output>.map`. The map embeds the source, so browsers
+// show it without access to the Beaver file
+func buildJS(program *ast.Program, file string, input []byte, output string) error {
+	mapFile := output + ".map"
+
+	source, err := filepath.Rel(filepath.Dir(mapFile), file)
+	if err != nil {
+		source = file
+	}
+	result, err := jsgen.Generate(program, filepath.ToSlash(source))
+	if err != nil {
+		return err
+	}
+	result.Map.File = filepath.Base(output)
+	result.Map.SourcesContent = []string{string(input)}
+
+	data, err := result.Map.JSON()
+	if err != nil {
+		return err
+	}
+	code := result.Code + "//# sourceMappingURL=" + filepath.Base(mapFile) + "\n"
+	if err := ioutil.WriteFile(output, []byte(code), 0644); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(mapFile, data, 0644)
+}
+
+// packageName - returns Go package name made of the letters of the name,
+// e.g. `my-script` becomes `myscript`
+func packageName(name string) string {
+	var out strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if r >= 'a' && r <= 'z' {
+			out.WriteRune(r)
+		}
+	}
+	if out.Len() == 0 {
+		return "script"
+	}
+	return out.String()
+}
@@ -0,0 +1,40 @@
+package cgen
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Build - compiles the C source into the executable with the system
+// C compiler, the compiler is taken from CC environment variable
+// and defaults to `cc`
+func Build(source string, output string) error {
+	dir, err := ioutil.TempDir("", "beaver")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "program.c")
+	if err := ioutil.WriteFile(file, []byte(source), 0644); err != nil {
+		return err
+	}
+
+	cmd := exec.Command(compilerCommand(), "-std=c99", "-O2", "-o", output, file)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s failed: %s\n%s", compilerCommand(), err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// compilerCommand - returns command of the C compiler
+func compilerCommand() string {
+	if cc := os.Getenv("CC"); cc != "" {
+		return cc
+	}
+	return "cc"
+}
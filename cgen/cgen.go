@@ -0,0 +1,630 @@
+// Package cgen - translates Beaver programs into portable C source code,
+// which builds with the system C compiler into standalone executables
+package cgen
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/technoboom/compiler/ast"
+	"github.com/technoboom/compiler/object"
+)
+
+// builtins - immortal values of the builtin functions implemented
+// by the runtime
+var builtins = map[string]string{
+	"puts": "bv_puts_value",
+}
+
+// scope - bindings of the function being generated (or of the program)
+type scope struct {
+	outer *scope
+
+	// C variables of the cells of the bindings
+	locals map[string]string
+	// names of the bindings captured from the enclosing functions,
+	// in the order of their indexes in the closure
+	free []string
+
+	out    *bytes.Buffer
+	indent int
+}
+
+// generator - keeps generated functions and the scope being generated
+type generator struct {
+	// prototypes and parameter names of the functions
+	declarations bytes.Buffer
+	// definitions of the functions
+	functions bytes.Buffer
+
+	scope *scope
+
+	numTemps     int
+	numFunctions int
+}
+
+// Generate - returns C source of the program: the runtime, functions of
+// the program and `main` which prints result of the program. Programs
+// using features without C support are rejected with an error
+func Generate(program *ast.Program) (string, error) {
+	g := &generator{}
+
+	main := &scope{locals: map[string]string{}, out: &bytes.Buffer{}, indent: 1}
+	g.scope = main
+
+	// bindings of the program are global cells
+	var globals bytes.Buffer
+	for _, name := range collectLets(program.Statements) {
+		cell := "g_" + name
+		main.locals[name] = cell
+		globals.WriteString(fmt.Sprintf("static bv_cell *%s;\n", cell))
+		g.emit("%s = bv_cell_new(NULL);", cell)
+	}
+
+	for _, s := range program.Statements {
+		if err := g.generateProgramStatement(s); err != nil {
+			return "", err
+		}
+	}
+
+	var out bytes.Buffer
+	out.WriteString(runtime)
+	out.WriteString("\n")
+	out.WriteString(g.declarations.String())
+	out.WriteString(globals.String())
+	out.WriteString(g.functions.String())
+	out.WriteString("int main(void) {\n")
+	out.WriteString("\tbv_value *result = NULL;\n")
+	out.WriteString(main.out.String())
+	out.WriteString("done:\n")
+	out.WriteString("\tif (result != NULL) {\n")
+	out.WriteString("\t\tbv_inspect(stdout, result);\n")
+	out.WriteString("\t\tfputs(\"\\n\", stdout);\n")
+	out.WriteString("\t}\n")
+	out.WriteString("\treturn 0;\n")
+	out.WriteString("}\n")
+
+	return out.String(), nil
+}
+
+// generateProgramStatement - generates statement of the program, value of
+// the last expression statement is the result of the program
+func (g *generator) generateProgramStatement(node ast.Statement) error {
+	switch node := node.(type) {
+	case *ast.ExpressionStatement:
+		value, err := g.generateExpression(node.Expression)
+		if err != nil {
+			return err
+		}
+		g.emit("if (result != NULL) bv_release(result);")
+		g.emit("result = %s;", value)
+		return nil
+	case *ast.LetStatement:
+		if err := g.generateLetStatement(node); err != nil {
+			return err
+		}
+		g.emit("if (result != NULL) bv_release(result);")
+		g.emit("result = NULL;")
+		return nil
+	case *ast.ReturnStatement:
+		g.emit("if (result != NULL) bv_release(result);")
+		return g.generateReturnStatement(node)
+	default:
+		return fmt.Errorf("%T is not supported by C backend", node)
+	}
+}
+
+// generateStatements - generates statements of the block, returns
+// C variable with the value of the last expression statement or null
+func (g *generator) generateStatements(statements []ast.Statement) (string, error) {
+	for idx, s := range statements {
+		last := idx == len(statements)-1
+
+		switch s := s.(type) {
+		case *ast.ExpressionStatement:
+			value, err := g.generateExpression(s.Expression)
+			if err != nil {
+				return "", err
+			}
+			if last {
+				return value, nil
+			}
+			g.emit("bv_release(%s);", value)
+		case *ast.LetStatement:
+			if err := g.generateLetStatement(s); err != nil {
+				return "", err
+			}
+		case *ast.ReturnStatement:
+			if err := g.generateReturnStatement(s); err != nil {
+				return "", err
+			}
+		default:
+			return "", fmt.Errorf("%T is not supported by C backend", s)
+		}
+	}
+
+	return g.temp("bv_null()"), nil
+}
+
+// generateLetStatement - binds the value to the cell of the name
+func (g *generator) generateLetStatement(node *ast.LetStatement) error {
+	ident, ok := node.Pattern.(*ast.Identifier)
+	if !ok {
+		return fmt.Errorf("destructuring is not supported by C backend")
+	}
+
+	value, err := g.generateExpression(node.Value)
+	if err != nil {
+		return err
+	}
+
+	cell, err := g.resolve(ident.Value)
+	if err != nil {
+		return err
+	}
+	g.emit("bv_cell_set(%s, %s);", cell, value)
+	return nil
+}
+
+// generateReturnStatement - jumps to the end of the function (or program)
+// with the value
+func (g *generator) generateReturnStatement(node *ast.ReturnStatement) error {
+	value, err := g.generateExpression(node.ReturnValue)
+	if err != nil {
+		return err
+	}
+	g.emit("result = %s;", value)
+	g.emit("goto done;")
+	return nil
+}
+
+// generateExpression - generates statements evaluating the expression
+// in the order of the evaluator, returns C variable with the owned value
+func (g *generator) generateExpression(node ast.Expression) (string, error) {
+	switch node := node.(type) {
+	case *ast.IntegerLiteral:
+		return g.temp("bv_integer(INT64_C(%d))", node.Value), nil
+	case *ast.StringLiteral:
+		return g.temp("bv_string(%s, %d)", quote(node.Value), len(node.Value)), nil
+	case *ast.Boolean:
+		if node.Value {
+			return g.temp("bv_bool(1)"), nil
+		}
+		return g.temp("bv_bool(0)"), nil
+	case *ast.NullLiteral:
+		return g.temp("bv_null()"), nil
+	case *ast.Identifier:
+		if _, ok := g.scope.resolve(node.Value); !ok {
+			if builtin, ok := builtins[node.Value]; ok {
+				return g.temp("&%s", builtin), nil
+			}
+			if _, ok := object.GetBuiltinByName(node.Value); ok {
+				return "", fmt.Errorf("builtin %s is not supported by C backend", node.Value)
+			}
+			// unknown name fails only when it's evaluated, like in the evaluator
+			return g.temp("bv_name_error(%s)", quote(node.Value)), nil
+		}
+		cell, err := g.resolve(node.Value)
+		if err != nil {
+			return "", err
+		}
+		return g.temp("bv_cell_get(%s, %s)", cell, quote(node.Value)), nil
+	case *ast.PrefixExpression:
+		if node.Operator != "!" && node.Operator != "-" {
+			return "", fmt.Errorf("unknown operator %s", node.Operator)
+		}
+		right, err := g.generateExpression(node.Right)
+		if err != nil {
+			return "", err
+		}
+		return g.temp("bv_prefix(%s, %s)", quote(node.Operator), right), nil
+	case *ast.InfixExpression:
+		return g.generateInfixExpression(node)
+	case *ast.IfExpression:
+		return g.generateConditional(node.Condition, node.Consequence, node.Alternative)
+	case *ast.ConditionalExpression:
+		return g.generateConditional(node.Condition, node.Consequence, node.Alternative)
+	case *ast.ArrayLiteral:
+		elements, err := g.generateExpressions(node.Elements)
+		if err != nil {
+			return "", err
+		}
+		return g.temp("bv_array(%s)", variadic(elements)), nil
+	case *ast.HashLiteral:
+		values := []string{}
+		for _, pair := range node.Pairs {
+			if pair.Value == nil {
+				return "", fmt.Errorf("spread elements are not supported by C backend")
+			}
+			pairValues, err := g.generateExpressions([]ast.Expression{pair.Key, pair.Value})
+			if err != nil {
+				return "", err
+			}
+			values = append(values, pairValues...)
+		}
+		return g.temp("bv_hash(%d%s)", len(node.Pairs), list(values)), nil
+	case *ast.FunctionLiteral:
+		return g.generateFunctionLiteral(node)
+	case *ast.CallExpression:
+		return g.generateCallExpression(node)
+	default:
+		return "", fmt.Errorf("%T is not supported by C backend", node)
+	}
+}
+
+// generateInfixExpression - generates operands and applies the operator,
+// the right operand of `??` is evaluated only if the left one is null
+func (g *generator) generateInfixExpression(node *ast.InfixExpression) (string, error) {
+	switch node.Operator {
+	case "+", "-", "*", "/", "<", ">", "==", "!=", "??":
+	default:
+		return "", fmt.Errorf("unknown operator %s", node.Operator)
+	}
+
+	left, err := g.generateExpression(node.Left)
+	if err != nil {
+		return "", err
+	}
+
+	if node.Operator == "??" {
+		g.emit("if (bv_is_null(%s)) {", left)
+		g.scope.indent++
+		right, err := g.generateExpression(node.Right)
+		if err != nil {
+			return "", err
+		}
+		g.emit("bv_release(%s);", left)
+		g.emit("%s = %s;", left, right)
+		g.scope.indent--
+		g.emit("}")
+		return left, nil
+	}
+
+	right, err := g.generateExpression(node.Right)
+	if err != nil {
+		return "", err
+	}
+	return g.temp("bv_infix(%s, %s, %s)", quote(node.Operator), left, right), nil
+}
+
+// generateConditional - generates if/else and ternary expressions,
+// missing alternative produces null
+func (g *generator) generateConditional(
+	condition ast.Expression,
+	consequence ast.Node,
+	alternative ast.Node,
+) (string, error) {
+	cond, err := g.generateExpression(condition)
+	if err != nil {
+		return "", err
+	}
+
+	result := g.newTemp()
+	g.emit("bv_value *%s;", result)
+	g.emit("if (bv_truthy(%s)) {", cond)
+	if err := g.generateBranch(result, consequence); err != nil {
+		return "", err
+	}
+	g.emit("} else {")
+	if err := g.generateBranch(result, alternative); err != nil {
+		return "", err
+	}
+	g.emit("}")
+
+	return result, nil
+}
+
+// generateBranch - generates block or expression of the conditional
+// and stores its value in the result
+func (g *generator) generateBranch(result string, node ast.Node) error {
+	g.scope.indent++
+	defer func() { g.scope.indent-- }()
+
+	var value string
+	var err error
+
+	switch node := node.(type) {
+	case *ast.BlockStatement:
+		if node == nil {
+			value = g.temp("bv_null()")
+			break
+		}
+		value, err = g.generateStatements(node.Statements)
+	case ast.Expression:
+		value, err = g.generateExpression(node)
+	default:
+		value = g.temp("bv_null()")
+	}
+	if err != nil {
+		return err
+	}
+
+	g.emit("%s = %s;", result, value)
+	return nil
+}
+
+// generateFunctionLiteral - generates C function for the literal and
+// creates the closure capturing cells of the enclosing functions
+func (g *generator) generateFunctionLiteral(node *ast.FunctionLiteral) (string, error) {
+	if len(node.Defaults) > 0 || node.Rest != nil {
+		return "", fmt.Errorf("default and rest parameters are not supported by C backend")
+	}
+
+	g.numFunctions++
+	name := fmt.Sprintf("fn_%d", g.numFunctions)
+
+	fn := &scope{
+		outer:  g.scope,
+		locals: map[string]string{},
+		out:    &bytes.Buffer{},
+		indent: 1,
+	}
+	g.scope = fn
+
+	params := make([]string, len(node.Parameters))
+	for idx, p := range node.Parameters {
+		params[idx] = quote(p.Value)
+		fn.locals[p.Value] = "l_" + p.Value
+		g.emit("bv_cell *l_%s = bv_cell_new(args[%d]);", p.Value, idx)
+	}
+	for _, local := range collectLets(node.Body.Statements) {
+		if _, ok := fn.locals[local]; !ok {
+			fn.locals[local] = "l_" + local
+			g.emit("bv_cell *l_%s = bv_cell_new(NULL);", local)
+		}
+	}
+
+	value, err := g.generateStatements(node.Body.Statements)
+	g.scope = fn.outer
+	if err != nil {
+		return "", err
+	}
+
+	paramsName := "NULL"
+	if len(params) > 0 {
+		paramsName = name + "_params"
+		g.declarations.WriteString(fmt.Sprintf("static const char *%s[] = {%s};\n",
+			paramsName, strings.Join(params, ", ")))
+	}
+	g.declarations.WriteString(fmt.Sprintf(
+		"static bv_value *%s(bv_cell **cells, bv_value **args);\n", name))
+
+	g.functions.WriteString(fmt.Sprintf(
+		"static bv_value *%s(bv_cell **cells, bv_value **args) {\n", name))
+	g.functions.WriteString("\tbv_value *result;\n")
+	g.functions.WriteString("\t(void)cells;\n\t(void)args;\n")
+	g.functions.WriteString(fn.out.String())
+	g.functions.WriteString(fmt.Sprintf("\tresult = %s;\n", value))
+	g.functions.WriteString("\tgoto done;\n")
+	g.functions.WriteString("done:\n")
+	for _, local := range sortedLocals(fn.locals) {
+		g.functions.WriteString(fmt.Sprintf("\tbv_cell_release(%s);\n", local))
+	}
+	g.functions.WriteString("\treturn result;\n")
+	g.functions.WriteString("}\n\n")
+
+	cells := []string{}
+	for _, free := range fn.free {
+		cell, err := g.resolve(free)
+		if err != nil {
+			return "", err
+		}
+		cells = append(cells, cell)
+	}
+
+	return g.temp("bv_closure(%s, %d, %s, %d%s)",
+		name, len(params), paramsName, len(cells), list(cells)), nil
+}
+
+// generateCallExpression - generates the function and its positional
+// arguments and calls the function
+func (g *generator) generateCallExpression(node *ast.CallExpression) (string, error) {
+	if _, ok := node.Function.(*ast.MemberExpression); ok {
+		return "", fmt.Errorf("method calls are not supported by C backend")
+	}
+
+	fn, err := g.generateExpression(node.Function)
+	if err != nil {
+		return "", err
+	}
+
+	args, err := g.generateExpressions(node.Arguments)
+	if err != nil {
+		return "", err
+	}
+
+	if len(args) == 0 {
+		return g.temp("bv_call(%s, 0, NULL)", fn), nil
+	}
+
+	argsName := g.newTemp()
+	g.emit("bv_value *%s[] = {%s};", argsName, strings.Join(args, ", "))
+	return g.temp("bv_call(%s, %d, %s)", fn, len(args), argsName), nil
+}
+
+// generateExpressions - generates expressions in order, keyword arguments
+// and spread elements are not supported
+func (g *generator) generateExpressions(expressions []ast.Expression) ([]string, error) {
+	values := []string{}
+
+	for _, e := range expressions {
+		switch e.(type) {
+		case *ast.KeywordArgument:
+			return nil, fmt.Errorf("keyword arguments are not supported by C backend")
+		case *ast.SpreadElement:
+			return nil, fmt.Errorf("spread elements are not supported by C backend")
+		}
+
+		value, err := g.generateExpression(e)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+
+	return values, nil
+}
+
+// resolve - returns C expression of the cell of the binding, bindings of
+// the enclosing functions are captured by the closures of the functions
+// between them and the current one
+func (g *generator) resolve(name string) (string, error) {
+	cell, ok := g.scope.resolve(name)
+	if !ok {
+		return "", fmt.Errorf("identifier not found: %s", name)
+	}
+	return cell, nil
+}
+
+// resolve - searches the binding in the scope and its outer scopes
+func (s *scope) resolve(name string) (string, bool) {
+	if cell, ok := s.locals[name]; ok {
+		return cell, true
+	}
+	for idx, free := range s.free {
+		if free == name {
+			return fmt.Sprintf("cells[%d]", idx), true
+		}
+	}
+	if s.outer == nil {
+		return "", false
+	}
+
+	cell, ok := s.outer.resolve(name)
+	if !ok || strings.HasPrefix(cell, "g_") {
+		return cell, ok
+	}
+
+	s.free = append(s.free, name)
+	return fmt.Sprintf("cells[%d]", len(s.free)-1), true
+}
+
+// emit - writes the line of C code into the current function
+func (g *generator) emit(format string, a ...interface{}) {
+	g.scope.out.WriteString(strings.Repeat("\t", g.scope.indent))
+	g.scope.out.WriteString(fmt.Sprintf(format, a...))
+	g.scope.out.WriteString("\n")
+}
+
+// newTemp - returns name of the new C variable
+func (g *generator) newTemp() string {
+	g.numTemps++
+	return fmt.Sprintf("t%d", g.numTemps)
+}
+
+// temp - declares new C variable initialized with the expression
+func (g *generator) temp(format string, a ...interface{}) string {
+	name := g.newTemp()
+	g.emit("bv_value *%s = %s;", name, fmt.Sprintf(format, a...))
+	return name
+}
+
+// collectLets - returns names bound by let statements of the function
+// body (or the program) including nested blocks, but not nested functions
+func collectLets(statements []ast.Statement) []string {
+	names := []string{}
+	seen := map[string]bool{}
+
+	var walk func(node ast.Node)
+	walk = func(node ast.Node) {
+		switch node := node.(type) {
+		case *ast.LetStatement:
+			if ident, ok := node.Pattern.(*ast.Identifier); ok && !seen[ident.Value] {
+				seen[ident.Value] = true
+				names = append(names, ident.Value)
+			}
+			walk(node.Value)
+		case *ast.ExpressionStatement:
+			walk(node.Expression)
+		case *ast.ReturnStatement:
+			walk(node.ReturnValue)
+		case *ast.BlockStatement:
+			if node == nil {
+				return
+			}
+			for _, s := range node.Statements {
+				walk(s)
+			}
+		case *ast.IfExpression:
+			walk(node.Condition)
+			walk(node.Consequence)
+			walk(node.Alternative)
+		case *ast.ConditionalExpression:
+			walk(node.Condition)
+			walk(node.Consequence)
+			walk(node.Alternative)
+		case *ast.PrefixExpression:
+			walk(node.Right)
+		case *ast.InfixExpression:
+			walk(node.Left)
+			walk(node.Right)
+		case *ast.CallExpression:
+			walk(node.Function)
+			for _, arg := range node.Arguments {
+				walk(arg)
+			}
+		case *ast.ArrayLiteral:
+			for _, e := range node.Elements {
+				walk(e)
+			}
+		case *ast.HashLiteral:
+			for _, pair := range node.Pairs {
+				walk(pair.Key)
+				walk(pair.Value)
+			}
+		}
+	}
+
+	for _, s := range statements {
+		walk(s)
+	}
+	return names
+}
+
+// sortedLocals - returns C variables of the local cells in stable order
+func sortedLocals(locals map[string]string) []string {
+	cells := []string{}
+	for _, cell := range locals {
+		cells = append(cells, cell)
+	}
+	sort.Strings(cells)
+	return cells
+}
+
+// quote - returns C string literal of the string
+func quote(s string) string {
+	var out bytes.Buffer
+	out.WriteString("\"")
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"' || c == '\\':
+			out.WriteString("\\" + string(c))
+		case c < 0x20 || c >= 0x7f || c == '?':
+			// octal escapes avoid trigraphs and hex escapes
+			// swallowing the following digits
+			out.WriteString(fmt.Sprintf("\\%03o", c))
+		default:
+			out.WriteByte(c)
+		}
+	}
+	out.WriteString("\"")
+	return out.String()
+}
+
+// variadic - returns number of the values and the values as arguments
+// of the variadic C function
+func variadic(values []string) string {
+	return strconv.Itoa(len(values)) + list(values)
+}
+
+// list - returns the values as the tail of C argument list
+func list(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return ", " + strings.Join(values, ", ")
+}
@@ -0,0 +1,186 @@
+package cgen
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/technoboom/compiler/internal/testutil"
+)
+
+func TestGenerateErrors(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"let [a] = [1];", "destructuring is not supported by C backend"},
+		{"let f = (a) => a; f(a: 1)", "keyword arguments are not supported by C backend"},
+		{"[...[1]]", "spread elements are not supported by C backend"},
+		{"{...{}}", "spread elements are not supported by C backend"},
+		{`"a".upper()`, "method calls are not supported by C backend"},
+		{"(a = 1) => a", "default and rest parameters are not supported by C backend"},
+		{"throw 1", "*ast.ThrowStatement is not supported by C backend"},
+		{"struct P { x }", "*ast.StructStatement is not supported by C backend"},
+		{"tag(1)", "builtin tag is not supported by C backend"},
+	}
+
+	for _, tt := range tests {
+		_, err := Generate(testutil.Parse(tt.input))
+		if err == nil {
+			t.Errorf("expected error for %q, got none", tt.input)
+			continue
+		}
+		if err.Error() != tt.expectedError {
+			t.Errorf("wrong error for %q. want=%q, got=%q",
+				tt.input, tt.expectedError, err.Error())
+		}
+	}
+}
+
+func TestQuote(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"beaver", `"beaver"`},
+		{`say "hi"\`, `"say \"hi\"\\"`},
+		{"a\n1", `"a\0121"`},
+		{"??=", `"\077\077="`},
+	}
+
+	for _, tt := range tests {
+		if quoted := quote(tt.input); quoted != tt.expected {
+			t.Errorf("wrong quoted string. want=%s, got=%s", tt.expected, quoted)
+		}
+	}
+}
+
+// TestNativeEquivalence - builds programs into executables and checks
+// that they print the same results and errors as the evaluator
+func TestNativeEquivalence(t *testing.T) {
+	if _, err := exec.LookPath(compilerCommand()); err != nil {
+		t.Skipf("C compiler is not available: %s", err)
+	}
+
+	inputs := []string{
+		"5 + 5 * 2 - 10 / 5",
+		"-(3 * 3) + 50 / 2",
+		"1 < 2 == true",
+		"!5; !!null",
+		`"beaver"`,
+		"\"multi\nline ??=\"",
+		`"a" == "a"`,
+		"1 == true",
+		"null == null",
+		"[1, \"two\", true, null, [3], {}]",
+		`{"a": 1, 2: "b", true: [3], "a": 4}`,
+		"if (1 > 2) { 10 }",
+		"if (null) { 10 } else { let x = 5; x * 2 }",
+		"1 < 2 ? \"yes\" : \"no\"",
+		"null ?? false ?? 3",
+		"let a = 5; let b = a; let a = 7; a + b",
+		"let x = 1;",
+		"let identity = (x) => x; identity(5)",
+		"let add = function(x, y) { x + y; }; add(5 + 5, add(5, 5))",
+		"function(x) { x; }(5)",
+		"9; return 2 * 5; 9;",
+		"if (10 > 1) { if (10 > 1) { return 10; } return 1; }",
+		"let f = (x) => { if (x) { return [x] }; [] }; [f(1), f(false)]",
+		"let fib = (n) => n < 2 ? n : fib(n - 1) + fib(n - 2); fib(20)",
+		"let newAdder = (a) => (b) => a + b; let addTwo = newAdder(2); addTwo(3)",
+		"let make = (x) => () => x; let a = make(1); let b = make(2); [a(), b()]",
+		"let f = (a) => (b) => (c) => a * 100 + b * 10 + c; f(1)(2)(3)",
+		"let f = () => { let x = 1; let g = () => x; let x = 2; g() }; f()",
+		"let f = () => { let g = () => x; let x = 5; g() }; f()",
+		`let f = () => {
+			let even = (n) => n == 0 ? true : odd(n - 1);
+			let odd = (n) => n == 0 ? false : even(n - 1);
+			[even(10), odd(7)]
+		};
+		f()`,
+		"let f = () => { let loop = (n) => n == 0 ? 0 : loop(n - 1); loop(100) }; f()",
+		"let g = 10; let f = () => g; let g = 20; f()",
+		"5 + true; 5;",
+		"-true",
+		`"a" - "b"`,
+		"let f = () => { 1 + null }; [1, f()]",
+		"let f = (a) => a; f(1, 2)",
+		"let f = (a, b) => a; f(1)",
+		"1(2)",
+		"{[1]: 2}",
+		"let f = () => { x }; let r = f(); let x = 1;",
+		// unknown names fail only when they are evaluated
+		"a",
+		"let f = () => b; 1",
+		"if (false) { unknown }",
+		"let f = () => [1, b]; puts(1); f()",
+		// output of puts is followed by the result or the error
+		`puts(1, "two", [3], {"a": null}); puts()`,
+		"let f = (x) => { puts(x); x * 2 }; puts(f(1) + f(2))",
+		"puts; let p = puts; [p == puts, p(1)]",
+		"let puts = (x) => x * 2; puts(21)",
+		`puts("before"); -true`,
+	}
+
+	testNativeEquivalence(t, inputs)
+}
+
+// TestNativeIntegerOverflow - checks that integers wrap around on overflow
+// like in the evaluator instead of undefined behaviour of C
+func TestNativeIntegerOverflow(t *testing.T) {
+	if _, err := exec.LookPath(compilerCommand()); err != nil {
+		t.Skipf("C compiler is not available: %s", err)
+	}
+
+	inputs := []string{
+		"9223372036854775807 + 1",
+		"-9223372036854775807 - 2",
+		"4611686018427387904 * 2",
+		"3037000500 * 3037000500",
+		"let min = -9223372036854775807 - 1; [min / -1, -min, min * -1, min / 1]",
+		"let f = (n, k) => k == 0 ? n : f(n * 1000003 - 7, k - 1); f(1, 50)",
+	}
+
+	testNativeEquivalence(t, inputs)
+}
+
+// testNativeEquivalence - builds programs into executables and compares
+// their output with results of the evaluator
+func testNativeEquivalence(t *testing.T, inputs []string) {
+	dir, err := ioutil.TempDir("", "cgen")
+	if err != nil {
+		t.Fatalf("cannot create directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	for idx, input := range inputs {
+		expected := testutil.Evaluate(input)
+
+		source, err := Generate(testutil.Parse(input))
+		if err != nil {
+			t.Errorf("generation error for %q: %s", input, err)
+			continue
+		}
+
+		executable := filepath.Join(dir, fmt.Sprintf("program%d", idx))
+		if err := Build(source, executable); err != nil {
+			t.Fatalf("build error for %q: %s", input, err)
+		}
+
+		var stdout, stderr bytes.Buffer
+		cmd := exec.Command(executable)
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		cmd.Run()
+
+		actual := strings.TrimSuffix(stdout.String()+stderr.String(), "\n")
+		if actual != expected {
+			t.Errorf("results differ for %q. evaluator=%q, native=%q", input, expected, actual)
+		}
+	}
+}
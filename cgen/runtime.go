@@ -0,0 +1,494 @@
+package cgen
+
+// runtime - C runtime included into every generated program: tagged
+// values with reference counting, cells of variables shared by closures,
+// operators with the same rules and errors as the evaluator
+const runtime = `#include <stdarg.h>
+#include <stdint.h>
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+
+typedef enum {
+	BV_NULL,
+	BV_BOOLEAN,
+	BV_INTEGER,
+	BV_STRING,
+	BV_ARRAY,
+	BV_HASH,
+	BV_FUNCTION,
+	BV_BUILTIN
+} bv_type;
+
+typedef struct bv_value bv_value;
+typedef struct bv_cell bv_cell;
+
+/* compiled function: receives cells captured by the closure and owned
+   arguments, returns owned result */
+typedef bv_value *(*bv_fn)(bv_cell **free, bv_value **args);
+
+/* builtin function: receives any number of owned arguments, returns
+   owned result */
+typedef bv_value *(*bv_builtin_fn)(int argc, bv_value **args);
+
+/* variable, values of unset variables are NULL */
+struct bv_cell {
+	long refs;
+	bv_value *value;
+};
+
+/* value, immortal values have negative reference count */
+struct bv_value {
+	bv_type type;
+	long refs;
+	union {
+		int boolean;
+		int64_t integer;
+		struct {
+			size_t len;
+			char *data;
+		} string;
+		struct {
+			size_t len;
+			bv_value **items;
+		} array;
+		struct {
+			size_t len;
+			bv_value **keys;
+			bv_value **values;
+		} hash;
+		struct {
+			bv_fn fn;
+			int nparams;
+			const char **params;
+			int nfree;
+			bv_cell **free;
+		} function;
+		bv_builtin_fn builtin;
+	} as;
+};
+
+static bv_value bv_null_value = {BV_NULL, -1, {0}};
+static bv_value bv_true_value = {BV_BOOLEAN, -1, {1}};
+static bv_value bv_false_value = {BV_BOOLEAN, -1, {0}};
+
+static const char *bv_type_names[] = {
+	"NULL", "BOOLEAN", "INTEGER", "STRING", "ARRAY", "HASH", "FUNCTION", "BUILTIN"
+};
+
+static void bv_fail(const char *kind, const char *format, ...) {
+	va_list args;
+	/* the error follows the output printed before it */
+	fflush(stdout);
+	fprintf(stderr, "%s: ", kind);
+	va_start(args, format);
+	vfprintf(stderr, format, args);
+	va_end(args);
+	fprintf(stderr, "\n");
+	exit(1);
+}
+
+/* reports the name which isn't bound anywhere, never returns */
+static bv_value *bv_name_error(const char *name) {
+	bv_fail("NameError", "identifier not found: %s", name);
+	return NULL;
+}
+
+static void *bv_alloc(size_t size) {
+	void *ptr = calloc(1, size > 0 ? size : 1);
+	if (ptr == NULL) {
+		bv_fail("Error", "out of memory");
+	}
+	return ptr;
+}
+
+static bv_value *bv_new(bv_type type) {
+	bv_value *v = bv_alloc(sizeof(bv_value));
+	v->type = type;
+	v->refs = 1;
+	return v;
+}
+
+static bv_value *bv_retain(bv_value *v) {
+	if (v->refs >= 0) {
+		v->refs++;
+	}
+	return v;
+}
+
+static void bv_cell_release(bv_cell *c);
+
+static void bv_release(bv_value *v) {
+	size_t i;
+	int j;
+
+	if (v->refs < 0 || --v->refs > 0) {
+		return;
+	}
+
+	switch (v->type) {
+	case BV_STRING:
+		free(v->as.string.data);
+		break;
+	case BV_ARRAY:
+		for (i = 0; i < v->as.array.len; i++) {
+			bv_release(v->as.array.items[i]);
+		}
+		free(v->as.array.items);
+		break;
+	case BV_HASH:
+		for (i = 0; i < v->as.hash.len; i++) {
+			bv_release(v->as.hash.keys[i]);
+			bv_release(v->as.hash.values[i]);
+		}
+		free(v->as.hash.keys);
+		free(v->as.hash.values);
+		break;
+	case BV_FUNCTION:
+		for (j = 0; j < v->as.function.nfree; j++) {
+			bv_cell_release(v->as.function.free[j]);
+		}
+		free(v->as.function.free);
+		break;
+	default:
+		break;
+	}
+	free(v);
+}
+
+static bv_value *bv_null(void) {
+	return &bv_null_value;
+}
+
+static bv_value *bv_bool(int value) {
+	return value ? &bv_true_value : &bv_false_value;
+}
+
+static bv_value *bv_integer(int64_t value) {
+	bv_value *v = bv_new(BV_INTEGER);
+	v->as.integer = value;
+	return v;
+}
+
+static bv_value *bv_string(const char *data, size_t len) {
+	bv_value *v = bv_new(BV_STRING);
+	v->as.string.data = bv_alloc(len + 1);
+	memcpy(v->as.string.data, data, len);
+	v->as.string.len = len;
+	return v;
+}
+
+/* creates array of n owned values */
+static bv_value *bv_array(int n, ...) {
+	va_list args;
+	int i;
+	bv_value *v = bv_new(BV_ARRAY);
+
+	v->as.array.items = bv_alloc(n * sizeof(bv_value *));
+	v->as.array.len = n;
+
+	va_start(args, n);
+	for (i = 0; i < n; i++) {
+		v->as.array.items[i] = va_arg(args, bv_value *);
+	}
+	va_end(args);
+	return v;
+}
+
+static int bv_equal(bv_value *left, bv_value *right) {
+	if (left->type != right->type) {
+		return 0;
+	}
+
+	switch (left->type) {
+	case BV_NULL:
+		return 1;
+	case BV_BOOLEAN:
+		return left->as.boolean == right->as.boolean;
+	case BV_INTEGER:
+		return left->as.integer == right->as.integer;
+	case BV_STRING:
+		return left->as.string.len == right->as.string.len &&
+			memcmp(left->as.string.data, right->as.string.data, left->as.string.len) == 0;
+	default:
+		return left == right;
+	}
+}
+
+/* creates hash of n owned pairs passed as keys followed by values,
+   repeated key replaces the value and keeps its position */
+static bv_value *bv_hash(int n, ...) {
+	va_list args;
+	int i;
+	size_t j;
+	bv_value *v = bv_new(BV_HASH);
+
+	v->as.hash.keys = bv_alloc(n * sizeof(bv_value *));
+	v->as.hash.values = bv_alloc(n * sizeof(bv_value *));
+
+	va_start(args, n);
+	for (i = 0; i < n; i++) {
+		bv_value *key = va_arg(args, bv_value *);
+		bv_value *value = va_arg(args, bv_value *);
+
+		if (key->type != BV_INTEGER && key->type != BV_STRING && key->type != BV_BOOLEAN) {
+			bv_fail("TypeError", "unusable as hash key: %s", bv_type_names[key->type]);
+		}
+
+		for (j = 0; j < v->as.hash.len; j++) {
+			if (bv_equal(v->as.hash.keys[j], key)) {
+				break;
+			}
+		}
+		if (j < v->as.hash.len) {
+			bv_release(key);
+			bv_release(v->as.hash.values[j]);
+			v->as.hash.values[j] = value;
+			continue;
+		}
+
+		v->as.hash.keys[v->as.hash.len] = key;
+		v->as.hash.values[v->as.hash.len] = value;
+		v->as.hash.len++;
+	}
+	va_end(args);
+	return v;
+}
+
+static bv_cell *bv_cell_new(bv_value *value) {
+	bv_cell *c = bv_alloc(sizeof(bv_cell));
+	c->refs = 1;
+	c->value = value;
+	return c;
+}
+
+static bv_cell *bv_cell_retain(bv_cell *c) {
+	c->refs++;
+	return c;
+}
+
+static void bv_cell_release(bv_cell *c) {
+	if (--c->refs > 0) {
+		return;
+	}
+	if (c->value != NULL) {
+		bv_release(c->value);
+	}
+	free(c);
+}
+
+/* returns owned value of the variable */
+static bv_value *bv_cell_get(bv_cell *c, const char *name) {
+	if (c->value == NULL) {
+		bv_fail("NameError", "identifier not found: %s", name);
+	}
+	return bv_retain(c->value);
+}
+
+/* binds the owned value to the variable */
+static void bv_cell_set(bv_cell *c, bv_value *value) {
+	if (c->value != NULL) {
+		bv_release(c->value);
+	}
+	c->value = value;
+}
+
+/* creates closure capturing nfree cells */
+static bv_value *bv_closure(bv_fn fn, int nparams, const char **params, int nfree, ...) {
+	va_list args;
+	int i;
+	bv_value *v = bv_new(BV_FUNCTION);
+
+	v->as.function.fn = fn;
+	v->as.function.nparams = nparams;
+	v->as.function.params = params;
+	v->as.function.nfree = nfree;
+	v->as.function.free = bv_alloc(nfree * sizeof(bv_cell *));
+
+	va_start(args, nfree);
+	for (i = 0; i < nfree; i++) {
+		v->as.function.free[i] = bv_cell_retain(va_arg(args, bv_cell *));
+	}
+	va_end(args);
+	return v;
+}
+
+/* calls the owned function with owned arguments */
+static bv_value *bv_call(bv_value *fn, int argc, bv_value **args) {
+	bv_value *result;
+
+	if (fn->type == BV_BUILTIN) {
+		return fn->as.builtin(argc, args);
+	}
+	if (fn->type != BV_FUNCTION) {
+		bv_fail("TypeError", "not a function: %s", bv_type_names[fn->type]);
+	}
+	if (argc > fn->as.function.nparams) {
+		bv_fail("ArgumentError", "wrong number of arguments: want=%d, got=%d",
+			fn->as.function.nparams, argc);
+	}
+	if (argc < fn->as.function.nparams) {
+		bv_fail("ArgumentError", "missing argument: %s", fn->as.function.params[argc]);
+	}
+
+	result = fn->as.function.fn(fn->as.function.free, args);
+	bv_release(fn);
+	return result;
+}
+
+/* checks truthiness of the owned value */
+static int bv_truthy(bv_value *v) {
+	int result = v->type != BV_NULL && !(v->type == BV_BOOLEAN && !v->as.boolean);
+	bv_release(v);
+	return result;
+}
+
+static int bv_is_null(bv_value *v) {
+	return v->type == BV_NULL;
+}
+
+/* applies prefix operator to the owned operand */
+static bv_value *bv_prefix(const char *op, bv_value *right) {
+	bv_value *result;
+
+	if (strcmp(op, "!") == 0) {
+		return bv_bool(!bv_truthy(right));
+	}
+
+	if (right->type != BV_INTEGER) {
+		bv_fail("TypeError", "unknown operator: %s%s", op, bv_type_names[right->type]);
+	}
+	/* negation wraps around like in Go: -INT64_MIN == INT64_MIN */
+	result = bv_integer((int64_t)(0 - (uint64_t)right->as.integer));
+	bv_release(right);
+	return result;
+}
+
+/* applies infix operator to the owned operands */
+static bv_value *bv_infix(const char *op, bv_value *left, bv_value *right) {
+	bv_value *result = NULL;
+
+	if (left->type == BV_INTEGER && right->type == BV_INTEGER) {
+		int64_t l = left->as.integer, r = right->as.integer;
+		/* signed overflow is undefined in C, so the arithmetic is done on
+		   unsigned integers, which wrap around like int64 of Go */
+		switch (op[0]) {
+		case '+':
+			result = bv_integer((int64_t)((uint64_t)l + (uint64_t)r));
+			break;
+		case '-':
+			result = bv_integer((int64_t)((uint64_t)l - (uint64_t)r));
+			break;
+		case '*':
+			result = bv_integer((int64_t)((uint64_t)l * (uint64_t)r));
+			break;
+		case '/':
+			if (r == 0) {
+				bv_fail("Error", "division by zero");
+			}
+			/* INT64_MIN / -1 overflows (SIGFPE on x86), Go returns INT64_MIN */
+			if (r == -1) {
+				result = bv_integer((int64_t)(0 - (uint64_t)l));
+			} else {
+				result = bv_integer(l / r);
+			}
+			break;
+		case '<':
+			result = bv_bool(l < r);
+			break;
+		case '>':
+			result = bv_bool(l > r);
+			break;
+		case '=':
+			result = bv_bool(l == r);
+			break;
+		case '!':
+			result = bv_bool(l != r);
+			break;
+		}
+	} else if (strcmp(op, "==") == 0) {
+		result = bv_bool(bv_equal(left, right));
+	} else if (strcmp(op, "!=") == 0) {
+		result = bv_bool(!bv_equal(left, right));
+	} else if (left->type != right->type) {
+		bv_fail("TypeError", "type mismatch: %s %s %s",
+			bv_type_names[left->type], op, bv_type_names[right->type]);
+	} else {
+		bv_fail("TypeError", "unknown operator: %s %s %s",
+			bv_type_names[left->type], op, bv_type_names[right->type]);
+	}
+
+	bv_release(left);
+	bv_release(right);
+	return result;
+}
+
+static void bv_inspect(FILE *out, bv_value *v) {
+	size_t i;
+	int j;
+
+	switch (v->type) {
+	case BV_NULL:
+		fputs("null", out);
+		break;
+	case BV_BOOLEAN:
+		fputs(v->as.boolean ? "true" : "false", out);
+		break;
+	case BV_INTEGER:
+		fprintf(out, "%lld", (long long)v->as.integer);
+		break;
+	case BV_STRING:
+		fwrite(v->as.string.data, 1, v->as.string.len, out);
+		break;
+	case BV_ARRAY:
+		fputs("[", out);
+		for (i = 0; i < v->as.array.len; i++) {
+			if (i > 0) {
+				fputs(", ", out);
+			}
+			bv_inspect(out, v->as.array.items[i]);
+		}
+		fputs("]", out);
+		break;
+	case BV_HASH:
+		fputs("{", out);
+		for (i = 0; i < v->as.hash.len; i++) {
+			if (i > 0) {
+				fputs(", ", out);
+			}
+			bv_inspect(out, v->as.hash.keys[i]);
+			fputs(": ", out);
+			bv_inspect(out, v->as.hash.values[i]);
+		}
+		fputs("}", out);
+		break;
+	case BV_FUNCTION:
+		fputs("function(", out);
+		for (j = 0; j < v->as.function.nparams; j++) {
+			if (j > 0) {
+				fputs(", ", out);
+			}
+			fputs(v->as.function.params[j], out);
+		}
+		fputs(") { <native> }", out);
+		break;
+	case BV_BUILTIN:
+		fputs("builtin function", out);
+		break;
+	}
+}
+
+/* puts: prints the owned arguments on separate lines, returns null */
+static bv_value *bv_puts(int argc, bv_value **args) {
+	int i;
+
+	for (i = 0; i < argc; i++) {
+		bv_inspect(stdout, args[i]);
+		fputs("\n", stdout);
+		bv_release(args[i]);
+	}
+	return bv_null();
+}
+
+static bv_value bv_puts_value = {BV_BUILTIN, -1, {.builtin = bv_puts}};
+`
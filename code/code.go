@@ -0,0 +1,271 @@
+// Package code - defines bytecode instructions of the Beaver virtual machine
+package code
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// Instructions - sequence of encoded instructions: each instruction is
+// an opcode followed by its operands
+type Instructions []byte
+
+// String - returns human-readable disassembly of the instructions,
+// one instruction per line prefixed with its offset
+func (ins Instructions) String() string {
+	var out bytes.Buffer
+
+	i := 0
+	for i < len(ins) {
+		def, err := Lookup(ins[i])
+		if err != nil {
+			fmt.Fprintf(&out, "ERROR: %s\n", err)
+			i++
+			continue
+		}
+
+		if i+1+def.operandsWidth() > len(ins) {
+			fmt.Fprintf(&out, "%04d ERROR: truncated %s\n", i, def.Name)
+			break
+		}
+
+		operands, read := ReadOperands(def, ins[i+1:])
+		fmt.Fprintf(&out, "%04d %s\n", i, ins.fmtInstruction(def, operands))
+
+		i += 1 + read
+	}
+
+	return out.String()
+}
+
+// fmtInstruction - formats single instruction: name of the opcode and
+// its operands
+func (ins Instructions) fmtInstruction(def *Definition, operands []int) string {
+	operandCount := len(def.OperandWidths)
+
+	if len(operands) != operandCount {
+		return fmt.Sprintf("ERROR: operand len %d does not match defined %d",
+			len(operands), operandCount)
+	}
+
+	switch operandCount {
+	case 0:
+		return def.Name
+	case 1:
+		return fmt.Sprintf("%s %d", def.Name, operands[0])
+	case 2:
+		return fmt.Sprintf("%s %d %d", def.Name, operands[0], operands[1])
+	}
+
+	return fmt.Sprintf("ERROR: unhandled operandCount for %s", def.Name)
+}
+
+// Opcode - the first byte of the instruction which defines the operation
+type Opcode byte
+
+const (
+	// OpConstant - pushes the constant from the pool by its index
+	OpConstant Opcode = iota
+	// OpPop - pops the top of the stack
+	OpPop
+
+	// OpAdd - pops two values and pushes their sum
+	OpAdd
+	// OpSub - pops two values and pushes their difference
+	OpSub
+	// OpMul - pops two values and pushes their product
+	OpMul
+	// OpDiv - pops two values and pushes their quotient
+	OpDiv
+
+	// OpTrue - pushes true
+	OpTrue
+	// OpFalse - pushes false
+	OpFalse
+	// OpNull - pushes null
+	OpNull
+
+	// OpEqual - pops two values and pushes result of `==`
+	OpEqual
+	// OpNotEqual - pops two values and pushes result of `!=`
+	OpNotEqual
+	// OpGreaterThan - pops two values and pushes result of `>`
+	OpGreaterThan
+	// OpLessThan - pops two values and pushes result of `<`
+	OpLessThan
+
+	// OpMinus - negates the top of the stack
+	OpMinus
+	// OpBang - replaces the top of the stack with its logical negation
+	OpBang
+
+	// OpJump - jumps to the absolute offset
+	OpJump
+	// OpJumpNotTruthy - pops the condition and jumps to the absolute offset
+	// if the condition is not truthy
+	OpJumpNotTruthy
+	// OpJumpNotNull - jumps to the absolute offset keeping the top of the
+	// stack if it's not null, otherwise pops it
+	OpJumpNotNull
+
+	// OpGetGlobal - pushes the global binding by its index
+	OpGetGlobal
+	// OpSetGlobal - pops the value into the global binding by its index
+	OpSetGlobal
+	// OpGetLocal - pushes the local binding of the current frame
+	OpGetLocal
+	// OpSetLocal - pops the value into the local binding of the current frame
+	OpSetLocal
+	// OpGetBuiltin - pushes the builtin function by its index
+	OpGetBuiltin
+	// OpGetFree - pushes the value of the free variable of the current closure
+	OpGetFree
+	// OpGetLocalCell - pushes the cell of the local binding of the current
+	// frame to capture it by the closure
+	OpGetLocalCell
+	// OpGetFreeCell - pushes the cell of the free variable of the current
+	// closure to capture it by the nested closure
+	OpGetFreeCell
+	// OpClosure - pops the given number of cells and pushes the closure over
+	// the compiled function from the constant pool
+	OpClosure
+
+	// OpArray - pops the given number of elements and pushes the array
+	OpArray
+	// OpHash - pops the given number of keys and values (twice the number
+	// of pairs) and pushes the hash
+	OpHash
+
+	// OpCall - calls the function below the given number of arguments
+	OpCall
+	// OpReturnValue - returns the top of the stack from the function
+	OpReturnValue
+	// OpReturn - returns null from the function
+	OpReturn
+)
+
+// Definition - describes opcode: its readable name and widths
+// of the operands in bytes
+type Definition struct {
+	Name          string
+	OperandWidths []int
+}
+
+// operandsWidth - returns total width of the operands in bytes
+func (def *Definition) operandsWidth() int {
+	width := 0
+	for _, w := range def.OperandWidths {
+		width += w
+	}
+	return width
+}
+
+var definitions = map[Opcode]*Definition{
+	OpConstant: {"OpConstant", []int{2}},
+	OpPop:      {"OpPop", []int{}},
+
+	OpAdd: {"OpAdd", []int{}},
+	OpSub: {"OpSub", []int{}},
+	OpMul: {"OpMul", []int{}},
+	OpDiv: {"OpDiv", []int{}},
+
+	OpTrue:  {"OpTrue", []int{}},
+	OpFalse: {"OpFalse", []int{}},
+	OpNull:  {"OpNull", []int{}},
+
+	OpEqual:       {"OpEqual", []int{}},
+	OpNotEqual:    {"OpNotEqual", []int{}},
+	OpGreaterThan: {"OpGreaterThan", []int{}},
+	OpLessThan:    {"OpLessThan", []int{}},
+
+	OpMinus: {"OpMinus", []int{}},
+	OpBang:  {"OpBang", []int{}},
+
+	OpJump:          {"OpJump", []int{2}},
+	OpJumpNotTruthy: {"OpJumpNotTruthy", []int{2}},
+	OpJumpNotNull:   {"OpJumpNotNull", []int{2}},
+
+	OpGetGlobal:  {"OpGetGlobal", []int{2}},
+	OpSetGlobal:  {"OpSetGlobal", []int{2}},
+	OpGetLocal:   {"OpGetLocal", []int{1}},
+	OpSetLocal:   {"OpSetLocal", []int{1}},
+	OpGetBuiltin: {"OpGetBuiltin", []int{1}},
+
+	OpGetFree:      {"OpGetFree", []int{1}},
+	OpGetLocalCell: {"OpGetLocalCell", []int{1}},
+	OpGetFreeCell:  {"OpGetFreeCell", []int{1}},
+	OpClosure:      {"OpClosure", []int{2, 1}},
+
+	OpArray: {"OpArray", []int{2}},
+	OpHash:  {"OpHash", []int{2}},
+
+	OpCall:        {"OpCall", []int{1}},
+	OpReturnValue: {"OpReturnValue", []int{}},
+	OpReturn:      {"OpReturn", []int{}},
+}
+
+// Lookup - returns definition of the opcode
+func Lookup(op byte) (*Definition, error) {
+	def, ok := definitions[Opcode(op)]
+	if !ok {
+		return nil, fmt.Errorf("opcode %d undefined", op)
+	}
+
+	return def, nil
+}
+
+// Make - encodes the instruction: the opcode followed by operands in
+// big-endian order. Returns empty instruction for unknown opcodes
+func Make(op Opcode, operands ...int) []byte {
+	def, ok := definitions[op]
+	if !ok {
+		return []byte{}
+	}
+
+	instruction := make([]byte, 1+def.operandsWidth())
+	instruction[0] = byte(op)
+
+	offset := 1
+	for i, o := range operands {
+		width := def.OperandWidths[i]
+		switch width {
+		case 2:
+			binary.BigEndian.PutUint16(instruction[offset:], uint16(o))
+		case 1:
+			instruction[offset] = byte(o)
+		}
+		offset += width
+	}
+
+	return instruction
+}
+
+// ReadOperands - decodes operands of the instruction described by the
+// definition, returns operands and number of bytes read
+func ReadOperands(def *Definition, ins Instructions) ([]int, int) {
+	operands := make([]int, len(def.OperandWidths))
+	offset := 0
+
+	for i, width := range def.OperandWidths {
+		switch width {
+		case 2:
+			operands[i] = int(ReadUint16(ins[offset:]))
+		case 1:
+			operands[i] = int(ReadUint8(ins[offset:]))
+		}
+		offset += width
+	}
+
+	return operands, offset
+}
+
+// ReadUint16 - decodes two-byte operand
+func ReadUint16(ins Instructions) uint16 {
+	return binary.BigEndian.Uint16(ins)
+}
+
+// ReadUint8 - decodes one-byte operand
+func ReadUint8(ins Instructions) uint8 {
+	return uint8(ins[0])
+}
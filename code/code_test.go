@@ -0,0 +1,209 @@
+package code
+
+import "testing"
+
+func TestMake(t *testing.T) {
+	tests := []struct {
+		op       Opcode
+		operands []int
+		expected []byte
+	}{
+		{OpConstant, []int{65534}, []byte{byte(OpConstant), 255, 254}},
+		{OpAdd, []int{}, []byte{byte(OpAdd)}},
+		{OpGetLocal, []int{255}, []byte{byte(OpGetLocal), 255}},
+		{OpJump, []int{258}, []byte{byte(OpJump), 1, 2}},
+		{OpClosure, []int{65534, 255}, []byte{byte(OpClosure), 255, 254, 255}},
+	}
+
+	for _, tt := range tests {
+		instruction := Make(tt.op, tt.operands...)
+
+		if len(instruction) != len(tt.expected) {
+			t.Errorf("instruction has wrong length. want=%d, got=%d",
+				len(tt.expected), len(instruction))
+			continue
+		}
+
+		for i, b := range tt.expected {
+			if instruction[i] != tt.expected[i] {
+				t.Errorf("wrong byte at pos %d. want=%d, got=%d",
+					i, b, instruction[i])
+			}
+		}
+	}
+}
+
+func TestMakeUnknownOpcode(t *testing.T) {
+	if instruction := Make(Opcode(255)); len(instruction) != 0 {
+		t.Errorf("instruction for unknown opcode is not empty. got=%v", instruction)
+	}
+}
+
+func TestInstructionsString(t *testing.T) {
+	instructions := []Instructions{
+		Make(OpAdd),
+		Make(OpGetLocal, 1),
+		Make(OpConstant, 2),
+		Make(OpConstant, 65535),
+		Make(OpJumpNotTruthy, 7),
+		Make(OpCall, 3),
+		Make(OpClosure, 65535, 255),
+	}
+
+	expected := `0000 OpAdd
+0001 OpGetLocal 1
+0003 OpConstant 2
+0006 OpConstant 65535
+0009 OpJumpNotTruthy 7
+0012 OpCall 3
+0014 OpClosure 65535 255
+`
+
+	concatted := Instructions{}
+	for _, ins := range instructions {
+		concatted = append(concatted, ins...)
+	}
+
+	if concatted.String() != expected {
+		t.Errorf("instructions wrongly formatted.\nwant=%q\ngot=%q",
+			expected, concatted.String())
+	}
+}
+
+func TestInstructionsStringErrors(t *testing.T) {
+	tests := []struct {
+		instructions Instructions
+		expected     string
+	}{
+		{Instructions{255}, "ERROR: opcode 255 undefined\n"},
+		{Instructions{byte(OpPop), byte(OpConstant), 1},
+			"0000 OpPop\n0001 ERROR: truncated OpConstant\n"},
+	}
+
+	for _, tt := range tests {
+		if tt.instructions.String() != tt.expected {
+			t.Errorf("instructions wrongly formatted.\nwant=%q\ngot=%q",
+				tt.expected, tt.instructions.String())
+		}
+	}
+}
+
+func TestReadOperands(t *testing.T) {
+	tests := []struct {
+		op        Opcode
+		operands  []int
+		bytesRead int
+	}{
+		{OpConstant, []int{65535}, 2},
+		{OpGetLocal, []int{255}, 1},
+		{OpPop, []int{}, 0},
+		{OpClosure, []int{65535, 255}, 3},
+	}
+
+	for _, tt := range tests {
+		instruction := Make(tt.op, tt.operands...)
+
+		def, err := Lookup(byte(tt.op))
+		if err != nil {
+			t.Fatalf("definition not found: %q\n", err)
+		}
+
+		operandsRead, n := ReadOperands(def, instruction[1:])
+		if n != tt.bytesRead {
+			t.Fatalf("n wrong. want=%d, got=%d", tt.bytesRead, n)
+		}
+
+		for i, want := range tt.operands {
+			if operandsRead[i] != want {
+				t.Errorf("operand wrong. want=%d, got=%d", want, operandsRead[i])
+			}
+		}
+	}
+}
+
+// TestRoundTrip - encodes every defined opcode with boundary operand values
+// and checks that decoding returns the same opcode and operands
+func TestRoundTrip(t *testing.T) {
+	for op, def := range definitions {
+		for _, value := range []int{0, 1, 127, 255, 256, 65535} {
+			operands := make([]int, len(def.OperandWidths))
+			for i, width := range def.OperandWidths {
+				// values are truncated to the width of the operand
+				operands[i] = value & (1<<(8*uint(width)) - 1)
+			}
+
+			instruction := Make(op, operands...)
+			if len(instruction) != 1+def.operandsWidth() {
+				t.Fatalf("%s: instruction has wrong length. want=%d, got=%d",
+					def.Name, 1+def.operandsWidth(), len(instruction))
+			}
+
+			decoded, err := Lookup(instruction[0])
+			if err != nil {
+				t.Fatalf("%s: %s", def.Name, err)
+			}
+			if decoded != def {
+				t.Fatalf("%s: decoded wrong definition %s", def.Name, decoded.Name)
+			}
+
+			operandsRead, n := ReadOperands(decoded, instruction[1:])
+			if n != def.operandsWidth() {
+				t.Fatalf("%s: n wrong. want=%d, got=%d", def.Name, def.operandsWidth(), n)
+			}
+			for i, want := range operands {
+				if operandsRead[i] != want {
+					t.Errorf("%s: operand %d wrong. want=%d, got=%d",
+						def.Name, i, want, operandsRead[i])
+				}
+			}
+		}
+	}
+}
+
+// TestDefinitionsUnique - checks that every opcode has its own name
+func TestDefinitionsUnique(t *testing.T) {
+	names := make(map[string]Opcode)
+
+	for op, def := range definitions {
+		if other, ok := names[def.Name]; ok {
+			t.Errorf("opcodes %d and %d have the same name %s", other, op, def.Name)
+		}
+		names[def.Name] = op
+	}
+}
+
+func TestDebugInfoLines(t *testing.T) {
+	debug := &DebugInfo{}
+	debug.AddLine(0, 1)
+	debug.AddLine(3, 1)
+	debug.AddLine(5, 2)
+	debug.AddLine(9, 4)
+	// instructions after the offset 7 were removed
+	debug.AddLine(7, 3)
+
+	expected := []LineEntry{{0, 1}, {5, 2}, {7, 3}}
+	if len(debug.Lines) != len(expected) {
+		t.Fatalf("wrong line table. want=%v, got=%v", expected, debug.Lines)
+	}
+	for i, entry := range expected {
+		if debug.Lines[i] != entry {
+			t.Errorf("wrong entry %d. want=%v, got=%v", i, entry, debug.Lines[i])
+		}
+	}
+
+	tests := []struct {
+		offset int
+		line   int
+	}{
+		{0, 1}, {4, 1}, {5, 2}, {6, 2}, {7, 3}, {100, 3},
+	}
+	for _, tt := range tests {
+		if line := debug.Line(tt.offset); line != tt.line {
+			t.Errorf("wrong line of offset %d. want=%d, got=%d", tt.offset, tt.line, line)
+		}
+	}
+
+	if line := (&DebugInfo{}).Line(0); line != 0 {
+		t.Errorf("empty line table returned line %d", line)
+	}
+}
@@ -0,0 +1,49 @@
+package code
+
+import "sort"
+
+// LineEntry - source line of the instructions starting at the offset
+// up to the offset of the next entry
+type LineEntry struct {
+	Offset int
+	Line   int
+}
+
+// DebugInfo - maps compiled instructions back to the source
+type DebugInfo struct {
+	// line table sorted by offsets
+	Lines []LineEntry
+	// names of the local bindings by their indexes (global bindings
+	// for the program)
+	Locals []string
+	// names of the variables captured by the function by their indexes
+	Free []string
+}
+
+// AddLine - records that instructions starting at the offset come from
+// the line, entries of removed instructions at or after the offset
+// are dropped
+func (d *DebugInfo) AddLine(offset int, line int) {
+	n := len(d.Lines)
+	for n > 0 && d.Lines[n-1].Offset >= offset {
+		n--
+	}
+	d.Lines = d.Lines[:n]
+
+	if n > 0 && d.Lines[n-1].Line == line {
+		return
+	}
+	d.Lines = append(d.Lines, LineEntry{Offset: offset, Line: line})
+}
+
+// Line - returns source line of the instruction at the offset,
+// returns 0 if the line is unknown
+func (d *DebugInfo) Line(offset int) int {
+	idx := sort.Search(len(d.Lines), func(i int) bool {
+		return d.Lines[i].Offset > offset
+	})
+	if idx == 0 {
+		return 0
+	}
+	return d.Lines[idx-1].Line
+}
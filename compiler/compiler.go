@@ -0,0 +1,696 @@
+// Package compiler - compiles Beaver AST into bytecode
+package compiler
+
+import (
+	"fmt"
+
+	"github.com/technoboom/compiler/ast"
+	"github.com/technoboom/compiler/code"
+	"github.com/technoboom/compiler/object"
+)
+
+// EmittedInstruction - opcode and position of the emitted instruction
+type EmittedInstruction struct {
+	Opcode   code.Opcode
+	Position int
+}
+
+// CompilationScope - instructions of the function being compiled
+type CompilationScope struct {
+	instructions        code.Instructions
+	lastInstruction     EmittedInstruction
+	previousInstruction EmittedInstruction
+	// line table of the instructions
+	debug code.DebugInfo
+}
+
+// Compiler - walks AST and emits instructions and constants
+type Compiler struct {
+	constants []object.Object
+
+	symbolTable *SymbolTable
+
+	scopes     []CompilationScope
+	scopeIndex int
+
+	// source line of the statement being compiled
+	line int
+
+	// the first emitted operand which doesn't fit into its width
+	err error
+}
+
+// Bytecode - result of the compilation: instructions of the program
+// and the constant pool
+type Bytecode struct {
+	Instructions code.Instructions
+	Constants    []object.Object
+	// line table of the program instructions (nil if stripped)
+	Debug *code.DebugInfo
+}
+
+// New - creates new compiler
+func New() *Compiler {
+	mainScope := CompilationScope{
+		instructions:        code.Instructions{},
+		lastInstruction:     EmittedInstruction{},
+		previousInstruction: EmittedInstruction{},
+	}
+
+	symbolTable := NewSymbolTable()
+	for idx, def := range object.Builtins {
+		symbolTable.DefineBuiltin(idx, def.Name)
+	}
+
+	return &Compiler{
+		constants:   []object.Object{},
+		symbolTable: symbolTable,
+		scopes:      []CompilationScope{mainScope},
+		scopeIndex:  0,
+	}
+}
+
+// NewWithState - creates new compiler which keeps symbols and constants
+// of the previous compilations (used by REPL), the symbol table is
+// expected to come from the previous compiler, so it has the builtins
+func NewWithState(s *SymbolTable, constants []object.Object) *Compiler {
+	compiler := New()
+	compiler.symbolTable = s
+	compiler.constants = constants
+	return compiler
+}
+
+// Compile - compiles the node and all its children
+func (c *Compiler) Compile(node ast.Node) error {
+	if err := c.compile(node); err != nil {
+		return err
+	}
+	return c.err
+}
+
+// compile - compiles the node, children are compiled with Compile,
+// so the compilation stops at the first error
+func (c *Compiler) compile(node ast.Node) error {
+	switch node := node.(type) {
+	// Statements
+	case *ast.Program:
+		for _, s := range node.Statements {
+			c.declare(s)
+		}
+		for _, s := range node.Statements {
+			if err := c.Compile(s); err != nil {
+				return err
+			}
+		}
+	case *ast.ExpressionStatement:
+		c.line = node.Token.Line
+		if err := c.Compile(node.Expression); err != nil {
+			return err
+		}
+		c.emit(code.OpPop)
+	case *ast.BlockStatement:
+		// instructions following the block belong to the enclosing statement
+		line := c.line
+		for _, s := range node.Statements {
+			if err := c.Compile(s); err != nil {
+				return err
+			}
+		}
+		c.line = line
+	case *ast.LetStatement:
+		c.line = node.Token.Line
+		return c.compileLetStatement(node)
+	case *ast.ReturnStatement:
+		c.line = node.Token.Line
+		if err := c.Compile(node.ReturnValue); err != nil {
+			return err
+		}
+		c.emit(code.OpReturnValue)
+	// Expressions
+	case *ast.IntegerLiteral:
+		integer := &object.Integer{Value: node.Value}
+		c.emit(code.OpConstant, c.addConstant(integer))
+	case *ast.StringLiteral:
+		str := &object.String{Value: node.Value}
+		c.emit(code.OpConstant, c.addConstant(str))
+	case *ast.Boolean:
+		if node.Value {
+			c.emit(code.OpTrue)
+		} else {
+			c.emit(code.OpFalse)
+		}
+	case *ast.NullLiteral:
+		c.emit(code.OpNull)
+	case *ast.PrefixExpression:
+		return c.compilePrefixExpression(node)
+	case *ast.InfixExpression:
+		return c.compileInfixExpression(node)
+	case *ast.IfExpression:
+		return c.compileConditional(node.Condition, node.Consequence, node.Alternative)
+	case *ast.ConditionalExpression:
+		return c.compileConditional(node.Condition, node.Consequence, node.Alternative)
+	case *ast.Identifier:
+		symbol, ok := c.symbolTable.Resolve(node.Value)
+		if !ok {
+			// unknown name isn't a compile-time error on purpose: it gets
+			// the global, which may be bound by the later part of the program
+			// compiled with the same state (line of REPL) before the code
+			// runs. Otherwise reading it fails with NameError when it's
+			// evaluated, like in the evaluator
+			symbol = c.symbolTable.DeclareGlobal(node.Value)
+		}
+		c.loadSymbol(symbol)
+	case *ast.ArrayLiteral:
+		if err := c.compileExpressions(node.Elements); err != nil {
+			return err
+		}
+		c.emit(code.OpArray, len(node.Elements))
+	case *ast.HashLiteral:
+		for _, pair := range node.Pairs {
+			if pair.Value == nil {
+				return fmt.Errorf("spread elements are not supported by compiler")
+			}
+			if err := c.Compile(pair.Key); err != nil {
+				return err
+			}
+			if err := c.Compile(pair.Value); err != nil {
+				return err
+			}
+		}
+		c.emit(code.OpHash, len(node.Pairs)*2)
+	case *ast.FunctionLiteral:
+		return c.compileFunctionLiteral(node)
+	case *ast.CallExpression:
+		return c.compileCallExpression(node)
+	default:
+		return unsupported(node)
+	}
+
+	return nil
+}
+
+// unsupported - returns error naming the feature of the node which
+// the compiler doesn't support
+func unsupported(node ast.Node) error {
+	var feature string
+	switch node.(type) {
+	case *ast.StructStatement:
+		feature = "structs"
+	case *ast.EnumStatement:
+		feature = "enums"
+	case *ast.TraitStatement:
+		feature = "traits"
+	case *ast.ImplStatement:
+		feature = "impl blocks"
+	case *ast.MatchExpression:
+		feature = "match expressions"
+	case *ast.TryExpression:
+		feature = "try expressions"
+	case *ast.ThrowStatement:
+		feature = "throw statements"
+	case *ast.MemberExpression:
+		feature = "member expressions"
+	case *ast.AssignExpression:
+		feature = "field assignments"
+	default:
+		feature = fmt.Sprintf("%T nodes", node)
+	}
+	return fmt.Errorf("%s are not supported by compiler", feature)
+}
+
+// declare - declares names bound by let statements of the node in the
+// current symbol table, so globals are visible before their definitions
+// and locals get their indexes. Nested functions are skipped as they
+// declare their own names
+func (c *Compiler) declare(node ast.Node) {
+	switch node := node.(type) {
+	case *ast.LetStatement:
+		if ident, ok := node.Pattern.(*ast.Identifier); ok {
+			c.symbolTable.Declare(ident.Value)
+		}
+		c.declare(node.Value)
+	case *ast.ExpressionStatement:
+		c.declare(node.Expression)
+	case *ast.ReturnStatement:
+		c.declare(node.ReturnValue)
+	case *ast.BlockStatement:
+		if node == nil {
+			return
+		}
+		for _, s := range node.Statements {
+			c.declare(s)
+		}
+	case *ast.PrefixExpression:
+		c.declare(node.Right)
+	case *ast.InfixExpression:
+		c.declare(node.Left)
+		c.declare(node.Right)
+	case *ast.IfExpression:
+		c.declare(node.Condition)
+		c.declare(node.Consequence)
+		c.declare(node.Alternative)
+	case *ast.ConditionalExpression:
+		c.declare(node.Condition)
+		c.declare(node.Consequence)
+		c.declare(node.Alternative)
+	case *ast.CallExpression:
+		c.declare(node.Function)
+		for _, arg := range node.Arguments {
+			c.declare(arg)
+		}
+	case *ast.ArrayLiteral:
+		for _, e := range node.Elements {
+			c.declare(e)
+		}
+	case *ast.HashLiteral:
+		for _, pair := range node.Pairs {
+			c.declare(pair.Key)
+			if pair.Value != nil {
+				c.declare(pair.Value)
+			}
+		}
+	}
+}
+
+// compileLetStatement - compiles binding of the value to the name, the
+// name is defined after the value, so `let x = x` in the function reads
+// the outer x. The function still can call itself recursively: its body
+// sees all variables of the enclosing scopes
+func (c *Compiler) compileLetStatement(node *ast.LetStatement) error {
+	ident, ok := node.Pattern.(*ast.Identifier)
+	if !ok {
+		return fmt.Errorf("destructuring is not supported by compiler")
+	}
+
+	if err := c.Compile(node.Value); err != nil {
+		return err
+	}
+
+	symbol := c.symbolTable.Define(ident.Value)
+
+	if symbol.Scope == GlobalScope {
+		c.emit(code.OpSetGlobal, symbol.Index)
+	} else {
+		c.emit(code.OpSetLocal, symbol.Index)
+	}
+
+	return nil
+}
+
+// compilePrefixExpression - compiles operand and the prefix operator
+func (c *Compiler) compilePrefixExpression(node *ast.PrefixExpression) error {
+	if err := c.Compile(node.Right); err != nil {
+		return err
+	}
+
+	switch node.Operator {
+	case "!":
+		c.emit(code.OpBang)
+	case "-":
+		c.emit(code.OpMinus)
+	default:
+		return fmt.Errorf("unknown operator %s", node.Operator)
+	}
+
+	return nil
+}
+
+// compileInfixExpression - compiles operands (left to right) and
+// the infix operator, the right operand of `??` is evaluated only
+// if the left one is null
+func (c *Compiler) compileInfixExpression(node *ast.InfixExpression) error {
+	if err := c.Compile(node.Left); err != nil {
+		return err
+	}
+
+	if node.Operator == "??" {
+		jumpPos := c.emit(code.OpJumpNotNull, 9999)
+		if err := c.Compile(node.Right); err != nil {
+			return err
+		}
+		c.changeOperand(jumpPos, len(c.currentInstructions()))
+		return nil
+	}
+
+	if err := c.Compile(node.Right); err != nil {
+		return err
+	}
+
+	switch node.Operator {
+	case "+":
+		c.emit(code.OpAdd)
+	case "-":
+		c.emit(code.OpSub)
+	case "*":
+		c.emit(code.OpMul)
+	case "/":
+		c.emit(code.OpDiv)
+	case ">":
+		c.emit(code.OpGreaterThan)
+	case "<":
+		c.emit(code.OpLessThan)
+	case "==":
+		c.emit(code.OpEqual)
+	case "!=":
+		c.emit(code.OpNotEqual)
+	default:
+		return fmt.Errorf("unknown operator %s", node.Operator)
+	}
+
+	return nil
+}
+
+// compileConditional - compiles if/else and ternary expressions: the
+// condition is followed by jump over the consequence, missing alternative
+// produces null
+func (c *Compiler) compileConditional(
+	condition ast.Expression,
+	consequence ast.Node,
+	alternative ast.Node,
+) error {
+	if err := c.Compile(condition); err != nil {
+		return err
+	}
+
+	jumpNotTruthyPos := c.emit(code.OpJumpNotTruthy, 9999)
+
+	if err := c.compileBranch(consequence); err != nil {
+		return err
+	}
+
+	jumpPos := c.emit(code.OpJump, 9999)
+	c.changeOperand(jumpNotTruthyPos, len(c.currentInstructions()))
+
+	if isNilNode(alternative) {
+		c.emit(code.OpNull)
+	} else if err := c.compileBranch(alternative); err != nil {
+		return err
+	}
+
+	c.changeOperand(jumpPos, len(c.currentInstructions()))
+
+	return nil
+}
+
+// compileBranch - compiles branch of the conditional so its value stays
+// on the stack: the last pop of the block is removed, blocks which don't
+// end with an expression produce null
+func (c *Compiler) compileBranch(branch ast.Node) error {
+	if err := c.Compile(branch); err != nil {
+		return err
+	}
+
+	if _, ok := branch.(*ast.BlockStatement); !ok {
+		return nil
+	}
+
+	if c.lastInstructionIs(code.OpPop) {
+		c.removeLastPop()
+	} else if !c.lastInstructionIs(code.OpReturnValue) {
+		c.emit(code.OpNull)
+	}
+
+	return nil
+}
+
+// isNilNode - checks if the node is missing (nil interface or nil pointer)
+func isNilNode(node ast.Node) bool {
+	if node == nil {
+		return true
+	}
+	block, ok := node.(*ast.BlockStatement)
+	return ok && block == nil
+}
+
+// compileFunctionLiteral - compiles body of the function in the new scope,
+// emits the compiled function as a constant and creates the closure over
+// the variables of the enclosing functions used in the body
+func (c *Compiler) compileFunctionLiteral(node *ast.FunctionLiteral) error {
+	if len(node.Defaults) > 0 || node.Rest != nil {
+		return fmt.Errorf("default and rest parameters are not supported by compiler")
+	}
+
+	c.enterScope()
+
+	parameters := make([]string, len(node.Parameters))
+	for idx, p := range node.Parameters {
+		c.symbolTable.Define(p.Value)
+		parameters[idx] = p.Value
+	}
+	c.declare(node.Body)
+
+	if err := c.Compile(node.Body); err != nil {
+		// the compiler with the state (e.g. of REPL) stays usable
+		c.leaveScope()
+		return err
+	}
+
+	// the value of the last expression is returned implicitly
+	if c.lastInstructionIs(code.OpPop) {
+		c.replaceLastPopWithReturn()
+	}
+	if !c.lastInstructionIs(code.OpReturnValue) {
+		c.emit(code.OpReturn)
+	}
+
+	freeSymbols := c.symbolTable.FreeSymbols
+	numLocals := c.symbolTable.numDefinitions
+	debug := &code.DebugInfo{
+		Lines:  c.scopes[c.scopeIndex].debug.Lines,
+		Locals: c.symbolTable.LocalNames(),
+		Free:   make([]string, len(freeSymbols)),
+	}
+	for idx, s := range freeSymbols {
+		debug.Free[idx] = s.Name
+	}
+	instructions := c.leaveScope()
+
+	// cells of the captured variables are pushed in the order
+	// of their free indexes
+	for _, s := range freeSymbols {
+		c.captureSymbol(s)
+	}
+
+	compiledFn := &object.CompiledFunction{
+		Instructions: instructions,
+		NumLocals:    numLocals,
+		Parameters:   parameters,
+		Debug:        debug,
+	}
+	c.emit(code.OpClosure, c.addConstant(compiledFn), len(freeSymbols))
+
+	return nil
+}
+
+// compileCallExpression - compiles the function and its positional arguments
+func (c *Compiler) compileCallExpression(node *ast.CallExpression) error {
+	if _, ok := node.Function.(*ast.MemberExpression); ok {
+		return fmt.Errorf("method calls are not supported by compiler")
+	}
+
+	if err := c.Compile(node.Function); err != nil {
+		return err
+	}
+
+	if err := c.compileExpressions(node.Arguments); err != nil {
+		return err
+	}
+
+	c.emit(code.OpCall, len(node.Arguments))
+
+	return nil
+}
+
+// compileExpressions - compiles expressions in order, keyword arguments
+// and spread elements are not supported
+func (c *Compiler) compileExpressions(expressions []ast.Expression) error {
+	for _, e := range expressions {
+		switch e.(type) {
+		case *ast.KeywordArgument:
+			return fmt.Errorf("keyword arguments are not supported by compiler")
+		case *ast.SpreadElement:
+			return fmt.Errorf("spread elements are not supported by compiler")
+		}
+
+		if err := c.Compile(e); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// loadSymbol - emits instruction which pushes value of the symbol
+func (c *Compiler) loadSymbol(s Symbol) {
+	switch s.Scope {
+	case GlobalScope:
+		c.emit(code.OpGetGlobal, s.Index)
+	case LocalScope:
+		c.emit(code.OpGetLocal, s.Index)
+	case BuiltinScope:
+		c.emit(code.OpGetBuiltin, s.Index)
+	case FreeScope:
+		c.emit(code.OpGetFree, s.Index)
+	}
+}
+
+// captureSymbol - emits instruction which pushes cell of the local or free
+// symbol of the enclosing function for the closure
+func (c *Compiler) captureSymbol(s Symbol) {
+	switch s.Scope {
+	case LocalScope:
+		c.emit(code.OpGetLocalCell, s.Index)
+	case FreeScope:
+		c.emit(code.OpGetFreeCell, s.Index)
+	}
+}
+
+// addConstant - adds the object to the constant pool, returns its index
+func (c *Compiler) addConstant(obj object.Object) int {
+	c.constants = append(c.constants, obj)
+	return len(c.constants) - 1
+}
+
+// emit - emits the instruction in the current scope, returns its position.
+// Operands which don't fit into their widths fail the compilation
+func (c *Compiler) emit(op code.Opcode, operands ...int) int {
+	c.checkOperands(op, operands)
+
+	ins := code.Make(op, operands...)
+	pos := c.addInstruction(ins)
+
+	c.setLastInstruction(op, pos)
+
+	return pos
+}
+
+// checkOperands - remembers the error if some operand of the instruction
+// doesn't fit into its width (e.g. index of the 257th local variable)
+func (c *Compiler) checkOperands(op code.Opcode, operands []int) {
+	def, err := code.Lookup(byte(op))
+	if err != nil {
+		c.fail(err)
+		return
+	}
+
+	for idx, operand := range operands {
+		max := 1<<(8*uint(def.OperandWidths[idx])) - 1
+		if operand > max {
+			c.fail(fmt.Errorf("operand of %s is too large: %d (max %d)", def.Name, operand, max))
+		}
+	}
+}
+
+// fail - remembers the first error of the emitted instructions
+func (c *Compiler) fail(err error) {
+	if c.err == nil {
+		c.err = err
+	}
+}
+
+// addInstruction - appends the instruction to the current scope
+func (c *Compiler) addInstruction(ins []byte) int {
+	posNewInstruction := len(c.currentInstructions())
+	c.scopes[c.scopeIndex].instructions = append(c.currentInstructions(), ins...)
+	c.scopes[c.scopeIndex].debug.AddLine(posNewInstruction, c.line)
+	return posNewInstruction
+}
+
+// setLastInstruction - remembers two last emitted instructions
+func (c *Compiler) setLastInstruction(op code.Opcode, pos int) {
+	previous := c.scopes[c.scopeIndex].lastInstruction
+	last := EmittedInstruction{Opcode: op, Position: pos}
+
+	c.scopes[c.scopeIndex].previousInstruction = previous
+	c.scopes[c.scopeIndex].lastInstruction = last
+}
+
+// currentInstructions - returns instructions of the current scope
+func (c *Compiler) currentInstructions() code.Instructions {
+	return c.scopes[c.scopeIndex].instructions
+}
+
+// lastInstructionIs - checks opcode of the last emitted instruction
+func (c *Compiler) lastInstructionIs(op code.Opcode) bool {
+	if len(c.currentInstructions()) == 0 {
+		return false
+	}
+
+	return c.scopes[c.scopeIndex].lastInstruction.Opcode == op
+}
+
+// removeLastPop - removes the last emitted OpPop instruction
+func (c *Compiler) removeLastPop() {
+	last := c.scopes[c.scopeIndex].lastInstruction
+	previous := c.scopes[c.scopeIndex].previousInstruction
+
+	old := c.currentInstructions()
+	c.scopes[c.scopeIndex].instructions = old[:last.Position]
+	c.scopes[c.scopeIndex].lastInstruction = previous
+}
+
+// replaceLastPopWithReturn - replaces the last emitted OpPop instruction
+// with OpReturnValue
+func (c *Compiler) replaceLastPopWithReturn() {
+	lastPos := c.scopes[c.scopeIndex].lastInstruction.Position
+	c.replaceInstruction(lastPos, code.Make(code.OpReturnValue))
+
+	c.scopes[c.scopeIndex].lastInstruction.Opcode = code.OpReturnValue
+}
+
+// replaceInstruction - replaces the instruction at the position
+// with the new one of the same width
+func (c *Compiler) replaceInstruction(pos int, newInstruction []byte) {
+	ins := c.currentInstructions()
+
+	for i := 0; i < len(newInstruction); i++ {
+		ins[pos+i] = newInstruction[i]
+	}
+}
+
+// changeOperand - replaces operand of the instruction at the position
+func (c *Compiler) changeOperand(opPos int, operand int) {
+	op := code.Opcode(c.currentInstructions()[opPos])
+	c.checkOperands(op, []int{operand})
+	newInstruction := code.Make(op, operand)
+
+	c.replaceInstruction(opPos, newInstruction)
+}
+
+// enterScope - starts compilation of the function
+func (c *Compiler) enterScope() {
+	scope := CompilationScope{
+		instructions:        code.Instructions{},
+		lastInstruction:     EmittedInstruction{},
+		previousInstruction: EmittedInstruction{},
+	}
+	c.scopes = append(c.scopes, scope)
+	c.scopeIndex++
+
+	c.symbolTable = NewEnclosedSymbolTable(c.symbolTable)
+}
+
+// leaveScope - finishes compilation of the function, returns its
+// instructions
+func (c *Compiler) leaveScope() code.Instructions {
+	instructions := c.currentInstructions()
+
+	c.scopes = c.scopes[:len(c.scopes)-1]
+	c.scopeIndex--
+
+	c.symbolTable = c.symbolTable.Outer
+
+	return instructions
+}
+
+// Bytecode - returns result of the compilation
+func (c *Compiler) Bytecode() *Bytecode {
+	return &Bytecode{
+		Instructions: c.currentInstructions(),
+		Constants:    c.constants,
+		Debug: &code.DebugInfo{
+			Lines:  c.scopes[c.scopeIndex].debug.Lines,
+			Locals: c.symbolTable.LocalNames(),
+		},
+	}
+}
@@ -0,0 +1,454 @@
+package compiler
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/technoboom/compiler/code"
+	"github.com/technoboom/compiler/object"
+)
+
+// FileExtension - extension of the files with serialized bytecode
+const FileExtension = ".bvc"
+
+// FormatVersion - version of the bytecode file format, files of other
+// versions are rejected by Decode
+const FormatVersion uint16 = 2
+
+// magic - the first bytes of every bytecode file
+var magic = []byte("BVC\x00")
+
+// flags of the bytecode file
+const flagDebugInfo byte = 1 << 0
+
+// tags of the constants in the constant pool
+const (
+	tagInteger byte = iota + 1
+	tagString
+	tagBoolean
+	tagNull
+	tagArray
+	tagHash
+	tagCompiledFunction
+)
+
+// Layout of the file, all numbers are big-endian:
+//
+//	magic      4 bytes "BVC\0"
+//	version    uint16
+//	flags      byte, flagDebugInfo if line tables and local names follow
+//	constants  uint32 count, then tag byte and payload of each constant
+//	program    uint32 length and instructions
+//	debug      line table and global names of the program (only with flagDebugInfo)
+//
+// Strings are encoded as uint32 length and bytes. Compiled function is
+// encoded as instructions, uint16 number of locals, uint16 number of
+// parameters and their names, and its line table, local names and names
+// of captured variables (only with flagDebugInfo).
+
+// Encode - writes the bytecode in the binary format, debug info is written
+// if the program has it
+func Encode(w io.Writer, bytecode *Bytecode) error {
+	e := &encoder{w: bufio.NewWriter(w), debug: bytecode.Debug != nil}
+
+	var flags byte
+	if e.debug {
+		flags |= flagDebugInfo
+	}
+
+	e.write(magic)
+	e.write(FormatVersion)
+	e.write(flags)
+
+	e.write(uint32(len(bytecode.Constants)))
+	for _, constant := range bytecode.Constants {
+		e.writeConstant(constant)
+	}
+
+	e.writeBytes(bytecode.Instructions)
+	if e.debug {
+		e.writeLines(bytecode.Debug.Lines)
+		e.writeStrings(bytecode.Debug.Locals)
+	}
+
+	if e.err != nil {
+		return e.err
+	}
+	return e.w.Flush()
+}
+
+// Decode - reads the bytecode written by Encode and checks that it
+// can be run, broken or malicious files are rejected with an error
+func Decode(r io.Reader) (*Bytecode, error) {
+	d := &decoder{r: bufio.NewReader(r)}
+
+	header := make([]byte, len(magic))
+	d.read(header)
+	if d.err == nil && !bytes.Equal(header, magic) {
+		return nil, fmt.Errorf("not a bytecode file")
+	}
+
+	var version uint16
+	d.read(&version)
+	if d.err == nil && version != FormatVersion {
+		return nil, fmt.Errorf("unsupported bytecode version: got=%d, supported=%d",
+			version, FormatVersion)
+	}
+
+	var flags byte
+	d.read(&flags)
+	d.debug = flags&flagDebugInfo != 0
+
+	bytecode := &Bytecode{}
+
+	var count uint32
+	d.read(&count)
+	for i := uint32(0); i < count && d.err == nil; i++ {
+		bytecode.Constants = append(bytecode.Constants, d.readConstant())
+	}
+
+	bytecode.Instructions = d.readBytes()
+	if d.debug {
+		bytecode.Debug = &code.DebugInfo{Lines: d.readLines(), Locals: d.readStrings()}
+	}
+
+	if d.err != nil {
+		return nil, d.err
+	}
+	if err := validate(bytecode); err != nil {
+		return nil, err
+	}
+	return bytecode, nil
+}
+
+// validate - checks that the decoded bytecode can be run: instructions
+// are known opcodes with complete operands, which refer to existing
+// constants, locals, builtins and jump targets
+func validate(bytecode *Bytecode) error {
+	for idx, constant := range bytecode.Constants {
+		fn, ok := constant.(*object.CompiledFunction)
+		if !ok {
+			continue
+		}
+		if len(fn.Parameters) > fn.NumLocals {
+			return fmt.Errorf("constant %d: function has %d parameters, but %d locals",
+				idx, len(fn.Parameters), fn.NumLocals)
+		}
+		if err := validateInstructions(fn.Instructions, bytecode.Constants, fn.NumLocals); err != nil {
+			return fmt.Errorf("constant %d: %s", idx, err)
+		}
+	}
+
+	// the program has no locals
+	return validateInstructions(bytecode.Instructions, bytecode.Constants, 0)
+}
+
+// validateInstructions - checks instructions of the program or the function
+// with the number of locals
+func validateInstructions(ins code.Instructions, constants []object.Object, numLocals int) error {
+	for offset := 0; offset < len(ins); {
+		def, err := code.Lookup(ins[offset])
+		if err != nil {
+			return fmt.Errorf("unknown opcode %d at offset %d", ins[offset], offset)
+		}
+
+		width := 0
+		for _, w := range def.OperandWidths {
+			width += w
+		}
+		if offset+1+width > len(ins) {
+			return fmt.Errorf("truncated operands of %s at offset %d", def.Name, offset)
+		}
+		operands, _ := code.ReadOperands(def, ins[offset+1:])
+
+		switch code.Opcode(ins[offset]) {
+		case code.OpConstant:
+			if operands[0] >= len(constants) {
+				return fmt.Errorf("constant index %d out of range at offset %d", operands[0], offset)
+			}
+		case code.OpClosure:
+			if operands[0] >= len(constants) {
+				return fmt.Errorf("constant index %d out of range at offset %d", operands[0], offset)
+			}
+			if _, ok := constants[operands[0]].(*object.CompiledFunction); !ok {
+				return fmt.Errorf("closure of %s constant at offset %d",
+					constants[operands[0]].Type(), offset)
+			}
+		case code.OpGetLocal, code.OpSetLocal, code.OpGetLocalCell:
+			if operands[0] >= numLocals {
+				return fmt.Errorf("local index %d out of range at offset %d", operands[0], offset)
+			}
+		case code.OpGetBuiltin:
+			if operands[0] >= len(object.Builtins) {
+				return fmt.Errorf("builtin index %d out of range at offset %d", operands[0], offset)
+			}
+		case code.OpJump, code.OpJumpNotTruthy, code.OpJumpNotNull:
+			if operands[0] > len(ins) {
+				return fmt.Errorf("jump target %d out of range at offset %d", operands[0], offset)
+			}
+		}
+
+		offset += 1 + width
+	}
+	return nil
+}
+
+// StripDebugInfo - removes line tables and local names from the program
+// and its compiled functions, so they are not encoded
+func (b *Bytecode) StripDebugInfo() {
+	b.Debug = nil
+	for _, constant := range b.Constants {
+		if fn, ok := constant.(*object.CompiledFunction); ok {
+			fn.Debug = nil
+		}
+	}
+}
+
+// encoder - writes values and remembers the first error,
+// so the error is checked once at the end
+type encoder struct {
+	w     *bufio.Writer
+	debug bool
+	err   error
+}
+
+// write - writes the fixed-size value or the byte slice
+func (e *encoder) write(data interface{}) {
+	if e.err != nil {
+		return
+	}
+	e.err = binary.Write(e.w, binary.BigEndian, data)
+}
+
+// writeBytes - writes length of the bytes and the bytes
+func (e *encoder) writeBytes(data []byte) {
+	e.write(uint32(len(data)))
+	e.write(data)
+}
+
+// writeString - writes length of the string and the string
+func (e *encoder) writeString(s string) {
+	e.writeBytes([]byte(s))
+}
+
+// writeStrings - writes number of the strings and the strings
+func (e *encoder) writeStrings(strs []string) {
+	if len(strs) > math.MaxUint16 && e.err == nil {
+		e.err = fmt.Errorf("too many names: %d, the limit is %d", len(strs), math.MaxUint16)
+	}
+	e.write(uint16(len(strs)))
+	for _, s := range strs {
+		e.writeString(s)
+	}
+}
+
+// writeLines - writes the line table
+func (e *encoder) writeLines(lines []code.LineEntry) {
+	e.write(uint32(len(lines)))
+	for _, entry := range lines {
+		e.write(uint32(entry.Offset))
+		e.write(uint32(entry.Line))
+	}
+}
+
+// writeConstant - writes tag and payload of the constant
+func (e *encoder) writeConstant(obj object.Object) {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		e.write(tagInteger)
+		e.write(obj.Value)
+	case *object.String:
+		e.write(tagString)
+		e.writeString(obj.Value)
+	case *object.Boolean:
+		e.write(tagBoolean)
+		e.write(obj.Value)
+	case *object.Null:
+		e.write(tagNull)
+	case *object.Array:
+		e.write(tagArray)
+		e.write(uint32(len(obj.Elements)))
+		for _, element := range obj.Elements {
+			e.writeConstant(element)
+		}
+	case *object.Hash:
+		e.write(tagHash)
+		e.write(uint32(obj.Len()))
+		for _, pair := range obj.Pairs() {
+			e.writeConstant(pair.Key)
+			e.writeConstant(pair.Value)
+		}
+	case *object.CompiledFunction:
+		e.write(tagCompiledFunction)
+		e.writeBytes(obj.Instructions)
+		if obj.NumLocals > math.MaxUint16 && e.err == nil {
+			e.err = fmt.Errorf("too many locals: %d, the limit is %d", obj.NumLocals, math.MaxUint16)
+		}
+		e.write(uint16(obj.NumLocals))
+		e.writeStrings(obj.Parameters)
+		if e.debug {
+			debug := obj.Debug
+			if debug == nil {
+				debug = &code.DebugInfo{}
+			}
+			e.writeLines(debug.Lines)
+			e.writeStrings(debug.Locals)
+			e.writeStrings(debug.Free)
+		}
+	default:
+		if e.err == nil {
+			e.err = fmt.Errorf("cannot encode constant of type %s", obj.Type())
+		}
+	}
+}
+
+// decoder - reads values and remembers the first error,
+// the values read after the error are zero
+type decoder struct {
+	r     *bufio.Reader
+	debug bool
+	err   error
+}
+
+// read - reads the fixed-size value or fills the byte slice
+func (d *decoder) read(data interface{}) {
+	if d.err != nil {
+		return
+	}
+
+	err := binary.Read(d.r, binary.BigEndian, data)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		err = fmt.Errorf("unexpected end of bytecode")
+	}
+	d.err = err
+}
+
+// readBytes - reads length of the bytes and the bytes
+func (d *decoder) readBytes() []byte {
+	var length uint32
+	d.read(&length)
+	if d.err != nil {
+		return nil
+	}
+
+	// the buffer grows while reading, so broken length
+	// doesn't allocate more than the input has
+	var buf bytes.Buffer
+	n, err := io.CopyN(&buf, d.r, int64(length))
+	if err != nil || n != int64(length) {
+		d.err = fmt.Errorf("unexpected end of bytecode")
+		return nil
+	}
+	return buf.Bytes()
+}
+
+// readString - reads length of the string and the string
+func (d *decoder) readString() string {
+	return string(d.readBytes())
+}
+
+// readStrings - reads number of the strings and the strings
+func (d *decoder) readStrings() []string {
+	var count uint16
+	d.read(&count)
+
+	strs := []string{}
+	for i := uint16(0); i < count && d.err == nil; i++ {
+		strs = append(strs, d.readString())
+	}
+	return strs
+}
+
+// readLines - reads the line table
+func (d *decoder) readLines() []code.LineEntry {
+	var count uint32
+	d.read(&count)
+
+	lines := []code.LineEntry{}
+	for i := uint32(0); i < count && d.err == nil; i++ {
+		var offset, line uint32
+		d.read(&offset)
+		d.read(&line)
+		lines = append(lines, code.LineEntry{Offset: int(offset), Line: int(line)})
+	}
+	return lines
+}
+
+// readConstant - reads tag and payload of the constant
+func (d *decoder) readConstant() object.Object {
+	var tag byte
+	d.read(&tag)
+	if d.err != nil {
+		return nil
+	}
+
+	switch tag {
+	case tagInteger:
+		var value int64
+		d.read(&value)
+		return &object.Integer{Value: value}
+	case tagString:
+		return &object.String{Value: d.readString()}
+	case tagBoolean:
+		var value bool
+		d.read(&value)
+		if value {
+			return object.TRUE
+		}
+		return object.FALSE
+	case tagNull:
+		return object.NULL
+	case tagArray:
+		var count uint32
+		d.read(&count)
+
+		elements := []object.Object{}
+		for i := uint32(0); i < count && d.err == nil; i++ {
+			elements = append(elements, d.readConstant())
+		}
+		return &object.Array{Elements: elements}
+	case tagHash:
+		var count uint32
+		d.read(&count)
+
+		hash := object.NewHash()
+		for i := uint32(0); i < count && d.err == nil; i++ {
+			key := d.readConstant()
+			value := d.readConstant()
+			if d.err != nil {
+				break
+			}
+
+			hashKey, ok := key.(object.Hashable)
+			if !ok {
+				d.err = fmt.Errorf("unusable as hash key: %s", key.Type())
+				break
+			}
+			hash.Set(hashKey, value)
+		}
+		return hash
+	case tagCompiledFunction:
+		fn := &object.CompiledFunction{Instructions: d.readBytes()}
+
+		var numLocals uint16
+		d.read(&numLocals)
+		fn.NumLocals = int(numLocals)
+		fn.Parameters = d.readStrings()
+
+		if d.debug {
+			fn.Debug = &code.DebugInfo{
+				Lines:  d.readLines(),
+				Locals: d.readStrings(),
+				Free:   d.readStrings(),
+			}
+		}
+		return fn
+	default:
+		d.err = fmt.Errorf("unknown constant tag: %d", tag)
+		return nil
+	}
+}
@@ -0,0 +1,280 @@
+package compiler
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/technoboom/compiler/code"
+	"github.com/technoboom/compiler/internal/testutil"
+	"github.com/technoboom/compiler/object"
+)
+
+const encodingProgram = `let greeting = "hello";
+let add = (a, b) => {
+	let sum = a + b;
+	sum
+};
+let adder = (a) => (b) => a + b;
+add(1, adder(2)(3));`
+
+func TestEncodeDecode(t *testing.T) {
+	bytecode := compileProgram(t, encodingProgram)
+	bytecode.Constants = append(bytecode.Constants,
+		object.TRUE,
+		object.NULL,
+		&object.Array{Elements: []object.Object{&object.Integer{Value: -1}}},
+		hashOf(&object.String{Value: "k"}, &object.Integer{Value: 2}),
+	)
+
+	decoded := roundTrip(t, bytecode)
+
+	if !bytes.Equal(decoded.Instructions, bytecode.Instructions) {
+		t.Errorf("wrong instructions.\nwant=%q\ngot =%q",
+			bytecode.Instructions, decoded.Instructions)
+	}
+	if !reflect.DeepEqual(decoded.Debug, bytecode.Debug) {
+		t.Errorf("wrong debug info. want=%+v, got=%+v", bytecode.Debug, decoded.Debug)
+	}
+
+	if len(decoded.Constants) != len(bytecode.Constants) {
+		t.Fatalf("wrong number of constants. want=%d, got=%d",
+			len(bytecode.Constants), len(decoded.Constants))
+	}
+	for i, want := range bytecode.Constants {
+		got := decoded.Constants[i]
+		if got.Type() != want.Type() || got.Inspect() != want.Inspect() {
+			t.Errorf("constant %d - want=%s %s, got=%s %s",
+				i, want.Type(), want.Inspect(), got.Type(), got.Inspect())
+		}
+
+		if fn, ok := want.(*object.CompiledFunction); ok {
+			if !reflect.DeepEqual(got, fn) {
+				t.Errorf("constant %d - wrong function. want=%+v, got=%+v", i, fn, got)
+			}
+		}
+	}
+}
+
+func TestEncodeDebugInfo(t *testing.T) {
+	bytecode := compileProgram(t, encodingProgram)
+
+	// the function body spans lines 2-5
+	add := bytecode.Constants[1].(*object.CompiledFunction)
+	expected := &code.DebugInfo{
+		Lines:  []code.LineEntry{{Offset: 0, Line: 3}, {Offset: 7, Line: 4}},
+		Locals: []string{"a", "b", "sum"},
+		Free:   []string{},
+	}
+	if !reflect.DeepEqual(add.Debug, expected) {
+		t.Errorf("wrong debug info. want=%+v, got=%+v", expected, add.Debug)
+	}
+
+	withDebug := encode(t, bytecode)
+	bytecode.StripDebugInfo()
+	stripped := encode(t, bytecode)
+
+	if len(stripped) >= len(withDebug) {
+		t.Errorf("stripped bytecode is not smaller. with debug=%d, stripped=%d",
+			len(withDebug), len(stripped))
+	}
+
+	decoded := roundTrip(t, bytecode)
+	if decoded.Debug != nil {
+		t.Errorf("stripped bytecode has debug info: %+v", decoded.Debug)
+	}
+	if fn := decoded.Constants[1].(*object.CompiledFunction); fn.Debug != nil {
+		t.Errorf("stripped function has debug info: %+v", fn.Debug)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	encoded := encode(t, compileProgram(t, encodingProgram))
+
+	wrongVersion := append([]byte{}, encoded...)
+	wrongVersion[4], wrongVersion[5] = 0, 3
+
+	wrongTag := append([]byte{}, encoded[:11]...)
+	wrongTag = append(wrongTag, 99)
+
+	tests := []struct {
+		input         []byte
+		expectedError string
+	}{
+		{[]byte("#!/bin/beaver"), "not a bytecode file"},
+		{wrongVersion, "unsupported bytecode version: got=3, supported=2"},
+		{wrongTag, "unknown constant tag: 99"},
+		{encoded[:3], "unexpected end of bytecode"},
+		{encoded[:len(encoded)-1], "unexpected end of bytecode"},
+	}
+
+	for _, tt := range tests {
+		_, err := Decode(bytes.NewReader(tt.input))
+		if err == nil {
+			t.Errorf("expected decoding error %q, got none", tt.expectedError)
+			continue
+		}
+		if err.Error() != tt.expectedError {
+			t.Errorf("wrong error. want=%q, got=%q", tt.expectedError, err.Error())
+		}
+	}
+
+	// every truncated file is rejected
+	for i := 0; i < len(encoded); i++ {
+		if _, err := Decode(bytes.NewReader(encoded[:i])); err == nil {
+			t.Errorf("file truncated to %d bytes decoded without error", i)
+		}
+	}
+}
+
+func TestDecodeInvalidBytecode(t *testing.T) {
+	function := func(numLocals int, parameters []string, ins ...code.Instructions) *object.CompiledFunction {
+		return &object.CompiledFunction{
+			Instructions: concatInstructions(ins),
+			NumLocals:    numLocals,
+			Parameters:   parameters,
+		}
+	}
+
+	tests := []struct {
+		bytecode      *Bytecode
+		expectedError string
+	}{
+		{
+			&Bytecode{Instructions: []byte{255}},
+			"unknown opcode 255 at offset 0",
+		},
+		{
+			&Bytecode{Instructions: concatInstructions([]code.Instructions{
+				code.Make(code.OpNull),
+				code.Make(code.OpConstant, 0)[:2],
+			})},
+			"truncated operands of OpConstant at offset 1",
+		},
+		{
+			&Bytecode{
+				Instructions: code.Make(code.OpConstant, 1),
+				Constants:    []object.Object{&object.Integer{Value: 1}},
+			},
+			"constant index 1 out of range at offset 0",
+		},
+		{
+			&Bytecode{
+				Instructions: code.Make(code.OpClosure, 0, 0),
+				Constants:    []object.Object{&object.Integer{Value: 1}},
+			},
+			"closure of INTEGER constant at offset 0",
+		},
+		{
+			&Bytecode{Instructions: code.Make(code.OpGetLocal, 0)},
+			"local index 0 out of range at offset 0",
+		},
+		{
+			&Bytecode{Instructions: code.Make(code.OpGetBuiltin, 200)},
+			"builtin index 200 out of range at offset 0",
+		},
+		{
+			&Bytecode{Instructions: code.Make(code.OpJump, 10)},
+			"jump target 10 out of range at offset 0",
+		},
+		{
+			&Bytecode{Constants: []object.Object{
+				function(1, nil, code.Make(code.OpGetLocal, 0), code.Make(code.OpSetLocal, 1)),
+			}},
+			"constant 0: local index 1 out of range at offset 2",
+		},
+		{
+			&Bytecode{Constants: []object.Object{
+				function(1, []string{"a", "b"}, code.Make(code.OpReturn)),
+			}},
+			"constant 0: function has 2 parameters, but 1 locals",
+		},
+	}
+
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := Encode(&buf, tt.bytecode); err != nil {
+			t.Fatalf("encoding error: %s", err)
+		}
+
+		_, err := Decode(&buf)
+		if err == nil {
+			t.Errorf("expected decoding error %q, got none", tt.expectedError)
+			continue
+		}
+		if err.Error() != tt.expectedError {
+			t.Errorf("wrong error. want=%q, got=%q", tt.expectedError, err.Error())
+		}
+	}
+}
+
+func TestEncodeLimits(t *testing.T) {
+	names := make([]string, 1<<16)
+	for i := range names {
+		names[i] = "p"
+	}
+
+	tests := []struct {
+		fn            *object.CompiledFunction
+		expectedError string
+	}{
+		{&object.CompiledFunction{NumLocals: 1 << 16}, "too many locals: 65536, the limit is 65535"},
+		{&object.CompiledFunction{NumLocals: 1, Parameters: names}, "too many names: 65536, the limit is 65535"},
+	}
+
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		err := Encode(&buf, &Bytecode{Constants: []object.Object{tt.fn}})
+		if err == nil || err.Error() != tt.expectedError {
+			t.Errorf("wrong error. want=%q, got=%v", tt.expectedError, err)
+		}
+	}
+}
+
+func TestEncodeUnsupportedConstant(t *testing.T) {
+	bytecode := &Bytecode{
+		Constants: []object.Object{&object.Builtin{}},
+	}
+
+	var buf bytes.Buffer
+	err := Encode(&buf, bytecode)
+	if err == nil || err.Error() != "cannot encode constant of type BUILTIN" {
+		t.Errorf("wrong error. got=%v", err)
+	}
+}
+
+func compileProgram(t *testing.T, input string) *Bytecode {
+	t.Helper()
+
+	compiler := New()
+	if err := compiler.Compile(testutil.Parse(input)); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	return compiler.Bytecode()
+}
+
+func encode(t *testing.T, bytecode *Bytecode) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, bytecode); err != nil {
+		t.Fatalf("encoding error: %s", err)
+	}
+	return buf.Bytes()
+}
+
+func roundTrip(t *testing.T, bytecode *Bytecode) *Bytecode {
+	t.Helper()
+
+	decoded, err := Decode(bytes.NewReader(encode(t, bytecode)))
+	if err != nil {
+		t.Fatalf("decoding error: %s", err)
+	}
+	return decoded
+}
+
+func hashOf(key object.Hashable, value object.Object) *object.Hash {
+	hash := object.NewHash()
+	hash.Set(key, value)
+	return hash
+}
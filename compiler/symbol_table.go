@@ -0,0 +1,151 @@
+package compiler
+
+// SymbolScope - scope where the symbol is defined
+type SymbolScope string
+
+const (
+	GlobalScope  SymbolScope = "GLOBAL"
+	LocalScope   SymbolScope = "LOCAL"
+	BuiltinScope SymbolScope = "BUILTIN"
+	FreeScope    SymbolScope = "FREE"
+)
+
+// Symbol - resolved binding: its name, scope and index in the scope
+type Symbol struct {
+	Name  string
+	Scope SymbolScope
+	Index int
+}
+
+// SymbolTable - keeps symbols defined in the scope, local tables are
+// enclosed by the table of the outer scope
+type SymbolTable struct {
+	Outer *SymbolTable
+
+	// symbols of the enclosing functions captured by the function,
+	// in the order of their free indexes
+	FreeSymbols []Symbol
+
+	store          map[string]Symbol
+	numDefinitions int
+
+	// captured symbols by their names, kept apart from the store as the
+	// function may use the outer variable before its own one is defined
+	free map[string]Symbol
+	// local symbols declared, but not defined yet: the function sees
+	// them after their let statements, nested functions see them always
+	pending map[string]bool
+}
+
+// NewSymbolTable - creates global symbol table
+func NewSymbolTable() *SymbolTable {
+	return &SymbolTable{
+		store:   make(map[string]Symbol),
+		free:    make(map[string]Symbol),
+		pending: make(map[string]bool),
+	}
+}
+
+// NewEnclosedSymbolTable - creates local symbol table enclosed by
+// the outer one
+func NewEnclosedSymbolTable(outer *SymbolTable) *SymbolTable {
+	s := NewSymbolTable()
+	s.Outer = outer
+	return s
+}
+
+// Define - defines the symbol in the table, redefinition of the name
+// reuses its index. Definition of the name which was captured from
+// the enclosing function or names the builtin shadows it
+func (s *SymbolTable) Define(name string) Symbol {
+	symbol := s.Declare(name)
+	delete(s.pending, name)
+	return symbol
+}
+
+// Declare - reserves index of the symbol before its definition. Global
+// symbols are visible right away, so functions may refer to the globals
+// defined later. Local symbol stays pending until it's defined: before
+// that the name refers to the variable of the enclosing scopes
+func (s *SymbolTable) Declare(name string) Symbol {
+	scope := GlobalScope
+	if s.Outer != nil {
+		scope = LocalScope
+	}
+
+	if symbol, ok := s.store[name]; ok && symbol.Scope == scope {
+		return symbol
+	}
+
+	symbol := Symbol{Name: name, Scope: scope, Index: s.numDefinitions}
+	s.store[name] = symbol
+	s.numDefinitions++
+	if scope == LocalScope {
+		s.pending[name] = true
+	}
+	return symbol
+}
+
+// DeclareGlobal - declares the global symbol in the outermost table
+func (s *SymbolTable) DeclareGlobal(name string) Symbol {
+	global := s
+	for global.Outer != nil {
+		global = global.Outer
+	}
+	return global.Declare(name)
+}
+
+// DefineBuiltin - defines the builtin function by its index
+func (s *SymbolTable) DefineBuiltin(index int, name string) Symbol {
+	symbol := Symbol{Name: name, Scope: BuiltinScope, Index: index}
+	s.store[name] = symbol
+	return symbol
+}
+
+// Resolve - searches the symbol in the table and its outer tables,
+// local symbols of the enclosing functions become free symbols
+func (s *SymbolTable) Resolve(name string) (Symbol, bool) {
+	return s.resolve(name, false)
+}
+
+// resolve - searches the symbol visible from the function of the table
+// or from the function nested into it, which sees pending symbols too
+func (s *SymbolTable) resolve(name string, nested bool) (Symbol, bool) {
+	if obj, ok := s.store[name]; ok && (nested || !s.pending[name]) {
+		return obj, true
+	}
+	if obj, ok := s.free[name]; ok {
+		return obj, true
+	}
+	if s.Outer == nil {
+		return Symbol{}, false
+	}
+
+	obj, ok := s.Outer.resolve(name, true)
+	if !ok || obj.Scope == GlobalScope || obj.Scope == BuiltinScope {
+		return obj, ok
+	}
+
+	return s.defineFree(obj), true
+}
+
+// defineFree - defines the symbol captured from the enclosing function
+func (s *SymbolTable) defineFree(original Symbol) Symbol {
+	s.FreeSymbols = append(s.FreeSymbols, original)
+
+	symbol := Symbol{Name: original.Name, Scope: FreeScope, Index: len(s.FreeSymbols) - 1}
+	s.free[original.Name] = symbol
+	return symbol
+}
+
+// LocalNames - returns names of the local (global for the global table)
+// bindings by their indexes
+func (s *SymbolTable) LocalNames() []string {
+	names := make([]string, s.numDefinitions)
+	for name, symbol := range s.store {
+		if symbol.Scope == LocalScope || symbol.Scope == GlobalScope {
+			names[symbol.Index] = name
+		}
+	}
+	return names
+}
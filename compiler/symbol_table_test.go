@@ -0,0 +1,144 @@
+package compiler
+
+import "testing"
+
+func TestDefine(t *testing.T) {
+	expected := map[string]Symbol{
+		"a": {Name: "a", Scope: GlobalScope, Index: 0},
+		"b": {Name: "b", Scope: GlobalScope, Index: 1},
+		"c": {Name: "c", Scope: LocalScope, Index: 0},
+		"d": {Name: "d", Scope: LocalScope, Index: 1},
+		"e": {Name: "e", Scope: LocalScope, Index: 0},
+	}
+
+	global := NewSymbolTable()
+	if a := global.Define("a"); a != expected["a"] {
+		t.Errorf("expected a=%+v, got=%+v", expected["a"], a)
+	}
+	if b := global.Define("b"); b != expected["b"] {
+		t.Errorf("expected b=%+v, got=%+v", expected["b"], b)
+	}
+
+	firstLocal := NewEnclosedSymbolTable(global)
+	if c := firstLocal.Define("c"); c != expected["c"] {
+		t.Errorf("expected c=%+v, got=%+v", expected["c"], c)
+	}
+	if d := firstLocal.Define("d"); d != expected["d"] {
+		t.Errorf("expected d=%+v, got=%+v", expected["d"], d)
+	}
+
+	secondLocal := NewEnclosedSymbolTable(firstLocal)
+	if e := secondLocal.Define("e"); e != expected["e"] {
+		t.Errorf("expected e=%+v, got=%+v", expected["e"], e)
+	}
+
+	// redefinition keeps the index
+	if a := global.Define("a"); a != expected["a"] {
+		t.Errorf("expected a=%+v, got=%+v", expected["a"], a)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	global := NewSymbolTable()
+	global.Define("a")
+	global.Define("b")
+
+	local := NewEnclosedSymbolTable(global)
+	local.Define("c")
+	local.Define("a")
+
+	expected := []Symbol{
+		{Name: "a", Scope: LocalScope, Index: 1},
+		{Name: "b", Scope: GlobalScope, Index: 1},
+		{Name: "c", Scope: LocalScope, Index: 0},
+	}
+
+	for _, sym := range expected {
+		result, ok := local.Resolve(sym.Name)
+		if !ok {
+			t.Errorf("name %s not resolvable", sym.Name)
+			continue
+		}
+		if result != sym {
+			t.Errorf("expected %s to resolve to %+v, got=%+v", sym.Name, sym, result)
+		}
+	}
+
+	if _, ok := local.Resolve("unknown"); ok {
+		t.Errorf("name unknown resolved")
+	}
+}
+
+func TestResolveFree(t *testing.T) {
+	global := NewSymbolTable()
+	global.Define("a")
+	global.DefineBuiltin(0, "len")
+
+	first := NewEnclosedSymbolTable(global)
+	first.Define("b")
+
+	second := NewEnclosedSymbolTable(first)
+	second.Define("c")
+
+	expected := []Symbol{
+		{Name: "a", Scope: GlobalScope, Index: 0},
+		{Name: "len", Scope: BuiltinScope, Index: 0},
+		{Name: "c", Scope: LocalScope, Index: 0},
+		{Name: "b", Scope: FreeScope, Index: 0},
+	}
+
+	for _, sym := range expected {
+		result, ok := second.Resolve(sym.Name)
+		if !ok {
+			t.Errorf("name %s not resolvable", sym.Name)
+			continue
+		}
+		if result != sym {
+			t.Errorf("expected %s to resolve to %+v, got=%+v", sym.Name, sym, result)
+		}
+	}
+
+	expectedFree := []Symbol{{Name: "b", Scope: LocalScope, Index: 0}}
+	if len(second.FreeSymbols) != len(expectedFree) || second.FreeSymbols[0] != expectedFree[0] {
+		t.Errorf("wrong free symbols. want=%+v, got=%+v", expectedFree, second.FreeSymbols)
+	}
+
+	// local definition shadows the captured variable
+	if b := second.Define("b"); b != (Symbol{Name: "b", Scope: LocalScope, Index: 1}) {
+		t.Errorf("expected b to be redefined as local, got=%+v", b)
+	}
+}
+
+func TestResolveDeclared(t *testing.T) {
+	global := NewSymbolTable()
+	global.Declare("a")
+
+	first := NewEnclosedSymbolTable(global)
+	first.Declare("b")
+	first.Declare("a")
+
+	second := NewEnclosedSymbolTable(first)
+
+	// the global is visible before its definition
+	if a, ok := global.Resolve("a"); !ok || a != (Symbol{Name: "a", Scope: GlobalScope, Index: 0}) {
+		t.Errorf("expected a to resolve to the global, got=%+v", a)
+	}
+
+	// the function sees its local after the definition only
+	if _, ok := first.Resolve("b"); ok {
+		t.Errorf("declared local b resolved")
+	}
+	if a, ok := first.Resolve("a"); !ok || a.Scope != GlobalScope {
+		t.Errorf("expected a to resolve to the global, got=%+v", a)
+	}
+
+	// the nested function sees all locals of the enclosing one
+	if b, ok := second.Resolve("b"); !ok || b != (Symbol{Name: "b", Scope: FreeScope, Index: 0}) {
+		t.Errorf("expected b to resolve to the free symbol, got=%+v", b)
+	}
+
+	first.Define("a")
+	if a, ok := first.Resolve("a"); !ok || a != (Symbol{Name: "a", Scope: LocalScope, Index: 1}) {
+		t.Errorf("expected a to resolve to the local, got=%+v", a)
+	}
+}
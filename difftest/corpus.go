@@ -0,0 +1,43 @@
+package difftest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/technoboom/compiler/lexer"
+	"github.com/technoboom/compiler/parser"
+)
+
+// RunCorpus - runs every Beaver script (`*.bvr` file) of the directory
+// with the backends and returns differences of their results. Scripts
+// which can't be parsed are reported as an error
+func RunCorpus(dir string, backends ...Backend) ([]*Diff, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.bvr"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	diffs := []*Diff{}
+	for _, file := range files {
+		input, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		p := parser.New(lexer.New(string(input)))
+		program := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			return nil, fmt.Errorf("%s: %s", file, strings.Join(p.Errors(), "; "))
+		}
+
+		if diff := Compare(file, program, backends...); diff != nil {
+			diffs = append(diffs, diff)
+		}
+	}
+
+	return diffs, nil
+}
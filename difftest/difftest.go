@@ -0,0 +1,195 @@
+// Package difftest - differential testing of the ways to run Beaver
+// programs: runs programs with several backends and reports programs
+// where results, errors or printed output differ
+package difftest
+
+import (
+	"bytes"
+	"fmt"
+	"runtime/debug"
+	"strings"
+	"sync"
+
+	"github.com/technoboom/compiler/ast"
+	"github.com/technoboom/compiler/compiler"
+	"github.com/technoboom/compiler/evaluator"
+	"github.com/technoboom/compiler/object"
+	"github.com/technoboom/compiler/optimizer"
+	"github.com/technoboom/compiler/vm"
+)
+
+// Result - observable behaviour of the program
+type Result struct {
+	// result of the program, empty if the program has no result
+	Value string
+	// runtime error without position
+	Error string
+	// output printed by the program
+	Output string
+	// value and stack of the panic of the backend, it's a bug of the
+	// backend, so results with panics never match
+	Panic string
+}
+
+// String - returns the result in the form used in reports
+func (r Result) String() string {
+	var out bytes.Buffer
+	if r.Panic != "" {
+		out.WriteString("panic: " + r.Panic)
+	} else if r.Error != "" {
+		out.WriteString("error: " + r.Error)
+	} else {
+		out.WriteString("value: " + r.Value)
+	}
+	if r.Output != "" {
+		out.WriteString(fmt.Sprintf(", output: %q", r.Output))
+	}
+	return out.String()
+}
+
+// Backend - way to run programs. Run returns an error if the backend
+// can't run the program (e.g. the program uses unsupported features),
+// such backends are skipped in the comparison
+type Backend interface {
+	Name() string
+	Run(program *ast.Program) (Result, error)
+}
+
+// Evaluator - runs programs with the tree-walking evaluator
+type Evaluator struct{}
+
+// Name - returns name of the backend
+func (Evaluator) Name() string {
+	return "evaluator"
+}
+
+// Run - evaluates the program
+func (Evaluator) Run(program *ast.Program) (Result, error) {
+	return capture(func() object.Object {
+		return evaluator.Eval(program, object.NewEnvironment())
+	}), nil
+}
+
+// VM - compiles programs into bytecode optimized at the level
+// and runs them with the virtual machine
+type VM struct {
+	Level optimizer.Level
+}
+
+// Name - returns name of the backend
+func (b VM) Name() string {
+	if b.Level == optimizer.O0 {
+		return "vm"
+	}
+	return fmt.Sprintf("vm -O%d", b.Level)
+}
+
+// Run - compiles and runs the program
+func (b VM) Run(program *ast.Program) (Result, error) {
+	comp := compiler.New()
+	if err := comp.Compile(program); err != nil {
+		return Result{}, err
+	}
+	bytecode := optimizer.Optimize(comp.Bytecode(), b.Level)
+
+	return capture(func() object.Object {
+		machine := vm.New(bytecode)
+		if err := machine.Run(); err != nil {
+			if e, ok := err.(*object.Error); ok {
+				return e
+			}
+			return &object.Error{Message: err.Error()}
+		}
+		return machine.LastPoppedStackElem()
+	}), nil
+}
+
+// outputMutex - guards the output of puts, which is shared by the backends
+var outputMutex sync.Mutex
+
+// capture - runs the program and returns its result and output
+func capture(run func() object.Object) (result Result) {
+	outputMutex.Lock()
+	defer outputMutex.Unlock()
+
+	var out bytes.Buffer
+	output := object.Output
+	object.Output = &out
+
+	defer func() {
+		object.Output = output
+		result.Output = out.String()
+		if r := recover(); r != nil {
+			result.Value, result.Error = "", ""
+			result.Panic = fmt.Sprintf("%v\n%s", r, debug.Stack())
+		}
+	}()
+
+	value := run()
+	if err, ok := value.(*object.Error); ok {
+		e := *err
+		e.Line, e.Column = 0, 0
+		result.Error = e.Inspect()
+	} else if value != nil {
+		result.Value = value.Inspect()
+	}
+	return result
+}
+
+// Outcome - result of the program produced by the backend
+type Outcome struct {
+	Backend string
+	Result  Result
+	// error of the backend which can't run the program
+	Err error
+}
+
+// Diff - outcomes of the program, which differ between backends
+type Diff struct {
+	// name of the program in the report (file or seed)
+	Name     string
+	Outcomes []Outcome
+}
+
+// String - returns report of the difference
+func (d *Diff) String() string {
+	lines := []string{d.Name + ": results differ"}
+	for _, o := range d.Outcomes {
+		if o.Err != nil {
+			lines = append(lines, fmt.Sprintf("  %s: skipped: %s", o.Backend, o.Err))
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("  %s: %s", o.Backend, o.Result))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Compare - runs the program with the backends and returns the difference
+// of their results, nil if the backends which can run the program agree.
+// The panic of any backend is always reported
+func Compare(name string, program *ast.Program, backends ...Backend) *Diff {
+	diff := &Diff{Name: name}
+	var first *Result
+	same := true
+
+	for _, backend := range backends {
+		result, err := backend.Run(program)
+		diff.Outcomes = append(diff.Outcomes, Outcome{Backend: backend.Name(), Result: result, Err: err})
+		if err != nil {
+			continue
+		}
+
+		if result.Panic != "" {
+			same = false
+		} else if first == nil {
+			first = &result
+		} else if result != *first {
+			same = false
+		}
+	}
+
+	if same {
+		return nil
+	}
+	return diff
+}
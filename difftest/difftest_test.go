@@ -0,0 +1,128 @@
+package difftest
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/technoboom/compiler/ast"
+	"github.com/technoboom/compiler/object"
+	"github.com/technoboom/compiler/optimizer"
+)
+
+func TestCorpus(t *testing.T) {
+	diffs, err := RunCorpus("testdata", Evaluator{}, VM{}, VM{Level: optimizer.O2})
+	if err != nil {
+		t.Fatalf("corpus error: %s", err)
+	}
+	for _, diff := range diffs {
+		t.Error(diff)
+	}
+}
+
+// fake - backend returning the same result for every program
+type fake struct {
+	name   string
+	result Result
+	err    error
+}
+
+func (f fake) Name() string {
+	return f.name
+}
+
+func (f fake) Run(program *ast.Program) (Result, error) {
+	return f.result, f.err
+}
+
+// panicking - backend which panics on every program
+type panicking struct {
+	name string
+}
+
+func (p panicking) Name() string {
+	return p.name
+}
+
+func (p panicking) Run(program *ast.Program) (Result, error) {
+	return capture(func() object.Object { panic("boom") }), nil
+}
+
+func TestCompare(t *testing.T) {
+	program := parse(t, "puts(1); 2")
+
+	tests := []struct {
+		name     string
+		backends []Backend
+		expected string
+	}{
+		{"same", []Backend{Evaluator{}, VM{}, fake{"fake", Result{Value: "2", Output: "1\n"}, nil}}, ""},
+		{
+			"skipped",
+			[]Backend{Evaluator{}, fake{"unsupported", Result{}, errors.New("not supported")}},
+			"",
+		},
+		{
+			"value",
+			[]Backend{Evaluator{}, fake{"fake", Result{Value: "3", Output: "1\n"}, nil}},
+			"program: results differ\n  evaluator: value: 2, output: \"1\\n\"\n  fake: value: 3, output: \"1\\n\"",
+		},
+		{
+			"error",
+			[]Backend{
+				VM{},
+				fake{"fake", Result{Error: "ERROR: boom"}, nil},
+				fake{"unsupported", Result{}, errors.New("not supported")},
+			},
+			"program: results differ\n  vm: value: 2, output: \"1\\n\"\n  fake: error: ERROR: boom\n  unsupported: skipped: not supported",
+		},
+		{
+			// the panic is reported even if every backend panics the same way
+			"panic",
+			[]Backend{panicking{"first"}, panicking{"second"}},
+			"program: results differ\n  first: panic: boom\n",
+		},
+	}
+
+	for _, tt := range tests {
+		diff := Compare("program", program, tt.backends...)
+		if tt.name == "panic" {
+			// the report ends with the stack of the panic
+			if diff == nil || !strings.HasPrefix(diff.String(), tt.expected) {
+				t.Errorf("%s: wrong difference. want prefix=%q, got=%v", tt.name, tt.expected, diff)
+			}
+			continue
+		}
+		actual := ""
+		if diff != nil {
+			actual = diff.String()
+		}
+		if actual != tt.expected {
+			t.Errorf("%s: wrong difference. want=%q, got=%q", tt.name, tt.expected, actual)
+		}
+	}
+}
+
+func TestCapture(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"puts(\"a\", [1]); 1", "value: 1, output: \"a\\n[1]\\n\""},
+		{"let a = 1", "value: "},
+		{"1 / 0", "error: Error: division by zero"},
+		{"puts(1); -true", "error: TypeError: unknown operator: -BOOLEAN, output: \"1\\n\""},
+	}
+
+	for _, tt := range tests {
+		for _, backend := range []Backend{Evaluator{}, VM{}} {
+			result, err := backend.Run(parse(t, tt.input))
+			if err != nil {
+				t.Fatalf("%s: backend error: %s", backend.Name(), err)
+			}
+			if result.String() != tt.expected {
+				t.Errorf("%s: wrong result of %q. want=%q, got=%q", backend.Name(), tt.input, tt.expected, result)
+			}
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package difftest
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/technoboom/compiler/ast"
+)
+
+// Format - returns source code of the program produced by the generator.
+// Unlike String of the AST nodes, the code can be parsed back, so failing
+// random programs can be saved into the corpus. Every compound expression
+// is wrapped in parentheses
+func Format(program *ast.Program) string {
+	var out bytes.Buffer
+	formatStatements(&out, program.Statements, "")
+	return out.String()
+}
+
+// formatStatements - writes statements separated by semicolons, each
+// statement on its own line with the indentation
+func formatStatements(out *bytes.Buffer, statements []ast.Statement, indent string) {
+	for idx, s := range statements {
+		out.WriteString(indent)
+		switch s := s.(type) {
+		case *ast.LetStatement:
+			out.WriteString("let " + s.Pattern.String() + " = ")
+			formatExpression(out, s.Value, indent)
+		case *ast.ReturnStatement:
+			out.WriteString("return ")
+			formatExpression(out, s.ReturnValue, indent)
+		case *ast.ExpressionStatement:
+			formatExpression(out, s.Expression, indent)
+		default:
+			panic(fmt.Sprintf("can't format statement %T", s))
+		}
+		if idx < len(statements)-1 {
+			out.WriteString(";")
+		}
+		out.WriteString("\n")
+	}
+}
+
+// formatBlock - writes block statement in braces
+func formatBlock(out *bytes.Buffer, block *ast.BlockStatement, indent string) {
+	if len(block.Statements) == 0 {
+		out.WriteString("{ }")
+		return
+	}
+	out.WriteString("{\n")
+	formatStatements(out, block.Statements, indent+"  ")
+	out.WriteString(indent + "}")
+}
+
+// formatList - writes comma separated expressions
+func formatList(out *bytes.Buffer, expressions []ast.Expression, indent string) {
+	for idx, e := range expressions {
+		if idx > 0 {
+			out.WriteString(", ")
+		}
+		formatExpression(out, e, indent)
+	}
+}
+
+// formatExpression - writes the expression
+func formatExpression(out *bytes.Buffer, e ast.Expression, indent string) {
+	switch e := e.(type) {
+	case *ast.Identifier, *ast.IntegerLiteral, *ast.Boolean, *ast.NullLiteral:
+		out.WriteString(e.String())
+	case *ast.StringLiteral:
+		// strings of the generator consist of letters
+		out.WriteString(`"` + e.Value + `"`)
+	case *ast.PrefixExpression:
+		out.WriteString("(" + e.Operator)
+		formatExpression(out, e.Right, indent)
+		out.WriteString(")")
+	case *ast.InfixExpression:
+		out.WriteString("(")
+		formatExpression(out, e.Left, indent)
+		out.WriteString(" " + e.Operator + " ")
+		formatExpression(out, e.Right, indent)
+		out.WriteString(")")
+	case *ast.ConditionalExpression:
+		out.WriteString("(")
+		formatExpression(out, e.Condition, indent)
+		out.WriteString(" ? ")
+		formatExpression(out, e.Consequence, indent)
+		out.WriteString(" : ")
+		formatExpression(out, e.Alternative, indent)
+		out.WriteString(")")
+	case *ast.IfExpression:
+		out.WriteString("if (")
+		formatExpression(out, e.Condition, indent)
+		out.WriteString(") ")
+		formatBlock(out, e.Consequence, indent)
+		if e.Alternative != nil {
+			out.WriteString(" else ")
+			formatBlock(out, e.Alternative, indent)
+		}
+	case *ast.FunctionLiteral:
+		params := []string{}
+		for _, p := range e.Parameters {
+			params = append(params, p.Value)
+		}
+		out.WriteString("((" + strings.Join(params, ", ") + ") => ")
+		formatBlock(out, e.Body, indent)
+		out.WriteString(")")
+	case *ast.CallExpression:
+		formatExpression(out, e.Function, indent)
+		out.WriteString("(")
+		formatList(out, e.Arguments, indent)
+		out.WriteString(")")
+	case *ast.ArrayLiteral:
+		out.WriteString("[")
+		formatList(out, e.Elements, indent)
+		out.WriteString("]")
+	case *ast.HashLiteral:
+		// hash in parentheses isn't confused with block
+		out.WriteString("({")
+		for idx, pair := range e.Pairs {
+			if idx > 0 {
+				out.WriteString(", ")
+			}
+			formatExpression(out, pair.Key, indent)
+			out.WriteString(": ")
+			formatExpression(out, pair.Value, indent)
+		}
+		out.WriteString("})")
+	default:
+		panic(fmt.Sprintf("can't format expression %T", e))
+	}
+}
@@ -0,0 +1,51 @@
+package difftest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/technoboom/compiler/ast"
+	"github.com/technoboom/compiler/lexer"
+	"github.com/technoboom/compiler/parser"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1 + 2 * -x", "(1 + (2 * (-x)))"},
+		{"let a = !true; a", "let a = (!true);\na"},
+		{"x ?? 1 == y ? \"a\" : null", "((x ?? (1 == y)) ? \"a\" : null)"},
+		{"if (x) { 1 } else { }", "if (x) {\n  1\n} else { }"},
+		{"let f = (a, b) => { return a; b }; f(1, 2)",
+			"let f = ((a, b) => {\n  return a;\n  b\n});\nf(1, 2)"},
+		{"[1, {\"a\": []}]", "[1, ({\"a\": []})]"},
+	}
+
+	for _, tt := range tests {
+		actual := strings.TrimSuffix(Format(parse(t, tt.input)), "\n")
+		if actual != tt.expected {
+			t.Errorf("wrong format of %q. want=%q, got=%q", tt.input, tt.expected, actual)
+		}
+	}
+}
+
+func TestFormatRoundTrip(t *testing.T) {
+	g := NewGenerator(1)
+	for idx := 0; idx < 100; idx++ {
+		source := Format(g.Program())
+		if formatted := Format(parse(t, source)); formatted != source {
+			t.Fatalf("formatted program differs after parsing:\n%s\n---\n%s", source, formatted)
+		}
+	}
+}
+
+func parse(t *testing.T, input string) *ast.Program {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors for %q: %s", input, strings.Join(p.Errors(), "; "))
+	}
+	return program
+}
@@ -0,0 +1,528 @@
+package difftest
+
+import (
+	"math/rand"
+	"strconv"
+
+	"github.com/technoboom/compiler/ast"
+	"github.com/technoboom/compiler/token"
+)
+
+// kind - type of the generated values
+type kind int
+
+const (
+	intKind kind = iota
+	boolKind
+	stringKind
+	nullKind
+	arrayKind
+	hashKind
+	funcKind
+	numKinds
+)
+
+// signature - parameters and result of the generated function
+type signature struct {
+	params []kind
+	result kind
+	// signature of the function returned by the function
+	returns *signature
+}
+
+// variable - binding visible to the generated code
+type variable struct {
+	name string
+	kind kind
+	fn   *signature
+}
+
+// Generator - produces random well-formed programs in the subset of the
+// language supported by every backend. Values mostly have the types the
+// operators expect, so programs don't stop at the first operation, but
+// some of them fail with runtime errors. Functions are never printed, as
+// every backend shows them in its own way. Programs exercise the scoping
+// rules: functions refer to globals defined after them, rebind variables
+// and shadow variables of the enclosing scopes. Programs always terminate:
+// names are never reused for functions, so functions can call only
+// functions defined before them
+type Generator struct {
+	// maximum depth of nested expressions
+	MaxDepth int
+	// maximum number of statements of the program and function bodies
+	MaxStatements int
+	// probability of the operand of unexpected type
+	ErrorRate float64
+
+	rand  *rand.Rand
+	scope []variable
+	// index of the first variable of the current function in the scope
+	locals int
+	// result of the current function (or the program)
+	result   kind
+	returns  *signature
+	numNames int
+	// globals used by the functions before their let statements, they are
+	// bound right after the statement of the program which uses them
+	forward []variable
+	// the function bound by the let statement of the program is generated,
+	// it's called after the statement, so it can use forward globals
+	deferred bool
+	// number of the enclosing functions and of those of them which are
+	// invoked in place, the latter run before the forward globals are bound
+	functions, invoked int
+}
+
+// NewGenerator - creates generator of the programs, generators with
+// the same seed produce the same programs
+func NewGenerator(seed int64) *Generator {
+	return &Generator{
+		MaxDepth:      4,
+		MaxStatements: 6,
+		ErrorRate:     0.02,
+		rand:          rand.New(rand.NewSource(seed)),
+	}
+}
+
+// Program - returns the new random program, it ends with expression
+// which is the result of the program
+func (g *Generator) Program() *ast.Program {
+	g.scope = nil
+	g.locals = 0
+	g.result, g.returns = g.printable(), nil
+	g.numNames = 0
+	g.forward = nil
+	g.deferred, g.functions, g.invoked = false, 0, 0
+
+	return &ast.Program{Statements: g.statements(g.result, nil, 0)}
+}
+
+// statements - returns lets and expression statements ending with the
+// expression of the kind. Functions may return early
+func (g *Generator) statements(k kind, fn *signature, depth int) []ast.Statement {
+	visible := len(g.scope)
+	defer func() { g.scope = g.scope[:visible] }()
+
+	statements := []ast.Statement{}
+	for n := g.rand.Intn(g.MaxStatements); n > 0; n-- {
+		if depth == 0 {
+			statements = append(statements, g.bindForward()...)
+		}
+
+		switch r := g.rand.Intn(10); {
+		case r < 5:
+			statements = append(statements, g.let(depth))
+		case r < 8:
+			statements = append(statements, expressionStatement(g.puts(depth)))
+		default:
+			// early return from the function (or the program)
+			statements = append(statements, expressionStatement(&ast.IfExpression{
+				Token:       token.Token{Type: token.IF, Literal: "if"},
+				Condition:   g.expression(boolKind, depth+1),
+				Consequence: block(g.returnStatement(depth + 1)),
+			}))
+		}
+	}
+
+	if depth == 0 {
+		statements = append(statements, g.bindForward()...)
+	}
+	if g.rand.Intn(5) == 0 {
+		return append(statements, g.returnStatement(depth))
+	}
+	return append(statements, expressionStatement(g.typed(k, fn, depth)))
+}
+
+// bindForward - returns let statements of the forward globals used by the
+// previous statement of the program
+func (g *Generator) bindForward() []ast.Statement {
+	statements := []ast.Statement{}
+	for len(g.forward) > 0 {
+		v := g.forward[0]
+		g.forward = g.forward[1:]
+		statements = append(statements, letStatement(v.name, g.expression(v.kind, 1)))
+		g.scope = append(g.scope, v)
+	}
+	return statements
+}
+
+// let - returns let statement binding the new name, assigning other
+// value of the same kind to the existing variable of the function or
+// shadowing the variable of the enclosing scopes (except functions)
+func (g *Generator) let(depth int) *ast.LetStatement {
+	if locals := g.scope[g.locals:]; len(locals) > 0 && g.rand.Intn(4) == 0 {
+		v := locals[g.rand.Intn(len(locals))]
+		if v.kind != funcKind {
+			return letStatement(v.name, g.expression(v.kind, depth+1))
+		}
+	}
+	if outer := g.scope[:g.locals]; len(outer) > 0 && g.rand.Intn(6) == 0 {
+		// the value may still read the outer variable
+		v := outer[g.rand.Intn(len(outer))]
+		if v.kind != funcKind {
+			value := g.expression(v.kind, depth+1)
+			g.scope = append(g.scope, v)
+			return letStatement(v.name, value)
+		}
+	}
+
+	k := g.kind()
+	var fn *signature
+	if k == funcKind {
+		fn = g.signature(depth)
+	}
+	g.deferred = depth == 0 && k == funcKind
+	value := g.typed(k, fn, depth+1)
+	g.deferred = false
+
+	name := g.newName()
+	g.scope = append(g.scope, variable{name: name, kind: k, fn: fn})
+	return letStatement(name, value)
+}
+
+// returnStatement - returns return statement with the result of the
+// current function
+func (g *Generator) returnStatement(depth int) *ast.ReturnStatement {
+	return &ast.ReturnStatement{
+		Token:       token.Token{Type: token.RETURN, Literal: "return"},
+		ReturnValue: g.typed(g.result, g.returns, depth),
+	}
+}
+
+// puts - returns call of puts with random values
+func (g *Generator) puts(depth int) ast.Expression {
+	args := []ast.Expression{}
+	for n := g.rand.Intn(3) + 1; n > 0; n-- {
+		args = append(args, g.expression(g.printable(), depth+1))
+	}
+	return call(identifier("puts"), args)
+}
+
+// kind - returns random kind of values
+func (g *Generator) kind() kind {
+	return kind(g.rand.Intn(int(numKinds)))
+}
+
+// printable - returns random kind of values except functions
+func (g *Generator) printable() kind {
+	return kind(g.rand.Intn(int(funcKind)))
+}
+
+// signature - returns random signature of the function
+func (g *Generator) signature(depth int) *signature {
+	fn := &signature{result: g.kind()}
+	for n := g.rand.Intn(3); n > 0; n-- {
+		fn.params = append(fn.params, kind(g.rand.Intn(int(nullKind))))
+	}
+	if fn.result == funcKind {
+		if depth >= g.MaxDepth {
+			fn.result = intKind
+		} else {
+			fn.returns = g.signature(depth + 1)
+		}
+	}
+	return fn
+}
+
+// expression - returns expression of the kind, functions get random
+// signature
+func (g *Generator) expression(k kind, depth int) ast.Expression {
+	if g.rand.Float64() < g.ErrorRate {
+		k = g.printable()
+	}
+	var fn *signature
+	if k == funcKind {
+		fn = g.signature(depth)
+	}
+	return g.typed(k, fn, depth)
+}
+
+// typed - returns expression of the kind, functions have the signature
+func (g *Generator) typed(k kind, fn *signature, depth int) ast.Expression {
+	leaf := depth >= g.MaxDepth
+	choice := g.rand.Intn(10)
+
+	// variables and calls of the functions producing the kind
+	if choice < 3 {
+		if e := g.fromScope(k, fn, depth); e != nil {
+			return e
+		}
+	}
+	if !leaf && choice == 3 {
+		return g.conditional(k, fn, depth)
+	}
+	if !leaf && choice == 4 && k != funcKind {
+		// immediately invoked function
+		g.invoked++
+		literal := g.function(&signature{result: k, returns: fn}, depth)
+		g.invoked--
+		return call(literal, g.arguments(nil, depth))
+	}
+
+	switch k {
+	case intKind:
+		if leaf || choice < 7 {
+			return integer(int64(g.rand.Intn(100)))
+		}
+		if choice == 7 {
+			return prefix("-", g.expression(intKind, depth+1))
+		}
+		if choice == 8 {
+			left := g.expression(nullKind, depth+1)
+			if g.rand.Intn(2) == 0 {
+				left = g.expression(intKind, depth+1)
+			}
+			return infix(left, "??", g.expression(intKind, depth+1))
+		}
+		operators := []string{"+", "-", "*", "/"}
+		return infix(g.expression(intKind, depth+1), operators[g.rand.Intn(len(operators))],
+			g.expression(intKind, depth+1))
+	case boolKind:
+		if leaf || choice < 6 {
+			return boolean(g.rand.Intn(2) == 0)
+		}
+		if choice == 6 {
+			return prefix("!", g.expression(g.kind(), depth+1))
+		}
+		if choice == 7 {
+			operators := []string{"<", ">"}
+			return infix(g.expression(intKind, depth+1), operators[g.rand.Intn(2)],
+				g.expression(intKind, depth+1))
+		}
+		operand := g.kind()
+		operators := []string{"==", "!="}
+		return infix(g.expression(operand, depth+1), operators[g.rand.Intn(2)],
+			g.expression(operand, depth+1))
+	case stringKind:
+		return str(g.word())
+	case nullKind:
+		if leaf || choice < 8 {
+			return null()
+		}
+		return g.puts(depth)
+	case arrayKind:
+		elements := []ast.Expression{}
+		if !leaf {
+			for n := g.rand.Intn(4); n > 0; n-- {
+				elements = append(elements, g.expression(g.printable(), depth+1))
+			}
+		}
+		return &ast.ArrayLiteral{Token: token.Token{Type: token.LSQUARE, Literal: "["}, Elements: elements}
+	case hashKind:
+		pairs := []*ast.HashPair{}
+		if !leaf {
+			for n := g.rand.Intn(4); n > 0; n-- {
+				key := g.expression(kind(g.rand.Intn(int(nullKind))), depth+1)
+				pairs = append(pairs, &ast.HashPair{Key: key, Value: g.expression(g.printable(), depth+1)})
+			}
+		}
+		return &ast.HashLiteral{Token: token.Token{Type: token.LBRACKET, Literal: "{"}, Pairs: pairs}
+	default:
+		return g.function(fn, depth)
+	}
+}
+
+// fromScope - returns variable of the kind or call of the function which
+// returns the kind, nil if there are no such variables
+func (g *Generator) fromScope(k kind, fn *signature, depth int) ast.Expression {
+	variables := []variable{}
+	calls := []variable{}
+	for _, v := range g.scope {
+		switch {
+		case v.kind == k && (k != funcKind || sameSignature(v.fn, fn)):
+			variables = append(variables, v)
+		case v.kind == funcKind && v.fn.result == k && depth < g.MaxDepth &&
+			(k != funcKind || sameSignature(v.fn.returns, fn)):
+			calls = append(calls, v)
+		}
+	}
+
+	// global bound after the statement of the program, which defines
+	// the function
+	if g.deferred && g.functions > 0 && g.invoked == 0 && k != funcKind && g.rand.Intn(8) == 0 {
+		v := variable{name: g.newName(), kind: k}
+		g.forward = append(g.forward, v)
+		return identifier(v.name)
+	}
+
+	n := len(variables) + len(calls)
+	if n == 0 {
+		return nil
+	}
+	if idx := g.rand.Intn(n); idx < len(variables) {
+		return identifier(variables[idx].name)
+	} else {
+		v := calls[idx-len(variables)]
+		return call(identifier(v.name), g.arguments(v.fn, depth))
+	}
+}
+
+// conditional - returns if expression or ternary with branches of the kind
+func (g *Generator) conditional(k kind, fn *signature, depth int) ast.Expression {
+	condition := g.expression(g.kind(), depth+1)
+
+	if g.rand.Intn(2) == 0 {
+		return &ast.ConditionalExpression{
+			Token:       token.Token{Type: token.QUESTION, Literal: "?"},
+			Condition:   condition,
+			Consequence: g.typed(k, fn, depth+1),
+			Alternative: g.typed(k, fn, depth+1),
+		}
+	}
+
+	expression := &ast.IfExpression{
+		Token:       token.Token{Type: token.IF, Literal: "if"},
+		Condition:   condition,
+		Consequence: block(g.statements(k, fn, depth+1)...),
+	}
+	// missing alternative produces null
+	if k != nullKind || g.rand.Intn(2) == 0 {
+		expression.Alternative = block(g.statements(k, fn, depth+1)...)
+	}
+	return expression
+}
+
+// function - returns function literal of the signature
+func (g *Generator) function(fn *signature, depth int) ast.Expression {
+	literal := &ast.FunctionLiteral{Token: token.Token{Type: token.ARROW, Literal: "=>"}}
+
+	visible, locals := len(g.scope), g.locals
+	result, returns := g.result, g.returns
+	g.locals = visible
+	g.functions++
+	g.result, g.returns = fn.result, fn.returns
+	for _, k := range fn.params {
+		name := g.newName()
+		literal.Parameters = append(literal.Parameters, identifier(name))
+		g.scope = append(g.scope, variable{name: name, kind: k})
+	}
+	literal.Body = block(g.statements(fn.result, fn.returns, depth+1)...)
+	g.scope, g.locals = g.scope[:visible], locals
+	g.result, g.returns = result, returns
+	g.functions--
+
+	return literal
+}
+
+// arguments - returns arguments of the call of the function, some calls
+// have wrong number of arguments
+func (g *Generator) arguments(fn *signature, depth int) []ast.Expression {
+	params := []kind{}
+	if fn != nil {
+		params = fn.params
+	}
+	if g.rand.Float64() < g.ErrorRate {
+		params = append(params, intKind)
+	}
+
+	args := []ast.Expression{}
+	for _, k := range params {
+		args = append(args, g.expression(k, depth+1))
+	}
+	return args
+}
+
+// newName - returns the new name of the variable, names consist of
+// letters only
+func (g *Generator) newName() string {
+	name := []byte{}
+	for n := g.numNames; ; n = n/26 - 1 {
+		name = append([]byte{byte('a' + n%26)}, name...)
+		if n < 26 {
+			break
+		}
+	}
+	g.numNames++
+	// names of keywords and builtins get a prefix
+	return "v" + string(name)
+}
+
+// word - returns random string of letters
+func (g *Generator) word() string {
+	letters := make([]byte, g.rand.Intn(4))
+	for idx := range letters {
+		letters[idx] = byte('a' + g.rand.Intn(26))
+	}
+	return string(letters)
+}
+
+// sameSignature - checks if functions of the signatures are interchangeable
+func sameSignature(a *signature, b *signature) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if a.result != b.result || len(a.params) != len(b.params) {
+		return false
+	}
+	for idx := range a.params {
+		if a.params[idx] != b.params[idx] {
+			return false
+		}
+	}
+	return sameSignature(a.returns, b.returns)
+}
+
+func identifier(name string) *ast.Identifier {
+	return &ast.Identifier{Token: token.Token{Type: token.IDENT, Literal: name}, Value: name}
+}
+
+func integer(value int64) *ast.IntegerLiteral {
+	literal := strconv.FormatInt(value, 10)
+	return &ast.IntegerLiteral{Token: token.Token{Type: token.INT, Literal: literal}, Value: value}
+}
+
+func boolean(value bool) *ast.Boolean {
+	if value {
+		return &ast.Boolean{Token: token.Token{Type: token.TRUE, Literal: "true"}, Value: true}
+	}
+	return &ast.Boolean{Token: token.Token{Type: token.FALSE, Literal: "false"}, Value: false}
+}
+
+func str(value string) *ast.StringLiteral {
+	return &ast.StringLiteral{Token: token.Token{Type: token.STRING, Literal: value}, Value: value}
+}
+
+func null() *ast.NullLiteral {
+	return &ast.NullLiteral{Token: token.Token{Type: token.NULL, Literal: "null"}}
+}
+
+func prefix(operator string, right ast.Expression) *ast.PrefixExpression {
+	return &ast.PrefixExpression{
+		Token:    token.Token{Type: token.Type(operator), Literal: operator},
+		Operator: operator,
+		Right:    right,
+	}
+}
+
+func infix(left ast.Expression, operator string, right ast.Expression) *ast.InfixExpression {
+	return &ast.InfixExpression{
+		Token:    token.Token{Type: token.Type(operator), Literal: operator},
+		Left:     left,
+		Operator: operator,
+		Right:    right,
+	}
+}
+
+func call(fn ast.Expression, args []ast.Expression) *ast.CallExpression {
+	return &ast.CallExpression{
+		Token:     token.Token{Type: token.LPAREN, Literal: "("},
+		Function:  fn,
+		Arguments: args,
+	}
+}
+
+func block(statements ...ast.Statement) *ast.BlockStatement {
+	return &ast.BlockStatement{Token: token.Token{Type: token.LBRACKET, Literal: "{"}, Statements: statements}
+}
+
+func expressionStatement(e ast.Expression) *ast.ExpressionStatement {
+	return &ast.ExpressionStatement{Token: token.Token{Literal: e.TokenLiteral()}, Expression: e}
+}
+
+func letStatement(name string, value ast.Expression) *ast.LetStatement {
+	return &ast.LetStatement{
+		Token:   token.Token{Type: token.LET, Literal: "let"},
+		Pattern: identifier(name),
+		Value:   value,
+	}
+}
@@ -0,0 +1,137 @@
+package difftest
+
+import (
+	"flag"
+	"os"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/technoboom/compiler/ast"
+	"github.com/technoboom/compiler/optimizer"
+)
+
+// defaultSeed - seed of the random programs if neither the flag nor
+// the environment variable sets it, so runs are reproducible
+const defaultSeed = 1
+
+var (
+	numPrograms = flag.Int("programs", 300, "number of random programs compared by TestRandomPrograms")
+	seed        = flag.Int64("seed", 0, "seed of the random programs, overrides $DIFFTEST_SEED")
+)
+
+// programsSeed - returns seed of the random programs: the flag, then
+// $DIFFTEST_SEED, then the default one
+func programsSeed() (int64, error) {
+	if *seed != 0 {
+		return *seed, nil
+	}
+	if env := os.Getenv("DIFFTEST_SEED"); env != "" {
+		return strconv.ParseInt(env, 10, 64)
+	}
+	return defaultSeed, nil
+}
+
+func TestRandomPrograms(t *testing.T) {
+	s, err := programsSeed()
+	if err != nil {
+		t.Fatalf("wrong seed: %s", err)
+	}
+	t.Logf("seed: %d", s)
+
+	g := NewGenerator(s)
+	backends := []Backend{Evaluator{}, VM{}, VM{Level: optimizer.O2}}
+	for idx := 0; idx < *numPrograms; idx++ {
+		program := g.Program()
+		if diff := Compare("program", program, backends...); diff != nil {
+			t.Fatalf("%s\nprogram %d of seed %d:\n%s", diff, idx, s, Format(program))
+		}
+	}
+}
+
+func TestGeneratorDeterminism(t *testing.T) {
+	a, b := NewGenerator(42), NewGenerator(42)
+	for idx := 0; idx < 20; idx++ {
+		first, second := Format(a.Program()), Format(b.Program())
+		if first != second {
+			t.Fatalf("programs %d of the same seed differ:\n%s\n---\n%s", idx, first, second)
+		}
+	}
+}
+
+// TestGeneratorScoping - programs use globals before their let statements
+// and rebind variables
+func TestGeneratorScoping(t *testing.T) {
+	g := NewGenerator(defaultSeed)
+
+	var forward, shadowed bool
+	for idx := 0; idx < 200 && !(forward && shadowed); idx++ {
+		program := g.Program()
+		forward = forward || usesForward(program)
+		shadowed = shadowed || rebinds(program)
+	}
+	if !forward {
+		t.Errorf("no program uses globals before their let statements")
+	}
+	if !shadowed {
+		t.Errorf("no function rebinds variables of the enclosing scopes")
+	}
+}
+
+// usesForward - checks if the function bound by the program refers to
+// the global bound later
+func usesForward(program *ast.Program) bool {
+	for idx, s := range program.Statements {
+		let, ok := s.(*ast.LetStatement)
+		if !ok {
+			continue
+		}
+		if _, ok := let.Value.(*ast.FunctionLiteral); !ok {
+			continue
+		}
+		for _, later := range program.Statements[idx+1:] {
+			l, ok := later.(*ast.LetStatement)
+			if ok && regexp.MustCompile(`\b`+l.Pattern.String()+`\b`).MatchString(let.Value.String()) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// rebinds - checks if the function of the program has let statement
+// of the name bound outside of it
+func rebinds(program *ast.Program) bool {
+	globals := map[string]bool{}
+	for _, s := range program.Statements {
+		if let, ok := s.(*ast.LetStatement); ok {
+			globals[let.Pattern.String()] = true
+		}
+	}
+
+	found := false
+	var walk func(node ast.Node, inside bool)
+	walk = func(node ast.Node, inside bool) {
+		switch node := node.(type) {
+		case *ast.LetStatement:
+			if inside && globals[node.Pattern.String()] {
+				found = true
+			}
+			walk(node.Value, inside)
+		case *ast.FunctionLiteral:
+			walk(node.Body, true)
+		case *ast.BlockStatement:
+			for _, s := range node.Statements {
+				walk(s, inside)
+			}
+		case *ast.ExpressionStatement:
+			walk(node.Expression, inside)
+		case *ast.CallExpression:
+			walk(node.Function, inside)
+		}
+	}
+	for _, s := range program.Statements {
+		walk(s, false)
+	}
+	return found
+}
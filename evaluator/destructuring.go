@@ -0,0 +1,246 @@
+package evaluator
+
+import (
+	"fmt"
+
+	"github.com/technoboom/compiler/ast"
+	"github.com/technoboom/compiler/object"
+)
+
+// mismatch - describes why the value doesn't match the pattern
+type mismatch struct {
+	message string
+}
+
+// newMismatch - creates mismatch with given message as formatted string
+func newMismatch(format string, a ...interface{}) *mismatch {
+	return &mismatch{message: fmt.Sprintf(format, a...)}
+}
+
+// bindPattern - destructures the value accordingly to the pattern and binds
+// all names of the pattern in the environment
+// returns error object if shape of the value doesn't match the pattern,
+// otherwise returns nil
+func bindPattern(
+	pattern ast.Pattern,
+	value object.Object,
+	env *object.Environment,
+) object.Object {
+	m, err := destructure(pattern, value, env)
+	if err != nil {
+		return err
+	}
+	if m != nil {
+		return newError(object.MATCH_ERROR, "%s", m.message)
+	}
+	return nil
+}
+
+// matchPattern - checks if the value matches the pattern, binds all names of
+// the pattern in the environment on the way
+// returns error object only if evaluation of default values failed
+func matchPattern(
+	pattern ast.Pattern,
+	value object.Object,
+	env *object.Environment,
+) (bool, object.Object) {
+	m, err := destructure(pattern, value, env)
+	if err != nil {
+		return false, err
+	}
+	return m == nil, nil
+}
+
+// destructure - walks the pattern and the value together and binds names
+// of the pattern. Returns mismatch if shape of the value doesn't match the
+// pattern or error object if evaluation of default values failed
+func destructure(
+	pattern ast.Pattern,
+	value object.Object,
+	env *object.Environment,
+) (*mismatch, object.Object) {
+	switch pattern := pattern.(type) {
+	case *ast.Identifier:
+		if variant := variantConstant(pattern, env); variant != nil {
+			if !objectsEqual(variant, value) {
+				return newMismatch("value doesn't match pattern: want=%s, got=%s",
+					variant.Inspect(), value.Inspect()), nil
+			}
+		}
+		bind(pattern, value, env)
+		return nil, nil
+	case *ast.VariantPattern:
+		return destructureVariant(pattern, value, env)
+	case *ast.WildcardPattern:
+		return nil, nil
+	case *ast.LiteralPattern:
+		return destructureLiteral(pattern, value, env)
+	case *ast.DefaultPattern:
+		return destructure(pattern.Target, value, env)
+	case *ast.ArrayPattern:
+		return destructureArray(pattern, value, env)
+	case *ast.HashPattern:
+		return destructureHash(pattern, value, env)
+	default:
+		return nil, newError(object.ERROR, "unknown pattern: %s", pattern.String())
+	}
+}
+
+// destructureLiteral - checks that the value equals to the literal
+func destructureLiteral(
+	pattern *ast.LiteralPattern,
+	value object.Object,
+	env *object.Environment,
+) (*mismatch, object.Object) {
+	literal := Eval(pattern.Value, env)
+	if isError(literal) {
+		return nil, literal
+	}
+
+	if !objectsEqual(literal, value) {
+		return newMismatch("value doesn't match pattern: want=%s, got=%s",
+			literal.Inspect(), value.Inspect()), nil
+	}
+	return nil, nil
+}
+
+// destructureArray - destructures array elements one by one, extra elements
+// are collected into the rest element (if pattern doesn't have it, extra
+// elements are treated as a mismatch)
+func destructureArray(
+	pattern *ast.ArrayPattern,
+	value object.Object,
+	env *object.Environment,
+) (*mismatch, object.Object) {
+	array, ok := value.(*object.Array)
+	if !ok {
+		return newMismatch("cannot destructure %s as array", value.Type()), nil
+	}
+
+	for idx, element := range pattern.Elements {
+		if idx < len(array.Elements) {
+			m, err := destructure(element, array.Elements[idx], env)
+			if m != nil || err != nil {
+				return m, err
+			}
+			continue
+		}
+
+		bound, err := bindDefault(element, env)
+		if err != nil {
+			return nil, err
+		}
+		if !bound {
+			return newMismatch("not enough elements to destructure: want=%d, got=%d",
+				len(pattern.Elements), len(array.Elements)), nil
+		}
+	}
+
+	if pattern.Rest != nil {
+		rest := []object.Object{}
+		if len(array.Elements) > len(pattern.Elements) {
+			rest = append(rest, array.Elements[len(pattern.Elements):]...)
+		}
+		bind(pattern.Rest, &object.Array{Elements: rest}, env)
+		return nil, nil
+	}
+
+	if len(array.Elements) > len(pattern.Elements) {
+		return newMismatch("too many elements to destructure: want=%d, got=%d",
+			len(pattern.Elements), len(array.Elements)), nil
+	}
+
+	return nil, nil
+}
+
+// destructureHash - destructures hash values by keys, pairs which weren't
+// destructured are collected into the rest element (or ignored)
+func destructureHash(
+	pattern *ast.HashPattern,
+	value object.Object,
+	env *object.Environment,
+) (*mismatch, object.Object) {
+	hash, ok := value.(*object.Hash)
+	if !ok {
+		return newMismatch("cannot destructure %s as hash", value.Type()), nil
+	}
+
+	used := make(map[object.HashKey]bool)
+	for _, pair := range pattern.Pairs {
+		key := &object.String{Value: pair.Key.Value}
+		used[key.HashKey()] = true
+
+		if val, ok := hash.Get(key); ok {
+			m, err := destructure(pair.Value, val, env)
+			if m != nil || err != nil {
+				return m, err
+			}
+			continue
+		}
+
+		bound, err := bindDefault(pair.Value, env)
+		if err != nil {
+			return nil, err
+		}
+		if !bound {
+			return newMismatch("key not found: %s", pair.Key.Value), nil
+		}
+	}
+
+	if pattern.Rest != nil {
+		rest := object.NewHash()
+		for _, pair := range hash.Pairs() {
+			key := pair.Key.(object.Hashable)
+			if !used[key.HashKey()] {
+				rest.Set(key, pair.Value)
+			}
+		}
+		bind(pattern.Rest, rest, env)
+	}
+
+	return nil, nil
+}
+
+// bindDefault - evaluates default value of the pattern and binds it
+// returns false if the pattern doesn't have default value
+func bindDefault(
+	pattern ast.Pattern,
+	env *object.Environment,
+) (bool, object.Object) {
+	def, ok := pattern.(*ast.DefaultPattern)
+	if !ok {
+		return false, nil
+	}
+
+	value := Eval(def.Default, env)
+	if isError(value) {
+		return true, value
+	}
+
+	return true, bindPattern(def.Target, value, env)
+}
+
+// objectsEqual - compares objects by value (integers, strings, booleans and
+// null), other objects are compared by identity
+func objectsEqual(left, right object.Object) bool {
+	if left.Type() != right.Type() {
+		return false
+	}
+
+	switch left := left.(type) {
+	case *object.Integer:
+		return left.Value == right.(*object.Integer).Value
+	case *object.String:
+		return left.Value == right.(*object.String).Value
+	case *object.Boolean:
+		return left.Value == right.(*object.Boolean).Value
+	case *object.Null:
+		return true
+	case *object.Struct:
+		return structsEqual(left, right.(*object.Struct))
+	case *object.Variant:
+		return variantsEqual(left, right.(*object.Variant))
+	default:
+		return left == right
+	}
+}
@@ -0,0 +1,182 @@
+package evaluator
+
+import (
+	"github.com/technoboom/compiler/ast"
+	"github.com/technoboom/compiler/object"
+)
+
+// evalEnumStatement - declares enum type and its variants in the environment.
+// Variants with fields are bound as constructors, variants without fields
+// are bound as values
+func evalEnumStatement(node *ast.EnumStatement, env *object.Environment) {
+	enum := &object.EnumType{
+		Name:           node.Name.Value,
+		Members:        make(map[string]object.Object),
+		Implementation: object.NewImplementation(),
+	}
+
+	for _, v := range node.Variants {
+		fields := make([]string, len(v.Fields))
+		for idx, field := range v.Fields {
+			fields[idx] = field.Value
+		}
+
+		variant := &object.VariantType{Enum: enum, Name: v.Name.Value, Fields: fields}
+		enum.Variants = append(enum.Variants, variant)
+
+		var member object.Object = variant
+		if len(fields) == 0 {
+			member = &object.Variant{Definition: variant, Values: []object.Object{}}
+		}
+		enum.Members[variant.Name] = member
+		bind(v.Name, member, env)
+	}
+
+	bind(node.Name, enum, env)
+}
+
+// newVariant - creates value of the enum variant, fields are set
+// from positional and keyword arguments, all fields are required
+func newVariant(
+	vt *object.VariantType,
+	args []object.Object,
+	kwargs []keywordArgument,
+) object.Object {
+	values, err := matchArguments(vt.Fields, false, args, kwargs)
+	if err != nil {
+		return err
+	}
+
+	for idx, name := range vt.Fields {
+		if values[idx] == nil {
+			return newError(object.ARGUMENT_ERROR, "missing field: %s", name)
+		}
+	}
+
+	return &object.Variant{Definition: vt, Values: values}
+}
+
+// enumMember - returns constructor or value of the variant by its name
+func enumMember(enum *object.EnumType, name string) object.Object {
+	member, ok := enum.Members[name]
+	if !ok {
+		return newError(object.NAME_ERROR, "unknown variant: %s.%s", enum.Name, name)
+	}
+	return member
+}
+
+// variantField - returns value of the variant field by its name
+func variantField(variant *object.Variant, name string) object.Object {
+	idx := nameIndex(variant.Definition.Fields, name)
+	if idx < 0 {
+		return newError(object.NAME_ERROR,
+			"unknown field: %s.%s", variant.Definition.Name, name)
+	}
+	return variant.Values[idx]
+}
+
+// variantConstant - returns the variant without fields the name of the
+// pattern shadows, such names in patterns are compared with the value
+// instead of being bound. Returns nil if the name shadows anything else.
+// Names of unresolved patterns are looked up by name
+func variantConstant(name *ast.Identifier, env *object.Environment) *object.Variant {
+	var obj object.Object
+	var ok bool
+	switch {
+	case name.Location == nil:
+		obj, ok = env.LookupName(name.Value)
+	case name.Shadowed != nil:
+		obj, ok = env.Lookup(*name.Shadowed)
+	}
+	if !ok {
+		return nil
+	}
+
+	variant, ok := obj.(*object.Variant)
+	if !ok || len(variant.Values) != 0 || variant.Definition.Name != name.Value {
+		return nil
+	}
+	return variant
+}
+
+// destructureVariant - checks that the value is the variant of the pattern
+// and destructures its fields one by one
+func destructureVariant(
+	pattern *ast.VariantPattern,
+	value object.Object,
+	env *object.Environment,
+) (*mismatch, object.Object) {
+	ctor := variantPatternMember(pattern, env)
+	if isError(ctor) {
+		return nil, ctor
+	}
+
+	var definition *object.VariantType
+	switch ctor := ctor.(type) {
+	case *object.VariantType:
+		definition = ctor
+	case *object.Variant:
+		definition = ctor.Definition
+	default:
+		return nil, newError(object.TYPE_ERROR,
+			"not a variant: %s", pattern.Name.Value)
+	}
+
+	if len(pattern.Elements) != len(definition.Fields) {
+		return nil, newError(object.TYPE_ERROR,
+			"wrong number of fields in pattern %s: want=%d, got=%d",
+			definition.Name, len(definition.Fields), len(pattern.Elements))
+	}
+
+	variant, ok := value.(*object.Variant)
+	if !ok || variant.Definition != definition {
+		return newMismatch("value doesn't match pattern: want=%s, got=%s",
+			definition.Name, value.Inspect()), nil
+	}
+
+	for idx, element := range pattern.Elements {
+		m, err := destructure(element, variant.Values[idx], env)
+		if m != nil || err != nil {
+			return m, err
+		}
+	}
+
+	return nil, nil
+}
+
+// variantPatternMember - returns constructor or value of the variant
+// the pattern matches, the qualified variant is looked up in its enum
+func variantPatternMember(
+	pattern *ast.VariantPattern,
+	env *object.Environment,
+) object.Object {
+	if pattern.Enum == nil {
+		return evalIdentifier(pattern.Name, env)
+	}
+
+	obj := evalIdentifier(pattern.Enum, env)
+	if isError(obj) {
+		return obj
+	}
+	enum, ok := obj.(*object.EnumType)
+	if !ok {
+		return newError(object.TYPE_ERROR, "not an enum: %s", pattern.Enum.Value)
+	}
+	return enumMember(enum, pattern.Name.Value)
+}
+
+// variantsEqual - compares variants: both have the same tag
+// and all their fields are equal
+func variantsEqual(left, right *object.Variant) bool {
+	if left.Definition != right.Definition {
+		return false
+	}
+
+	for idx, value := range left.Values {
+		if !objectsEqual(value, right.Values[idx]) {
+			return false
+		}
+	}
+
+	return true
+}
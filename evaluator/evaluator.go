@@ -3,13 +3,14 @@ package evaluator
 import (
 	"github.com/technoboom/compiler/ast"
 	"github.com/technoboom/compiler/object"
+	"github.com/technoboom/compiler/token"
 	"fmt"
 )
 
 var (
-	NULL = &object.Null{}
-	TRUE = &object.Boolean{Value:true}
-	FALSE = &object.Boolean{Value:false}
+	NULL = object.NULL
+	TRUE = object.TRUE
+	FALSE = object.FALSE
 )
 
 // Eval - evaluates current node (traverses AST)
@@ -30,63 +31,108 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		if isError(right) {
 			return right
 		}
-		return evalPrefixExpression(node.Operator, right)
+		return withPosition(evalPrefixExpression(node.Operator, right), node.Token)
 	case *ast.InfixExpression:
 		left := Eval(node.Left, env)
 		if isError(left) {
 			return left
 		}
 
+		// right operand of ?? is evaluated only if left one is null
+		if node.Operator == "??" {
+			if left != NULL {
+				return left
+			}
+			return Eval(node.Right, env)
+		}
+
 		right := Eval(node.Right, env)
 		if isError(right) {
 			return right
 		}
 
-		return evalInfixExpression(node.Operator, left, right)
+		return withPosition(evalInfixExpression(node.Operator, left, right), node.Token)
 
 	case *ast.BlockStatement:
 		return evalBlockStatement(node, env)
 	case *ast.IfExpression:
 		return evalIfExpression(node, env)
+	case *ast.MatchExpression:
+		return withPosition(evalMatchExpression(node, env), node.Token)
+	case *ast.TryExpression:
+		return evalTryExpression(node, env)
+	case *ast.ConditionalExpression:
+		return evalConditionalExpression(node, env)
+	case *ast.NullLiteral:
+		return NULL
 	case *ast.ReturnStatement:
 		val := Eval(node.ReturnValue, env)
 		if isError(val) {
 			return val
 		}
 		return &object.ReturnValue{Value:val}
+	case *ast.ThrowStatement:
+		val := Eval(node.Value, env)
+		if isError(val) {
+			return val
+		}
+		return withPosition(newThrownError(val), node.Token)
 	case *ast.LetStatement:
 		val := Eval(node.Value, env)
 		if isError(val) {
 			return val
 		}
-		env.Set(node.Name.Value, val)
+		if err := bindPattern(node.Pattern, val, env); err != nil {
+			return withPosition(err, node.Token)
+		}
 	case *ast.Identifier:
-		return evalIdentifier(node, env)
+		return withPosition(evalIdentifier(node, env), node.Token)
 	case *ast.FunctionLiteral:
 		params := node.Parameters
 		body := node.Body
-		return &object.Function{Parameters:params, Env:env, Body:body}
-	case *ast.CallExpression:
-		function := Eval(node.Function, env)
-		if isError(function) {
-			return function
+		return &object.Function{
+			Parameters: params,
+			Defaults:   node.Defaults,
+			Rest:       node.Rest,
+			Env:        env,
+			Body:       body,
+			Scope:      node.Scope,
 		}
-		args := evalExpressions(node.Arguments, env)
-		if len(args) == 1 && isError(args[0]) {
-			return args[0]
-		}
-		return applyFunction(function, args)
+	case *ast.CallExpression:
+		return endChain(evalChainLink(node, env))
 	case *ast.StringLiteral:
 		return &object.String{Value:node.Value}
+	case *ast.ArrayLiteral:
+		elements := evalExpressions(node.Elements, env)
+		if len(elements) == 1 && isError(elements[0]) {
+			return elements[0]
+		}
+		return &object.Array{Elements: elements}
+	case *ast.HashLiteral:
+		return withPosition(evalHashLiteral(node, env), node.Token)
+	case *ast.StructStatement:
+		evalStructStatement(node, env)
+	case *ast.EnumStatement:
+		evalEnumStatement(node, env)
+	case *ast.TraitStatement:
+		evalTraitStatement(node, env)
+	case *ast.ImplStatement:
+		return withPosition(evalImplStatement(node, env), node.Token)
+	case *ast.MemberExpression:
+		return endChain(evalChainLink(node, env))
+	case *ast.AssignExpression:
+		return withPosition(evalAssignExpression(node, env), node.Token)
 	}
 	return nil
 }
 
-// evalProgram - evaluates all statements of the program
+// evalProgram - resolves and evaluates all statements of the program
 // returns result of evaluation
 func evalProgram(program *ast.Program, env *object.Environment) object.Object {
 	var result object.Object
 
+	resolve(program, env)
+
 	for _, statement := range program.Statements {
 		result = Eval(statement, env)
 
@@ -106,7 +152,7 @@ func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) obje
 	var result object.Object
 
 	for _, statement := range block.Statements {
-		result := Eval(statement, env)
+		result = Eval(statement, env)
 
 		if result != nil {
 			rt := result.Type()
@@ -147,13 +193,17 @@ func nativeBoolToBooleanObject(input bool) *object.Boolean {
 // evalPrefixExpression - contains switch to decide how to evaluate expression
 // with prefix structure
 func evalPrefixExpression(operator string, right object.Object) object.Object {
+	if result, ok := evalPrefixOperatorMethod(operator, right); ok {
+		return result
+	}
+
 	switch operator {
 	case "!":
 		return evalBangOperatorExpression(right)
 	case "-":
 		return evalMinusPrefixOperatorExpression(right)
 	default:
-		return newError("unknown operator: %s%s", operator, right.Type())
+		return newError(object.TYPE_ERROR, "unknown operator: %s%s", operator, right.Type())
 	}
 }
 
@@ -176,29 +226,34 @@ func evalBangOperatorExpression(right object.Object) object.Object {
 // returns evaluated object if expression is integer, else - returns NULL
 func evalMinusPrefixOperatorExpression(right object.Object) object.Object {
 	if right.Type() != object.INTEGER_OBJ {
-		return newError("unknown operator: -%s", right.Type())
+		return newError(object.TYPE_ERROR, "unknown operator: -%s", right.Type())
 	}
 
 	value := right.(*object.Integer).Value
 	return &object.Integer{Value: -value}
 }
 
-// evalInfixExpression - evaluates infix expressions
+// evalInfixExpression - evaluates infix expressions, operators of user
+// declared types are dispatched to their methods
 func evalInfixExpression(operator string,
 	left, right object.Object,
 ) object.Object {
+	if result, ok := evalOperatorMethod(operator, left, right); ok {
+		return result
+	}
+
 	switch {
 	case left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ:
 		return evalIntegerInfixExpression(operator, left, right)
 	case operator == "==":
-		return nativeBoolToBooleanObject(left == right)
+		return nativeBoolToBooleanObject(objectsEqual(left, right))
 	case operator == "!=":
-		return nativeBoolToBooleanObject(left != right)
+		return nativeBoolToBooleanObject(!objectsEqual(left, right))
 	case left.Type() != right.Type():
-		return newError("type mismatch: %s %s %s",
+		return newError(object.TYPE_ERROR, "type mismatch: %s %s %s",
 		left.Type(), operator, right.Type())
 	default:
-		return newError("unknown operator: %s %s %s",
+		return newError(object.TYPE_ERROR, "unknown operator: %s %s %s",
 		left.Type(), operator, right.Type())
 	}
 }
@@ -219,6 +274,9 @@ func evalIntegerInfixExpression(operator string,
 	case "*":
 		return &object.Integer{Value: leftVal * rightVal}
 	case "/":
+		if rightVal == 0 {
+			return newError(object.ERROR, "division by zero")
+		}
 		return &object.Integer{Value: leftVal / rightVal}
 	case "<":
 		return nativeBoolToBooleanObject(leftVal < rightVal)
@@ -229,7 +287,7 @@ func evalIntegerInfixExpression(operator string,
 	case "!=":
 		return nativeBoolToBooleanObject(leftVal != rightVal)
 	default:
-		return newError("unknown operator: %s %s %s",
+		return newError(object.TYPE_ERROR, "unknown operator: %s %s %s",
 		left.Type(), operator, right.Type())
 	}
 }
@@ -242,12 +300,142 @@ func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Obje
 		return condition
 	}
 
+	var result object.Object
 	if isTruly(condition) {
-		return Eval(ie.Consequence, env)
+		result = Eval(ie.Consequence, env)
 	} else if ie.Alternative != nil {
-		return Eval(ie.Alternative, env)
+		result = Eval(ie.Alternative, env)
+	}
+
+	// branch without value (e.g. ending with let) produces null
+	if result == nil {
+		return NULL
 	}
-	return NULL
+	return result
+}
+
+// evalConditionalExpression - evaluates ternary conditional expression,
+// only one of the branches is evaluated
+func evalConditionalExpression(
+	ce *ast.ConditionalExpression,
+	env *object.Environment,
+) object.Object {
+	condition := Eval(ce.Condition, env)
+
+	if isError(condition) {
+		return condition
+	}
+
+	if isTruly(condition) {
+		return Eval(ce.Consequence, env)
+	}
+	return Eval(ce.Alternative, env)
+}
+
+// evalMatchExpression - evaluates arms of the match expression top-to-bottom
+// and returns result of the first arm which pattern matches the value (and
+// guard is truly). Names bound by the pattern are visible only inside the arm
+func evalMatchExpression(
+	me *ast.MatchExpression,
+	env *object.Environment,
+) object.Object {
+	subject := Eval(me.Subject, env)
+	if isError(subject) {
+		return subject
+	}
+
+	for _, arm := range me.Arms {
+		armEnv := object.NewEnclosedEnvironment(env, arm.Scope)
+
+		matched, err := matchPattern(arm.Pattern, subject, armEnv)
+		if err != nil {
+			return err
+		}
+		if !matched {
+			continue
+		}
+
+		if arm.Guard != nil {
+			guard := Eval(arm.Guard, armEnv)
+			if isError(guard) {
+				return guard
+			}
+			if !isTruly(guard) {
+				continue
+			}
+		}
+
+		return Eval(arm.Body, armEnv)
+	}
+
+	return newError(object.MATCH_ERROR, "no match for value: %s", subject.Inspect())
+}
+
+// evalTryExpression - evaluates the try block, if it produced an error
+// evaluates the catch block with the error (converted into the hash) bound
+// to the catch parameter. The finally block is evaluated in any case, its
+// result is ignored unless it produced an error or returned from function
+func evalTryExpression(te *ast.TryExpression, env *object.Environment) object.Object {
+	result := Eval(te.Block, env)
+
+	if err, ok := result.(*object.Error); ok && te.Catch != nil {
+		catchEnv := object.NewEnclosedEnvironment(env, te.CatchScope)
+		if te.Parameter != nil {
+			bind(te.Parameter, err.Hash(), catchEnv)
+		}
+		result = Eval(te.Catch, catchEnv)
+	}
+
+	if te.Finally != nil {
+		finalResult := Eval(te.Finally, env)
+		if finalResult != nil {
+			rt := finalResult.Type()
+			if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ {
+				return finalResult
+			}
+		}
+	}
+
+	return result
+}
+
+// newThrownError - creates error from the value passed to throw. Hashes
+// with message key (e.g. caught errors) become errors with the same message,
+// kind and position, other values are wrapped into the error of Error kind
+func newThrownError(value object.Object) *object.Error {
+	var message object.Object
+
+	hash, ok := value.(*object.Hash)
+	if ok {
+		message, ok = hash.Get(&object.String{Value: "message"})
+	}
+	if !ok {
+		return &object.Error{
+			Kind:    object.ERROR,
+			Message: value.Inspect(),
+			Value:   value,
+		}
+	}
+
+	err := &object.Error{Kind: object.ERROR, Message: message.Inspect()}
+	if kind, ok := hash.Get(&object.String{Value: "kind"}); ok {
+		err.Kind = kind.Inspect()
+	}
+	if line, ok := hash.Get(&object.String{Value: "line"}); ok {
+		if line, ok := line.(*object.Integer); ok {
+			err.Line = int(line.Value)
+		}
+	}
+	if column, ok := hash.Get(&object.String{Value: "column"}); ok {
+		if column, ok := column.(*object.Integer); ok {
+			err.Column = int(column.Value)
+		}
+	}
+	if thrown, ok := hash.Get(&object.String{Value: "value"}); ok {
+		err.Value = thrown
+	}
+
+	return err
 }
 
 // isTruly - checks if the object is truly
@@ -264,17 +452,75 @@ func isTruly(obj object.Object) bool {
 	}
 }
 
-// newError - creates object.Error with given message as formatted string
-func newError(format string, a ...interface{}) *object.Error {
-	return &object.Error{Message: fmt.Sprintf(format, a...)}
+// evalHashLiteral - evaluates keys and values of the hash literal in order,
+// spread hashes are expanded into their pairs (later pairs override
+// earlier ones), returns an error if some key can't be used as a hash key
+func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
+	hash := object.NewHash()
+	// keys are checked when the hash is built after evaluation of all
+	// pairs, so errors of the later pairs are reported first, like in
+	// the compiled code which builds the hash from the evaluated pairs
+	var unusable object.Object
+
+	for _, pair := range node.Pairs {
+		if spread, ok := pair.Key.(*ast.SpreadElement); ok {
+			if err := evalHashSpread(spread, hash, env); err != nil {
+				return err
+			}
+			continue
+		}
+
+		key := Eval(pair.Key, env)
+		if isError(key) {
+			return key
+		}
+
+		value := Eval(pair.Value, env)
+		if isError(value) {
+			return value
+		}
+
+		hashKey, ok := key.(object.Hashable)
+		if !ok {
+			if unusable == nil {
+				unusable = key
+			}
+			continue
+		}
+		hash.Set(hashKey, value)
+	}
+
+	if unusable != nil {
+		return newError(object.TYPE_ERROR, "unusable as hash key: %s", unusable.Type())
+	}
+	return hash
+}
+
+// newError - creates object.Error of the given kind with given message
+// as formatted string
+func newError(kind string, format string, a ...interface{}) *object.Error {
+	return &object.Error{Kind: kind, Message: fmt.Sprintf(format, a...)}
+}
+
+// withPosition - sets position of the error object (if it doesn't have one)
+// to the position of the token, the innermost node which produced the error
+// sets the position first
+func withPosition(obj object.Object, tok token.Token) object.Object {
+	if err, ok := obj.(*object.Error); ok && err.Line == 0 {
+		err.Line = tok.Line
+		err.Column = tok.Column
+	}
+	return obj
 }
 
 // isError - checks whenever given object is error object
 // if yes - returns true,
-// otherwise, returns false
+// otherwise, returns false. Values returned from the function
+// (e.g. by if expression in the argument) are propagated in the same
+// way as errors, so they are treated as errors too
 func isError(obj object.Object) bool {
 	if obj != nil {
-		return obj.Type() == object.ERROR_OBJ
+		return obj.Type() == object.ERROR_OBJ || obj.Type() == object.RETURN_VALUE_OBJ
 	}
 	return false
 }
@@ -285,15 +531,34 @@ func evalIdentifier(
 	node *ast.Identifier,
 	env *object.Environment,
 ) object.Object {
-	val, ok := env.Get(node.Value)
-	if !ok {
-		return newError("identifier not found: " + node.Value)
+	if node.Location == nil {
+		// the name is declared by the later part of the program (line of
+		// REPL) or the node isn't resolved, so it's looked up by name
+		if val, ok := env.LookupName(node.Value); ok {
+			return val
+		}
+		if builtin, ok := object.GetBuiltinByName(node.Value); ok {
+			return builtin
+		}
+	} else if val, ok := env.Lookup(*node.Location); ok {
+		return val
+	}
+
+	return newError(object.NAME_ERROR, "identifier not found: %s", node.Value)
+}
+
+// bind - binds the value to the name in its slot of the environment,
+// names are always bound in the innermost scope
+func bind(name *ast.Identifier, value object.Object, env *object.Environment) {
+	if name.Location == nil {
+		env.SetName(name.Value, value)
+		return
 	}
-	return val
+	env.Set(name.Location.Slot, value)
 }
 
 // evalExpressions - evaluates given expressions in loop and
-// returns a result
+// returns a result, spread arrays are expanded into their elements
 func evalExpressions(
 	expressions []ast.Expression,
 	env *object.Environment,
@@ -301,6 +566,15 @@ func evalExpressions(
 	var result []object.Object
 
 	for _, e := range expressions {
+		if spread, ok := e.(*ast.SpreadElement); ok {
+			elements := evalSpreadElement(spread, env)
+			if len(elements) == 1 && isError(elements[0]) {
+				return elements
+			}
+			result = append(result, elements...)
+			continue
+		}
+
 		evaluated := Eval(e, env)
 		if isError(evaluated) {
 			return []object.Object{evaluated}
@@ -310,36 +584,251 @@ func evalExpressions(
 	return result
 }
 
+// keywordArgument - evaluated argument passed to the function by name
+type keywordArgument struct {
+	name  string
+	value object.Object
+}
+
+// splitArguments - separates positional arguments of the call from
+// keyword arguments (parser guarantees keywords go last)
+func splitArguments(
+	arguments []ast.Expression,
+) ([]ast.Expression, []*ast.KeywordArgument) {
+	var positional []ast.Expression
+	var keywords []*ast.KeywordArgument
+
+	for _, arg := range arguments {
+		if keyword, ok := arg.(*ast.KeywordArgument); ok {
+			keywords = append(keywords, keyword)
+			continue
+		}
+		positional = append(positional, arg)
+	}
+	return positional, keywords
+}
+
+// evalKeywordArguments - evaluates values of keyword arguments in order,
+// returns the first occurred error
+func evalKeywordArguments(
+	keywords []*ast.KeywordArgument,
+	env *object.Environment,
+) ([]keywordArgument, object.Object) {
+	var result []keywordArgument
+
+	for _, k := range keywords {
+		evaluated := Eval(k.Value, env)
+		if isError(evaluated) {
+			return nil, evaluated
+		}
+		result = append(result, keywordArgument{name: k.Name.Value, value: evaluated})
+	}
+	return result, nil
+}
+
+// skippedChain - result of the member and call chain skipped by the
+// optional access of null: `a?.b.c()` skips `.b` and `.c()` if `a` is null
+type skippedChain struct {
+	// pointers to distinct zero-size values may be equal
+	_ byte
+}
+
+func (sc *skippedChain) Type() object.ObjectType { return object.NULL_OBJ }
+func (sc *skippedChain) Inspect() string         { return "null" }
+
+var skipped object.Object = &skippedChain{}
+
+// evalChainLink - evaluates the member expression or the call which is
+// a link of the chain, the skipped links propagate to the end of the chain
+// without being evaluated. Other expressions are evaluated as usual
+func evalChainLink(node ast.Expression, env *object.Environment) object.Object {
+	switch node := node.(type) {
+	case *ast.MemberExpression:
+		return withPosition(evalMemberExpression(node, env), node.Token)
+	case *ast.CallExpression:
+		return withPosition(evalCallExpression(node, env), node.Token)
+	default:
+		return Eval(node, env)
+	}
+}
+
+// endChain - returns null for the skipped chain
+func endChain(obj object.Object) object.Object {
+	if obj == skipped {
+		return NULL
+	}
+	return obj
+}
+
+// evalCallExpression - evaluates the function and the arguments and calls
+// the function, member functions are called as methods
+func evalCallExpression(node *ast.CallExpression, env *object.Environment) object.Object {
+	if member, ok := node.Function.(*ast.MemberExpression); ok {
+		return evalMethodCall(node, member, env)
+	}
+	function := evalChainLink(node.Function, env)
+	if isError(function) || function == skipped {
+		return function
+	}
+	positional, keywords := splitArguments(node.Arguments)
+	args := evalExpressions(positional, env)
+	if len(args) == 1 && isError(args[0]) {
+		return args[0]
+	}
+	kwargs, err := evalKeywordArguments(keywords, env)
+	if err != nil {
+		return err
+	}
+	return applyFunction(function, args, kwargs)
+}
+
 // applyFunction - extends environment with function arguments,
 // executes function and returns value
-func applyFunction(fn object.Object, args []object.Object) object.Object {
-	function, ok := fn.(*object.Function)
-	if !ok {
-		return newError("not a function: %s", fn.Type())
+func applyFunction(
+	fn object.Object,
+	args []object.Object,
+	kwargs []keywordArgument,
+) object.Object {
+	var function *object.Function
+
+	switch fn := fn.(type) {
+	case *object.Function:
+		function = fn
+	case *object.StructType:
+		return newStruct(fn, args, kwargs)
+	case *object.VariantType:
+		return newVariant(fn, args, kwargs)
+	case *object.Variant:
+		// variants without fields are called as their own constructors
+		if len(fn.Definition.Fields) > 0 {
+			return newError(object.TYPE_ERROR, "not a function: %s", fn.Type())
+		}
+		return newVariant(fn.Definition, args, kwargs)
+	case *object.Builtin:
+		if len(kwargs) > 0 {
+			return newError(object.ARGUMENT_ERROR,
+				"keyword arguments are not supported by builtin functions")
+		}
+		return fn.Fn(args...)
+	default:
+		return newError(object.TYPE_ERROR, "not a function: %s", fn.Type())
 	}
 
-	expendedEnv := extendFunctionEnv(function, args)
+	expendedEnv, err := extendFunctionEnv(function, args, kwargs)
+	if err != nil {
+		return err
+	}
 	evaluated := Eval(function.Body, expendedEnv)
 	return unwrapReturnValue(evaluated)
 }
 
 // extendFunctionEnv - creates new environment from function environment that
-// includes all arguments
-func extendFunctionEnv(fn *object.Function, args []object.Object) *object.Environment {
-	env := object.NewEnclosedEnvironment(fn.Env)
+// includes all arguments. Arguments are matched with parameters, missed
+// parameters get their default values (evaluated in the new environment)
+// and extra positional arguments are collected into the rest parameter
+func extendFunctionEnv(
+	fn *object.Function,
+	args []object.Object,
+	kwargs []keywordArgument,
+) (*object.Environment, object.Object) {
+	env := object.NewEnclosedEnvironment(fn.Env, fn.Scope)
+
+	names := make([]string, len(fn.Parameters))
+	for idx, param := range fn.Parameters {
+		names[idx] = param.Value
+	}
+
+	values, err := matchArguments(names, fn.Rest != nil, args, kwargs)
+	if err != nil {
+		return nil, err
+	}
+
+	for idx, param := range fn.Parameters {
+		if values[idx] != nil {
+			bind(param, values[idx], env)
+			continue
+		}
+		def, ok := fn.Defaults[param.Value]
+		if !ok {
+			return nil, newError(object.ARGUMENT_ERROR,
+				"missing argument: %s", param.Value)
+		}
+		val := Eval(def, env)
+		if isError(val) {
+			return nil, val
+		}
+		bind(param, val, env)
+	}
 
-	for paramIdx, param := range fn.Parameters {
-		env.Set(param.Value, args[paramIdx])
+	if fn.Rest != nil {
+		rest := []object.Object{}
+		if len(args) > len(fn.Parameters) {
+			rest = append(rest, args[len(fn.Parameters):]...)
+		}
+		bind(fn.Rest, &object.Array{Elements: rest}, env)
 	}
 
-	return env
+	return env, nil
+}
+
+// matchArguments - matches arguments of the call with the names. Positional
+// arguments are taken first, after that keyword arguments are matched by
+// name. Returns values in order of names (nil for names without arguments)
+// extra positional arguments are allowed only for variadic calls
+func matchArguments(
+	names []string,
+	variadic bool,
+	args []object.Object,
+	kwargs []keywordArgument,
+) ([]object.Object, object.Object) {
+	if len(args) > len(names) && !variadic {
+		return nil, newError(object.ARGUMENT_ERROR,
+			"wrong number of arguments: want=%d, got=%d", len(names), len(args))
+	}
+
+	values := make([]object.Object, len(names))
+	copy(values, args)
+
+	for _, kwarg := range kwargs {
+		idx := nameIndex(names, kwarg.name)
+		if idx < 0 {
+			return nil, newError(object.ARGUMENT_ERROR,
+				"unknown keyword argument: %s", kwarg.name)
+		}
+		if values[idx] != nil && idx < len(args) {
+			return nil, newError(object.ARGUMENT_ERROR,
+				"multiple values for argument: %s", kwarg.name)
+		}
+		if values[idx] != nil {
+			return nil, newError(object.ARGUMENT_ERROR,
+				"duplicate keyword argument: %s", kwarg.name)
+		}
+		values[idx] = kwarg.value
+	}
+
+	return values, nil
+}
+
+// nameIndex - returns position of the name in the list or -1 if there
+// is no such name
+func nameIndex(names []string, name string) int {
+	for idx, n := range names {
+		if n == name {
+			return idx
+		}
+	}
+	return -1
 }
 
 // unwrapReturnValue - returns value of ReturnValue object
-// if the obj is not ReturnValue - returns obj
+// if the obj is not ReturnValue - returns obj,
+// function body without value (e.g. ending with let) returns null
 func unwrapReturnValue(obj object.Object) object.Object {
 	if returnValue, ok := obj.(*object.ReturnValue); ok {
 		return returnValue.Value
 	}
+	if obj == nil {
+		return NULL
+	}
 	return obj
 }
\ No newline at end of file
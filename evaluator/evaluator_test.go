@@ -1,10 +1,14 @@
 package evaluator
 
 import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
 	"testing"
+	"github.com/technoboom/compiler/compiler"
 	"github.com/technoboom/compiler/object"
-	"github.com/technoboom/compiler/lexer"
-	"github.com/technoboom/compiler/parser"
+	"github.com/technoboom/compiler/vm"
 )
 
 func TestEvalIntegerExpressions(t *testing.T) {
@@ -27,18 +31,68 @@ func TestEvalIntegerExpressions(t *testing.T) {
 	}
 
 	for _, tt := range tests {
-		evaluated := testEval(tt.input)
+		evaluated := testEval(t, tt.input)
 		testIntegerObject(t, evaluated, tt.expected)
 	}
 }
 
-func testEval(input string) object.Object {
-	l := lexer.New(input)
-	p := parser.New(l)
-	program := p.ParseProgram()
-	env := object.NewEnvironment()
+// testEval - evaluates the program and runs it through the compiler and
+// the VM, the compiled program must produce the same value, error and output.
+// Programs using features the compiler doesn't support are only evaluated
+func testEval(t *testing.T, input string) object.Object {
+	output := object.Output
+	defer func() { object.Output = output }()
 
-	return Eval(program, env)
+	var evalOut bytes.Buffer
+	object.Output = &evalOut
+	evaluated := Eval(parseProgram(input), object.NewEnvironment())
+	fmt.Fprint(output, evalOut.String())
+
+	var vmOut bytes.Buffer
+	object.Output = &vmOut
+	compiled, err := runCompiled(input)
+	if err != nil && strings.HasSuffix(err.Error(), "not supported by compiler") {
+		return evaluated
+	}
+
+	switch {
+	case evaluated == nil:
+	case isError(evaluated):
+		want := evaluated.(*object.Error)
+		if err == nil || err.Error() != want.Message && err.Error() != want.Kind+": "+want.Message {
+			t.Errorf("%q: vm error differs. evaluator=%s, vm=%v", input, want.Inspect(), err)
+		}
+		return evaluated
+	case err != nil:
+		t.Errorf("%q: vm error: %s, evaluator=%s", input, err, evaluated.Inspect())
+		return evaluated
+	case evaluated.Type() == object.FUNCTION_OBJ:
+		// compiled functions are shown without their bodies
+	case compiled.Inspect() != evaluated.Inspect():
+		t.Errorf("%q: vm result differs. evaluator=%s, vm=%s",
+			input, evaluated.Inspect(), compiled.Inspect())
+	}
+	if vmOut.String() != evalOut.String() {
+		t.Errorf("%q: vm output differs. evaluator=%q, vm=%q",
+			input, evalOut.String(), vmOut.String())
+	}
+
+	return evaluated
+}
+
+// runCompiled - compiles the program and runs it in the VM, compilation
+// errors are returned as is, runtime errors are returned with their kinds
+func runCompiled(input string) (object.Object, error) {
+	comp := compiler.New()
+	if err := comp.Compile(parseProgram(input)); err != nil {
+		return nil, err
+	}
+
+	machine := vm.New(comp.Bytecode())
+	if err := machine.Run(); err != nil {
+		return nil, err
+	}
+	return machine.LastPoppedStackElem(), nil
 }
 
 func testIntegerObject(t *testing.T, obj object.Object, expected int64) bool {
@@ -89,7 +143,7 @@ func TestEvalBooleanExpressions(t *testing.T) {
 	}
 
 	for _, tt := range tests {
-		evaluated := testEval(tt.input)
+		evaluated := testEval(t, tt.input)
 		testBooleanObject(t, evaluated, tt.expected)
 	}
 }
@@ -127,7 +181,7 @@ func TestBangOperator(t *testing.T) {
 	}
 
 	for _, tt := range tests {
-		evaluated := testEval(tt.input)
+		evaluated := testEval(t, tt.input)
 		testBooleanObject(t, evaluated, tt.expected)
 	}
 }
@@ -145,10 +199,14 @@ func TestIfElseExpression(t *testing.T) {
 		{"if (10 > 20) { true }", nil},
 		{"if (2 > 1) { return 1; } else { return 2; }", 1},
 		{"if (2 < 1) { return 1; } else { return 2; }", 2},
+		// branches without value produce null
+		{"if (true) { let x = 1; }", nil},
+		{"let f = () => { let x = 1; }; f()", nil},
+		{"let f = () => { }; f()", nil},
 	}
 
 	for _, tt := range tests {
-		evaluated := testEval(tt.input)
+		evaluated := testEval(t, tt.input)
 		integer, ok := tt.expected.(int)
 		if ok {
 			testIntegerObject(t, evaluated, int64(integer))
@@ -176,18 +234,21 @@ func TestReturnStatements(t *testing.T) {
 		{"return 10; 8;", 10},
 		{"return 2 * 5; 8;", 10},
 		{"100; return 2 * 5; 8;", 10},
-		//{`
-		//	if (10 > 1) {
-		//		if (10 > 1) {
-		//			return 10;
-		//		}
-		//		return 1;
-		//	}
-		//`, 10},
+		{`
+			if (10 > 1) {
+				if (10 > 1) {
+					return 10;
+				}
+				return 1;
+			}
+		`, 10},
+		// return in the nested expression leaves the function
+		{"let f = () => { let x = [1, if (true) { return 7; }]; 8 }; f()", 7},
+		{"let f = (x) => x + 1; f(if (true) { return 3; }) + 1", 3},
 	}
 
 	for _, tt := range tests {
-		evaluated := testEval(tt.input)
+		evaluated := testEval(t, tt.input)
 		testIntegerObject(t, evaluated, tt.expected)
 	}
 }
@@ -209,6 +270,10 @@ func TestErrorHandling(t *testing.T) {
 			"-true;",
 			"unknown operator: -BOOLEAN",
 		},
+		{
+			"10 / (5 - 5);",
+			"division by zero",
+		},
 		{
 			"false + true;",
 			"unknown operator: BOOLEAN + BOOLEAN",
@@ -236,7 +301,7 @@ func TestErrorHandling(t *testing.T) {
 	}
 
 	for _, tt := range tests {
-		evaluated := testEval(tt.input)
+		evaluated := testEval(t, tt.input)
 
 		errObj, ok := evaluated.(*object.Error)
 		if !ok {
@@ -264,14 +329,14 @@ func TestLetStatements(t *testing.T) {
 	}
 
 	for _, tt := range tests {
-		testIntegerObject(t, testEval(tt.input), tt.expected)
+		testIntegerObject(t, testEval(t, tt.input), tt.expected)
 	}
 }
 
 func TestFunctionObject(t *testing.T) {
 	input := "function (a, b) { return a * b; }"
 
-	evaluated := testEval(input)
+	evaluated := testEval(t, input)
 
 	fn, ok := evaluated.(*object.Function)
 	if !ok {
@@ -307,7 +372,7 @@ func TestFunctionApplication(t *testing.T) {
 	}
 
 	for _, tt := range tests {
-		testIntegerObject(t, testEval(tt.input), tt.expected)
+		testIntegerObject(t, testEval(t, tt.input), tt.expected)
 	}
 }
 
@@ -321,13 +386,13 @@ func TestClosures(t *testing.T) {
 		return addTwo(2);
 	`
 
-	testIntegerObject(t, testEval(input), 4)
+	testIntegerObject(t, testEval(t, input), 4)
 }
 
 func TestStringLiteral(t *testing.T) {
 	input := `"Hello, world!"`
 
-	evaluated := testEval(input)
+	evaluated := testEval(t, input)
 	str, ok := evaluated.(*object.String)
 	if !ok {
 		t.Fatalf("object is not a string, got=%T (%+v)", evaluated, evaluated)
@@ -336,4 +401,1170 @@ func TestStringLiteral(t *testing.T) {
 	if str.Value != "Hello, world!" {
 		t.Errorf("str.Value is wrong, got=%q", str.Value)
 	}
-}
\ No newline at end of file
+}
+func TestDefaultParameters(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let f = function(a, b = 10) { a + b }; f(1);", 11},
+		{"let f = function(a, b = 10) { a + b }; f(1, 2);", 3},
+		{"let f = function(a, b = a * 2) { a + b }; f(5);", 15},
+		{"let x = 7; let f = function(a = x) { a }; f();", 7},
+		{"let x = 7; let f = function(a = x) { a }; let x = 8; f();", 8},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestRestParameters(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"function(a, ...rest) { rest }(1, 2, 3);", "[2, 3]"},
+		{"function(a, ...rest) { rest }(1);", "[]"},
+		{"function(...rest) { rest }(1, true, \"s\");", "[1, true, s]"},
+		{"function(a, b = 2, ...rest) { rest }(b: 5, a: 1);", "[]"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+		arr, ok := evaluated.(*object.Array)
+		if !ok {
+			t.Errorf("object is not Array. got=%T (%+v)", evaluated, evaluated)
+			continue
+		}
+		if arr.Inspect() != tt.expected {
+			t.Errorf("array has wrong value. want=%q, got=%q",
+				tt.expected, arr.Inspect())
+		}
+	}
+}
+
+func TestKeywordArguments(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let f = function(a, b) { a - b }; f(b: 2, a: 10);", 8},
+		{"let f = function(a, b) { a - b }; f(10, b: 2);", 8},
+		{"let f = function(a, b = 1, c = 100) { a - b - c }; f(200, c: 50);", 149},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestFunctionArgumentErrors(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{
+			"let f = function(a) { a }; f(c: 1);",
+			"unknown keyword argument: c",
+		},
+		{
+			"let f = function(a) { a }; f(a: 1, a: 2);",
+			"duplicate keyword argument: a",
+		},
+		{
+			"let f = function(a) { a }; f(1, a: 2);",
+			"multiple values for argument: a",
+		},
+		{
+			"let f = function(a, ...rest) { a }; f(rest: 2);",
+			"unknown keyword argument: rest",
+		},
+		{
+			"let f = function(a, b) { a }; f(1);",
+			"missing argument: b",
+		},
+		{
+			"let f = function(a) { a }; f(1, 2);",
+			"wrong number of arguments: want=1, got=2",
+		},
+		{
+			"let f = function(a = b) { a }; f();",
+			"identifier not found: b",
+		},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned. got=%T(%+v)",
+				evaluated, evaluated)
+			continue
+		}
+
+		if errObj.Message != tt.expectedMessage {
+			t.Errorf("wrong error message. expected=%q, got=%q",
+				tt.expectedMessage, errObj.Message)
+		}
+	}
+}
+
+func TestArrowFunctions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let double = (x) => x * 2; double(5);", 10},
+		{"let add = (a, b) => { let c = a + b; c }; add(2, 3);", 5},
+		{"let apply = (f, x) => f(x); apply((x) => x - 1, 10);", 9},
+		{"let adder = (x) => (y) => x + y; adder(2)(3);", 5},
+		{"((x, y = 4) => x * y)(5);", 20},
+		{"(() => { return 7; 8; })();", 7},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestPipelineOperator(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let double = (x) => x * 2; 5 |> double;", 10},
+		{"let double = (x) => x * 2; 5 |> double |> double;", 20},
+		{"let sub = (a, b) => a - b; 10 |> sub(3);", 7},
+		{"let sub = (a, b) => a - b; 2 + 8 |> sub(b: 4);", 6},
+		{"let inc = (x) => x + 1; 1 |> ((x) => x * 10) |> inc;", 11},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestConditionalExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"true ? 1 : 2", 1},
+		{"false ? 1 : 2", 2},
+		{"null ? 1 : 2", 2},
+		{"5 > 3 ? 10 * 2 : 0", 20},
+		{"1 > 2 ? 1 : 2 > 3 ? 2 : 3", 3},
+		{"let max = (a, b) => a > b ? a : b; max(4, 9);", 9},
+		// only the chosen branch is evaluated
+		{"true ? 1 : unknown", 1},
+		{"false ? unknown : 2", 2},
+		{"false ? 1 : null", nil},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+		integer, ok := tt.expected.(int)
+		if ok {
+			testIntegerObject(t, evaluated, int64(integer))
+		} else {
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestNullCoalescingOperator(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"null ?? 5", 5},
+		{"10 ?? 5", 10},
+		{"null ?? null ?? 3", 3},
+		{"null ?? null", nil},
+		{"if (false) { 1 } ?? 7", 7},
+		{"let f = (x = null) => x ?? 100; f();", 100},
+		{"let f = (x = null) => x ?? 100; f(1);", 1},
+		// right operand is evaluated only if the left one is null
+		{"1 ?? unknown", 1},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+		integer, ok := tt.expected.(int)
+		if ok {
+			testIntegerObject(t, evaluated, int64(integer))
+		} else {
+			testNullObject(t, evaluated)
+		}
+	}
+
+	// false is not null, so it's not replaced
+	testBooleanObject(t, testEval(t, "false ?? true"), false)
+
+	evaluated := testEval(t, "null ?? unknown")
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+	}
+	if errObj.Message != "identifier not found: unknown" {
+		t.Errorf("wrong error message. got=%q", errObj.Message)
+	}
+}
+
+func TestOptionalChaining(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"struct P { x }; let p = P(1); p?.x", "1"},
+		{"let p = null; p?.x", "null"},
+		{"struct P { x }; let p = P(null); p.x?.y ?? 5", "5"},
+		{"struct P { x }; P(P(2))?.x?.x", "2"},
+		{`let h = {"a": 1}; [h?.a, null?.a]`, "[1, null]"},
+		// methods are called only if the receiver isn't null
+		{`"abc"?.len()`, "3"},
+		{"null?.len(unknown)", "null"},
+		{"struct P { x }; let p = null; p?.x ?? 10", "10"},
+		// the rest of the chain is skipped if the optional access finds null
+		{"null?.x.y", "null"},
+		{"let p = null; p?.x.y.z", "null"},
+		{"null?.x.len()", "null"},
+		{"null?.f(unknown)(unknown).x", "null"},
+		{"struct P { x }; let p = null; [p?.x.y ?? 1, p?.x.y == null]", "[1, true]"},
+		{"struct P { x }; P(P(null)).x.x?.y.z", "null"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%q",
+				tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+
+	errorTests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		// only null is skipped, accessing fields of other values fails
+		{"let a = 5; a?.x", "cannot access field x of INTEGER"},
+		{"struct P { x }; P(null)?.x.y", "cannot access field y of NULL"},
+	}
+
+	for _, tt := range errorTests {
+		evaluated := testEval(t, tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned for %q. got=%T(%+v)",
+				tt.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Message != tt.expectedMessage {
+			t.Errorf("wrong error message for %q. expected=%q, got=%q",
+				tt.input, tt.expectedMessage, errObj.Message)
+		}
+	}
+}
+
+func TestArrayLiterals(t *testing.T) {
+	evaluated := testEval(t, "[1, 2 * 2, 3 + 3]")
+
+	result, ok := evaluated.(*object.Array)
+	if !ok {
+		t.Fatalf("object is not Array. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	if len(result.Elements) != 3 {
+		t.Fatalf("array has wrong num of elements. got=%d",
+			len(result.Elements))
+	}
+
+	testIntegerObject(t, result.Elements[0], 1)
+	testIntegerObject(t, result.Elements[1], 4)
+	testIntegerObject(t, result.Elements[2], 6)
+}
+
+func TestHashLiterals(t *testing.T) {
+	input := `let two = "two";
+	{"one": 10 - 9, two: 1 + 1, 4: 4, true: 5, false: 6, "one": 1}`
+
+	evaluated := testEval(t, input)
+	result, ok := evaluated.(*object.Hash)
+	if !ok {
+		t.Fatalf("Eval didn't return Hash. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	expected := map[object.HashKey]int64{
+		(&object.String{Value: "one"}).HashKey(): 1,
+		(&object.String{Value: "two"}).HashKey(): 2,
+		(&object.Integer{Value: 4}).HashKey():    4,
+		TRUE.HashKey():                           5,
+		FALSE.HashKey():                          6,
+	}
+
+	if result.Len() != len(expected) {
+		t.Fatalf("Hash has wrong num of pairs. got=%d", result.Len())
+	}
+
+	for _, pair := range result.Pairs() {
+		expectedValue, ok := expected[pair.Key.(object.Hashable).HashKey()]
+		if !ok {
+			t.Errorf("unexpected key in Pairs: %s", pair.Key.Inspect())
+			continue
+		}
+		testIntegerObject(t, pair.Value, expectedValue)
+	}
+
+	if result.Inspect() != "{one: 1, two: 2, 4: 4, true: 5, false: 6}" {
+		t.Errorf("hash.Inspect() wrong. got=%q", result.Inspect())
+	}
+
+	evaluated = testEval(t, `{[1]: 2}`)
+	errObj, ok := evaluated.(*object.Error)
+	if !ok || errObj.Message != "unusable as hash key: ARRAY" {
+		t.Errorf("expected unusable key error. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	// keys are checked after all pairs are evaluated
+	evaluated = testEval(t, `{[1]: 2, "a": 1 + true}`)
+	errObj, ok = evaluated.(*object.Error)
+	if !ok || errObj.Message != "type mismatch: INTEGER + BOOLEAN" {
+		t.Errorf("expected type mismatch error. got=%T (%+v)", evaluated, evaluated)
+	}
+}
+
+func TestDestructuringLetStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let [a, b] = [1, 2]; [b, a];", "[2, 1]"},
+		{"let [a, ...rest] = [1, 2, 3]; rest;", "[2, 3]"},
+		{"let [a, b, ...rest] = [1, 2]; [a, b, rest];", "[1, 2, []]"},
+		{"let [a, [b, c]] = [1, [2, 3]]; [a, b, c];", "[1, 2, 3]"},
+		{"let [a, b = a * 10] = [5]; b;", "50"},
+		{"let [a, b = 10] = [1, 2]; b;", "2"},
+		{`let {name, age} = {"name": "Bob", "age": 42}; [name, age];`, "[Bob, 42]"},
+		{`let {name: n} = {"name": "Bob"}; n;`, "Bob"},
+		{`let {age = 18} = {}; age;`, "18"},
+		{`let {a, ...rest} = {"a": 1, "b": 2, "c": 3}; rest;`, "{b: 2, c: 3}"},
+		{`let {pos: {x, y}, tags: [first, ...others]} = {"pos": {"x": 1, "y": 2}, "tags": [3, 4, 5]};
+		  [x, y, first, others];`, "[1, 2, 3, [4, 5]]"},
+		{`let [{x} = {"x": 7}] = []; x;`, "7"},
+		{"let f = (p) => { let [x, y] = p; x - y; }; f([10, 3]);", "7"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+		if evaluated == nil {
+			t.Errorf("Eval returned nil for %q", tt.input)
+			continue
+		}
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. want=%q, got=%q",
+				tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestDestructuringErrors(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{"let [a, b] = 5;", "cannot destructure INTEGER as array"},
+		{"let {a} = [1];", "cannot destructure ARRAY as hash"},
+		{"let [a, b, c] = [1, 2];", "not enough elements to destructure: want=3, got=2"},
+		{"let [a] = [1, 2];", "too many elements to destructure: want=1, got=2"},
+		{`let {name, age} = {"name": "Bob"};`, "key not found: age"},
+		{`let [a, [b]] = [1, 2];`, "cannot destructure INTEGER as array"},
+		{`let [a = unknown] = [];`, "identifier not found: unknown"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned. got=%T(%+v)",
+				evaluated, evaluated)
+			continue
+		}
+
+		if errObj.Message != tt.expectedMessage {
+			t.Errorf("wrong error message. expected=%q, got=%q",
+				tt.expectedMessage, errObj.Message)
+		}
+	}
+}
+
+func TestMatchExpressions(t *testing.T) {
+	dispatch := `
+	let describe = (value) => match (value) {
+		0 => "zero",
+		-1 => "minus one",
+		true => "yes",
+		null => "nothing",
+		"hi" => "greeting",
+		[] => "empty",
+		[n] if n > 100 => "big",
+		[x, y] => x + y,
+		[first, ...rest] => rest,
+		{kind: "circle", r} => r * r * 3,
+		{kind: "rect", w, h = w} => w * h,
+		_ => "other",
+	};
+	`
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"describe(0)", "zero"},
+		{"describe(-1)", "minus one"},
+		{"describe(true)", "yes"},
+		{"describe(false)", "other"},
+		{"describe(null)", "nothing"},
+		{`describe("hi")`, "greeting"},
+		{`describe("bye")`, "other"},
+		{"describe([])", "empty"},
+		{"describe([3, 4])", "7"},
+		{"describe([1, 2, 3])", "[2, 3]"},
+		{`describe({"kind": "circle", "r": 2})`, "12"},
+		{`describe({"kind": "rect", "w": 2, "h": 5})`, "10"},
+		{`describe({"kind": "rect", "w": 3})`, "9"},
+		{`describe({"kind": "oval"})`, "other"},
+		{"describe([500])", "big"},
+		{"describe([50])", "[]"},
+		{"describe(50)", "other"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(t, dispatch + tt.input)
+		if evaluated == nil {
+			t.Errorf("Eval returned nil for %q", tt.input)
+			continue
+		}
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. want=%q, got=%q",
+				tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestMatchExpressionScoping(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		// names bound by patterns don't leak out of the arm
+		{"let x = 1; match (5) { x => x }; x;", 1},
+		// failed arm doesn't leave its bindings for the next one
+		{"let y = 2; match ([10, 20]) { [y, 0] => y, [a, b] => y + b };", 22},
+		// return inside an arm leaves the enclosing function
+		{"let f = (v) => { match (v) { 1 => { return 10; }, _ => 0 }; 20 }; f(1);", 10},
+		{"let f = (v) => { match (v) { 1 => { return 10; }, _ => 0 }; 20 }; f(2);", 20},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestMatchExpressionErrors(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{"match (5) { 1 => 1, 2 => 2 }", "no match for value: 5"},
+		{"match ([1, 2]) { [a] => a }", "no match for value: [1, 2]"},
+		{"match (unknown) { _ => 1 }", "identifier not found: unknown"},
+		{"match (1) { x if x + true => 1 }", "type mismatch: INTEGER + BOOLEAN"},
+		{"match ([]) { [a = b] => a }", "identifier not found: b"},
+		{"let [a, 2] = [1, 3];", "value doesn't match pattern: want=2, got=3"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned. got=%T(%+v)",
+				evaluated, evaluated)
+			continue
+		}
+
+		if errObj.Message != tt.expectedMessage {
+			t.Errorf("wrong error message. expected=%q, got=%q",
+				tt.expectedMessage, errObj.Message)
+		}
+	}
+}
+
+func TestTryCatchExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"try { 1 } catch (e) { 2 }", "1"},
+		{"try { throw 5; 1 } catch (e) { 2 }", "2"},
+		{`try { throw "oops"; } catch (e) { let {message, kind, value} = e; [message, kind, value] }`,
+			"[oops, Error, oops]"},
+		{`try { throw {"message": "bad input", "kind": "InputError"}; } catch (e) {
+			let {message, kind} = e; [message, kind] }`,
+			"[bad input, InputError]"},
+		// internal errors are catchable the same way
+		{"try { 5 + true } catch (e) { let {message, kind} = e; [message, kind] }",
+			"[type mismatch: INTEGER + BOOLEAN, TypeError]"},
+		{"try { unknown } catch (e) { let {message, kind} = e; [message, kind] }",
+			"[identifier not found: unknown, NameError]"},
+		{"try { ((a) => a)() } catch (e) { let {kind} = e; kind }", "ArgumentError"},
+		{"try { match (1) { 2 => 2 } } catch (e) { let {kind} = e; kind }", "MatchError"},
+		// position of the error
+		{"try {\n  1 + true\n} catch (e) { let {line, column} = e; [line, column] }", "[2, 5]"},
+		{"try { throw 1; } catch (e) { let {line, column} = e; [line, column] }", "[1, 7]"},
+		// errors can be matched by their kind
+		{`let safe = (f) => try { f() } catch (e) {
+			match (e) { {kind: "NameError"} => "name", {kind: "TypeError"} => "type", _ => "other" }
+		};
+		[safe(() => x), safe(() => -true), safe(() => 1)]`, "[name, type, 1]"},
+		// errors propagate through function calls
+		{`let fail = () => { throw "deep"; 1 };
+		  let outer = () => { fail(); 2 };
+		  try { outer() } catch (e) { let {message} = e; message }`, "deep"},
+		// nested try blocks and rethrow
+		{`try {
+			try { throw "inner"; } catch (e) { throw e; }
+		  } catch (e) { let {message} = e; message }`, "inner"},
+		{`try { try { throw "a"; } catch (e) { throw "b"; } } catch (e) { let {message} = e; message }`, "b"},
+		{"try { 1 } catch { 2 }", "1"},
+		{"try { null + 1 } catch { 2 }", "2"},
+		// members of the caught error
+		{"try { 5 + true } catch (e) { [e.message, e.type, e.kind] }",
+			"[type mismatch: INTEGER + BOOLEAN, TypeError, TypeError]"},
+		{`try { throw {"message": "bad input", "kind": "InputError"}; } catch (e) { e.type }`, "InputError"},
+		{"try { throw 7; } catch (e) { [e.message, e.value, e.line] }", "[7, 7, 1]"},
+		{"try { try { 1 + true } catch (e) { e.name } } catch (e) { [e.message, e.type] }",
+			"[unknown key: name, NameError]"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+		if evaluated == nil {
+			t.Errorf("Eval returned nil for %q", tt.input)
+			continue
+		}
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. want=%q, got=%q",
+				tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestTryFinallyExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		// finally is evaluated, but its value is ignored
+		{"try { 1 } finally { 2 }", "1"},
+		{"try { throw 1; } catch (e) { 2 } finally { 3 }", "2"},
+		// finally is evaluated when the function returns from the try block
+		{`let f = () => { try { return 1; } finally { throw "from finally"; } };
+		  try { f() } catch (e) { let {message} = e; message }`, "from finally"},
+		{"let f = () => { try { return 1; } finally { 2 } }; f();", "1"},
+		{"let f = () => { try { return 1; } finally { return 2; } }; f();", "2"},
+		{"let f = () => { try { throw 1; } finally { return 2; } }; f();", "2"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+		if evaluated == nil {
+			t.Errorf("Eval returned nil for %q", tt.input)
+			continue
+		}
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. want=%q, got=%q",
+				tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+
+	// error without catch clause goes through finally
+	evaluated := testEval(t, "try { throw \"boom\"; } finally { 1 }")
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+	}
+	if errObj.Message != "boom" || errObj.Kind != object.ERROR {
+		t.Errorf("wrong error. got=%q", errObj.Inspect())
+	}
+}
+
+func TestUncaughtErrors(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedInspect string
+	}{
+		{"1;\n  true + 5;", "TypeError: type mismatch: BOOLEAN + INTEGER (line 2, column 8)"},
+		{"let a = b;", "NameError: identifier not found: b (line 1, column 9)"},
+		{`throw {"message": "custom", "kind": "MyError"};`, "MyError: custom (line 1, column 1)"},
+		{"let f = () => { -true }; f();", "TypeError: unknown operator: -BOOLEAN (line 1, column 17)"},
+		{"let [a] = 1;", "MatchError: cannot destructure INTEGER as array (line 1, column 1)"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned. got=%T(%+v)",
+				evaluated, evaluated)
+			continue
+		}
+
+		if errObj.Inspect() != tt.expectedInspect {
+			t.Errorf("wrong error. expected=%q, got=%q",
+				tt.expectedInspect, errObj.Inspect())
+		}
+	}
+}
+
+func TestStructs(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"struct Point { x, y }; Point(1, 2)", "Point{x: 1, y: 2}"},
+		{"struct Point { x, y }; Point(y: 2, x: 1)", "Point{x: 1, y: 2}"},
+		{"struct Point { x, y }; let p = Point(1, 2); p.x + p.y", "3"},
+		{"struct Point { x, y }; let p = Point(1, 2); p.x = 10; p", "Point{x: 10, y: 2}"},
+		{"struct Point { x, y }; let p = Point(1, 2); p.y = p.x = 5", "5"},
+		{"struct Box { value }; let b = Box(Box(1)); b.value.value = 2; b", "Box{value: Box{value: 2}}"},
+		// instances are shared by reference
+		{"struct Box { value }; let a = Box(1); let b = a; b.value = 2; a.value", "2"},
+		// structural equality
+		{"struct Point { x, y }; Point(1, 2) == Point(1, 2)", "true"},
+		{"struct Point { x, y }; Point(1, 2) != Point(1, 3)", "true"},
+		{`struct Name { value }; Name("a") == Name("a")`, "true"},
+		{"struct A { x }; struct B { x }; A(1) == B(1)", "false"},
+		{"struct Point { x, y }; match (Point(1, 2)) { p if p.x > 0 => p.y, _ => 0 }", "2"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%q",
+				tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestStructErrors(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{"struct Point { x, y }; Point(1)", "missing field: y"},
+		{"struct Point { x, y }; Point(1, 2, 3)", "wrong number of arguments: want=2, got=3"},
+		{"struct Point { x, y }; Point(1, z: 2)", "unknown keyword argument: z"},
+		{"struct Point { x, y }; Point(1, 2).z", "unknown field: Point.z"},
+		{"struct Point { x, y }; let p = Point(1, 2); p.z = 3", "unknown field: Point.z"},
+		{"let a = 5; a.x", "cannot access field x of INTEGER"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned. got=%T(%+v)",
+				evaluated, evaluated)
+			continue
+		}
+
+		if errObj.Message != tt.expectedMessage {
+			t.Errorf("wrong error message. expected=%q, got=%q",
+				tt.expectedMessage, errObj.Message)
+		}
+	}
+}
+
+func TestMethodCalls(t *testing.T) {
+	point := `
+	struct Point { x, y }
+	impl Point {
+		norm(self) { self.x * self.x + self.y * self.y };
+		scale(self, k = 2) { Point(self.x * k, self.y * k) }
+		move(self, dx, dy) { self.x = self.x + dx; self.y = self.y + dy; self }
+	}
+	`
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"Hello".upper()`, "HELLO"},
+		{`"Hello".lower().len()`, "5"},
+		{`"  hi  ".trim()`, "hi"},
+		{`"a,b,c".split(",")`, "[a, b, c]"},
+		{`"beaver".contains("eave")`, "true"},
+		{"let arr = [1, 2, 3]; arr.push(4); arr", "[1, 2, 3, 4]"},
+		{"[1, 2].push(3, 4).len()", "4"},
+		{"let arr = [1, 2, 3]; [arr.pop(), arr]", "[3, [1, 2]]"},
+		{"[].pop()", "null"},
+		{"[1, 2, 3].first() + [1, 2, 3].last()", "4"},
+		{`[1, "a", true].join("-")`, "1-a-true"},
+		{`let h = {"a": 1, "b": 2}; [h.len(), h.keys(), h.values(), h.has("a"), h.has("c")]`,
+			"[2, [a, b], [1, 2], true, false]"},
+		// methods of structs
+		{point + "Point(3, 4).norm()", "25"},
+		{point + "Point(1, 2).scale()", "Point{x: 2, y: 4}"},
+		{point + "Point(1, 2).scale(k: 3)", "Point{x: 3, y: 6}"},
+		{point + "let p = Point(1, 2); p.move(1, 1).move(2, 2); p", "Point{x: 4, y: 5}"},
+		{point + "1 |> Point(0, 0).move(2)", "Point{x: 1, y: 2}"},
+		// callable fields are called without receiver
+		{"struct Op { apply }; Op((a) => a * 2).apply(21)", "42"},
+		// methods are added by each impl block
+		{point + "impl Point { sum(self) { self.x + self.y } }; Point(1, 2).sum()", "3"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%q",
+				tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestMethodCallErrors(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{`"abc".reverse()`, "unknown method: STRING.reverse"},
+		{"5.abs()", "unknown method: INTEGER.abs"},
+		{"struct Point { x, y }; Point(1, 2).norm()", "unknown method: Point.norm"},
+		{`"abc".upper(1)`, "wrong number of arguments: want=0, got=1"},
+		{`"a,b".split(1)`, "argument to split must be STRING, got INTEGER"},
+		{`"abc".contains(sub: "a")`, "keyword arguments are not supported by STRING.contains"},
+		{`{}.has([1])`, "unusable as hash key: ARRAY"},
+		{"let a = 1; impl a { f(self) { 1 } }", "cannot implement methods for INTEGER"},
+		{"impl Unknown { f(self) { 1 } }", "identifier not found: Unknown"},
+		{"struct P { x }; impl P { f(self, a) { a } }; P(1).f()", "missing argument: a"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned. got=%T(%+v)",
+				evaluated, evaluated)
+			continue
+		}
+
+		if errObj.Message != tt.expectedMessage {
+			t.Errorf("wrong error message. expected=%q, got=%q",
+				tt.expectedMessage, errObj.Message)
+		}
+	}
+}
+
+func TestEnums(t *testing.T) {
+	shape := `
+	enum Shape { Circle(r), Rect(w, h), Empty }
+	let area = (s) => match (s) {
+		Circle(r) => 3 * r * r,
+		Rect(w, h) => w * h,
+		Empty => 0,
+	};
+	`
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{shape + "Circle(2)", "Circle(2)"},
+		{shape + "Rect(h: 3, w: 2)", "Rect(2, 3)"},
+		{shape + "Empty", "Empty"},
+		{shape + "Shape.Rect(1, 2)", "Rect(1, 2)"},
+		{shape + "Shape.Empty == Empty", "true"},
+		// variants without fields can be called without arguments
+		{shape + "[Shape.Empty(), Empty(), Shape.Empty() == Empty]", "[Empty, Empty, true]"},
+		// qualified variants in patterns
+		{shape + `enum Other { Circle(r), Empty }
+		let f = (s) => match (s) {
+			Shape.Circle(r) => r,
+			Shape.Empty => "shape",
+			Other.Empty() => "other",
+			_ => "no match",
+		};
+		[f(Shape.Circle(2)), f(Other.Circle(2)), f(Shape.Empty), f(Other.Empty), f(Empty)]`,
+			"[2, no match, shape, other, other]"},
+		{shape + "let Shape.Rect(w, h) = Shape.Rect(4, 5); w * h", "20"},
+		{shape + "[area(Circle(2)), area(Rect(2, 3)), area(Empty)]", "[12, 6, 0]"},
+		{shape + "Rect(2, 3).h", "3"},
+		{shape + "[tag(Circle(1)), tag(Empty)]", "[Circle, Empty]"},
+		// equality
+		{shape + "Circle(1) == Circle(1)", "true"},
+		{shape + "Circle(1) != Circle(2)", "true"},
+		{shape + "Rect(1, 1) == Empty", "false"},
+		{shape + "enum Other { Circle(r) }; Circle(1) == Shape.Circle(1)", "false"},
+		// destructuring with let
+		{shape + "let Rect(w, h) = Rect(4, 5); w + h", "9"},
+		{shape + "let Circle(r = 1) = Circle(7); r", "7"},
+		// variable with the value of the variant still binds in patterns
+		{shape + "let e = Empty; match (Circle(1)) { e => tag(e) }", "Circle"},
+		{shape + "match (Circle(1)) { Empty() => 0, Circle(1) => 1, _ => 2 }", "1"},
+		{shape + "match ([Circle(1), Empty]) { [Circle(r), Empty] => r, _ => 0 }", "1"},
+		// state machine
+		{`enum State { Idle, Running(ticks), Done(result) }
+		let step = (s) => match (s) {
+			Idle => Running(0),
+			Running(t) if t < 2 => Running(t + 1),
+			Running(t) => Done(t * 10),
+			Done(_) => s,
+		};
+		step(step(step(step(Idle))))`, "Done(20)"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%q",
+				tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestEnumErrors(t *testing.T) {
+	shape := "enum Shape { Circle(r), Rect(w, h), Empty }; "
+
+	tests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{shape + "Rect(1)", "missing field: h"},
+		{shape + "Circle(1, 2)", "wrong number of arguments: want=1, got=2"},
+		{shape + "Shape.Square", "unknown variant: Shape.Square"},
+		{shape + "Shape.Square(1)", "unknown variant: Shape.Square"},
+		{shape + "Circle(1).x", "unknown field: Circle.x"},
+		{shape + "Empty(1)", "wrong number of arguments: want=0, got=1"},
+		{shape + "Shape.Empty(1)", "wrong number of arguments: want=0, got=1"},
+		{shape + "Circle(1)(2)", "not a function: Shape"},
+		{shape + "match (Empty) { Shape.Square => 0 }", "unknown variant: Shape.Square"},
+		{shape + "match (Empty) { Circle.Empty => 0 }", "not an enum: Circle"},
+		{shape + "match (Empty) { Other.Empty => 0 }", "identifier not found: Other"},
+		{shape + "let Shape.Circle = Circle(1);", "wrong number of fields in pattern Circle: want=1, got=0"},
+		{shape + "let Rect(w, h) = Circle(1);", "value doesn't match pattern: want=Rect, got=Circle(1)"},
+		{shape + "let Rect(w) = Rect(1, 2);", "wrong number of fields in pattern Rect: want=2, got=1"},
+		{shape + "let area = 1; let area(x) = 1;", "not a variant: area"},
+		{shape + "match (Empty) { Square(x) => x }", "identifier not found: Square"},
+		{shape + "match (Rect(1, 2)) { Circle(r) => r, Empty => 0 }", "no match for value: Rect(1, 2)"},
+		{"tag(1)", "argument to tag must be enum variant, got INTEGER"},
+		{"tag()", "wrong number of arguments: want=1, got=0"},
+		{shape + "tag(v: Empty)", "keyword arguments are not supported by builtin functions"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned. got=%T(%+v)",
+				evaluated, evaluated)
+			continue
+		}
+
+		if errObj.Message != tt.expectedMessage {
+			t.Errorf("wrong error message. expected=%q, got=%q",
+				tt.expectedMessage, errObj.Message)
+		}
+	}
+}
+
+func TestTraits(t *testing.T) {
+	shapes := `
+	trait Shape { area(); perimeter() }
+	struct Circle { r }
+	struct Rect { w, h }
+	impl Shape for Circle {
+		area(self) { 3 * self.r * self.r }
+		perimeter(self) { 6 * self.r }
+	}
+	impl Shape for Rect {
+		area(self) { self.w * self.h }
+		perimeter(self) { 2 * (self.w + self.h) }
+		square(self) { self.w == self.h }
+	}
+	`
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{shapes + "[Circle(2).area(), Rect(2, 3).area()]", "[12, 6]"},
+		{shapes + "[Circle(1).perimeter(), Rect(2, 3).perimeter()]", "[6, 10]"},
+		{shapes + "let total = (a, b) => a.area() + b.area(); total(Circle(1), Rect(1, 2))", "5"},
+		{shapes + "Rect(2, 2).square()", "true"},
+		{shapes + "[implements(Circle(1), Shape), implements(Rect(1, 2), Shape)]", "[true, true]"},
+		{shapes + "struct Line { l }; implements(Line(1), Shape)", "false"},
+		{shapes + `[implements(1, Shape), implements("a", Shape)]`, "[false, false]"},
+		// inherent impl doesn't implement the trait
+		{shapes + "struct Sq { s }; impl Sq { area(self) { 1 } perimeter(self) { 1 } }; implements(Sq(1), Shape)", "false"},
+		// enums implement traits too
+		{`trait Named { name() }
+		enum Color { Red, Custom(n) }
+		impl Named for Color {
+			name(self) { match (self) { Red => "red", Custom(n) => n } }
+		}
+		[Red.name(), Custom("teal").name(), implements(Red, Named)]`, "[red, teal, true]"},
+		// parameters of the trait methods don't include the receiver
+		{shapes + `trait Scale { scale(k) }
+		impl Scale for Circle { scale(self, k) { Circle(self.r * k) } }
+		[Circle(1).scale(2).area(), implements(Circle(1), Scale)]`, "[12, true]"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%q",
+				tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestTraitErrors(t *testing.T) {
+	shapes := "trait Shape { area(); perimeter() }; struct Circle { r }; "
+
+	tests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{shapes + "impl Shape for Circle { area(self) { 1 } }",
+			"missing method perimeter of trait Shape for Circle"},
+		{shapes + "impl Circle for Circle { area(self) { 1 } }", "not a trait: Circle"},
+		{shapes + "impl Unknown for Circle { area(self) { 1 } }", "identifier not found: Unknown"},
+		{shapes + "impl Shape for Shape { area(self) { 1 } }", "cannot implement methods for TRAIT"},
+		// methods are not added if the impl block failed
+		{shapes + "impl Shape for Circle { area(self) { 1 } }; Circle(1).area()",
+			"missing method perimeter of trait Shape for Circle"},
+		{shapes + "implements(Circle(1), Circle)", "argument to implements must be trait, got STRUCT_TYPE"},
+		{shapes + "implements(Circle(1))", "wrong number of arguments: want=2, got=1"},
+		{shapes + "impl Shape for Circle { area(self, extra) { 1 } perimeter(self) { 1 } }",
+			"method area of Circle has 1 parameters, trait Shape requires 0"},
+		{"trait Scale { scale(k) }; struct P { x }; impl Scale for P { scale(self) { 1 } }",
+			"method scale of P has 0 parameters, trait Scale requires 1"},
+		{shapes + `trait Sized { area() }
+		impl Shape for Circle { area(self) { 1 } perimeter(self) { 2 } }
+		impl Sized for Circle { area(self) { 3 } }`,
+			"conflicting method area of traits Shape and Sized for Circle"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned. got=%T(%+v)",
+				evaluated, evaluated)
+			continue
+		}
+
+		if errObj.Message != tt.expectedMessage {
+			t.Errorf("wrong error message. expected=%q, got=%q",
+				tt.expectedMessage, errObj.Message)
+		}
+	}
+}
+
+func TestOperatorOverloading(t *testing.T) {
+	vector := `
+	struct Vec { x, y }
+	impl Vec {
+		__add__(self, other) { Vec(self.x + other.x, self.y + other.y) }
+		__sub__(self, other) { Vec(self.x - other.x, self.y - other.y) }
+		__mul__(self, k) { Vec(self.x * k, self.y * k) }
+		__div__(self, k) { Vec(self.x / k, self.y / k) }
+		__neg__(self) { Vec(-self.x, -self.y) }
+	}
+	`
+	money := `
+	struct Money { cents, currency }
+	impl Money {
+		__add__(self, other) { Money(self.cents + other.cents, self.currency) }
+		__eq__(self, other) { self.cents == other.cents }
+		__lt__(self, other) { self.cents < other.cents }
+		__gt__(self, other) { self.cents > other.cents }
+	}
+	`
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{vector + "Vec(1, 2) + Vec(3, 4)", "Vec{x: 4, y: 6}"},
+		{vector + "Vec(5, 5) - Vec(1, 2) * 2", "Vec{x: 3, y: 1}"},
+		{vector + "Vec(4, 6) / 2", "Vec{x: 2, y: 3}"},
+		{vector + "-Vec(1, -2)", "Vec{x: -1, y: 2}"},
+		{vector + "-Vec(1, 1) + Vec(1, 1) == Vec(0, 0)", "true"},
+		{money + `Money(100, "USD") + Money(50, "USD")`, "Money{cents: 150, currency: USD}"},
+		// __eq__ replaces structural equality
+		{money + `Money(100, "USD") == Money(100, "EUR")`, "true"},
+		{money + `Money(100, "USD") != Money(100, "EUR")`, "false"},
+		{money + `Money(1, "USD") < Money(2, "USD")`, "true"},
+		{money + `Money(1, "USD") > Money(2, "USD")`, "false"},
+		// reflected methods are called if the left operand doesn't
+		// overload the operator
+		{vector + `
+		impl Vec {
+			__rmul__(self, k) { Vec(k * self.x, k * self.y) }
+			__radd__(self, k) { Vec(k + self.x, k + self.y) }
+			__rsub__(self, k) { Vec(k - self.x, k - self.y) }
+			__rdiv__(self, k) { Vec(k / self.x, k / self.y) }
+		}
+		[2 * Vec(1, 2), 1 + Vec(1, 2), 5 - Vec(1, 2), 6 / Vec(1, 2)]`,
+			"[Vec{x: 2, y: 4}, Vec{x: 2, y: 3}, Vec{x: 4, y: 3}, Vec{x: 6, y: 3}]"},
+		{vector + "Vec(1, 2) * 2", "Vec{x: 2, y: 4}"},
+		{`struct Cents { v }
+		impl Cents {
+			__eq__(self, n) { self.v == n }
+			__lt__(self, n) { self.v < n }
+			__gt__(self, n) { self.v > n }
+		}
+		[100 == Cents(100), 100 != Cents(100), 1 < Cents(2), 1 > Cents(2)]`,
+			"[true, false, true, false]"},
+		// comparisons are derived by swapping the operands
+		{`struct N { v }
+		impl N { __lt__(self, other) { self.v < other.v } }
+		[N(1) < N(2), N(1) > N(2), N(2) > N(1)]`, "[true, false, true]"},
+		{`struct N { v }
+		impl N { __gt__(self, other) { self.v > other.v } }
+		[N(1) < N(2), N(2) < N(1)]`, "[true, false]"},
+		// enums overload operators the same way
+		{`enum Bit { Zero, One }
+		impl Bit { __add__(self, other) { self == Zero ? other : One } }
+		[Zero + One, One + Zero, Zero + Zero]`, "[One, One, Zero]"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%q",
+				tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestOperatorOverloadingErrors(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{"struct P { x }; P(1) + P(2)", "unknown operator: P + P"},
+		{"struct P { x }; -P(1)", "unknown operator: -P"},
+		{"struct P { x }; impl P { __add__(self, o) { self.x + o.x } }; P(1) + 2",
+			"cannot access field x of INTEGER"},
+		{"struct P { x }; impl P { __add__(self) { 1 } }; P(1) + P(2)",
+			"wrong number of arguments: want=1, got=2"},
+		// the right operand dispatches only reflected methods
+		{"struct P { x }; impl P { __add__(self, o) { 1 } }; 1 + P(1)",
+			"type mismatch: INTEGER + P"},
+		{"struct P { x }; impl P { __lt__(self, o) { true } }; 1 < P(1)",
+			"type mismatch: INTEGER < P"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned. got=%T(%+v)",
+				evaluated, evaluated)
+			continue
+		}
+
+		if errObj.Message != tt.expectedMessage {
+			t.Errorf("wrong error message. expected=%q, got=%q",
+				tt.expectedMessage, errObj.Message)
+		}
+	}
+}
+
+func TestSpreadElements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let a = [1, 2]; let b = [3]; [...a, ...b]", "[1, 2, 3]"},
+		{"let a = [2, 3]; [1, ...a, 4, ...[]]", "[1, 2, 3, 4]"},
+		{"let add = (a, b, c) => a + b + c; add(...[1, 2, 3])", "6"},
+		{"let add = (a, b, c) => a + b + c; add(1, ...[2], c: 3)", "6"},
+		{"let count = (...items) => items; count(0, ...[1, 2], ...[3])", "[0, 1, 2, 3]"},
+		// generic wrapper
+		{`let wrap = (f) => (...args) => [f(...args)];
+		let sub = (a, b = 1) => a - b;
+		[wrap(sub)(10, 3), wrap(sub)(10)]`, "[[7], [9]]"},
+		// spread copies the array
+		{"let a = [1]; let b = [...a]; b.push(2); a", "[1]"},
+		{`let defaults = {"host": "localhost", "port": 80};
+		{...defaults, "port": 8080}`, "{host: localhost, port: 8080}"},
+		{`let a = {"x": 1}; {"x": 0, "y": 2, ...a}`, "{x: 1, y: 2}"},
+		{`{...{}, ...{"a": 1}}`, "{a: 1}"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%q",
+				tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestSpreadElementErrors(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{"[...5]", "cannot spread INTEGER as array"},
+		{`let f = (a) => a; f(..."abc")`, "cannot spread STRING as array"},
+		{`[...{"a": 1}]`, "cannot spread HASH as array"},
+		{"{...[1, 2]}", "cannot spread ARRAY as hash"},
+		{"[...unknown]", "identifier not found: unknown"},
+		{"let f = (a) => a; f(...[1, 2])", "wrong number of arguments: want=1, got=2"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned. got=%T(%+v)",
+				evaluated, evaluated)
+			continue
+		}
+
+		if errObj.Message != tt.expectedMessage {
+			t.Errorf("wrong error message. expected=%q, got=%q",
+				tt.expectedMessage, errObj.Message)
+		}
+	}
+}
+
+func TestPuts(t *testing.T) {
+	var out bytes.Buffer
+	object.Output = &out
+	defer func() { object.Output = os.Stdout }()
+
+	evaluated := testEval(t, `puts(1, "two", [3]); puts()`)
+	if evaluated != NULL {
+		t.Errorf("puts should return null. got=%T(%+v)", evaluated, evaluated)
+	}
+	if out.String() != "1\ntwo\n[3]\n" {
+		t.Errorf("wrong output. got=%q", out.String())
+	}
+}
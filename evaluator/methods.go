@@ -0,0 +1,301 @@
+package evaluator
+
+import (
+	"github.com/technoboom/compiler/ast"
+	"github.com/technoboom/compiler/object"
+	"strings"
+)
+
+// builtinMethod - method implemented by interpreter, receives the receiver
+// and arguments of the call
+type builtinMethod func(receiver object.Object, args ...object.Object) object.Object
+
+// methods - builtin methods available for the values of each type
+var methods = map[object.ObjectType]map[string]builtinMethod{
+	object.STRING_OBJ: {
+		"len":      stringLen,
+		"upper":    stringUpper,
+		"lower":    stringLower,
+		"trim":     stringTrim,
+		"split":    stringSplit,
+		"contains": stringContains,
+	},
+	object.ARRAY_OBJ: {
+		"len":   arrayLen,
+		"push":  arrayPush,
+		"pop":   arrayPop,
+		"first": arrayFirst,
+		"last":  arrayLast,
+		"join":  arrayJoin,
+	},
+	object.HASH_OBJ: {
+		"len":    hashLen,
+		"keys":   hashKeys,
+		"values": hashValues,
+		"has":    hashHas,
+	},
+}
+
+// evalMethodCall - calls the method of the receiver. Callable field of
+// the struct and variant constructor of the enum are called as usual
+// functions, otherwise the method is searched in methods of the struct
+// or enum type or in builtin methods of the receiver type
+// and the receiver is passed as the first argument
+func evalMethodCall(
+	node *ast.CallExpression,
+	member *ast.MemberExpression,
+	env *object.Environment,
+) object.Object {
+	receiver := evalChainLink(member.Object, env)
+	if isError(receiver) || receiver == skipped {
+		return receiver
+	}
+	// arguments aren't evaluated if the optional call is skipped
+	if member.Optional && receiver == NULL {
+		return skipped
+	}
+
+	positional, keywords := splitArguments(node.Arguments)
+	args := evalExpressions(positional, env)
+	if len(args) == 1 && isError(args[0]) {
+		return args[0]
+	}
+	kwargs, err := evalKeywordArguments(keywords, env)
+	if err != nil {
+		return err
+	}
+
+	name := member.Member.Value
+	switch r := receiver.(type) {
+	case *object.Struct:
+		if field, ok := r.Fields[name]; ok {
+			return applyFunction(field, args, kwargs)
+		}
+	case *object.EnumType:
+		ctor := enumMember(r, name)
+		if isError(ctor) {
+			return ctor
+		}
+		return applyFunction(ctor, args, kwargs)
+	}
+
+	if impl := object.ImplementationOf(receiver); impl != nil {
+		if method, ok := impl.Methods[name]; ok {
+			return applyFunction(method, append([]object.Object{receiver}, args...), kwargs)
+		}
+	}
+
+	method, ok := methods[receiver.Type()][name]
+	if !ok {
+		return newError(object.NAME_ERROR,
+			"unknown method: %s.%s", receiver.Type(), name)
+	}
+	if len(kwargs) > 0 {
+		return newError(object.ARGUMENT_ERROR,
+			"keyword arguments are not supported by %s.%s", receiver.Type(), name)
+	}
+
+	return method(receiver, args...)
+}
+
+// checkArguments - checks number and types of the arguments
+// of the builtin method
+func checkArguments(
+	name string,
+	args []object.Object,
+	types ...object.ObjectType,
+) object.Object {
+	if len(args) != len(types) {
+		return newError(object.ARGUMENT_ERROR,
+			"wrong number of arguments: want=%d, got=%d", len(types), len(args))
+	}
+
+	for idx, arg := range args {
+		if arg.Type() != types[idx] {
+			return newError(object.TYPE_ERROR,
+				"argument to %s must be %s, got %s", name, types[idx], arg.Type())
+		}
+	}
+
+	return nil
+}
+
+// stringLen - returns number of characters in the string
+func stringLen(receiver object.Object, args ...object.Object) object.Object {
+	if err := checkArguments("len", args); err != nil {
+		return err
+	}
+	return &object.Integer{Value: int64(len(receiver.(*object.String).Value))}
+}
+
+// stringUpper - returns the string in upper case
+func stringUpper(receiver object.Object, args ...object.Object) object.Object {
+	if err := checkArguments("upper", args); err != nil {
+		return err
+	}
+	return &object.String{Value: strings.ToUpper(receiver.(*object.String).Value)}
+}
+
+// stringLower - returns the string in lower case
+func stringLower(receiver object.Object, args ...object.Object) object.Object {
+	if err := checkArguments("lower", args); err != nil {
+		return err
+	}
+	return &object.String{Value: strings.ToLower(receiver.(*object.String).Value)}
+}
+
+// stringTrim - returns the string without leading and trailing whitespaces
+func stringTrim(receiver object.Object, args ...object.Object) object.Object {
+	if err := checkArguments("trim", args); err != nil {
+		return err
+	}
+	return &object.String{Value: strings.TrimSpace(receiver.(*object.String).Value)}
+}
+
+// stringSplit - splits the string by the separator into array of strings
+func stringSplit(receiver object.Object, args ...object.Object) object.Object {
+	if err := checkArguments("split", args, object.STRING_OBJ); err != nil {
+		return err
+	}
+
+	parts := strings.Split(receiver.(*object.String).Value, args[0].(*object.String).Value)
+	elements := make([]object.Object, len(parts))
+	for idx, part := range parts {
+		elements[idx] = &object.String{Value: part}
+	}
+
+	return &object.Array{Elements: elements}
+}
+
+// stringContains - checks if the string contains the substring
+func stringContains(receiver object.Object, args ...object.Object) object.Object {
+	if err := checkArguments("contains", args, object.STRING_OBJ); err != nil {
+		return err
+	}
+
+	value := receiver.(*object.String).Value
+	return nativeBoolToBooleanObject(strings.Contains(value, args[0].(*object.String).Value))
+}
+
+// arrayLen - returns number of elements in the array
+func arrayLen(receiver object.Object, args ...object.Object) object.Object {
+	if err := checkArguments("len", args); err != nil {
+		return err
+	}
+	return &object.Integer{Value: int64(len(receiver.(*object.Array).Elements))}
+}
+
+// arrayPush - appends the values to the end of the array, returns the array
+func arrayPush(receiver object.Object, args ...object.Object) object.Object {
+	arr := receiver.(*object.Array)
+	arr.Elements = append(arr.Elements, args...)
+	return arr
+}
+
+// arrayPop - removes the last element of the array and returns it,
+// returns null for empty array
+func arrayPop(receiver object.Object, args ...object.Object) object.Object {
+	if err := checkArguments("pop", args); err != nil {
+		return err
+	}
+
+	arr := receiver.(*object.Array)
+	if len(arr.Elements) == 0 {
+		return NULL
+	}
+
+	last := arr.Elements[len(arr.Elements)-1]
+	arr.Elements = arr.Elements[:len(arr.Elements)-1]
+	return last
+}
+
+// arrayFirst - returns the first element of the array or null
+func arrayFirst(receiver object.Object, args ...object.Object) object.Object {
+	if err := checkArguments("first", args); err != nil {
+		return err
+	}
+
+	arr := receiver.(*object.Array)
+	if len(arr.Elements) == 0 {
+		return NULL
+	}
+	return arr.Elements[0]
+}
+
+// arrayLast - returns the last element of the array or null
+func arrayLast(receiver object.Object, args ...object.Object) object.Object {
+	if err := checkArguments("last", args); err != nil {
+		return err
+	}
+
+	arr := receiver.(*object.Array)
+	if len(arr.Elements) == 0 {
+		return NULL
+	}
+	return arr.Elements[len(arr.Elements)-1]
+}
+
+// arrayJoin - joins string representations of the elements with the separator
+func arrayJoin(receiver object.Object, args ...object.Object) object.Object {
+	if err := checkArguments("join", args, object.STRING_OBJ); err != nil {
+		return err
+	}
+
+	arr := receiver.(*object.Array)
+	parts := make([]string, len(arr.Elements))
+	for idx, e := range arr.Elements {
+		parts[idx] = e.Inspect()
+	}
+
+	return &object.String{Value: strings.Join(parts, args[0].(*object.String).Value)}
+}
+
+// hashLen - returns number of pairs in the hash
+func hashLen(receiver object.Object, args ...object.Object) object.Object {
+	if err := checkArguments("len", args); err != nil {
+		return err
+	}
+	return &object.Integer{Value: int64(receiver.(*object.Hash).Len())}
+}
+
+// hashKeys - returns array of keys of the hash in insertion order
+func hashKeys(receiver object.Object, args ...object.Object) object.Object {
+	if err := checkArguments("keys", args); err != nil {
+		return err
+	}
+
+	keys := []object.Object{}
+	for _, pair := range receiver.(*object.Hash).Pairs() {
+		keys = append(keys, pair.Key)
+	}
+	return &object.Array{Elements: keys}
+}
+
+// hashValues - returns array of values of the hash in insertion order
+func hashValues(receiver object.Object, args ...object.Object) object.Object {
+	if err := checkArguments("values", args); err != nil {
+		return err
+	}
+
+	values := []object.Object{}
+	for _, pair := range receiver.(*object.Hash).Pairs() {
+		values = append(values, pair.Value)
+	}
+	return &object.Array{Elements: values}
+}
+
+// hashHas - checks if the hash contains the key
+func hashHas(receiver object.Object, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError(object.ARGUMENT_ERROR,
+			"wrong number of arguments: want=1, got=%d", len(args))
+	}
+
+	key, ok := args[0].(object.Hashable)
+	if !ok {
+		return newError(object.TYPE_ERROR, "unusable as hash key: %s", args[0].Type())
+	}
+
+	_, found := receiver.(*object.Hash).Get(key)
+	return nativeBoolToBooleanObject(found)
+}
@@ -0,0 +1,91 @@
+package evaluator
+
+import (
+	"github.com/technoboom/compiler/object"
+)
+
+// operatorMethods - names of methods which overload operators
+// for user declared types
+var operatorMethods = map[string]string{
+	"+":  "__add__",
+	"-":  "__sub__",
+	"*":  "__mul__",
+	"/":  "__div__",
+	"==": "__eq__",
+	"!=": "__eq__",
+	"<":  "__lt__",
+	">":  "__gt__",
+}
+
+// reflectedMethods - names of methods of the right operand which are
+// called if the left operand doesn't overload the operator. The operands
+// of comparisons are swapped, so `a > b` is evaluated as `b < a` and
+// a type declaring only `__lt__` supports both `<` and `>`
+var reflectedMethods = map[string]string{
+	"+":  "__radd__",
+	"-":  "__rsub__",
+	"*":  "__rmul__",
+	"/":  "__rdiv__",
+	"==": "__eq__",
+	"!=": "__eq__",
+	"<":  "__gt__",
+	">":  "__lt__",
+}
+
+// evalOperatorMethod - calls the method which overloads infix operator if
+// the type of the left operand declares it, the left operand is passed as
+// the receiver and the right one as the argument. Otherwise the reflected
+// method of the right operand is called with the right operand as the
+// receiver and the left one as the argument: `1 + v` calls `v.__radd__(1)`
+// and `a > b` calls `b.__lt__(a)` if `a` doesn't declare `__gt__`.
+// `!=` is evaluated as negated `__eq__`. Returns false if the operator
+// is not overloaded
+func evalOperatorMethod(
+	operator string,
+	left, right object.Object,
+) (object.Object, bool) {
+	args := []object.Object{left, right}
+	method, ok := operatorMethod(left, operatorMethods[operator])
+	if !ok {
+		method, ok = operatorMethod(right, reflectedMethods[operator])
+		if !ok {
+			return nil, false
+		}
+		args = []object.Object{right, left}
+	}
+
+	result := applyFunction(method, args, nil)
+	if operator == "!=" && !isError(result) {
+		return nativeBoolToBooleanObject(!isTruly(result)), true
+	}
+	return result, true
+}
+
+// evalPrefixOperatorMethod - calls `__neg__` method which overloads
+// prefix `-` operator. Returns false if the operator is not overloaded
+func evalPrefixOperatorMethod(
+	operator string,
+	right object.Object,
+) (object.Object, bool) {
+	if operator != "-" {
+		return nil, false
+	}
+
+	method, ok := operatorMethod(right, "__neg__")
+	if !ok {
+		return nil, false
+	}
+
+	return applyFunction(method, []object.Object{right}, nil), true
+}
+
+// operatorMethod - returns the method of the user declared type by its name
+func operatorMethod(obj object.Object, name string) (object.Object, bool) {
+	impl := object.ImplementationOf(obj)
+	if impl == nil || name == "" {
+		return nil, false
+	}
+
+	method, ok := impl.Methods[name]
+	return method, ok
+}
@@ -0,0 +1,424 @@
+package evaluator
+
+import (
+	"github.com/technoboom/compiler/ast"
+	"github.com/technoboom/compiler/object"
+)
+
+// resolver - assigns slots to the variables and locations to the
+// identifiers of the program before its evaluation
+type resolver struct {
+	// scopes visible from the current node, the innermost is the last one
+	scopes []*resolverScope
+}
+
+// resolverScope - scope being resolved and variables of the scope
+// declared before the current node
+type resolverScope struct {
+	scope *ast.Scope
+	// variables whose declarations are already passed, other variables
+	// of the scope are visible only from the nested functions
+	visible map[string]bool
+	// all variables of the program are visible from the start
+	hoisted bool
+	// functions are evaluated when they are called, unlike match arms
+	// and catch blocks evaluated in place
+	function bool
+}
+
+// resolve - resolves identifiers of the program evaluated in the environment.
+// Variables of the program get slots in the environment, functions, match
+// arms and catch blocks get their own scopes. Each identifier gets exactly
+// one location:
+//   - variables of the program are visible everywhere, so functions can
+//     refer to the functions declared after them
+//   - inside a function (match arm, catch block) its own variable is visible
+//     after its declaration, before that the name refers to the variable
+//     of the enclosing scope (let x = x + 1 reads the outer x). After the
+//     declaration the local variable hides the outer one, even if its let
+//     isn't evaluated (declared in the branch which isn't taken), reading
+//     it is a NameError then
+//   - nested functions see all variables of the enclosing functions, as
+//     they are called after the declarations
+//
+// Names which aren't declared anywhere are left without location, they
+// are looked up by name when evaluated: builtins, variables declared by
+// the later parts of the program (lines of REPL) or unknown identifiers
+func resolve(program *ast.Program, env *object.Environment) {
+	r := &resolver{}
+	r.enter(env.Scope(), false)
+	r.current().hoisted = true
+	r.declareStatements(program.Statements)
+	r.resolveStatements(program.Statements)
+}
+
+// current - returns the innermost scope
+func (r *resolver) current() *resolverScope {
+	return r.scopes[len(r.scopes)-1]
+}
+
+// enter - makes the scope the innermost one
+func (r *resolver) enter(scope *ast.Scope, function bool) {
+	r.scopes = append(r.scopes, &resolverScope{
+		scope:    scope,
+		visible:  make(map[string]bool),
+		function: function,
+	})
+}
+
+// leave - returns to the enclosing scope
+func (r *resolver) leave() {
+	r.scopes = r.scopes[:len(r.scopes)-1]
+}
+
+// lookup - returns location of the variable the name refers to at the
+// current node, nil if there is no such variable
+func (r *resolver) lookup(name string) *ast.Location {
+	nested := false
+	for idx := len(r.scopes) - 1; idx >= 0; idx-- {
+		s := r.scopes[idx]
+		slot, ok := s.scope.Lookup(name)
+		if ok && (s.hoisted || nested || s.visible[name]) {
+			return &ast.Location{Depth: len(r.scopes) - 1 - idx, Slot: slot}
+		}
+		if s.function {
+			nested = true
+		}
+	}
+	return nil
+}
+
+// bind - makes the variable of the current scope visible and assigns its
+// location to the identifier which binds it
+func (r *resolver) bind(name *ast.Identifier) {
+	s := r.current()
+	slot, _ := s.scope.Lookup(name.Value)
+	s.visible[name.Value] = true
+	name.Location = &ast.Location{Depth: 0, Slot: slot}
+}
+
+// declareStatements - declares variables bound by the statements
+func (r *resolver) declareStatements(statements []ast.Statement) {
+	for _, s := range statements {
+		r.declare(s)
+	}
+}
+
+// declare - declares variables bound by the node in the current scope,
+// nested functions, match arms and catch blocks are skipped as they have
+// their own scopes
+func (r *resolver) declare(node ast.Node) {
+	scope := r.current().scope
+
+	switch node := node.(type) {
+	case *ast.LetStatement:
+		r.declarePattern(node.Pattern)
+		r.declare(node.Value)
+	case *ast.ExpressionStatement:
+		r.declare(node.Expression)
+	case *ast.ReturnStatement:
+		r.declare(node.ReturnValue)
+	case *ast.ThrowStatement:
+		r.declare(node.Value)
+	case *ast.BlockStatement:
+		r.declareStatements(node.Statements)
+	case *ast.StructStatement:
+		scope.Declare(node.Name.Value)
+	case *ast.EnumStatement:
+		for _, v := range node.Variants {
+			scope.Declare(v.Name.Value)
+		}
+		scope.Declare(node.Name.Value)
+	case *ast.TraitStatement:
+		scope.Declare(node.Name.Value)
+	case *ast.PrefixExpression:
+		r.declare(node.Right)
+	case *ast.InfixExpression:
+		r.declare(node.Left)
+		r.declare(node.Right)
+	case *ast.IfExpression:
+		r.declare(node.Condition)
+		r.declare(node.Consequence)
+		if node.Alternative != nil {
+			r.declare(node.Alternative)
+		}
+	case *ast.ConditionalExpression:
+		r.declare(node.Condition)
+		r.declare(node.Consequence)
+		r.declare(node.Alternative)
+	case *ast.MatchExpression:
+		r.declare(node.Subject)
+	case *ast.TryExpression:
+		r.declare(node.Block)
+		if node.Finally != nil {
+			r.declare(node.Finally)
+		}
+	case *ast.CallExpression:
+		r.declare(node.Function)
+		for _, arg := range node.Arguments {
+			r.declare(arg)
+		}
+	case *ast.KeywordArgument:
+		r.declare(node.Value)
+	case *ast.SpreadElement:
+		r.declare(node.Value)
+	case *ast.ArrayLiteral:
+		for _, e := range node.Elements {
+			r.declare(e)
+		}
+	case *ast.HashLiteral:
+		for _, pair := range node.Pairs {
+			r.declare(pair.Key)
+			if pair.Value != nil {
+				r.declare(pair.Value)
+			}
+		}
+	case *ast.MemberExpression:
+		r.declare(node.Object)
+	case *ast.AssignExpression:
+		r.declare(node.Target)
+		r.declare(node.Value)
+	}
+}
+
+// declarePattern - declares names bound by the pattern and variables
+// bound by its default values
+func (r *resolver) declarePattern(pattern ast.Pattern) {
+	scope := r.current().scope
+
+	switch pattern := pattern.(type) {
+	case *ast.Identifier:
+		scope.Declare(pattern.Value)
+	case *ast.VariantPattern:
+		for _, element := range pattern.Elements {
+			r.declarePattern(element)
+		}
+	case *ast.LiteralPattern:
+		r.declare(pattern.Value)
+	case *ast.DefaultPattern:
+		r.declarePattern(pattern.Target)
+		r.declare(pattern.Default)
+	case *ast.ArrayPattern:
+		for _, element := range pattern.Elements {
+			r.declarePattern(element)
+		}
+		if pattern.Rest != nil {
+			scope.Declare(pattern.Rest.Value)
+		}
+	case *ast.HashPattern:
+		for _, pair := range pattern.Pairs {
+			r.declarePattern(pair.Value)
+		}
+		if pattern.Rest != nil {
+			scope.Declare(pattern.Rest.Value)
+		}
+	}
+}
+
+// resolveStatements - resolves identifiers of the statements
+func (r *resolver) resolveStatements(statements []ast.Statement) {
+	for _, s := range statements {
+		r.resolve(s)
+	}
+}
+
+// resolve - resolves identifiers of the node in order of evaluation,
+// names which aren't variables (fields, methods, keywords) stay unresolved
+func (r *resolver) resolve(node ast.Node) {
+	switch node := node.(type) {
+	case *ast.Identifier:
+		node.Location = r.lookup(node.Value)
+	case *ast.LetStatement:
+		r.resolve(node.Value)
+		r.resolvePattern(node.Pattern)
+	case *ast.ExpressionStatement:
+		r.resolve(node.Expression)
+	case *ast.ReturnStatement:
+		r.resolve(node.ReturnValue)
+	case *ast.ThrowStatement:
+		r.resolve(node.Value)
+	case *ast.BlockStatement:
+		r.resolveStatements(node.Statements)
+	case *ast.FunctionLiteral:
+		r.resolveFunction(node)
+	case *ast.StructStatement:
+		r.bind(node.Name)
+	case *ast.EnumStatement:
+		for _, v := range node.Variants {
+			r.bind(v.Name)
+		}
+		r.bind(node.Name)
+	case *ast.TraitStatement:
+		r.bind(node.Name)
+	case *ast.ImplStatement:
+		r.resolve(node.Name)
+		if node.Trait != nil {
+			r.resolve(node.Trait)
+		}
+		for _, method := range node.Methods {
+			r.resolveFunction(method.Function)
+		}
+	case *ast.PrefixExpression:
+		r.resolve(node.Right)
+	case *ast.InfixExpression:
+		r.resolve(node.Left)
+		r.resolve(node.Right)
+	case *ast.IfExpression:
+		r.resolve(node.Condition)
+		r.resolve(node.Consequence)
+		if node.Alternative != nil {
+			r.resolve(node.Alternative)
+		}
+	case *ast.ConditionalExpression:
+		r.resolve(node.Condition)
+		r.resolve(node.Consequence)
+		r.resolve(node.Alternative)
+	case *ast.MatchExpression:
+		r.resolve(node.Subject)
+		for _, arm := range node.Arms {
+			r.resolveArm(arm)
+		}
+	case *ast.TryExpression:
+		r.resolveTry(node)
+	case *ast.CallExpression:
+		r.resolve(node.Function)
+		for _, arg := range node.Arguments {
+			r.resolve(arg)
+		}
+	case *ast.KeywordArgument:
+		r.resolve(node.Value)
+	case *ast.SpreadElement:
+		r.resolve(node.Value)
+	case *ast.ArrayLiteral:
+		for _, e := range node.Elements {
+			r.resolve(e)
+		}
+	case *ast.HashLiteral:
+		for _, pair := range node.Pairs {
+			r.resolve(pair.Key)
+			if pair.Value != nil {
+				r.resolve(pair.Value)
+			}
+		}
+	case *ast.MemberExpression:
+		r.resolve(node.Object)
+	case *ast.AssignExpression:
+		r.resolve(node.Target)
+		r.resolve(node.Value)
+	}
+}
+
+// resolvePattern - resolves expressions of the pattern and binds its names
+// in order of destructuring: the default value is evaluated before its
+// target is bound. Before the name is bound, the variable it shadows is
+// remembered for the check of variant constants
+func (r *resolver) resolvePattern(pattern ast.Pattern) {
+	switch pattern := pattern.(type) {
+	case *ast.Identifier:
+		pattern.Shadowed = r.lookup(pattern.Value)
+		r.bind(pattern)
+	case *ast.VariantPattern:
+		if pattern.Enum != nil {
+			r.resolve(pattern.Enum)
+		} else {
+			r.resolve(pattern.Name)
+		}
+		for _, element := range pattern.Elements {
+			r.resolvePattern(element)
+		}
+	case *ast.LiteralPattern:
+		r.resolve(pattern.Value)
+	case *ast.DefaultPattern:
+		r.resolve(pattern.Default)
+		r.resolvePattern(pattern.Target)
+	case *ast.ArrayPattern:
+		for _, element := range pattern.Elements {
+			r.resolvePattern(element)
+		}
+		if pattern.Rest != nil {
+			r.bind(pattern.Rest)
+		}
+	case *ast.HashPattern:
+		for _, pair := range pattern.Pairs {
+			r.resolvePattern(pair.Value)
+		}
+		if pattern.Rest != nil {
+			r.bind(pattern.Rest)
+		}
+	}
+}
+
+// resolveFunction - resolves the function in its own scope: parameters
+// are bound in order, so default values see the previous parameters
+func (r *resolver) resolveFunction(fn *ast.FunctionLiteral) {
+	fn.Scope = ast.NewScope()
+	r.enter(fn.Scope, true)
+	defer r.leave()
+
+	for _, param := range fn.Parameters {
+		fn.Scope.Declare(param.Value)
+	}
+	if fn.Rest != nil {
+		fn.Scope.Declare(fn.Rest.Value)
+	}
+	for _, param := range fn.Parameters {
+		if def, ok := fn.Defaults[param.Value]; ok {
+			r.declare(def)
+		}
+	}
+	r.declare(fn.Body)
+
+	for _, param := range fn.Parameters {
+		if def, ok := fn.Defaults[param.Value]; ok {
+			r.resolve(def)
+		}
+		r.bind(param)
+	}
+	if fn.Rest != nil {
+		r.bind(fn.Rest)
+	}
+	r.resolve(fn.Body)
+}
+
+// resolveArm - resolves the match arm in its own scope
+func (r *resolver) resolveArm(arm *ast.MatchArm) {
+	arm.Scope = ast.NewScope()
+	r.enter(arm.Scope, false)
+	defer r.leave()
+
+	r.declarePattern(arm.Pattern)
+	if arm.Guard != nil {
+		r.declare(arm.Guard)
+	}
+	r.declare(arm.Body)
+
+	r.resolvePattern(arm.Pattern)
+	if arm.Guard != nil {
+		r.resolve(arm.Guard)
+	}
+	r.resolve(arm.Body)
+}
+
+// resolveTry - resolves blocks of the try expression, the catch block
+// gets its own scope
+func (r *resolver) resolveTry(te *ast.TryExpression) {
+	r.resolve(te.Block)
+
+	if te.Catch != nil {
+		te.CatchScope = ast.NewScope()
+		r.enter(te.CatchScope, false)
+		if te.Parameter != nil {
+			te.CatchScope.Declare(te.Parameter.Value)
+		}
+		r.declare(te.Catch)
+		if te.Parameter != nil {
+			r.bind(te.Parameter)
+		}
+		r.resolve(te.Catch)
+		r.leave()
+	}
+
+	if te.Finally != nil {
+		r.resolve(te.Finally)
+	}
+}
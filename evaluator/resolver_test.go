@@ -0,0 +1,263 @@
+package evaluator
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/technoboom/compiler/ast"
+	"github.com/technoboom/compiler/lexer"
+	"github.com/technoboom/compiler/object"
+	"github.com/technoboom/compiler/parser"
+)
+
+func TestResolvedLookup(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		// variables of the function are visible after their let
+		{"let x = 1; let f = () => { let y = x; let x = 2; [y, x] }; f()", "[1, 2]"},
+		{"let x = 1; let f = () => { let x = x + 1; x }; [f(), x]", "[2, 1]"},
+		{"let x = 1; let f = (c) => { if (c) { let x = 2; } x }; f(true)", "2"},
+		// closures and functions see bindings made after their creation
+		{"let f = () => { let g = () => n; let n = 5; g() }; f()", "5"},
+		{"let f = () => later; let later = 3; f()", "3"},
+		{"let f = (n) => n < 2 ? n : f(n - 1) + f(n - 2); f(10)", "55"},
+		{"let a = 1; let f = () => () => () => a; let a = 2; f()()()", "2"},
+		// parameters, defaults and the rest
+		{"let f = (a, b = a * 2) => a + b; [f(3), f(3, 1)]", "[9, 4]"},
+		{"let f = (a, ...rest) => [a, rest]; f(1, 2, 3)", "[1, [2, 3]]"},
+		{"let a = 10; let f = (a) => a; [f(1), a]", "[1, 10]"},
+		// match arms and catch blocks have their own scopes
+		{"let x = 1; let r = match (5) { x => x + 1 }; [r, x]", "[6, 1]"},
+		{"let r = match ([1, 2]) { [a, b] if a > 1 => 0, [a, b] => b }; r", "2"},
+		{"enum E { A, B }; let f = (e) => match (e) { A => 1, B => 2 }; [f(A), f(B)]", "[1, 2]"},
+		{"let e = 1; let r = try { throw 2; } catch (e) { let y = e; y }; [r, e]", "[{message: 2, kind: Error, type: Error, line: 1, column: 26, value: 2}, 1]"},
+		// destructuring
+		{"let [a, b = 5, ...c] = [1]; let {x, ...y} = {\"x\": a, \"z\": 2}; [a, b, c, x, y]",
+			"[1, 5, [], 1, {z: 2}]"},
+		// structs and methods
+		{"struct P { x } impl P { get(self) { self.x } } let p = P(4); p.get()", "4"},
+		// builtins can be shadowed
+		{"let puts = (x) => x * 2; puts(21)", "42"},
+		// variables of the program are hoisted, so functions may refer to
+		// the ones declared later
+		{"let isEven = (n) => n == 0 ? true : isOdd(n - 1); let isOdd = (n) => n == 0 ? false : isEven(n - 1); [isEven(10), isOdd(7)]",
+			"[true, true]"},
+		{"let f = () => x; let x = 7; f()", "7"},
+		{"let x = 1; let f = () => { let x = x; x }; f()", "1"},
+		// match arm binds the value under the name of the variant constant
+		{"enum C { Red, Green }; match (Red) { Red => Red }", "Red"},
+		{"enum C { Red, Green }; let f = (Red) => Red; f(1)", "1"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+		if evaluated == nil {
+			t.Errorf("%q: no result", tt.input)
+			continue
+		}
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%q: wrong result. want=%s, got=%s", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestResolvedNameErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		// the variable is declared, but its let isn't evaluated
+		{"let f = (a) => { if (a) { let y = 3 }; y }; f(false)", "identifier not found: y"},
+		// the local variable hides the outer one even if its let isn't evaluated
+		{"let x = 1; let f = (c) => { if (c) { let x = 2; } x }; [f(true), f(false)]", "identifier not found: x"},
+		{"let a = a", "identifier not found: a"},
+		{"let f = () => x; f(); let x = 1", "identifier not found: x"},
+		// the name isn't declared at all
+		{"let f = () => { let y = z; let z = 1; y }; f()", "identifier not found: z"},
+		{"unknown", "identifier not found: unknown"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("%q: no error object returned. got=%T(%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Kind != object.NAME_ERROR || errObj.Message != tt.expected {
+			t.Errorf("%q: wrong error. want=%s, got=%s: %s",
+				tt.input, tt.expected, errObj.Kind, errObj.Message)
+		}
+	}
+}
+
+func TestResolvedEnvironmentIsShared(t *testing.T) {
+	env := object.NewEnvironment()
+	env.SetName("base", &object.Integer{Value: 100})
+
+	// like lines of REPL: the function refers to the name bound later
+	inputs := []string{
+		"let f = () => base + g()",
+		"let g = () => 7",
+		"f()",
+	}
+
+	var result object.Object
+	for _, input := range inputs {
+		result = Eval(parseProgram(input), env)
+	}
+	if result == nil || result.Inspect() != "107" {
+		t.Fatalf("wrong result. want=107, got=%v", result)
+	}
+
+	// unresolved nodes are looked up by name
+	statement := parseProgram("base + 1").Statements[0]
+	if result := Eval(statement, env); result == nil || result.Inspect() != "101" {
+		t.Fatalf("wrong result of unresolved node. want=101, got=%v", result)
+	}
+}
+
+func TestResolvedForwardReferences(t *testing.T) {
+	env := object.NewEnvironment()
+
+	// the function sees globals declared and rebound by the later lines
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let a = 1;", "nil"},
+		{"let f = function() { a + b };", "nil"},
+		{"f()", "identifier not found: b"},
+		{"let b = 2;", "nil"},
+		{"f()", "3"},
+		{"let a = 10;", "nil"},
+		{"f()", "12"},
+	}
+
+	for _, tt := range tests {
+		result := Eval(parseProgram(tt.input), env)
+		got := "nil"
+		switch result := result.(type) {
+		case nil:
+		case *object.Error:
+			got = result.Message
+		default:
+			got = result.Inspect()
+		}
+		if got != tt.expected {
+			t.Errorf("%q: wrong result. want=%s, got=%s", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	program := parseProgram("let a = 1; let f = (b) => { let a = a + b; let c = () => a + b; c() }")
+	resolve(program, object.NewEnvironment())
+
+	locations := map[string][]string{}
+	walkIdentifiers(program, func(id *ast.Identifier) {
+		locations[id.Value] = append(locations[id.Value], fmt.Sprint(*id.Location))
+	})
+
+	// the local a is visible after its let, the closure sees locals
+	// of the function
+	expected := map[string][]string{
+		"a": {"{0 0}", "{1 0}", "{0 1}", "{1 1}"},
+		"b": {"{0 0}", "{0 0}", "{1 0}"},
+		"f": {"{0 1}"},
+		"c": {"{0 2}", "{0 2}"},
+	}
+	for name, want := range expected {
+		if fmt.Sprint(locations[name]) != fmt.Sprint(want) {
+			t.Errorf("wrong locations of %s. want=%v, got=%v", name, want, locations[name])
+		}
+	}
+}
+
+// walkIdentifiers - calls the function for resolved identifiers of
+// the program in order of resolution
+func walkIdentifiers(node ast.Node, fn func(id *ast.Identifier)) {
+	switch node := node.(type) {
+	case *ast.Program:
+		for _, s := range node.Statements {
+			walkIdentifiers(s, fn)
+		}
+	case *ast.BlockStatement:
+		for _, s := range node.Statements {
+			walkIdentifiers(s, fn)
+		}
+	case *ast.LetStatement:
+		walkIdentifiers(node.Value, fn)
+		walkIdentifiers(node.Pattern, fn)
+	case *ast.ExpressionStatement:
+		walkIdentifiers(node.Expression, fn)
+	case *ast.FunctionLiteral:
+		for _, param := range node.Parameters {
+			walkIdentifiers(param, fn)
+		}
+		walkIdentifiers(node.Body, fn)
+	case *ast.InfixExpression:
+		walkIdentifiers(node.Left, fn)
+		walkIdentifiers(node.Right, fn)
+	case *ast.CallExpression:
+		walkIdentifiers(node.Function, fn)
+	case *ast.Identifier:
+		fn(node)
+	}
+}
+
+func parseProgram(input string) *ast.Program {
+	return parser.New(lexer.New(input)).ParseProgram()
+}
+
+const closuresProgram = `
+let counter = (start) => {
+	let step = (n) => {
+		let down = (k) => k < 1 ? start : down(k - 1) + 0;
+		down(n) + start
+	};
+	let run = (n) => n < 1 ? 0 : step(10) + run(n - 1);
+	run(200)
+};
+counter(1)
+`
+
+// evalByName - evaluates statements of the program without resolution,
+// every identifier is looked up by its name through the environments
+func evalByName(program *ast.Program, env *object.Environment) object.Object {
+	var result object.Object
+	for _, s := range program.Statements {
+		result = Eval(s, env)
+	}
+	return result
+}
+
+func benchmarkEval(b *testing.B, input string, resolved bool) {
+	program := parseProgram(input)
+
+	for i := 0; i < b.N; i++ {
+		if resolved {
+			Eval(program, object.NewEnvironment())
+		} else {
+			evalByName(program, object.NewEnvironment())
+		}
+	}
+}
+
+func BenchmarkFibonacciResolved(b *testing.B) {
+	benchmarkEval(b, "let fib = (n) => n < 2 ? n : fib(n - 1) + fib(n - 2); fib(20)", true)
+}
+
+func BenchmarkFibonacciByName(b *testing.B) {
+	benchmarkEval(b, "let fib = (n) => n < 2 ? n : fib(n - 1) + fib(n - 2); fib(20)", false)
+}
+
+func BenchmarkClosuresResolved(b *testing.B) {
+	benchmarkEval(b, closuresProgram, true)
+}
+
+func BenchmarkClosuresByName(b *testing.B) {
+	benchmarkEval(b, closuresProgram, false)
+}
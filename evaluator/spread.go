@@ -0,0 +1,50 @@
+package evaluator
+
+import (
+	"github.com/technoboom/compiler/ast"
+	"github.com/technoboom/compiler/object"
+)
+
+// evalSpreadElement - evaluates spread array and returns its elements,
+// returns the error as the only element if the value is not an array
+func evalSpreadElement(
+	spread *ast.SpreadElement,
+	env *object.Environment,
+) []object.Object {
+	value := Eval(spread.Value, env)
+	if isError(value) {
+		return []object.Object{value}
+	}
+
+	array, ok := value.(*object.Array)
+	if !ok {
+		err := newError(object.TYPE_ERROR, "cannot spread %s as array", value.Type())
+		return []object.Object{withPosition(err, spread.Token)}
+	}
+
+	return array.Elements
+}
+
+// evalHashSpread - evaluates spread hash and copies its pairs into the hash
+func evalHashSpread(
+	spread *ast.SpreadElement,
+	hash *object.Hash,
+	env *object.Environment,
+) object.Object {
+	value := Eval(spread.Value, env)
+	if isError(value) {
+		return value
+	}
+
+	source, ok := value.(*object.Hash)
+	if !ok {
+		err := newError(object.TYPE_ERROR, "cannot spread %s as hash", value.Type())
+		return withPosition(err, spread.Token)
+	}
+
+	for _, pair := range source.Pairs() {
+		hash.Set(pair.Key.(object.Hashable), pair.Value)
+	}
+
+	return nil
+}
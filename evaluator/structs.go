@@ -0,0 +1,134 @@
+package evaluator
+
+import (
+	"github.com/technoboom/compiler/ast"
+	"github.com/technoboom/compiler/object"
+)
+
+// evalStructStatement - declares struct type in the environment
+func evalStructStatement(node *ast.StructStatement, env *object.Environment) {
+	fields := make([]string, len(node.Fields))
+	for idx, field := range node.Fields {
+		fields[idx] = field.Value
+	}
+
+	bind(node.Name, &object.StructType{
+		Name:           node.Name.Value,
+		Fields:         fields,
+		Implementation: object.NewImplementation(),
+	}, env)
+}
+
+// newStruct - creates instance of the struct type, fields are set
+// from positional and keyword arguments, all fields are required
+func newStruct(
+	st *object.StructType,
+	args []object.Object,
+	kwargs []keywordArgument,
+) object.Object {
+	values, err := matchArguments(st.Fields, false, args, kwargs)
+	if err != nil {
+		return err
+	}
+
+	fields := make(map[string]object.Object, len(st.Fields))
+	for idx, name := range st.Fields {
+		if values[idx] == nil {
+			return newError(object.ARGUMENT_ERROR, "missing field: %s", name)
+		}
+		fields[name] = values[idx]
+	}
+
+	return &object.Struct{Definition: st, Fields: fields}
+}
+
+// evalMemberExpression - returns value of the struct field, variant field
+// or value of the string key of the hash (e.g. message of the caught error)
+func evalMemberExpression(
+	node *ast.MemberExpression,
+	env *object.Environment,
+) object.Object {
+	obj := evalChainLink(node.Object, env)
+	if isError(obj) || obj == skipped {
+		return obj
+	}
+	if node.Optional && obj == NULL {
+		return skipped
+	}
+
+	switch obj := obj.(type) {
+	case *object.EnumType:
+		return enumMember(obj, node.Member.Value)
+	case *object.Variant:
+		return variantField(obj, node.Member.Value)
+	case *object.Hash:
+		if value, ok := obj.Get(&object.String{Value: node.Member.Value}); ok {
+			return value
+		}
+		return newError(object.NAME_ERROR, "unknown key: %s", node.Member.Value)
+	}
+
+	instance, err := structField(obj, node.Member.Value)
+	if err != nil {
+		return err
+	}
+
+	return instance.Fields[node.Member.Value]
+}
+
+// evalAssignExpression - sets value of the struct field,
+// returns assigned value
+func evalAssignExpression(
+	node *ast.AssignExpression,
+	env *object.Environment,
+) object.Object {
+	obj := Eval(node.Target.Object, env)
+	if isError(obj) {
+		return obj
+	}
+
+	instance, err := structField(obj, node.Target.Member.Value)
+	if err != nil {
+		return err
+	}
+
+	val := Eval(node.Value, env)
+	if isError(val) {
+		return val
+	}
+
+	instance.Fields[node.Target.Member.Value] = val
+	return val
+}
+
+// structField - checks that the object is struct which has the field
+func structField(obj object.Object, name string) (*object.Struct, object.Object) {
+	instance, ok := obj.(*object.Struct)
+	if !ok {
+		return nil, newError(object.TYPE_ERROR,
+			"cannot access field %s of %s", name, obj.Type())
+	}
+
+	if _, ok := instance.Fields[name]; !ok {
+		return nil, newError(object.NAME_ERROR,
+			"unknown field: %s.%s", instance.Definition.Name, name)
+	}
+
+	return instance, nil
+}
+
+// structsEqual - compares structs structurally: both are instances of
+// the same struct type and all their fields are equal
+func structsEqual(left, right *object.Struct) bool {
+	if left.Definition != right.Definition {
+		return false
+	}
+
+	for _, name := range left.Definition.Fields {
+		if !objectsEqual(left.Fields[name], right.Fields[name]) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,118 @@
+package evaluator
+
+import (
+	"github.com/technoboom/compiler/ast"
+	"github.com/technoboom/compiler/object"
+)
+
+// evalTraitStatement - declares trait in the environment
+func evalTraitStatement(node *ast.TraitStatement, env *object.Environment) {
+	methods := make([]string, len(node.Methods))
+	arity := make(map[string]int, len(node.Methods))
+	for idx, method := range node.Methods {
+		methods[idx] = method.Name.Value
+		arity[method.Name.Value] = len(method.Parameters)
+	}
+
+	bind(node.Name, &object.Trait{Name: node.Name.Value, Methods: methods, Arity: arity}, env)
+}
+
+// evalImplStatement - adds methods to the struct or enum type, methods
+// are closures over the environment of the impl block. If the block
+// implements the trait, all methods required by the trait must be declared
+func evalImplStatement(node *ast.ImplStatement, env *object.Environment) object.Object {
+	definition := Eval(node.Name, env)
+	if isError(definition) {
+		return definition
+	}
+
+	var impl *object.Implementation
+	switch definition := definition.(type) {
+	case *object.StructType:
+		impl = &definition.Implementation
+	case *object.EnumType:
+		impl = &definition.Implementation
+	default:
+		return newError(object.TYPE_ERROR,
+			"cannot implement methods for %s", definition.Type())
+	}
+
+	var trait *object.Trait
+	if node.Trait != nil {
+		obj := Eval(node.Trait, env)
+		if isError(obj) {
+			return obj
+		}
+
+		var ok bool
+		if trait, ok = obj.(*object.Trait); !ok {
+			return newError(object.TYPE_ERROR, "not a trait: %s", node.Trait.Value)
+		}
+
+		if err := checkTraitMethods(trait, node); err != nil {
+			return err
+		}
+		if err := checkMethodConflicts(trait, impl, node); err != nil {
+			return err
+		}
+	}
+
+	for _, method := range node.Methods {
+		impl.Methods[method.Name.Value] = Eval(method.Function, env)
+		if trait != nil {
+			impl.Origins[method.Name.Value] = trait
+		} else {
+			delete(impl.Origins, method.Name.Value)
+		}
+	}
+	if trait != nil && !impl.Implements(trait) {
+		impl.Traits = append(impl.Traits, trait)
+	}
+
+	return nil
+}
+
+// checkTraitMethods - checks that impl block declares all methods
+// required by the trait with the same number of parameters
+func checkTraitMethods(trait *object.Trait, node *ast.ImplStatement) object.Object {
+	for _, required := range trait.Methods {
+		var found *ast.MethodDeclaration
+		for _, method := range node.Methods {
+			if method.Name.Value == required {
+				found = method
+				break
+			}
+		}
+
+		if found == nil {
+			return newError(object.TYPE_ERROR,
+				"missing method %s of trait %s for %s",
+				required, trait.Name, node.Name.Value)
+		}
+
+		// the receiver isn't listed in the signature of the trait
+		params := len(found.Function.Parameters) - 1
+		if params != trait.Arity[required] {
+			return newError(object.TYPE_ERROR,
+				"method %s of %s has %d parameters, trait %s requires %d",
+				required, node.Name.Value, params, trait.Name, trait.Arity[required])
+		}
+	}
+
+	return nil
+}
+
+// checkMethodConflicts - checks that methods of the impl block of the
+// trait aren't declared by the impl block of another trait for the type
+func checkMethodConflicts(trait *object.Trait, impl *object.Implementation, node *ast.ImplStatement) object.Object {
+	for _, method := range node.Methods {
+		origin := impl.Origins[method.Name.Value]
+		if origin != nil && origin != trait {
+			return newError(object.TYPE_ERROR,
+				"conflicting method %s of traits %s and %s for %s",
+				method.Name.Value, origin.Name, trait.Name, node.Name.Value)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,820 @@
+// Package gogen - translates Beaver programs into Go packages, top-level
+// functions of the program are exported as Go functions over object.Object
+// values, so the package builds with the Go toolchain into any Go program
+package gogen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	gotoken "go/token"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/technoboom/compiler/ast"
+	"github.com/technoboom/compiler/object"
+)
+
+// scope - bindings of the function being generated (or of the program)
+type scope struct {
+	outer *scope
+
+	// Go variables of the bindings
+	locals map[string]string
+	// bindings which always have a value (parameters), they are read
+	// without the check of unbound variables
+	params map[string]bool
+	// bindings read by the code of the function or the nested functions
+	read map[string]bool
+	// bindings assigned by let statements
+	assigned map[string]bool
+
+	out    *bytes.Buffer
+	indent int
+}
+
+// generator - keeps the scope being generated
+type generator struct {
+	scope    *scope
+	numTemps int
+
+	// set when generated code returns in all branches, the code
+	// following it is not generated
+	terminated bool
+}
+
+// export - top-level function of the program exported from the package
+type export struct {
+	name       string
+	goName     string
+	parameters []string
+}
+
+// Generate - returns source of the Go package with the program. `Run`
+// of the package runs the program once and returns its result, exported
+// functions call top-level functions of the program after it's run.
+// Programs using features without Go support are rejected with an error
+func Generate(program *ast.Program, pkg string) (string, error) {
+	if !gotoken.IsIdentifier(pkg) {
+		return "", fmt.Errorf("invalid package name: %q", pkg)
+	}
+
+	exports, err := collectExports(program.Statements)
+	if err != nil {
+		return "", err
+	}
+
+	g := &generator{}
+
+	main := newScope(nil, 1)
+	g.scope = main
+
+	// bindings of the program are package variables
+	globals := collectLets(program.Statements)
+	for _, name := range globals {
+		main.locals[name] = "g_" + name
+	}
+
+	// result of the program not ending with expression is nil
+	// as in the evaluator
+	value, terminated, err := g.generateStatements(program.Statements)
+	if err != nil {
+		return "", err
+	}
+	if !terminated {
+		if value == "" {
+			value = "nil"
+		}
+		g.emit("return %s", value)
+	}
+
+	var out bytes.Buffer
+	out.WriteString("// Code generated by beaver build -emit=go. DO NOT EDIT.\n\n")
+	out.WriteString(fmt.Sprintf("package %s\n\n", pkg))
+	out.WriteString("import (\n")
+	out.WriteString("\t\"sync\"\n\n")
+	out.WriteString("\t\"github.com/technoboom/compiler/gogen/rt\"\n")
+	out.WriteString("\t\"github.com/technoboom/compiler/object\"\n")
+	out.WriteString(")\n\n")
+
+	if len(globals) > 0 {
+		out.WriteString("// variables of the program\n")
+		out.WriteString("var (\n")
+		for _, name := range globals {
+			out.WriteString(fmt.Sprintf("\tg_%s object.Object\n", name))
+		}
+		out.WriteString(")\n\n")
+	}
+
+	out.WriteString("var (\n")
+	out.WriteString("\tonce   sync.Once\n")
+	out.WriteString("\tresult object.Object\n")
+	out.WriteString("\terr    error\n")
+	out.WriteString(")\n\n")
+
+	out.WriteString("// Run - runs the program on the first call and returns its result,\n")
+	out.WriteString("// runtime errors of the program are *object.Error\n")
+	out.WriteString("func Run() (object.Object, error) {\n")
+	out.WriteString("\tonce.Do(func() { result, err = rt.Run(program) })\n")
+	out.WriteString("\treturn result, err\n")
+	out.WriteString("}\n")
+
+	for _, e := range exports {
+		out.WriteString("\n")
+		out.WriteString(generateExport(e))
+	}
+
+	out.WriteString("\nfunc program() object.Object {\n")
+	out.WriteString(main.out.String())
+	out.WriteString("}\n")
+
+	source, err := format.Source(out.Bytes())
+	if err != nil {
+		return "", fmt.Errorf("generated code is invalid: %s", err)
+	}
+	return string(source), nil
+}
+
+// generateExport - returns Go function which calls the top-level function
+func generateExport(e export) string {
+	var out bytes.Buffer
+
+	params := make([]string, len(e.parameters))
+	for idx, p := range e.parameters {
+		params[idx] = parameterName(p)
+	}
+
+	signature := ""
+	if len(params) > 0 {
+		signature = strings.Join(params, ", ") + " object.Object"
+	}
+
+	out.WriteString(fmt.Sprintf("// %s - calls `%s` of the program\n", e.goName, e.name))
+	out.WriteString(fmt.Sprintf("func %s(%s) (object.Object, error) {\n", e.goName, signature))
+	out.WriteString("\tif _, err := Run(); err != nil {\n")
+	out.WriteString("\t\treturn nil, err\n")
+	out.WriteString("\t}\n")
+	out.WriteString("\treturn rt.Run(func() object.Object {\n")
+	out.WriteString(fmt.Sprintf("\t\treturn rt.Call(rt.Load(g_%s, %s)%s)\n",
+		e.name, strconv.Quote(e.name), list(params)))
+	out.WriteString("\t})\n")
+	out.WriteString("}\n")
+
+	return out.String()
+}
+
+// generateStatements - generates statements of the block, returns Go
+// expression with the value of the last expression statement (empty if
+// the block doesn't end with expression). Statements following return
+// are not generated, the block is terminated
+func (g *generator) generateStatements(statements []ast.Statement) (string, bool, error) {
+	for idx, s := range statements {
+		last := idx == len(statements)-1
+
+		switch s := s.(type) {
+		case *ast.ExpressionStatement:
+			value, err := g.generateExpression(s.Expression)
+			if err != nil {
+				return "", false, err
+			}
+			if g.takeTerminated() {
+				return "", true, nil
+			}
+			if last {
+				return value, false, nil
+			}
+			g.emit("_ = %s", value)
+		case *ast.LetStatement:
+			if err := g.generateLetStatement(s); err != nil {
+				return "", false, err
+			}
+		case *ast.ReturnStatement:
+			if err := g.generateReturnStatement(s); err != nil {
+				return "", false, err
+			}
+			g.terminated = true
+		default:
+			return "", false, fmt.Errorf("%T is not supported by Go backend", s)
+		}
+
+		if g.takeTerminated() {
+			return "", true, nil
+		}
+	}
+
+	return "", false, nil
+}
+
+// takeTerminated - checks if the code generated last always returns
+// and resets the flag
+func (g *generator) takeTerminated() bool {
+	terminated := g.terminated
+	g.terminated = false
+	return terminated
+}
+
+// generateLetStatement - assigns the value to the variable of the name
+func (g *generator) generateLetStatement(node *ast.LetStatement) error {
+	ident, ok := node.Pattern.(*ast.Identifier)
+	if !ok {
+		return fmt.Errorf("destructuring is not supported by Go backend")
+	}
+
+	variable, ok := g.scope.assign(ident.Value)
+	if !ok {
+		return fmt.Errorf("identifier not found: %s", ident.Value)
+	}
+
+	if fn, ok := node.Value.(*ast.FunctionLiteral); ok {
+		return g.generateFunctionLiteral(fn, variable+" =")
+	}
+
+	value, err := g.generateExpression(node.Value)
+	if err != nil {
+		return err
+	}
+	g.emit("%s = %s", variable, value)
+	return nil
+}
+
+// generateReturnStatement - returns the value from the function
+// (or the program)
+func (g *generator) generateReturnStatement(node *ast.ReturnStatement) error {
+	value, err := g.generateExpression(node.ReturnValue)
+	if err != nil {
+		return err
+	}
+	g.emit("return %s", value)
+	return nil
+}
+
+// generateExpression - returns Go expression evaluating the expression,
+// control flow is generated as statements in the order of the evaluator
+func (g *generator) generateExpression(node ast.Expression) (string, error) {
+	switch node := node.(type) {
+	case *ast.IntegerLiteral:
+		return fmt.Sprintf("&object.Integer{Value: %d}", node.Value), nil
+	case *ast.StringLiteral:
+		return fmt.Sprintf("&object.String{Value: %s}", strconv.Quote(node.Value)), nil
+	case *ast.Boolean:
+		if node.Value {
+			return "object.TRUE", nil
+		}
+		return "object.FALSE", nil
+	case *ast.NullLiteral:
+		return "object.NULL", nil
+	case *ast.Identifier:
+		variable, ok := g.scope.resolve(node.Value)
+		if !ok {
+			if _, ok := object.GetBuiltinByName(node.Value); ok {
+				return fmt.Sprintf("rt.Builtin(%s)", strconv.Quote(node.Value)), nil
+			}
+			// unknown name fails only when it's evaluated, like in the evaluator
+			return fmt.Sprintf("rt.Load(nil, %s)", strconv.Quote(node.Value)), nil
+		}
+		if g.scope.isParam(node.Value) {
+			return variable, nil
+		}
+		return fmt.Sprintf("rt.Load(%s, %s)", variable, strconv.Quote(node.Value)), nil
+	case *ast.PrefixExpression:
+		fn, ok := prefixFunctions[node.Operator]
+		if !ok {
+			return "", fmt.Errorf("unknown operator %s", node.Operator)
+		}
+		right, err := g.generateExpression(node.Right)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("rt.%s(%s)", fn, right), nil
+	case *ast.InfixExpression:
+		return g.generateInfixExpression(node)
+	case *ast.IfExpression:
+		return g.generateConditional(node.Condition, node.Consequence, node.Alternative)
+	case *ast.ConditionalExpression:
+		return g.generateConditional(node.Condition, node.Consequence, node.Alternative)
+	case *ast.ArrayLiteral:
+		elements, err := g.generateExpressions(node.Elements)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("rt.Array(%s)", strings.Join(elements, ", ")), nil
+	case *ast.HashLiteral:
+		expressions := []ast.Expression{}
+		for _, pair := range node.Pairs {
+			if pair.Value == nil {
+				return "", fmt.Errorf("spread elements are not supported by Go backend")
+			}
+			expressions = append(expressions, pair.Key, pair.Value)
+		}
+		values, err := g.generateExpressions(expressions)
+		if err != nil {
+			return "", err
+		}
+		for i := 0; i < len(values); i += 2 {
+			values[i] = fmt.Sprintf("rt.Key(%s)", values[i])
+		}
+		return fmt.Sprintf("rt.Hash(%s)", strings.Join(values, ", ")), nil
+	case *ast.FunctionLiteral:
+		temp := g.newTemp()
+		if err := g.generateFunctionLiteral(node, temp+" :="); err != nil {
+			return "", err
+		}
+		return temp, nil
+	case *ast.CallExpression:
+		if _, ok := node.Function.(*ast.MemberExpression); ok {
+			return "", fmt.Errorf("method calls are not supported by Go backend")
+		}
+		values, err := g.generateExpressions(
+			append([]ast.Expression{node.Function}, node.Arguments...))
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("rt.Call(%s)", strings.Join(values, ", ")), nil
+	default:
+		return "", fmt.Errorf("%T is not supported by Go backend", node)
+	}
+}
+
+// prefixFunctions - functions of the runtime applying prefix operators
+var prefixFunctions = map[string]string{
+	"!": "Not",
+	"-": "Neg",
+}
+
+// infixFunctions - functions of the runtime applying infix operators
+var infixFunctions = map[string]string{
+	"+":  "Add",
+	"-":  "Sub",
+	"*":  "Mul",
+	"/":  "Div",
+	"<":  "Less",
+	">":  "Greater",
+	"==": "Equal",
+	"!=": "NotEqual",
+}
+
+// generateInfixExpression - applies the operator to the operands,
+// the right operand of `??` is evaluated only if the left one is null
+func (g *generator) generateInfixExpression(node *ast.InfixExpression) (string, error) {
+	if node.Operator == "??" {
+		left, err := g.generateExpression(node.Left)
+		if err != nil {
+			return "", err
+		}
+
+		result := g.newTemp()
+		g.emit("var %s object.Object = %s", result, left)
+		g.emit("if %s == object.NULL {", result)
+		g.scope.indent++
+		right, err := g.generateExpression(node.Right)
+		if err != nil {
+			return "", err
+		}
+		if !g.takeTerminated() {
+			g.emit("%s = %s", result, right)
+		}
+		g.scope.indent--
+		g.emit("}")
+		return result, nil
+	}
+
+	fn, ok := infixFunctions[node.Operator]
+	if !ok {
+		return "", fmt.Errorf("unknown operator %s", node.Operator)
+	}
+
+	values, err := g.generateExpressions([]ast.Expression{node.Left, node.Right})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("rt.%s(%s, %s)", fn, values[0], values[1]), nil
+}
+
+// generateConditional - generates if/else and ternary expressions,
+// missing alternative produces null
+func (g *generator) generateConditional(
+	condition ast.Expression,
+	consequence ast.Node,
+	alternative ast.Node,
+) (string, error) {
+	cond, err := g.generateExpression(condition)
+	if err != nil {
+		return "", err
+	}
+
+	result := g.newTemp()
+
+	// branches are generated first, the result isn't declared
+	// if both of them return
+	out := g.scope.out
+	g.scope.out = &bytes.Buffer{}
+
+	g.emit("if rt.Truthy(%s) {", cond)
+	consequenceReturns, err := g.generateBranch(result, consequence)
+	if err != nil {
+		return "", err
+	}
+	g.emit("} else {")
+	alternativeReturns, err := g.generateBranch(result, alternative)
+	if err != nil {
+		return "", err
+	}
+	g.emit("}")
+
+	branches := g.scope.out
+	g.scope.out = out
+
+	if consequenceReturns && alternativeReturns {
+		g.scope.out.Write(branches.Bytes())
+		g.terminated = true
+		return "nil", nil
+	}
+
+	g.emit("var %s object.Object", result)
+	g.scope.out.Write(branches.Bytes())
+	return result, nil
+}
+
+// generateBranch - generates block or expression of the conditional
+// and assigns its value to the result, returns true if the branch
+// always returns
+func (g *generator) generateBranch(result string, node ast.Node) (bool, error) {
+	g.scope.indent++
+	defer func() { g.scope.indent-- }()
+
+	var value string
+	var terminated bool
+	var err error
+
+	switch node := node.(type) {
+	case *ast.BlockStatement:
+		if node != nil {
+			value, terminated, err = g.generateStatements(node.Statements)
+		}
+	case ast.Expression:
+		value, err = g.generateExpression(node)
+		terminated = g.takeTerminated()
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if !terminated {
+		if value == "" {
+			value = "object.NULL"
+		}
+		g.emit("%s = %s", result, value)
+	}
+	return terminated, nil
+}
+
+// generateFunctionLiteral - generates Go closure of the function and
+// assigns it with the assignment, e.g. `t1 :=`
+func (g *generator) generateFunctionLiteral(node *ast.FunctionLiteral, assignment string) error {
+	if len(node.Defaults) > 0 || node.Rest != nil {
+		return fmt.Errorf("default and rest parameters are not supported by Go backend")
+	}
+
+	outer, outerTerminated := g.scope, g.terminated
+	fn := newScope(outer, outer.indent+1)
+	g.scope, g.terminated = fn, false
+
+	params := make([]string, len(node.Parameters))
+	for idx, p := range node.Parameters {
+		params[idx] = strconv.Quote(p.Value)
+		fn.locals[p.Value] = "l_" + p.Value
+		fn.params[p.Value] = true
+	}
+	lets := []string{}
+	for _, local := range collectLets(node.Body.Statements) {
+		if _, ok := fn.locals[local]; !ok {
+			fn.locals[local] = "l_" + local
+			lets = append(lets, local)
+		}
+	}
+
+	value, terminated, err := g.generateStatements(node.Body.Statements)
+	if err != nil {
+		return err
+	}
+	if !terminated {
+		if value == "" {
+			value = "object.NULL"
+		}
+		g.emit("return %s", value)
+	}
+	g.scope, g.terminated = outer, outerTerminated
+
+	g.emit("%s rt.NewFunction([]string{%s}, func(args []object.Object) object.Object {",
+		assignment, strings.Join(params, ", "))
+	g.scope.indent++
+	for idx, p := range node.Parameters {
+		if fn.read[p.Value] || fn.assigned[p.Value] {
+			g.emit("l_%s := args[%d]", p.Value, idx)
+		}
+		if !fn.read[p.Value] && fn.assigned[p.Value] {
+			g.emit("_ = l_%s", p.Value)
+		}
+	}
+	for _, local := range lets {
+		g.emit("var l_%s object.Object", local)
+		if !fn.read[local] {
+			g.emit("_ = l_%s", local)
+		}
+	}
+	g.scope.indent--
+	g.scope.out.WriteString(fn.out.String())
+	g.emit("})")
+
+	return nil
+}
+
+// generateExpressions - generates expressions in order. Values of the
+// expressions preceding one with control flow are stored in temporary
+// variables (unless they are constants), so they are evaluated first
+func (g *generator) generateExpressions(expressions []ast.Expression) ([]string, error) {
+	values := []string{}
+
+	for _, e := range expressions {
+		switch e.(type) {
+		case *ast.KeywordArgument:
+			return nil, fmt.Errorf("keyword arguments are not supported by Go backend")
+		case *ast.SpreadElement:
+			return nil, fmt.Errorf("spread elements are not supported by Go backend")
+		}
+
+		if hasControlFlow(e) {
+			for idx, value := range values {
+				if !isTemp(value) && !strings.HasPrefix(value, "&object.") &&
+					!strings.HasPrefix(value, "object.") {
+					values[idx] = g.newTemp()
+					g.emit("%s := %s", values[idx], value)
+				}
+			}
+		}
+
+		value, err := g.generateExpression(e)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+
+	return values, nil
+}
+
+// emit - writes the line of Go code into the current function
+func (g *generator) emit(format string, a ...interface{}) {
+	g.scope.out.WriteString(strings.Repeat("\t", g.scope.indent))
+	g.scope.out.WriteString(fmt.Sprintf(format, a...))
+	g.scope.out.WriteString("\n")
+}
+
+// newTemp - returns name of the new temporary variable, names
+// of the bindings can't contain digits, so they don't clash
+func (g *generator) newTemp() string {
+	g.numTemps++
+	return fmt.Sprintf("t%d", g.numTemps)
+}
+
+// isTemp - checks if the value is a temporary variable
+func isTemp(value string) bool {
+	if len(value) < 2 || value[0] != 't' {
+		return false
+	}
+	_, err := strconv.Atoi(value[1:])
+	return err == nil
+}
+
+// newScope - creates scope of the function nested into the outer one
+func newScope(outer *scope, indent int) *scope {
+	return &scope{
+		outer:    outer,
+		locals:   map[string]string{},
+		params:   map[string]bool{},
+		read:     map[string]bool{},
+		assigned: map[string]bool{},
+		out:      &bytes.Buffer{},
+		indent:   indent,
+	}
+}
+
+// resolve - returns Go variable of the binding and marks it as read
+// in the scope which declares it
+func (s *scope) resolve(name string) (string, bool) {
+	if variable, ok := s.locals[name]; ok {
+		s.read[name] = true
+		return variable, true
+	}
+	if s.outer == nil {
+		return "", false
+	}
+	return s.outer.resolve(name)
+}
+
+// assign - returns Go variable of the binding and marks it as assigned
+// in the scope which declares it
+func (s *scope) assign(name string) (string, bool) {
+	if variable, ok := s.locals[name]; ok {
+		s.assigned[name] = true
+		return variable, true
+	}
+	if s.outer == nil {
+		return "", false
+	}
+	return s.outer.assign(name)
+}
+
+// isParam - checks if the binding visible in the scope is a parameter
+// which isn't rebound by let
+func (s *scope) isParam(name string) bool {
+	if _, ok := s.locals[name]; ok {
+		return s.params[name]
+	}
+	return s.outer != nil && s.outer.isParam(name)
+}
+
+// hasControlFlow - checks if generated code of the expression has
+// statements, bodies of nested functions are not executed in place
+func hasControlFlow(node ast.Node) bool {
+	found := false
+
+	var walk func(node ast.Node)
+	walk = func(node ast.Node) {
+		switch node := node.(type) {
+		case *ast.IfExpression, *ast.ConditionalExpression:
+			found = true
+		case *ast.InfixExpression:
+			if node.Operator == "??" {
+				found = true
+			}
+			walk(node.Left)
+			walk(node.Right)
+		case *ast.PrefixExpression:
+			walk(node.Right)
+		case *ast.CallExpression:
+			walk(node.Function)
+			for _, arg := range node.Arguments {
+				walk(arg)
+			}
+		case *ast.ArrayLiteral:
+			for _, e := range node.Elements {
+				walk(e)
+			}
+		case *ast.HashLiteral:
+			for _, pair := range node.Pairs {
+				walk(pair.Key)
+				walk(pair.Value)
+			}
+		}
+	}
+
+	walk(node)
+	return found
+}
+
+// collectExports - returns top-level functions of the program which are
+// exported from the package, the last definition of the name is used
+func collectExports(statements []ast.Statement) ([]export, error) {
+	byName := map[string]export{}
+	for _, s := range statements {
+		let, ok := s.(*ast.LetStatement)
+		if !ok {
+			continue
+		}
+		ident, ok := let.Pattern.(*ast.Identifier)
+		if !ok {
+			continue
+		}
+		fn, ok := let.Value.(*ast.FunctionLiteral)
+		if !ok {
+			delete(byName, ident.Value)
+			continue
+		}
+
+		e := export{name: ident.Value, goName: exportedName(ident.Value)}
+		for _, p := range fn.Parameters {
+			e.parameters = append(e.parameters, p.Value)
+		}
+		byName[ident.Value] = e
+	}
+
+	exports := []export{}
+	for _, e := range byName {
+		if e.goName != "" {
+			exports = append(exports, e)
+		}
+	}
+	sort.Slice(exports, func(i, j int) bool { return exports[i].name < exports[j].name })
+
+	used := map[string]string{"Run": "the package"}
+	for _, e := range exports {
+		if other, ok := used[e.goName]; ok {
+			return nil, fmt.Errorf("cannot export %s as %s: name is already used by %s",
+				e.name, e.goName, other)
+		}
+		used[e.goName] = e.name
+	}
+
+	return exports, nil
+}
+
+// exportedName - returns Go name of the top-level function, e.g.
+// `make_counter` is exported as MakeCounter
+func exportedName(name string) string {
+	var out bytes.Buffer
+	for _, part := range strings.Split(name, "_") {
+		if part == "" {
+			continue
+		}
+		runes := []rune(part)
+		out.WriteRune(unicode.ToUpper(runes[0]))
+		out.WriteString(string(runes[1:]))
+	}
+	return out.String()
+}
+
+// parameterName - returns name of the parameter of the exported function,
+// names which clash with Go keywords or names used by the function body
+// get underscore suffix
+func parameterName(name string) string {
+	switch {
+	case gotoken.IsKeyword(name),
+		name == "rt", name == "object", name == "nil", name == "err",
+		name == "Run", strings.HasPrefix(name, "g_"):
+		return name + "_"
+	}
+	return name
+}
+
+// collectLets - returns names bound by let statements of the function
+// body (or the program) including nested blocks, but not nested functions
+func collectLets(statements []ast.Statement) []string {
+	names := []string{}
+	seen := map[string]bool{}
+
+	var walk func(node ast.Node)
+	walk = func(node ast.Node) {
+		switch node := node.(type) {
+		case *ast.LetStatement:
+			if ident, ok := node.Pattern.(*ast.Identifier); ok && !seen[ident.Value] {
+				seen[ident.Value] = true
+				names = append(names, ident.Value)
+			}
+			walk(node.Value)
+		case *ast.ExpressionStatement:
+			walk(node.Expression)
+		case *ast.ReturnStatement:
+			walk(node.ReturnValue)
+		case *ast.BlockStatement:
+			if node == nil {
+				return
+			}
+			for _, s := range node.Statements {
+				walk(s)
+			}
+		case *ast.IfExpression:
+			walk(node.Condition)
+			walk(node.Consequence)
+			walk(node.Alternative)
+		case *ast.ConditionalExpression:
+			walk(node.Condition)
+			walk(node.Consequence)
+			walk(node.Alternative)
+		case *ast.PrefixExpression:
+			walk(node.Right)
+		case *ast.InfixExpression:
+			walk(node.Left)
+			walk(node.Right)
+		case *ast.CallExpression:
+			walk(node.Function)
+			for _, arg := range node.Arguments {
+				walk(arg)
+			}
+		case *ast.ArrayLiteral:
+			for _, e := range node.Elements {
+				walk(e)
+			}
+		case *ast.HashLiteral:
+			for _, pair := range node.Pairs {
+				walk(pair.Key)
+				walk(pair.Value)
+			}
+		}
+	}
+
+	for _, s := range statements {
+		walk(s)
+	}
+	return names
+}
+
+// list - returns the values as the tail of Go argument list
+func list(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return ", " + strings.Join(values, ", ")
+}
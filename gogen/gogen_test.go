@@ -0,0 +1,339 @@
+package gogen
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/technoboom/compiler/internal/testutil"
+)
+
+func TestGenerateErrors(t *testing.T) {
+	tests := []struct {
+		input         string
+		pkg           string
+		expectedError string
+	}{
+		{"1", "my-script", `invalid package name: "my-script"`},
+		{"let [a] = [1];", "script", "destructuring is not supported by Go backend"},
+		{"let f = (a) => a; f(a: 1)", "script", "keyword arguments are not supported by Go backend"},
+		{"[...[1]]", "script", "spread elements are not supported by Go backend"},
+		{"{...{}}", "script", "spread elements are not supported by Go backend"},
+		{`"a".upper()`, "script", "method calls are not supported by Go backend"},
+		{"(a = 1) => a", "script", "default and rest parameters are not supported by Go backend"},
+		{"throw 1", "script", "*ast.ThrowStatement is not supported by Go backend"},
+		{"struct P { x }", "script", "*ast.StructStatement is not supported by Go backend"},
+		{
+			"let run = () => 1;",
+			"script",
+			"cannot export run as Run: name is already used by the package",
+		},
+		{
+			"let make_list = () => 1; let makeList = () => 2;",
+			"script",
+			"cannot export make_list as MakeList: name is already used by makeList",
+		},
+	}
+
+	for _, tt := range tests {
+		_, err := Generate(testutil.Parse(tt.input), tt.pkg)
+		if err == nil {
+			t.Errorf("expected error for %q, got none", tt.input)
+			continue
+		}
+		if err.Error() != tt.expectedError {
+			t.Errorf("wrong error for %q. want=%q, got=%q",
+				tt.input, tt.expectedError, err.Error())
+		}
+	}
+}
+
+func TestExports(t *testing.T) {
+	input := `
+	let fib = (n) => n;
+	let make_counter = (start, type) => start;
+	let _ = () => 1;
+	let value = 5;
+	let replaced = () => 1;
+	let replaced = 2;
+	let twice = (a) => a;
+	let twice = (a, b) => b;
+	`
+
+	exports, err := collectExports(testutil.Parse(input).Statements)
+	if err != nil {
+		t.Fatalf("collectExports error: %s", err)
+	}
+
+	expected := []export{
+		{name: "fib", goName: "Fib", parameters: []string{"n"}},
+		{name: "make_counter", goName: "MakeCounter", parameters: []string{"start", "type"}},
+		{name: "twice", goName: "Twice", parameters: []string{"a", "b"}},
+	}
+
+	if len(exports) != len(expected) {
+		t.Fatalf("wrong number of exports. want=%d, got=%d (%+v)",
+			len(expected), len(exports), exports)
+	}
+	for idx, e := range expected {
+		if fmt.Sprintf("%+v", exports[idx]) != fmt.Sprintf("%+v", e) {
+			t.Errorf("wrong export %d. want=%+v, got=%+v", idx, e, exports[idx])
+		}
+	}
+}
+
+func TestNames(t *testing.T) {
+	tests := []struct {
+		name      string
+		exported  string
+		parameter string
+	}{
+		{"fib", "Fib", "fib"},
+		{"make_counter", "MakeCounter", "make_counter"},
+		{"toJSON", "ToJSON", "toJSON"},
+		{"_private_", "Private", "_private_"},
+		{"type", "Type", "type_"},
+		{"object", "Object", "object_"},
+		{"g_fib", "GFib", "g_fib_"},
+	}
+
+	for _, tt := range tests {
+		if name := exportedName(tt.name); name != tt.exported {
+			t.Errorf("wrong exported name of %s. want=%s, got=%s", tt.name, tt.exported, name)
+		}
+		if name := parameterName(tt.name); name != tt.parameter {
+			t.Errorf("wrong parameter name of %s. want=%s, got=%s", tt.name, tt.parameter, name)
+		}
+	}
+}
+
+// TestGoEquivalence - builds generated packages into one Go program and
+// checks that they produce the same results and errors as the evaluator
+func TestGoEquivalence(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skipf("Go toolchain is not available: %s", err)
+	}
+
+	inputs := []string{
+		"5 + 5 * 2 - 10 / 5",
+		"-(3 * 3) + 50 / 2",
+		"1 < 2 == true",
+		"!5; !!null",
+		`"beaver"`,
+		"\"multi\nline\"",
+		`"a" == "a"`,
+		"1 == true",
+		"null == null",
+		"[1, \"two\", true, null, [3], {}]",
+		`{"a": 1, 2: "b", true: [3], "a": 4}`,
+		"if (1 > 2) { 10 }",
+		"if (null) { 10 } else { let x = 5; x * 2 }",
+		"1 < 2 ? \"yes\" : \"no\"",
+		"null ?? false ?? 3",
+		"let a = 5; let b = a; let a = 7; a + b",
+		"let x = 1;",
+		"",
+		"let identity = (x) => x; identity(5)",
+		"let add = function(x, y) { x + y; }; add(5 + 5, add(5, 5))",
+		"function(x) { x; }(5)",
+		"9; return 2 * 5; 9;",
+		"if (10 > 1) { if (10 > 1) { return 10; } return 1; }",
+		"let f = (x) => { if (x) { return [x] }; [] }; [f(1), f(false)]",
+		"let f = (x) => { if (x) { return 1 } else { return 2 } }; [f(true), f(null)]",
+		"let f = (x) => { let y = x ? 1 : 2; [y, x ?? 3] }; [f(true), f(null)]",
+		"let f = (n) => { let n = n * 2; n }; f(4)",
+		"let f = (n) => { let n = 1; 2 }; f(4)",
+		"let f = (x) => [x, if (x) { let x = 5; x } else { 0 }, x]; f(1)",
+		"let f = () => { let x = 1; let r = [x, (() => { let x = 2; x })(), x]; r }; f()",
+		"let fib = (n) => n < 2 ? n : fib(n - 1) + fib(n - 2); fib(20)",
+		"let newAdder = (a) => (b) => a + b; let addTwo = newAdder(2); addTwo(3)",
+		"let make = (x) => () => x; let a = make(1); let b = make(2); [a(), b()]",
+		"let f = (a) => (b) => (c) => a * 100 + b * 10 + c; f(1)(2)(3)",
+		"let f = () => { let x = 1; let g = () => x; let x = 2; g() }; f()",
+		"let f = () => { let g = () => x; let x = 5; g() }; f()",
+		`let f = () => {
+			let even = (n) => n == 0 ? true : odd(n - 1);
+			let odd = (n) => n == 0 ? false : even(n - 1);
+			[even(10), odd(7)]
+		};
+		f()`,
+		"let g = 10; let f = () => g; let g = 20; f()",
+		"5 + true; 5;",
+		"-true",
+		`"a" - "b"`,
+		"let f = () => { 1 + null }; [1, f()]",
+		"let f = (a) => a; f(1, 2)",
+		"let f = (a, b) => a; f(1)",
+		"1(2)",
+		"{[1]: 2}",
+		"10 / (5 - 5)",
+		"let f = () => { x }; let r = f(); let x = 1;",
+		// unknown names fail only when they are evaluated
+		"a",
+		"let f = () => b; 1",
+		"if (false) { unknown }",
+		"let f = () => [1, b]; puts(1); f()",
+		// output of puts is followed by the result or the error
+		`puts(1, "two", [3], {"a": null}); puts()`,
+		"let f = (x) => { puts(x); x * 2 }; puts(f(1) + f(2))",
+		"puts; let p = puts; [p == puts, p(1)]",
+		"let puts = (x) => x * 2; puts(21)",
+		`puts("before"); -true`,
+		"tag(1)",
+	}
+
+	dir, importPath := tempPackageDir(t)
+	defer os.RemoveAll(dir)
+
+	var main strings.Builder
+	main.WriteString("package main\n\nimport (\n\t\"bytes\"\n\t\"fmt\"\n\t\"strings\"\n\n")
+	for idx, input := range inputs {
+		pkg := fmt.Sprintf("program%d", idx)
+		source, err := Generate(testutil.Parse(input), pkg)
+		if err != nil {
+			t.Fatalf("generation error for %q: %s", input, err)
+		}
+		writeFile(t, filepath.Join(dir, pkg, pkg+".go"), source)
+		main.WriteString(fmt.Sprintf("\t%q\n", importPath+"/"+pkg))
+	}
+	main.WriteString("\t\"github.com/technoboom/compiler/object\"\n)\n\n")
+	// output of puts is captured, so each program prints one line
+	main.WriteString("func inspect(run func() (object.Object, error)) string {\n")
+	main.WriteString("\tvar out bytes.Buffer\n\tobject.Output = &out\n")
+	main.WriteString("\tif result, err := run(); err != nil {\n\t\tout.WriteString(err.Error())\n")
+	main.WriteString("\t} else if result != nil {\n\t\tout.WriteString(result.Inspect())\n\t}\n")
+	main.WriteString("\treturn strings.TrimSuffix(out.String(), \"\\n\")\n}\n\n")
+	main.WriteString("func main() {\n")
+	for idx := range inputs {
+		main.WriteString(fmt.Sprintf("\tfmt.Printf(\"%%q\\n\", inspect(program%d.Run))\n", idx))
+	}
+	main.WriteString("}\n")
+	writeFile(t, filepath.Join(dir, "main", "main.go"), main.String())
+
+	lines := runMain(t, dir)
+	if len(lines) != len(inputs) {
+		t.Fatalf("wrong number of results. want=%d, got=%d", len(inputs), len(lines))
+	}
+	for idx, input := range inputs {
+		if expected := testutil.Evaluate(input); lines[idx] != expected {
+			t.Errorf("results differ for %q. evaluator=%q, go=%q", input, expected, lines[idx])
+		}
+	}
+}
+
+// TestExportedFunctions - calls exported functions of the generated
+// package from Go code
+func TestExportedFunctions(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skipf("Go toolchain is not available: %s", err)
+	}
+
+	input := `
+	let fib = (n) => n < 2 ? n : fib(n - 1) + fib(n - 2);
+	let scale = (value, type) => value * (type ?? 10);
+	let make_adder = (a) => (b) => a + b;
+	let calls = [];
+	[fib(10), scale(2, null)]
+	`
+
+	source, err := Generate(testutil.Parse(input), "scripts")
+	if err != nil {
+		t.Fatalf("generation error: %s", err)
+	}
+
+	dir, importPath := tempPackageDir(t)
+	defer os.RemoveAll(dir)
+
+	writeFile(t, filepath.Join(dir, "scripts", "scripts.go"), source)
+	writeFile(t, filepath.Join(dir, "main", "main.go"), `package main
+
+import (
+	"fmt"
+
+	"`+importPath+`/scripts"
+	"github.com/technoboom/compiler/object"
+)
+
+func show(result object.Object, err error) {
+	if err != nil {
+		fmt.Printf("%q\n", "error: "+err.Error())
+		return
+	}
+	fmt.Printf("%q\n", result.Inspect())
+}
+
+func main() {
+	show(scripts.Fib(&object.Integer{Value: 20}))
+	show(scripts.Scale(&object.Integer{Value: 3}, &object.Integer{Value: 2}))
+	show(scripts.Scale(&object.Integer{Value: 3}, object.NULL))
+	show(scripts.Scale(object.TRUE, object.NULL))
+	show(scripts.Run())
+	adder, _ := scripts.MakeAdder(&object.Integer{Value: 40})
+	show(adder, nil)
+}
+`)
+
+	expected := []string{
+		"6765",
+		"6",
+		"30",
+		"error: TypeError: type mismatch: BOOLEAN * INTEGER",
+		"[55, 20]",
+		"function(b) { <native> }",
+	}
+
+	lines := runMain(t, dir)
+	if strings.Join(lines, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("wrong output. want=%q, got=%q", expected, lines)
+	}
+}
+
+// tempPackageDir - creates directory for generated packages inside this
+// package, so they import the runtime by its path. The name starts with
+// underscore, so the directory is ignored by `./...` patterns
+func tempPackageDir(t *testing.T) (string, string) {
+	out, err := exec.Command("go", "list", "-f", "{{.ImportPath}}", ".").Output()
+	if err != nil {
+		t.Fatalf("cannot get import path: %s", err)
+	}
+
+	dir, err := ioutil.TempDir(".", "_gogen")
+	if err != nil {
+		t.Fatalf("cannot create directory: %s", err)
+	}
+	return dir, strings.TrimSpace(string(out)) + "/" + filepath.Base(dir)
+}
+
+func writeFile(t *testing.T, file string, content string) {
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		t.Fatalf("cannot create directory: %s", err)
+	}
+	if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatalf("cannot write file: %s", err)
+	}
+}
+
+// runMain - runs the main package of the directory and returns unquoted
+// lines of its output
+func runMain(t *testing.T, dir string) []string {
+	cmd := exec.Command("go", "run", "./"+filepath.Join(dir, "main"))
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("go run failed: %s\n%s", err, out)
+	}
+
+	lines := []string{}
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		unquoted, err := strconv.Unquote(line)
+		if err != nil {
+			t.Fatalf("unexpected output line %q", line)
+		}
+		lines = append(lines, unquoted)
+	}
+	return lines
+}
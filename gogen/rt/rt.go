@@ -0,0 +1,240 @@
+// Package rt - runtime of the Go code generated from Beaver programs,
+// operations follow the rules and errors of the evaluator
+package rt
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/technoboom/compiler/object"
+)
+
+// Function - function of the generated program
+type Function struct {
+	Parameters []string
+	// compiled body, receives exactly one argument per parameter
+	Fn func(args []object.Object) object.Object
+}
+
+// NewFunction - creates function value with the parameters and the body
+func NewFunction(parameters []string, fn func(args []object.Object) object.Object) *Function {
+	return &Function{Parameters: parameters, Fn: fn}
+}
+
+// Type - returns type of the object
+func (f *Function) Type() object.ObjectType {
+	return object.FUNCTION_OBJ
+}
+
+// Inspect - shows value of the object
+func (f *Function) Inspect() string {
+	return "function(" + strings.Join(f.Parameters, ", ") + ") { <native> }"
+}
+
+// thrown - runtime error raised by the operations, it unwinds
+// the generated code up to the closest Run
+type thrown struct {
+	err *object.Error
+}
+
+// fail - raises runtime error of the kind
+func fail(kind string, format string, a ...interface{}) {
+	panic(thrown{&object.Error{Kind: kind, Message: fmt.Sprintf(format, a...)}})
+}
+
+// Run - runs the generated code and returns its result, runtime errors
+// are returned as *object.Error
+func Run(fn func() object.Object) (result object.Object, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			t, ok := r.(thrown)
+			if !ok {
+				panic(r)
+			}
+			result, err = nil, t.err
+		}
+	}()
+
+	return fn(), nil
+}
+
+// Load - returns value of the variable, variables which are not bound
+// yet are nil
+func Load(value object.Object, name string) object.Object {
+	if value == nil {
+		fail(object.NAME_ERROR, "identifier not found: %s", name)
+	}
+	return value
+}
+
+// Builtin - returns builtin function of the interpreter by its name
+func Builtin(name string) object.Object {
+	builtin, ok := object.GetBuiltinByName(name)
+	if !ok {
+		fail(object.NAME_ERROR, "identifier not found: %s", name)
+	}
+	return builtin
+}
+
+// Call - calls the function with the arguments, errors returned by
+// builtins are raised
+func Call(fn object.Object, args ...object.Object) object.Object {
+	if builtin, ok := fn.(*object.Builtin); ok {
+		result := builtin.Fn(args...)
+		if err, ok := result.(*object.Error); ok {
+			panic(thrown{err})
+		}
+		return result
+	}
+
+	function, ok := fn.(*Function)
+	if !ok {
+		fail(object.TYPE_ERROR, "not a function: %s", fn.Type())
+	}
+
+	if len(args) > len(function.Parameters) {
+		fail(object.ARGUMENT_ERROR, "wrong number of arguments: want=%d, got=%d",
+			len(function.Parameters), len(args))
+	}
+	if len(args) < len(function.Parameters) {
+		fail(object.ARGUMENT_ERROR, "missing argument: %s", function.Parameters[len(args)])
+	}
+
+	return function.Fn(args)
+}
+
+// Truthy - checks if the value is considered true by conditions
+func Truthy(value object.Object) bool {
+	return value != object.NULL && value != object.FALSE
+}
+
+// Bool - returns boolean object of the value
+func Bool(value bool) object.Object {
+	if value {
+		return object.TRUE
+	}
+	return object.FALSE
+}
+
+// Not - applies `!` operator
+func Not(right object.Object) object.Object {
+	return Bool(!Truthy(right))
+}
+
+// Neg - applies prefix `-` operator
+func Neg(right object.Object) object.Object {
+	integer, ok := right.(*object.Integer)
+	if !ok {
+		fail(object.TYPE_ERROR, "unknown operator: -%s", right.Type())
+	}
+	return &object.Integer{Value: -integer.Value}
+}
+
+// Add - applies `+` operator
+func Add(left, right object.Object) object.Object {
+	l, r := integers("+", left, right)
+	return &object.Integer{Value: l + r}
+}
+
+// Sub - applies `-` operator
+func Sub(left, right object.Object) object.Object {
+	l, r := integers("-", left, right)
+	return &object.Integer{Value: l - r}
+}
+
+// Mul - applies `*` operator
+func Mul(left, right object.Object) object.Object {
+	l, r := integers("*", left, right)
+	return &object.Integer{Value: l * r}
+}
+
+// Div - applies `/` operator
+func Div(left, right object.Object) object.Object {
+	l, r := integers("/", left, right)
+	if r == 0 {
+		fail(object.ERROR, "division by zero")
+	}
+	return &object.Integer{Value: l / r}
+}
+
+// Less - applies `<` operator
+func Less(left, right object.Object) object.Object {
+	l, r := integers("<", left, right)
+	return Bool(l < r)
+}
+
+// Greater - applies `>` operator
+func Greater(left, right object.Object) object.Object {
+	l, r := integers(">", left, right)
+	return Bool(l > r)
+}
+
+// Equal - applies `==` operator
+func Equal(left, right object.Object) object.Object {
+	return Bool(equal(left, right))
+}
+
+// NotEqual - applies `!=` operator
+func NotEqual(left, right object.Object) object.Object {
+	return Bool(!equal(left, right))
+}
+
+// integers - returns values of the integer operands of the operator
+func integers(operator string, left, right object.Object) (int64, int64) {
+	l, leftOk := left.(*object.Integer)
+	r, rightOk := right.(*object.Integer)
+
+	if !leftOk || !rightOk {
+		if left.Type() != right.Type() {
+			fail(object.TYPE_ERROR, "type mismatch: %s %s %s",
+				left.Type(), operator, right.Type())
+		}
+		fail(object.TYPE_ERROR, "unknown operator: %s %s %s",
+			left.Type(), operator, right.Type())
+	}
+	return l.Value, r.Value
+}
+
+// equal - compares integers, strings, booleans and null by value,
+// other objects by identity
+func equal(left, right object.Object) bool {
+	if left.Type() != right.Type() {
+		return false
+	}
+
+	switch left := left.(type) {
+	case *object.Integer:
+		return left.Value == right.(*object.Integer).Value
+	case *object.String:
+		return left.Value == right.(*object.String).Value
+	case *object.Boolean:
+		return left.Value == right.(*object.Boolean).Value
+	case *object.Null:
+		return true
+	default:
+		return left == right
+	}
+}
+
+// Array - creates array of the elements
+func Array(elements ...object.Object) object.Object {
+	return &object.Array{Elements: elements}
+}
+
+// Key - checks that the value can be used as a hash key
+func Key(key object.Object) object.Object {
+	if _, ok := key.(object.Hashable); !ok {
+		fail(object.TYPE_ERROR, "unusable as hash key: %s", key.Type())
+	}
+	return key
+}
+
+// Hash - creates hash of the keys followed by their values,
+// keys must be checked by Key
+func Hash(pairs ...object.Object) object.Object {
+	hash := object.NewHash()
+	for i := 0; i < len(pairs); i += 2 {
+		hash.Set(pairs[i].(object.Hashable), pairs[i+1])
+	}
+	return hash
+}
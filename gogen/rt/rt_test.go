@@ -0,0 +1,107 @@
+package rt
+
+import (
+	"testing"
+
+	"github.com/technoboom/compiler/object"
+)
+
+func integer(value int64) object.Object {
+	return &object.Integer{Value: value}
+}
+
+func str(value string) object.Object {
+	return &object.String{Value: value}
+}
+
+func TestOperators(t *testing.T) {
+	tests := []struct {
+		name     string
+		fn       func() object.Object
+		expected string
+	}{
+		{"add", func() object.Object { return Add(integer(2), integer(3)) }, "5"},
+		{"sub", func() object.Object { return Sub(integer(2), integer(3)) }, "-1"},
+		{"mul", func() object.Object { return Mul(integer(2), integer(3)) }, "6"},
+		{"div", func() object.Object { return Div(integer(7), integer(2)) }, "3"},
+		{"less", func() object.Object { return Less(integer(2), integer(3)) }, "true"},
+		{"greater", func() object.Object { return Greater(integer(2), integer(3)) }, "false"},
+		{"equal integers", func() object.Object { return Equal(integer(2), integer(2)) }, "true"},
+		{"equal strings", func() object.Object { return Equal(str("a"), str("a")) }, "true"},
+		{"equal types", func() object.Object { return Equal(integer(1), object.TRUE) }, "false"},
+		{"not equal", func() object.Object { return NotEqual(object.NULL, object.NULL) }, "false"},
+		{"equal arrays", func() object.Object { return Equal(Array(), Array()) }, "false"},
+		{"not", func() object.Object { return Not(integer(0)) }, "false"},
+		{"not null", func() object.Object { return Not(object.NULL) }, "true"},
+		{"neg", func() object.Object { return Neg(integer(5)) }, "-5"},
+		{"array", func() object.Object { return Array(integer(1), str("a")) }, "[1, a]"},
+		{
+			"hash",
+			func() object.Object {
+				return Hash(Key(str("a")), integer(1), Key(integer(2)), str("b"), Key(str("a")), integer(3))
+			},
+			"{a: 3, 2: b}",
+		},
+		{"div by zero", func() object.Object { return Div(integer(1), integer(0)) }, "Error: division by zero"},
+		{"mismatch", func() object.Object { return Add(integer(1), object.TRUE) }, "TypeError: type mismatch: INTEGER + BOOLEAN"},
+		{"unknown infix", func() object.Object { return Sub(str("a"), str("b")) }, "TypeError: unknown operator: STRING - STRING"},
+		{"unknown prefix", func() object.Object { return Neg(object.TRUE) }, "TypeError: unknown operator: -BOOLEAN"},
+		{"hash key", func() object.Object { return Key(Array()) }, "TypeError: unusable as hash key: ARRAY"},
+		{"unbound", func() object.Object { return Load(nil, "x") }, "NameError: identifier not found: x"},
+	}
+
+	for _, tt := range tests {
+		if actual := inspect(Run(tt.fn)); actual != tt.expected {
+			t.Errorf("%s: wrong result. want=%q, got=%q", tt.name, tt.expected, actual)
+		}
+	}
+}
+
+func TestCall(t *testing.T) {
+	add := NewFunction([]string{"a", "b"}, func(args []object.Object) object.Object {
+		return Add(args[0], args[1])
+	})
+
+	tests := []struct {
+		fn       object.Object
+		args     []object.Object
+		expected string
+	}{
+		{add, []object.Object{integer(1), integer(2)}, "3"},
+		{add, []object.Object{integer(1)}, "ArgumentError: missing argument: b"},
+		{add, []object.Object{integer(1), integer(2), integer(3)},
+			"ArgumentError: wrong number of arguments: want=2, got=3"},
+		{integer(1), nil, "TypeError: not a function: INTEGER"},
+		// errors of builtins are raised
+		{Builtin("tag"), []object.Object{integer(1)}, "TypeError: argument to tag must be enum variant, got INTEGER"},
+		{Builtin("puts"), nil, "null"},
+	}
+
+	for _, tt := range tests {
+		actual := inspect(Run(func() object.Object { return Call(tt.fn, tt.args...) }))
+		if actual != tt.expected {
+			t.Errorf("wrong result. want=%q, got=%q", tt.expected, actual)
+		}
+	}
+
+	if add.Inspect() != "function(a, b) { <native> }" {
+		t.Errorf("wrong inspect of the function. got=%q", add.Inspect())
+	}
+}
+
+func TestRunPropagatesPanics(t *testing.T) {
+	defer func() {
+		if r := recover(); r != "bug" {
+			t.Errorf("expected panic to propagate, got=%v", r)
+		}
+	}()
+
+	Run(func() object.Object { panic("bug") })
+}
+
+func inspect(result object.Object, err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	return result.Inspect()
+}
@@ -0,0 +1,41 @@
+// Package testutil - helpers shared by tests of the compiler and its
+// backends, which compare their results with the evaluator
+package testutil
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/technoboom/compiler/ast"
+	"github.com/technoboom/compiler/evaluator"
+	"github.com/technoboom/compiler/lexer"
+	"github.com/technoboom/compiler/object"
+	"github.com/technoboom/compiler/parser"
+)
+
+// Parse - returns the program parsed from the input
+func Parse(input string) *ast.Program {
+	l := lexer.New(input)
+	p := parser.New(l)
+	return p.ParseProgram()
+}
+
+// Evaluate - returns what the evaluator prints for the program in the form
+// the backends produce: output of puts followed by the result, errors
+// without positions, which they don't track. The trailing new line
+// is trimmed
+func Evaluate(input string) string {
+	var out bytes.Buffer
+	output := object.Output
+	object.Output = &out
+	defer func() { object.Output = output }()
+
+	result := evaluator.Eval(Parse(input), object.NewEnvironment())
+	if result != nil {
+		if err, ok := result.(*object.Error); ok {
+			err.Line = 0
+		}
+		out.WriteString(result.Inspect())
+	}
+	return strings.TrimSuffix(out.String(), "\n")
+}
@@ -0,0 +1,131 @@
+package ir
+
+// postorder - returns blocks reachable from the entry in postorder
+func (f *Function) postorder() []*Block {
+	order := []*Block{}
+	visited := map[*Block]bool{}
+
+	var visit func(b *Block)
+	visit = func(b *Block) {
+		visited[b] = true
+		for _, succ := range b.Succs {
+			if !visited[succ] {
+				visit(succ)
+			}
+		}
+		order = append(order, b)
+	}
+	visit(f.Entry())
+
+	return order
+}
+
+// dominators - returns immediate dominators of the blocks by their IDs,
+// the entry block is its own dominator. Uses the iterative algorithm
+// of Cooper, Harvey and Kennedy
+func (f *Function) dominators() []*Block {
+	postorder := f.postorder()
+	number := make([]int, len(f.Blocks))
+	for idx, b := range postorder {
+		number[b.ID] = idx
+	}
+
+	idom := make([]*Block, len(f.Blocks))
+	entry := f.Entry()
+	idom[entry.ID] = entry
+
+	intersect := func(a *Block, b *Block) *Block {
+		for a != b {
+			for number[a.ID] < number[b.ID] {
+				a = idom[a.ID]
+			}
+			for number[b.ID] < number[a.ID] {
+				b = idom[b.ID]
+			}
+		}
+		return a
+	}
+
+	for changed := true; changed; {
+		changed = false
+		// reverse postorder without the entry
+		for idx := len(postorder) - 2; idx >= 0; idx-- {
+			b := postorder[idx]
+
+			var dom *Block
+			for _, pred := range b.Preds {
+				if idom[pred.ID] == nil {
+					continue
+				}
+				if dom == nil {
+					dom = pred
+				} else {
+					dom = intersect(pred, dom)
+				}
+			}
+
+			if idom[b.ID] != dom {
+				idom[b.ID] = dom
+				changed = true
+			}
+		}
+	}
+
+	return idom
+}
+
+// dominates - checks if the block a dominates the block b
+func dominates(idom []*Block, a *Block, b *Block) bool {
+	for {
+		if a == b {
+			return true
+		}
+		if idom[b.ID] == b {
+			return false
+		}
+		b = idom[b.ID]
+	}
+}
+
+// dominatorTree - returns blocks immediately dominated by the blocks,
+// children follow the order of the blocks in the function
+func (f *Function) dominatorTree(idom []*Block) [][]*Block {
+	children := make([][]*Block, len(f.Blocks))
+	for _, b := range f.Blocks {
+		if dom := idom[b.ID]; dom != b {
+			children[dom.ID] = append(children[dom.ID], b)
+		}
+	}
+	return children
+}
+
+// dominanceFrontiers - returns dominance frontiers of the blocks: blocks
+// where dominance of the block ends
+func (f *Function) dominanceFrontiers(idom []*Block) [][]*Block {
+	frontiers := make([][]*Block, len(f.Blocks))
+
+	for _, b := range f.Blocks {
+		if len(b.Preds) < 2 {
+			continue
+		}
+		for _, pred := range b.Preds {
+			for runner := pred; runner != idom[b.ID]; runner = idom[runner.ID] {
+				if !containsBlock(frontiers[runner.ID], b) {
+					frontiers[runner.ID] = append(frontiers[runner.ID], b)
+				}
+			}
+		}
+	}
+
+	return frontiers
+}
+
+// containsBlock - checks if the block is in the blocks
+func containsBlock(blocks []*Block, b *Block) bool {
+	for _, block := range blocks {
+		if block == b {
+			return true
+		}
+	}
+	return false
+}
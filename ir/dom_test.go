@@ -0,0 +1,37 @@
+package ir
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/technoboom/compiler/internal/testutil"
+)
+
+func TestDominators(t *testing.T) {
+	// b0 -> b1, b5; b1 -> b2, b3; b2, b3 -> b4; b4, b5 -> b6
+	program, err := Lower(testutil.Parse("if (1) { if (2) { 3 } else { 4 } } else { 5 }"))
+	if err != nil {
+		t.Fatalf("lowering error: %s", err)
+	}
+	f := program.Main()
+
+	idom := f.dominators()
+	expectedIdom := []int{0, 0, 1, 1, 1, 0, 0}
+	for _, b := range f.Blocks {
+		if idom[b.ID].ID != expectedIdom[b.ID] {
+			t.Errorf("wrong dominator of %s. want=b%d, got=%s", b, expectedIdom[b.ID], idom[b.ID])
+		}
+	}
+
+	frontiers := f.dominanceFrontiers(idom)
+	expectedFrontiers := []string{"[]", "[b6]", "[b4]", "[b4]", "[b6]", "[b6]", "[]"}
+	for _, b := range f.Blocks {
+		if actual := fmt.Sprint(frontiers[b.ID]); actual != expectedFrontiers[b.ID] {
+			t.Errorf("wrong dominance frontier of %s. want=%s, got=%s", b, expectedFrontiers[b.ID], actual)
+		}
+	}
+
+	if !dominates(idom, f.Blocks[1], f.Blocks[4]) || dominates(idom, f.Blocks[2], f.Blocks[4]) {
+		t.Errorf("wrong dominance of b4")
+	}
+}
@@ -0,0 +1,336 @@
+// Package ir - intermediate representation of Beaver programs for the
+// backends and optimizations: functions made of basic blocks with explicit
+// control flow, values in static single assignment (SSA) form
+package ir
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/technoboom/compiler/object"
+)
+
+// Op - operation of the value
+type Op int
+
+const (
+	// OpConst - constant value of Const
+	OpConst Op = iota
+	// OpParam - parameter of the function by Index
+	OpParam
+	// OpFree - cell of the variable captured by the closure, by Index
+	OpFree
+	// OpUndef - value of the local variable before its first assignment
+	OpUndef
+	// OpCheck - returns the operand, reading undef value of the variable
+	// Name is an error
+	OpCheck
+
+	// OpGet - reads the local variable Name, exists only before conversion
+	// into SSA
+	OpGet
+	// OpSet - assigns the operand to the local variable Name, exists only
+	// before conversion into SSA
+	OpSet
+
+	// OpCell - creates empty cell of the variable Name captured by closures
+	OpCell
+	// OpLoad - reads the cell of the variable Name, reading the empty cell
+	// is an error
+	OpLoad
+	// OpStore - stores the second operand into the cell
+	OpStore
+
+	// OpNeg - negates the integer
+	OpNeg
+	// OpNot - logical negation of the truthiness of the operand
+	OpNot
+	// OpAdd, OpSub, OpMul, OpDiv - arithmetic of integers
+	OpAdd
+	OpSub
+	OpMul
+	OpDiv
+	// OpLess, OpGreater - comparison of integers
+	OpLess
+	OpGreater
+	// OpEqual, OpNotEqual - equality with the rules of `==` and `!=`
+	OpEqual
+	OpNotEqual
+
+	// OpArray - array of the operands
+	OpArray
+	// OpHash - hash of the operands, which are keys followed by values
+	OpHash
+	// OpClosure - closure of the function Fn, operands are the cells
+	// of its free variables
+	OpClosure
+	// OpCall - calls the first operand with the rest of the operands
+	OpCall
+
+	// OpPhi - selects the operand by the predecessor the control came from,
+	// operands follow the order of the predecessors of the block
+	OpPhi
+)
+
+// opNames - names of the operations in the textual form
+var opNames = map[Op]string{
+	OpConst:    "const",
+	OpParam:    "param",
+	OpFree:     "free",
+	OpUndef:    "undef",
+	OpCheck:    "check",
+	OpGet:      "get",
+	OpSet:      "set",
+	OpCell:     "cell",
+	OpLoad:     "load",
+	OpStore:    "store",
+	OpNeg:      "neg",
+	OpNot:      "not",
+	OpAdd:      "add",
+	OpSub:      "sub",
+	OpMul:      "mul",
+	OpDiv:      "div",
+	OpLess:     "lt",
+	OpGreater:  "gt",
+	OpEqual:    "eq",
+	OpNotEqual: "ne",
+	OpArray:    "array",
+	OpHash:     "hash",
+	OpClosure:  "closure",
+	OpCall:     "call",
+	OpPhi:      "phi",
+}
+
+// String - returns name of the operation
+func (op Op) String() string {
+	if name, ok := opNames[op]; ok {
+		return name
+	}
+	return fmt.Sprintf("op(%d)", int(op))
+}
+
+// HasResult - checks if the operation produces value, which can be
+// the operand of other values
+func (op Op) HasResult() bool {
+	return op != OpSet && op != OpStore
+}
+
+// Value - instruction of the basic block and the value it produces
+type Value struct {
+	// number of the value, unique in the function
+	ID   int
+	Op   Op
+	Args []*Value
+	// block containing the value
+	Block *Block
+
+	// constant of OpConst
+	Const object.Object
+	// name of the variable (OpGet, OpSet, OpCheck, OpUndef, OpCell, OpLoad),
+	// the parameter (OpParam) or the free variable (OpFree)
+	Name string
+	// index of the parameter (OpParam) or the free variable (OpFree)
+	Index int
+	// function of OpClosure
+	Fn *Function
+}
+
+// String - returns name of the value used in operands
+func (v *Value) String() string {
+	return "v" + strconv.Itoa(v.ID)
+}
+
+// LongString - returns the value in the textual form of the instruction
+func (v *Value) LongString() string {
+	var out bytes.Buffer
+	if v.Op.HasResult() {
+		out.WriteString(v.String() + " = ")
+	}
+	out.WriteString(v.Op.String())
+
+	operands := []string{}
+	switch v.Op {
+	case OpConst:
+		operands = append(operands, constString(v.Const))
+	case OpParam, OpFree, OpUndef, OpGet, OpSet, OpCheck, OpCell, OpLoad:
+		operands = append(operands, v.Name)
+	case OpClosure:
+		operands = append(operands, v.Fn.Name)
+	}
+	for _, arg := range v.Args {
+		operands = append(operands, arg.String())
+	}
+
+	if len(operands) > 0 {
+		out.WriteString(" " + strings.Join(operands, ", "))
+	}
+	return out.String()
+}
+
+// constString - returns constant in the textual form, strings are quoted
+func constString(value object.Object) string {
+	if s, ok := value.(*object.String); ok {
+		return strconv.Quote(s.Value)
+	}
+	return value.Inspect()
+}
+
+// BlockKind - the way the block passes control
+type BlockKind int
+
+const (
+	// BlockJump - continues with the only successor
+	BlockJump BlockKind = iota
+	// BlockBranch - continues with the first successor if the control
+	// value is truthy, with the second one otherwise
+	BlockBranch
+	// BlockReturn - returns the control value from the function, the
+	// program without result returns no value
+	BlockReturn
+)
+
+// Block - basic block: values executed in order followed by the transfer
+// of control
+type Block struct {
+	// number of the block, unique in the function
+	ID     int
+	Values []*Value
+	Kind   BlockKind
+	// condition of BlockBranch, result of BlockReturn (may be nil)
+	Control *Value
+
+	Preds []*Block
+	Succs []*Block
+	// function containing the block
+	Func *Function
+}
+
+// String - returns name of the block
+func (b *Block) String() string {
+	return "b" + strconv.Itoa(b.ID)
+}
+
+// LongString - returns the block in the textual form
+func (b *Block) LongString() string {
+	var out bytes.Buffer
+
+	out.WriteString(b.String() + ":")
+	if len(b.Preds) > 0 {
+		preds := []string{}
+		for _, p := range b.Preds {
+			preds = append(preds, p.String())
+		}
+		out.WriteString(" <- " + strings.Join(preds, " "))
+	}
+	out.WriteString("\n")
+
+	for _, v := range b.Values {
+		out.WriteString("  " + v.LongString() + "\n")
+	}
+
+	switch b.Kind {
+	case BlockJump:
+		out.WriteString("  jump " + b.Succs[0].String() + "\n")
+	case BlockBranch:
+		out.WriteString(fmt.Sprintf("  br %s, %s, %s\n", b.Control, b.Succs[0], b.Succs[1]))
+	case BlockReturn:
+		if b.Control == nil {
+			out.WriteString("  ret\n")
+		} else {
+			out.WriteString("  ret " + b.Control.String() + "\n")
+		}
+	}
+	return out.String()
+}
+
+// addEdge - adds the successor of the block
+func (b *Block) addEdge(succ *Block) {
+	b.Succs = append(b.Succs, succ)
+	succ.Preds = append(succ.Preds, b)
+}
+
+// Function - function of the program, the entry block is the first one
+type Function struct {
+	// unique name of the function in the program
+	Name       string
+	Parameters []string
+	// names of the free variables captured by the closures
+	FreeVariables []string
+	Blocks        []*Block
+
+	// SSA tells if the function was converted into SSA form
+	SSA bool
+
+	numValues int
+	numBlocks int
+}
+
+// Entry - returns the entry block of the function
+func (f *Function) Entry() *Block {
+	return f.Blocks[0]
+}
+
+// String - returns the function in the textual form
+func (f *Function) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("func " + f.Name + "(" + strings.Join(f.Parameters, ", ") + ")")
+	if len(f.FreeVariables) > 0 {
+		out.WriteString(" free(" + strings.Join(f.FreeVariables, ", ") + ")")
+	}
+	out.WriteString(":\n")
+	for _, b := range f.Blocks {
+		out.WriteString(b.LongString())
+	}
+	return out.String()
+}
+
+// newBlock - creates block of the function, the block is added
+// to the function when it's started
+func (f *Function) newBlock() *Block {
+	b := &Block{ID: f.numBlocks, Func: f}
+	f.numBlocks++
+	return b
+}
+
+// newValue - creates value in the block (which is not added to the block)
+func (f *Function) newValue(b *Block, op Op, args ...*Value) *Value {
+	v := &Value{ID: f.numValues, Op: op, Args: args, Block: b}
+	f.numValues++
+	return v
+}
+
+// renumber - numbers blocks and values in the order of the blocks
+func (f *Function) renumber() {
+	f.numBlocks, f.numValues = 0, 0
+	for _, b := range f.Blocks {
+		b.ID = f.numBlocks
+		f.numBlocks++
+		for _, v := range b.Values {
+			v.ID = f.numValues
+			f.numValues++
+		}
+	}
+}
+
+// Program - functions of the program, the first one is the top level
+// of the program
+type Program struct {
+	Functions []*Function
+}
+
+// Main - returns the function of the top level
+func (p *Program) Main() *Function {
+	return p.Functions[0]
+}
+
+// String - returns the program in the textual form
+func (p *Program) String() string {
+	functions := []string{}
+	for _, f := range p.Functions {
+		functions = append(functions, f.String())
+	}
+	return strings.Join(functions, "\n")
+}
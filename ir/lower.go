@@ -0,0 +1,655 @@
+package ir
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/technoboom/compiler/ast"
+	"github.com/technoboom/compiler/object"
+)
+
+// builder - lowers the body of one function. Local variables read by
+// nested functions live in cells, other local variables are read and
+// assigned with get/set until the function is converted into SSA
+type builder struct {
+	fn      *Function
+	outer   *builder
+	program *Program
+	// number of functions with the name, used to make names unique
+	names map[string]int
+
+	// local variables of the function, the ones captured by nested
+	// functions have cells
+	locals map[string]bool
+	cells  map[string]*Value
+	// cells of the free variables of the function
+	free map[string]*Value
+
+	// block the values are added to
+	block    *Block
+	numTemps int
+}
+
+// Build - lowers the program into IR and converts it into SSA form
+func Build(program *ast.Program) (*Program, error) {
+	p, err := Lower(program)
+	if err != nil {
+		return nil, err
+	}
+	p.ToSSA()
+	return p, nil
+}
+
+// Lower - lowers the program into IR: functions of basic blocks which
+// read and assign local variables with get and set. Programs using
+// features without IR support are rejected with an error
+func Lower(program *ast.Program) (*Program, error) {
+	p := &Program{}
+	b := newBuilder(p, nil, map[string]int{}, "main", nil, program.Statements, nil)
+
+	value, err := b.lowerStatements(program.Statements)
+	if err != nil {
+		return nil, err
+	}
+	// the program without result returns no value
+	b.ret(value)
+	b.finish()
+
+	return p, nil
+}
+
+// newBuilder - creates the function of the program and starts its entry
+// block with parameters, cells and free variables
+func newBuilder(
+	p *Program,
+	outer *builder,
+	names map[string]int,
+	name string,
+	parameters []*ast.Identifier,
+	statements []ast.Statement,
+	freeVariables []string,
+) *builder {
+	fn := &Function{Name: uniqueName(names, name), FreeVariables: freeVariables}
+	p.Functions = append(p.Functions, fn)
+
+	b := &builder{
+		fn:      fn,
+		outer:   outer,
+		program: p,
+		names:   names,
+		locals:  map[string]bool{},
+		cells:   map[string]*Value{},
+		free:    map[string]*Value{},
+	}
+	b.startBlock(fn.newBlock())
+
+	locals := []string{}
+	for _, param := range parameters {
+		fn.Parameters = append(fn.Parameters, param.Value)
+		locals = append(locals, param.Value)
+	}
+	locals = append(locals, collectLets(statements)...)
+	captured := capturedNames(statements)
+
+	for _, name := range locals {
+		if b.locals[name] {
+			continue
+		}
+		b.locals[name] = true
+		if captured[name] {
+			cell := b.emit(OpCell)
+			cell.Name = name
+			b.cells[name] = cell
+		}
+	}
+
+	for idx, name := range freeVariables {
+		free := b.emit(OpFree)
+		free.Name, free.Index = name, idx
+		b.free[name] = free
+	}
+
+	for idx, name := range fn.Parameters {
+		param := b.emit(OpParam)
+		param.Name, param.Index = name, idx
+		b.assign(name, param)
+	}
+
+	return b
+}
+
+// uniqueName - returns name of the function which is unique in the
+// program, repeated names get number
+func uniqueName(names map[string]int, name string) string {
+	count := names[name]
+	names[name]++
+	if count == 0 {
+		return name
+	}
+	return name + "." + strconv.Itoa(count)
+}
+
+// lowerStatements - lowers statements of the block and returns the value
+// of the last expression, nil if the block doesn't end with expression
+func (b *builder) lowerStatements(statements []ast.Statement) (*Value, error) {
+	var last *Value
+
+	for _, s := range statements {
+		last = nil
+
+		switch s := s.(type) {
+		case *ast.ExpressionStatement:
+			value, err := b.lowerExpression(s.Expression, "")
+			if err != nil {
+				return nil, err
+			}
+			last = value
+		case *ast.LetStatement:
+			ident, ok := s.Pattern.(*ast.Identifier)
+			if !ok {
+				return nil, fmt.Errorf("destructuring is not supported by IR")
+			}
+			value, err := b.lowerExpression(s.Value, ident.Value)
+			if err != nil {
+				return nil, err
+			}
+			b.assign(ident.Value, value)
+		case *ast.ReturnStatement:
+			value, err := b.lowerExpression(s.ReturnValue, "")
+			if err != nil {
+				return nil, err
+			}
+			b.ret(value)
+			// the following statements are unreachable
+			b.startBlock(b.fn.newBlock())
+		default:
+			return nil, fmt.Errorf("%T is not supported by IR", s)
+		}
+	}
+
+	return last, nil
+}
+
+// lowerExpression - lowers the expression and returns its value, the name
+// is given to the function assigned to the variable
+func (b *builder) lowerExpression(node ast.Expression, name string) (*Value, error) {
+	switch node := node.(type) {
+	case *ast.IntegerLiteral:
+		return b.constant(&object.Integer{Value: node.Value}), nil
+	case *ast.StringLiteral:
+		return b.constant(&object.String{Value: node.Value}), nil
+	case *ast.Boolean:
+		if node.Value {
+			return b.constant(object.TRUE), nil
+		}
+		return b.constant(object.FALSE), nil
+	case *ast.NullLiteral:
+		return b.constant(object.NULL), nil
+	case *ast.Identifier:
+		return b.read(node.Value)
+	case *ast.PrefixExpression:
+		op, ok := prefixOps[node.Operator]
+		if !ok {
+			return nil, fmt.Errorf("unknown operator %s", node.Operator)
+		}
+		right, err := b.lowerExpression(node.Right, "")
+		if err != nil {
+			return nil, err
+		}
+		return b.emit(op, right), nil
+	case *ast.InfixExpression:
+		if node.Operator == "??" {
+			return b.lowerNullish(node)
+		}
+		op, ok := infixOps[node.Operator]
+		if !ok {
+			return nil, fmt.Errorf("unknown operator %s", node.Operator)
+		}
+		values, err := b.lowerExpressions([]ast.Expression{node.Left, node.Right})
+		if err != nil {
+			return nil, err
+		}
+		return b.emit(op, values...), nil
+	case *ast.IfExpression:
+		return b.lowerConditional(node.Condition, node.Consequence, node.Alternative)
+	case *ast.ConditionalExpression:
+		return b.lowerConditional(node.Condition, node.Consequence, node.Alternative)
+	case *ast.ArrayLiteral:
+		values, err := b.lowerExpressions(node.Elements)
+		if err != nil {
+			return nil, err
+		}
+		return b.emit(OpArray, values...), nil
+	case *ast.HashLiteral:
+		expressions := []ast.Expression{}
+		for _, pair := range node.Pairs {
+			if pair.Value == nil {
+				return nil, fmt.Errorf("spread elements are not supported by IR")
+			}
+			expressions = append(expressions, pair.Key, pair.Value)
+		}
+		values, err := b.lowerExpressions(expressions)
+		if err != nil {
+			return nil, err
+		}
+		return b.emit(OpHash, values...), nil
+	case *ast.FunctionLiteral:
+		return b.lowerFunctionLiteral(node, name)
+	case *ast.CallExpression:
+		if _, ok := node.Function.(*ast.MemberExpression); ok {
+			return nil, fmt.Errorf("method calls are not supported by IR")
+		}
+		values, err := b.lowerExpressions(append([]ast.Expression{node.Function}, node.Arguments...))
+		if err != nil {
+			return nil, err
+		}
+		return b.emit(OpCall, values...), nil
+	default:
+		return nil, fmt.Errorf("%T is not supported by IR", node)
+	}
+}
+
+// prefixOps, infixOps - operations of the operators
+var prefixOps = map[string]Op{
+	"!": OpNot,
+	"-": OpNeg,
+}
+
+var infixOps = map[string]Op{
+	"+":  OpAdd,
+	"-":  OpSub,
+	"*":  OpMul,
+	"/":  OpDiv,
+	"<":  OpLess,
+	">":  OpGreater,
+	"==": OpEqual,
+	"!=": OpNotEqual,
+}
+
+// lowerExpressions - lowers the expressions in order
+func (b *builder) lowerExpressions(expressions []ast.Expression) ([]*Value, error) {
+	values := []*Value{}
+	for _, e := range expressions {
+		switch e.(type) {
+		case *ast.KeywordArgument:
+			return nil, fmt.Errorf("keyword arguments are not supported by IR")
+		case *ast.SpreadElement:
+			return nil, fmt.Errorf("spread elements are not supported by IR")
+		}
+
+		value, err := b.lowerExpression(e, "")
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// lowerConditional - lowers if/else and ternary expressions into branches
+// assigning the result to the temporary variable, missing alternative
+// produces null
+func (b *builder) lowerConditional(condition ast.Expression, consequence ast.Node, alternative ast.Node) (*Value, error) {
+	cond, err := b.lowerExpression(condition, "")
+	if err != nil {
+		return nil, err
+	}
+
+	result := b.newTemp()
+	then, otherwise, merge := b.fn.newBlock(), b.fn.newBlock(), b.fn.newBlock()
+	b.branch(cond, then, otherwise)
+
+	for _, branch := range []struct {
+		block *Block
+		node  ast.Node
+	}{{then, consequence}, {otherwise, alternative}} {
+		b.startBlock(branch.block)
+		value, err := b.lowerBranch(branch.node)
+		if err != nil {
+			return nil, err
+		}
+		b.assign(result, value)
+		b.jump(merge)
+	}
+
+	b.startBlock(merge)
+	return b.read(result)
+}
+
+// lowerBranch - lowers block or expression of the conditional
+func (b *builder) lowerBranch(node ast.Node) (*Value, error) {
+	var value *Value
+	var err error
+
+	switch node := node.(type) {
+	case *ast.BlockStatement:
+		if node != nil {
+			value, err = b.lowerStatements(node.Statements)
+		}
+	case ast.Expression:
+		value, err = b.lowerExpression(node, "")
+	}
+
+	if err != nil || value != nil {
+		return value, err
+	}
+	return b.constant(object.NULL), nil
+}
+
+// lowerNullish - lowers `??`, the right operand is evaluated only if the
+// left one is null
+func (b *builder) lowerNullish(node *ast.InfixExpression) (*Value, error) {
+	left, err := b.lowerExpression(node.Left, "")
+	if err != nil {
+		return nil, err
+	}
+
+	result := b.newTemp()
+	b.assign(result, left)
+
+	right, merge := b.fn.newBlock(), b.fn.newBlock()
+	b.branch(b.emit(OpEqual, left, b.constant(object.NULL)), right, merge)
+
+	b.startBlock(right)
+	value, err := b.lowerExpression(node.Right, "")
+	if err != nil {
+		return nil, err
+	}
+	b.assign(result, value)
+	b.jump(merge)
+
+	b.startBlock(merge)
+	return b.read(result)
+}
+
+// lowerFunctionLiteral - lowers the function and creates its closure
+// with cells of the free variables
+func (b *builder) lowerFunctionLiteral(node *ast.FunctionLiteral, name string) (*Value, error) {
+	if len(node.Defaults) > 0 || node.Rest != nil {
+		return nil, fmt.Errorf("default and rest parameters are not supported by IR")
+	}
+	if name == "" {
+		name = "fn"
+	}
+
+	statements := node.Body.Statements
+	freeVariables := freeNames(node.Parameters, statements)
+	inner := newBuilder(b.program, b, b.names, name, node.Parameters, statements, freeVariables)
+
+	value, err := inner.lowerStatements(statements)
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		value = inner.constant(object.NULL)
+	}
+	inner.ret(value)
+	inner.finish()
+
+	cells := []*Value{}
+	for _, name := range freeVariables {
+		cell, err := b.cell(name)
+		if err != nil {
+			return nil, err
+		}
+		cells = append(cells, cell)
+	}
+
+	closure := b.emit(OpClosure, cells...)
+	closure.Fn = inner.fn
+	return closure, nil
+}
+
+// read - returns value of the variable
+func (b *builder) read(name string) (*Value, error) {
+	if b.locals[name] && b.cells[name] == nil {
+		get := b.emit(OpGet)
+		get.Name = name
+		return get, nil
+	}
+
+	cell, err := b.cell(name)
+	if err != nil {
+		return nil, err
+	}
+	load := b.emit(OpLoad, cell)
+	load.Name = name
+	return load, nil
+}
+
+// assign - assigns the value to the local variable
+func (b *builder) assign(name string, value *Value) {
+	if cell, ok := b.cells[name]; ok {
+		b.emit(OpStore, cell, value)
+		return
+	}
+	set := b.emit(OpSet, value)
+	set.Name = name
+}
+
+// cell - returns cell of the variable captured by nested function
+func (b *builder) cell(name string) (*Value, error) {
+	if cell, ok := b.cells[name]; ok {
+		return cell, nil
+	}
+	if cell, ok := b.free[name]; ok {
+		return cell, nil
+	}
+	return nil, fmt.Errorf("identifier not found: %s", name)
+}
+
+// newTemp - returns the new temporary variable, names of the variables
+// can't contain `$`, so they don't clash
+func (b *builder) newTemp() string {
+	b.numTemps++
+	name := "$t" + strconv.Itoa(b.numTemps)
+	b.locals[name] = true
+	return name
+}
+
+// constant - adds the constant
+func (b *builder) constant(value object.Object) *Value {
+	v := b.emit(OpConst)
+	v.Const = value
+	return v
+}
+
+// emit - adds the value to the current block
+func (b *builder) emit(op Op, args ...*Value) *Value {
+	v := b.fn.newValue(b.block, op, args...)
+	b.block.Values = append(b.block.Values, v)
+	return v
+}
+
+// startBlock - makes the block current and adds it to the function
+func (b *builder) startBlock(block *Block) {
+	b.fn.Blocks = append(b.fn.Blocks, block)
+	b.block = block
+}
+
+// jump, branch, ret - end the current block
+func (b *builder) jump(to *Block) {
+	b.block.Kind = BlockJump
+	b.block.addEdge(to)
+}
+
+func (b *builder) branch(cond *Value, then *Block, otherwise *Block) {
+	b.block.Kind = BlockBranch
+	b.block.Control = cond
+	b.block.addEdge(then)
+	b.block.addEdge(otherwise)
+}
+
+func (b *builder) ret(value *Value) {
+	b.block.Kind = BlockReturn
+	b.block.Control = value
+}
+
+// finish - removes blocks which are unreachable (code following return)
+// and numbers blocks and values
+func (b *builder) finish() {
+	reachable := map[*Block]bool{}
+	var visit func(block *Block)
+	visit = func(block *Block) {
+		if reachable[block] {
+			return
+		}
+		reachable[block] = true
+		for _, succ := range block.Succs {
+			visit(succ)
+		}
+	}
+	visit(b.fn.Entry())
+
+	blocks := []*Block{}
+	for _, block := range b.fn.Blocks {
+		if !reachable[block] {
+			continue
+		}
+		preds := []*Block{}
+		for _, pred := range block.Preds {
+			if reachable[pred] {
+				preds = append(preds, pred)
+			}
+		}
+		block.Preds = preds
+		blocks = append(blocks, block)
+	}
+
+	b.fn.Blocks = blocks
+	b.fn.renumber()
+}
+
+// collectLets - returns names bound by let statements of the function
+// body (or the program) including nested blocks, but not nested functions
+func collectLets(statements []ast.Statement) []string {
+	names := []string{}
+	walk(statements, func(node ast.Node) bool {
+		if let, ok := node.(*ast.LetStatement); ok {
+			if ident, ok := let.Pattern.(*ast.Identifier); ok {
+				names = append(names, ident.Value)
+			}
+		}
+		_, ok := node.(*ast.FunctionLiteral)
+		return !ok
+	})
+	return names
+}
+
+// capturedNames - returns local variables of the function body read
+// by nested functions
+func capturedNames(statements []ast.Statement) map[string]bool {
+	captured := map[string]bool{}
+	walk(statements, func(node ast.Node) bool {
+		fn, ok := node.(*ast.FunctionLiteral)
+		if !ok {
+			return true
+		}
+		for _, name := range freeNames(fn.Parameters, fn.Body.Statements) {
+			captured[name] = true
+		}
+		return false
+	})
+	return captured
+}
+
+// freeNames - returns names read by the function which are not its local
+// variables, including free names of nested functions, in order of
+// their first use
+func freeNames(parameters []*ast.Identifier, statements []ast.Statement) []string {
+	locals := map[string]bool{}
+	for _, param := range parameters {
+		locals[param.Value] = true
+	}
+	for _, name := range collectLets(statements) {
+		locals[name] = true
+	}
+
+	names := []string{}
+	seen := map[string]bool{}
+	add := func(name string) {
+		if !locals[name] && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+
+	walk(statements, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.Identifier:
+			add(node.Value)
+		case *ast.FunctionLiteral:
+			for _, name := range freeNames(node.Parameters, node.Body.Statements) {
+				add(name)
+			}
+			return false
+		}
+		return true
+	})
+	return names
+}
+
+// walk - visits nodes of the statements which can be lowered, children
+// of the node are visited if the visitor returns true. Patterns of let
+// statements and parameters of functions are not visited
+func walk(statements []ast.Statement, visit func(node ast.Node) bool) {
+	var walkNode func(node ast.Node)
+	walkNode = func(node ast.Node) {
+		if node == nil || !visit(node) {
+			return
+		}
+
+		switch node := node.(type) {
+		case *ast.LetStatement:
+			walkNode(node.Value)
+		case *ast.ExpressionStatement:
+			walkNode(node.Expression)
+		case *ast.ReturnStatement:
+			walkNode(node.ReturnValue)
+		case *ast.BlockStatement:
+			if node == nil {
+				return
+			}
+			for _, s := range node.Statements {
+				walkNode(s)
+			}
+		case *ast.IfExpression:
+			walkNode(node.Condition)
+			walkNode(node.Consequence)
+			if node.Alternative != nil {
+				walkNode(node.Alternative)
+			}
+		case *ast.ConditionalExpression:
+			walkNode(node.Condition)
+			walkNode(node.Consequence)
+			walkNode(node.Alternative)
+		case *ast.PrefixExpression:
+			walkNode(node.Right)
+		case *ast.InfixExpression:
+			walkNode(node.Left)
+			walkNode(node.Right)
+		case *ast.CallExpression:
+			walkNode(node.Function)
+			for _, arg := range node.Arguments {
+				walkNode(arg)
+			}
+		case *ast.ArrayLiteral:
+			for _, e := range node.Elements {
+				walkNode(e)
+			}
+		case *ast.HashLiteral:
+			for _, pair := range node.Pairs {
+				walkNode(pair.Key)
+				if pair.Value != nil {
+					walkNode(pair.Value)
+				}
+			}
+		case *ast.FunctionLiteral:
+			walkNode(node.Body)
+		}
+	}
+
+	for _, s := range statements {
+		walkNode(s)
+	}
+}
@@ -0,0 +1,166 @@
+package ir
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/technoboom/compiler/internal/testutil"
+)
+
+func TestLower(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			`let a = -1; [a, "b", true, null, {a: !a}]`,
+			`
+func main():
+b0:
+  v0 = const 1
+  v1 = neg v0
+  set a, v1
+  v3 = get a
+  v4 = const "b"
+  v5 = const true
+  v6 = const null
+  v7 = get a
+  v8 = get a
+  v9 = not v8
+  v10 = hash v7, v9
+  v11 = array v3, v4, v5, v6, v10
+  ret v11
+`,
+		},
+		{
+			"if (1 < 2) { 10 }",
+			`
+func main():
+b0:
+  v0 = const 1
+  v1 = const 2
+  v2 = lt v0, v1
+  br v2, b1, b2
+b1: <- b0
+  v3 = const 10
+  set $t1, v3
+  jump b3
+b2: <- b0
+  v5 = const null
+  set $t1, v5
+  jump b3
+b3: <- b1 b2
+  v7 = get $t1
+  ret v7
+`,
+		},
+		{
+			"let a = 1; let f = (b) => (c) => a + b + c; return f; 5",
+			`
+func main():
+b0:
+  v0 = cell a
+  v1 = const 1
+  store v0, v1
+  v3 = closure f, v0
+  set f, v3
+  v5 = get f
+  ret v5
+
+func f(b) free(a):
+b0:
+  v0 = cell b
+  v1 = free a
+  v2 = param b
+  store v0, v2
+  v4 = closure fn, v1, v0
+  ret v4
+
+func fn(c) free(a, b):
+b0:
+  v0 = free a
+  v1 = free b
+  v2 = param c
+  set c, v2
+  v4 = load a, v0
+  v5 = load b, v1
+  v6 = add v4, v5
+  v7 = get c
+  v8 = add v6, v7
+  ret v8
+`,
+		},
+		{
+			"let f = () => 1; let f = () => { let g = () => 2; }",
+			`
+func main():
+b0:
+  v0 = closure f
+  set f, v0
+  v2 = closure f.1
+  set f, v2
+  ret
+
+func f():
+b0:
+  v0 = const 1
+  ret v0
+
+func f.1():
+b0:
+  v0 = closure g
+  set g, v0
+  v2 = const null
+  ret v2
+
+func g():
+b0:
+  v0 = const 2
+  ret v0
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		program, err := Lower(testutil.Parse(tt.input))
+		if err != nil {
+			t.Fatalf("lowering error for %q: %s", tt.input, err)
+		}
+		if err := program.Verify(); err != nil {
+			t.Errorf("invalid IR of %q: %s", tt.input, err)
+		}
+		if actual := program.String(); actual != strings.TrimPrefix(tt.expected, "\n") {
+			t.Errorf("wrong IR of %q. want=\n%s\ngot=\n%s", tt.input, tt.expected, actual)
+		}
+	}
+}
+
+func TestLowerErrors(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"a", "identifier not found: a"},
+		{"let f = () => b;", "identifier not found: b"},
+		{"let [a] = [1];", "destructuring is not supported by IR"},
+		{"let f = (a) => a; f(a: 1)", "keyword arguments are not supported by IR"},
+		{"[...[1]]", "spread elements are not supported by IR"},
+		{"{...{}}", "spread elements are not supported by IR"},
+		{`"a".upper()`, "method calls are not supported by IR"},
+		{"(a = 1) => a", "default and rest parameters are not supported by IR"},
+		{"throw 1", "*ast.ThrowStatement is not supported by IR"},
+		{"struct P { x }", "*ast.StructStatement is not supported by IR"},
+	}
+
+	for _, tt := range tests {
+		_, err := Lower(testutil.Parse(tt.input))
+		if err == nil {
+			t.Errorf("expected error for %q, got none", tt.input)
+			continue
+		}
+		if err.Error() != tt.expectedError {
+			t.Errorf("wrong error for %q. want=%q, got=%q",
+				tt.input, tt.expectedError, err.Error())
+		}
+	}
+}
@@ -0,0 +1,304 @@
+package ir
+
+// ToSSA - converts the functions of the program into SSA form: local
+// variables become values, phis merge them where control flow joins.
+// Reads of variables which may be unassigned are kept as checks
+func (p *Program) ToSSA() {
+	for _, f := range p.Functions {
+		if !f.SSA {
+			f.toSSA()
+		}
+	}
+}
+
+// toSSA - converts the function with the algorithm of Cytron et al.:
+// phis are placed at the dominance frontiers of the assignments, then
+// variables are renamed walking the dominator tree
+func (f *Function) toSSA() {
+	idom := f.dominators()
+	f.insertPhis(f.dominanceFrontiers(idom))
+	newRenamer(f).rename(f.Entry(), f.dominatorTree(idom))
+
+	for f.removeTrivialPhis() || f.removeChecks() {
+	}
+	f.removeDeadValues()
+
+	f.renumber()
+	f.SSA = true
+}
+
+// insertPhis - adds phis of the variables at the dominance frontiers
+// of the blocks assigning them, phis assign the variable too
+func (f *Function) insertPhis(frontiers [][]*Block) {
+	variables := []string{}
+	assignments := map[string][]*Block{}
+
+	for _, b := range f.Blocks {
+		for _, v := range b.Values {
+			if v.Op != OpSet {
+				continue
+			}
+			if _, ok := assignments[v.Name]; !ok {
+				variables = append(variables, v.Name)
+			}
+			if !containsBlock(assignments[v.Name], b) {
+				assignments[v.Name] = append(assignments[v.Name], b)
+			}
+		}
+	}
+
+	for _, name := range variables {
+		work := append([]*Block{}, assignments[name]...)
+		hasPhi := map[*Block]bool{}
+
+		for len(work) > 0 {
+			b := work[len(work)-1]
+			work = work[:len(work)-1]
+
+			for _, frontier := range frontiers[b.ID] {
+				if hasPhi[frontier] {
+					continue
+				}
+				hasPhi[frontier] = true
+
+				phi := f.newValue(frontier, OpPhi)
+				phi.Name = name
+				phi.Args = make([]*Value, len(frontier.Preds))
+				frontier.Values = append([]*Value{phi}, frontier.Values...)
+
+				if !containsBlock(assignments[name], frontier) {
+					work = append(work, frontier)
+				}
+			}
+		}
+	}
+}
+
+// renamer - keeps current values of the variables while walking
+// the dominator tree
+type renamer struct {
+	f      *Function
+	stacks map[string][]*Value
+	// values of the variables before their first assignment
+	undefs map[string]*Value
+}
+
+func newRenamer(f *Function) *renamer {
+	return &renamer{
+		f:      f,
+		stacks: map[string][]*Value{},
+		undefs: map[string]*Value{},
+	}
+}
+
+// current - returns value of the variable at the point of the walk
+func (r *renamer) current(name string) *Value {
+	if stack := r.stacks[name]; len(stack) > 0 {
+		return stack[len(stack)-1]
+	}
+
+	if undef, ok := r.undefs[name]; ok {
+		return undef
+	}
+	entry := r.f.Entry()
+	undef := r.f.newValue(entry, OpUndef)
+	undef.Name = name
+	entry.Values = append([]*Value{undef}, entry.Values...)
+	r.undefs[name] = undef
+	return undef
+}
+
+// rename - replaces assignments of the variables in the block and blocks
+// dominated by it with values, reads become checks of the values
+func (r *renamer) rename(b *Block, children [][]*Block) {
+	pushed := []string{}
+	values := []*Value{}
+
+	for _, v := range b.Values {
+		switch v.Op {
+		case OpPhi:
+			if v.Name != "" {
+				r.stacks[v.Name] = append(r.stacks[v.Name], v)
+				pushed = append(pushed, v.Name)
+			}
+		case OpSet:
+			r.stacks[v.Name] = append(r.stacks[v.Name], v.Args[0])
+			pushed = append(pushed, v.Name)
+			continue
+		case OpGet:
+			v.Op = OpCheck
+			v.Args = []*Value{r.current(v.Name)}
+		}
+		values = append(values, v)
+	}
+	b.Values = values
+
+	for _, succ := range b.Succs {
+		for idx, pred := range succ.Preds {
+			if pred != b {
+				continue
+			}
+			for _, phi := range succ.Values {
+				if phi.Op == OpPhi && phi.Name != "" {
+					phi.Args[idx] = r.current(phi.Name)
+				}
+			}
+		}
+	}
+
+	for _, child := range children[b.ID] {
+		r.rename(child, children)
+	}
+
+	for _, name := range pushed {
+		r.stacks[name] = r.stacks[name][:len(r.stacks[name])-1]
+	}
+}
+
+// removeTrivialPhis - replaces phis which select the same value (or
+// themselves) from every predecessor with the value
+func (f *Function) removeTrivialPhis() bool {
+	replacements := map[*Value]*Value{}
+
+	for _, b := range f.Blocks {
+		for _, phi := range b.Values {
+			if phi.Op != OpPhi {
+				continue
+			}
+
+			var same *Value
+			trivial := true
+			for _, arg := range phi.Args {
+				if arg == phi || arg == same {
+					continue
+				}
+				if same != nil {
+					trivial = false
+					break
+				}
+				same = arg
+			}
+			if trivial && same != nil {
+				replacements[phi] = same
+			}
+		}
+	}
+
+	f.replaceValues(replacements)
+	return len(replacements) > 0
+}
+
+// removeChecks - replaces checks of the values which are surely assigned
+// with the values
+func (f *Function) removeChecks() bool {
+	maybeUndef := map[*Value]bool{}
+	for _, v := range f.Entry().Values {
+		if v.Op == OpUndef {
+			maybeUndef[v] = true
+		}
+	}
+	for changed := true; changed; {
+		changed = false
+		for _, b := range f.Blocks {
+			for _, v := range b.Values {
+				if v.Op != OpPhi || maybeUndef[v] {
+					continue
+				}
+				for _, arg := range v.Args {
+					if maybeUndef[arg] {
+						maybeUndef[v] = true
+						changed = true
+						break
+					}
+				}
+			}
+		}
+	}
+
+	replacements := map[*Value]*Value{}
+	for _, b := range f.Blocks {
+		for _, v := range b.Values {
+			if v.Op == OpCheck && !maybeUndef[v.Args[0]] {
+				replacements[v] = v.Args[0]
+			}
+		}
+	}
+
+	f.replaceValues(replacements)
+	return len(replacements) > 0
+}
+
+// removeDeadValues - removes phis and undefs which are not used
+// by other values
+func (f *Function) removeDeadValues() {
+	live := map[*Value]bool{}
+	work := []*Value{}
+	use := func(v *Value) {
+		if v != nil && !live[v] {
+			live[v] = true
+			work = append(work, v)
+		}
+	}
+
+	for _, b := range f.Blocks {
+		for _, v := range b.Values {
+			if v.Op != OpPhi && v.Op != OpUndef {
+				use(v)
+			}
+		}
+		use(b.Control)
+	}
+	for len(work) > 0 {
+		v := work[len(work)-1]
+		work = work[:len(work)-1]
+		for _, arg := range v.Args {
+			use(arg)
+		}
+	}
+
+	for _, b := range f.Blocks {
+		values := []*Value{}
+		for _, v := range b.Values {
+			if live[v] {
+				values = append(values, v)
+			}
+		}
+		b.Values = values
+	}
+}
+
+// replaceValues - removes the values and replaces their uses with
+// the replacements
+func (f *Function) replaceValues(replacements map[*Value]*Value) {
+	if len(replacements) == 0 {
+		return
+	}
+
+	resolve := func(v *Value) *Value {
+		for {
+			r, ok := replacements[v]
+			if !ok {
+				return v
+			}
+			v = r
+		}
+	}
+
+	for _, b := range f.Blocks {
+		values := []*Value{}
+		for _, v := range b.Values {
+			if _, ok := replacements[v]; ok {
+				continue
+			}
+			for idx, arg := range v.Args {
+				v.Args[idx] = resolve(arg)
+			}
+			values = append(values, v)
+		}
+		b.Values = values
+
+		if b.Control != nil {
+			b.Control = resolve(b.Control)
+		}
+	}
+}
@@ -0,0 +1,323 @@
+package ir
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/technoboom/compiler/gogen/rt"
+	"github.com/technoboom/compiler/internal/testutil"
+	"github.com/technoboom/compiler/object"
+)
+
+func TestToSSA(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			"let a = 1; let a = a + 2; a",
+			`
+func main():
+b0:
+  v0 = const 1
+  v1 = const 2
+  v2 = add v0, v1
+  ret v2
+`,
+		},
+		{
+			"let f = (x) => { let y = x ? 1 : 2; [y, x ?? 3] }",
+			`
+func main():
+b0:
+  v0 = closure f
+  ret
+
+func f(x):
+b0:
+  v0 = param x
+  br v0, b1, b2
+b1: <- b0
+  v1 = const 1
+  jump b3
+b2: <- b0
+  v2 = const 2
+  jump b3
+b3: <- b1 b2
+  v3 = phi v1, v2
+  v4 = const null
+  v5 = eq v0, v4
+  br v5, b4, b5
+b4: <- b3
+  v6 = const 3
+  jump b5
+b5: <- b3 b4
+  v7 = phi v0, v6
+  v8 = array v3, v7
+  ret v8
+`,
+		},
+		{
+			"let f = (x) => { if (x) { let y = 1 }; y }",
+			`
+func main():
+b0:
+  v0 = closure f
+  ret
+
+func f(x):
+b0:
+  v0 = undef y
+  v1 = param x
+  br v1, b1, b2
+b1: <- b0
+  v2 = const 1
+  v3 = const null
+  jump b3
+b2: <- b0
+  v4 = const null
+  jump b3
+b3: <- b1 b2
+  v5 = phi v2, v0
+  v6 = check y, v5
+  ret v6
+`,
+		},
+		{
+			"let f = (x) => { if (x) { return 1 }; x }",
+			`
+func main():
+b0:
+  v0 = closure f
+  ret
+
+func f(x):
+b0:
+  v0 = param x
+  br v0, b1, b2
+b1: <- b0
+  v1 = const 1
+  ret v1
+b2: <- b0
+  v2 = const null
+  jump b3
+b3: <- b2
+  ret v0
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		program, err := Build(testutil.Parse(tt.input))
+		if err != nil {
+			t.Fatalf("build error for %q: %s", tt.input, err)
+		}
+		if err := program.Verify(); err != nil {
+			t.Errorf("invalid IR of %q: %s", tt.input, err)
+		}
+		if actual := program.String(); actual != strings.TrimPrefix(tt.expected, "\n") {
+			t.Errorf("wrong IR of %q. want=\n%s\ngot=\n%s", tt.input, tt.expected, actual)
+		}
+	}
+}
+
+// TestEquivalence - interprets programs before and after conversion into
+// SSA and checks that they produce the same results and errors as the
+// evaluator
+func TestEquivalence(t *testing.T) {
+	inputs := []string{
+		"5 + 5 * 2 - 10 / 5",
+		"-(3 * 3) + 50 / 2",
+		"1 < 2 == true",
+		"!5; !!null",
+		`"beaver"`,
+		`"a" == "a"`,
+		"1 == true",
+		"null == null",
+		"[1, \"two\", true, null, [3], {}]",
+		`{"a": 1, 2: "b", true: [3], "a": 4}`,
+		"if (1 > 2) { 10 }",
+		"if (null) { 10 } else { let x = 5; x * 2 }",
+		"1 < 2 ? \"yes\" : \"no\"",
+		"null ?? false ?? 3",
+		"let a = 5; let b = a; let a = 7; a + b",
+		"let x = 1;",
+		"",
+		"let identity = (x) => x; identity(5)",
+		"let add = function(x, y) { x + y; }; add(5 + 5, add(5, 5))",
+		"function(x) { x; }(5)",
+		"9; return 2 * 5; 9;",
+		"if (10 > 1) { if (10 > 1) { return 10; } return 1; }",
+		"let f = (x) => { if (x) { return [x] }; [] }; [f(1), f(false)]",
+		"let f = (x) => { if (x) { return 1 } else { return 2 } }; [f(true), f(null)]",
+		"let f = (x) => { let y = x ? 1 : 2; [y, x ?? 3] }; [f(true), f(null)]",
+		"let f = (n) => { let n = n * 2; n }; f(4)",
+		"let f = (x) => [x, if (x) { let x = 5; x } else { 0 }, x]; [f(1), f(false)]",
+		"let f = () => { let x = 1; let r = [x, (() => { let x = 2; x })(), x]; r }; f()",
+		"let fib = (n) => n < 2 ? n : fib(n - 1) + fib(n - 2); fib(15)",
+		"let newAdder = (a) => (b) => a + b; let addTwo = newAdder(2); addTwo(3)",
+		"let f = (a) => (b) => (c) => a * 100 + b * 10 + c; f(1)(2)(3)",
+		"let f = () => { let x = 1; let g = () => x; let x = 2; g() }; f()",
+		"let f = () => { let g = () => x; let x = 5; g() }; f()",
+		`let f = () => {
+			let even = (n) => n == 0 ? true : odd(n - 1);
+			let odd = (n) => n == 0 ? false : even(n - 1);
+			[even(10), odd(7)]
+		};
+		f()`,
+		"let g = 10; let f = () => g; let g = 20; f()",
+		"let f = (x) => { if (x) { let y = 1 }; y }; [f(true), f(false)]",
+		"5 + true; 5;",
+		"-true",
+		`"a" - "b"`,
+		"let f = (a) => a; f(1, 2)",
+		"let f = (a, b) => a; f(1)",
+		"1(2)",
+		"{[1]: 2}",
+		"10 / (5 - 5)",
+		"let f = () => { x }; let r = f(); let x = 1;",
+	}
+
+	for _, input := range inputs {
+		expected := testutil.Evaluate(input)
+
+		program, err := Lower(testutil.Parse(input))
+		if err != nil {
+			t.Errorf("lowering error for %q: %s", input, err)
+			continue
+		}
+		if actual := run(program); actual != expected {
+			t.Errorf("results differ for %q before SSA. evaluator=%q, ir=%q", input, expected, actual)
+		}
+
+		program.ToSSA()
+		if err := program.Verify(); err != nil {
+			t.Errorf("invalid IR of %q: %s\n%s", input, err, program)
+		}
+		if actual := run(program); actual != expected {
+			t.Errorf("results differ for %q. evaluator=%q, ir=%q\n%s", input, expected, actual, program)
+		}
+	}
+}
+
+// cell - cell of the captured variable in the interpreter
+type cell struct {
+	value object.Object
+}
+
+func (c *cell) Type() object.ObjectType { return "CELL" }
+func (c *cell) Inspect() string         { return "cell" }
+
+// run - interprets the program with the runtime of the Go backend
+func run(program *Program) string {
+	result, err := rt.Run(func() object.Object {
+		return interpret(program.Main(), nil, nil)
+	})
+	if err != nil {
+		return err.Error()
+	}
+	if result == nil {
+		return ""
+	}
+	return result.Inspect()
+}
+
+func interpret(f *Function, args []object.Object, free []object.Object) object.Object {
+	values := map[*Value]object.Object{}
+	variables := map[string]object.Object{}
+	arg := func(v *Value, idx int) object.Object { return values[v.Args[idx]] }
+
+	var prev *Block
+	for b := f.Entry(); ; {
+		for _, v := range b.Values {
+			operands := []object.Object{}
+			for idx := range v.Args {
+				operands = append(operands, arg(v, idx))
+			}
+
+			var result object.Object
+			switch v.Op {
+			case OpConst:
+				result = v.Const
+			case OpParam:
+				result = args[v.Index]
+			case OpFree:
+				result = free[v.Index]
+			case OpUndef:
+			case OpCheck:
+				result = rt.Load(operands[0], v.Name)
+			case OpGet:
+				result = rt.Load(variables[v.Name], v.Name)
+			case OpSet:
+				variables[v.Name] = operands[0]
+			case OpCell:
+				result = &cell{}
+			case OpLoad:
+				result = rt.Load(operands[0].(*cell).value, v.Name)
+			case OpStore:
+				operands[0].(*cell).value = operands[1]
+			case OpNeg:
+				result = rt.Neg(operands[0])
+			case OpNot:
+				result = rt.Not(operands[0])
+			case OpAdd:
+				result = rt.Add(operands[0], operands[1])
+			case OpSub:
+				result = rt.Sub(operands[0], operands[1])
+			case OpMul:
+				result = rt.Mul(operands[0], operands[1])
+			case OpDiv:
+				result = rt.Div(operands[0], operands[1])
+			case OpLess:
+				result = rt.Less(operands[0], operands[1])
+			case OpGreater:
+				result = rt.Greater(operands[0], operands[1])
+			case OpEqual:
+				result = rt.Equal(operands[0], operands[1])
+			case OpNotEqual:
+				result = rt.NotEqual(operands[0], operands[1])
+			case OpArray:
+				result = rt.Array(operands...)
+			case OpHash:
+				for idx := 0; idx < len(operands); idx += 2 {
+					operands[idx] = rt.Key(operands[idx])
+				}
+				result = rt.Hash(operands...)
+			case OpClosure:
+				fn := v.Fn
+				result = rt.NewFunction(fn.Parameters, func(args []object.Object) object.Object {
+					return interpret(fn, args, operands)
+				})
+			case OpCall:
+				result = rt.Call(operands[0], operands[1:]...)
+			case OpPhi:
+				for idx, pred := range b.Preds {
+					if pred == prev {
+						result = operands[idx]
+					}
+				}
+			default:
+				panic(fmt.Sprintf("unknown operation %s", v.Op))
+			}
+			values[v] = result
+		}
+
+		prev = b
+		switch b.Kind {
+		case BlockJump:
+			b = b.Succs[0]
+		case BlockBranch:
+			if rt.Truthy(values[b.Control]) {
+				b = b.Succs[0]
+			} else {
+				b = b.Succs[1]
+			}
+		case BlockReturn:
+			if b.Control == nil {
+				return nil
+			}
+			return values[b.Control]
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package ir
+
+import "fmt"
+
+// Verify - checks that the program is well formed: blocks end with
+// control transfer matching their successors, predecessors match
+// successors, operands are values of the function. Functions in SSA
+// form have no variables, phis have operand for every predecessor and
+// every value dominates its uses
+func (p *Program) Verify() error {
+	for _, f := range p.Functions {
+		if err := f.verify(); err != nil {
+			return fmt.Errorf("%s: %s", f.Name, err)
+		}
+	}
+	return nil
+}
+
+// successors - number of successors of the blocks of the kind
+var successors = map[BlockKind]int{
+	BlockJump:   1,
+	BlockBranch: 2,
+	BlockReturn: 0,
+}
+
+func (f *Function) verify() error {
+	if len(f.Blocks) == 0 {
+		return fmt.Errorf("function has no blocks")
+	}
+	if len(f.Entry().Preds) > 0 {
+		return fmt.Errorf("entry block has predecessors")
+	}
+
+	blocks := map[*Block]bool{}
+	for _, b := range f.Blocks {
+		blocks[b] = true
+	}
+
+	// positions of the values in their blocks
+	positions := map[*Value]int{}
+	for _, b := range f.Blocks {
+		if b.Func != f {
+			return fmt.Errorf("%s: block of other function", b)
+		}
+		if count, ok := successors[b.Kind]; !ok || len(b.Succs) != count {
+			return fmt.Errorf("%s: wrong number of successors: %d", b, len(b.Succs))
+		}
+		if b.Kind == BlockBranch && b.Control == nil {
+			return fmt.Errorf("%s: branch without condition", b)
+		}
+		if b.Kind == BlockJump && b.Control != nil {
+			return fmt.Errorf("%s: jump with control value", b)
+		}
+
+		for _, succ := range b.Succs {
+			if !blocks[succ] {
+				return fmt.Errorf("%s: successor %s is not in the function", b, succ)
+			}
+			if countBlock(succ.Preds, b) != countBlock(b.Succs, succ) {
+				return fmt.Errorf("%s: missing predecessor of %s", b, succ)
+			}
+		}
+		for _, pred := range b.Preds {
+			if !blocks[pred] || countBlock(pred.Succs, b) == 0 {
+				return fmt.Errorf("%s: %s is not a predecessor", b, pred)
+			}
+		}
+
+		for idx, v := range b.Values {
+			if _, ok := positions[v]; ok {
+				return fmt.Errorf("%s: %s is repeated", b, v)
+			}
+			if v.Block != b {
+				return fmt.Errorf("%s: %s belongs to other block", b, v)
+			}
+			positions[v] = idx
+		}
+	}
+
+	var idom []*Block
+	if f.SSA {
+		idom = f.dominators()
+	}
+
+	// defined - checks that the value is defined before the end of the
+	// block (before the position in the block if it's not negative)
+	defined := func(v *Value, b *Block, position int) error {
+		idx, ok := positions[v]
+		if !ok {
+			return fmt.Errorf("%s is not in the function", v)
+		}
+		if !v.Op.HasResult() {
+			return fmt.Errorf("%s has no result", v)
+		}
+		if !f.SSA {
+			return nil
+		}
+		if v.Block == b && position >= 0 && idx >= position {
+			return fmt.Errorf("%s is used before its definition", v)
+		}
+		if !dominates(idom, v.Block, b) {
+			return fmt.Errorf("%s doesn't dominate its use", v)
+		}
+		return nil
+	}
+
+	for _, b := range f.Blocks {
+		phis := true
+		for idx, v := range b.Values {
+			switch {
+			case v.Op == OpPhi && !f.SSA:
+				return fmt.Errorf("%s: phi before conversion into SSA", b)
+			case v.Op == OpPhi && !phis:
+				return fmt.Errorf("%s: phi %s follows other values", b, v)
+			case v.Op == OpPhi && len(v.Args) != len(b.Preds):
+				return fmt.Errorf("%s: phi %s has %d operands, the block has %d predecessors",
+					b, v, len(v.Args), len(b.Preds))
+			case (v.Op == OpGet || v.Op == OpSet) && f.SSA:
+				return fmt.Errorf("%s: variable %s in SSA form", b, v.Name)
+			}
+			phis = phis && v.Op == OpPhi
+
+			for argIdx, arg := range v.Args {
+				if arg == nil {
+					return fmt.Errorf("%s: %s has no operand %d", b, v, argIdx)
+				}
+
+				var err error
+				if v.Op == OpPhi {
+					// the operand is used at the end of the predecessor
+					err = defined(arg, b.Preds[argIdx], -1)
+				} else {
+					err = defined(arg, b, idx)
+				}
+				if err != nil {
+					return fmt.Errorf("%s: operand of %s: %s", b, v, err)
+				}
+			}
+		}
+
+		if b.Control != nil {
+			if err := defined(b.Control, b, -1); err != nil {
+				return fmt.Errorf("%s: control: %s", b, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// countBlock - returns how many times the block is in the blocks
+func countBlock(blocks []*Block, b *Block) int {
+	count := 0
+	for _, block := range blocks {
+		if block == b {
+			count++
+		}
+	}
+	return count
+}
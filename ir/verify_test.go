@@ -0,0 +1,109 @@
+package ir
+
+import (
+	"testing"
+
+	"github.com/technoboom/compiler/internal/testutil"
+)
+
+func TestVerify(t *testing.T) {
+	input := "let f = (x) => { let y = x ? 1 : 2; y + x }"
+
+	tests := []struct {
+		name          string
+		ssa           bool
+		breakIR       func(f *Function)
+		expectedError string
+	}{
+		{
+			"missing successor",
+			true,
+			func(f *Function) { f.Blocks[1].Succs = nil },
+			"f: b1: wrong number of successors: 0",
+		},
+		{
+			"missing predecessor",
+			true,
+			func(f *Function) { f.Blocks[3].Preds = f.Blocks[3].Preds[:1] },
+			"f: b2: missing predecessor of b3",
+		},
+		{
+			"phi arity",
+			true,
+			func(f *Function) { f.Blocks[3].Values[0].Args = f.Blocks[3].Values[0].Args[:1] },
+			"f: b3: phi v3 has 1 operands, the block has 2 predecessors",
+		},
+		{
+			"phi order",
+			true,
+			func(f *Function) {
+				b := f.Blocks[3]
+				phi := &Value{ID: 50, Op: OpPhi, Args: b.Values[0].Args, Block: b}
+				b.Values = append(b.Values, phi)
+			},
+			"f: b3: phi v50 follows other values",
+		},
+		{
+			"dominance",
+			true,
+			func(f *Function) { f.Blocks[3].Values[1].Args[0] = f.Blocks[1].Values[0] },
+			"f: b3: operand of v4: v1 doesn't dominate its use",
+		},
+		{
+			"use before definition",
+			true,
+			func(f *Function) {
+				b := f.Blocks[3]
+				b.Values[1].Args[1] = b.Values[1]
+			},
+			"f: b3: operand of v4: v4 is used before its definition",
+		},
+		{
+			"operand of other function",
+			true,
+			func(f *Function) { f.Blocks[3].Values[1].Args[1] = &Value{ID: 100, Op: OpConst} },
+			"f: b3: operand of v4: v100 is not in the function",
+		},
+		{
+			"variable in SSA",
+			true,
+			func(f *Function) { f.Blocks[0].Values[0].Op = OpGet },
+			"f: b0: variable x in SSA form",
+		},
+		{
+			"operand without result",
+			false,
+			func(f *Function) { f.Blocks[0].Values[2].Args = []*Value{f.Blocks[0].Values[1]} },
+			"f: b0: operand of v2: v1 has no result",
+		},
+		{
+			"phi before SSA",
+			false,
+			func(f *Function) { f.Blocks[0].Values[0].Op = OpPhi },
+			"f: b0: phi before conversion into SSA",
+		},
+	}
+
+	for _, tt := range tests {
+		program, err := Lower(testutil.Parse(input))
+		if err != nil {
+			t.Fatalf("lowering error: %s", err)
+		}
+		if tt.ssa {
+			program.ToSSA()
+		}
+		if err := program.Verify(); err != nil {
+			t.Fatalf("%s: invalid IR before the change: %s", tt.name, err)
+		}
+
+		tt.breakIR(program.Functions[1])
+		err = program.Verify()
+		if err == nil {
+			t.Errorf("%s: expected error, got none\n%s", tt.name, program)
+			continue
+		}
+		if err.Error() != tt.expectedError {
+			t.Errorf("%s: wrong error. want=%q, got=%q", tt.name, tt.expectedError, err.Error())
+		}
+	}
+}
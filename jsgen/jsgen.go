@@ -0,0 +1,1023 @@
+// Package jsgen - translates Beaver programs into readable JavaScript
+// (ES2020) with source maps pointing back to the Beaver source
+package jsgen
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/technoboom/compiler/ast"
+	"github.com/technoboom/compiler/object"
+	"github.com/technoboom/compiler/token"
+)
+
+// Result - generated JavaScript and its source map
+type Result struct {
+	Code string
+	Map  *SourceMap
+}
+
+// builtins - builtin functions implemented by the runtime
+var builtins = map[string]string{
+	"puts": "$bv.puts",
+}
+
+// scope - bindings of the function being generated (or of the program)
+type scope struct {
+	outer *scope
+
+	// JavaScript names of the bindings
+	locals map[string]string
+	// bindings which surely have a value at the point being generated,
+	// they are read without the check of unbound variables
+	bound map[string]bool
+
+	out    *bytes.Buffer
+	indent int
+}
+
+// generator - keeps the scope being generated and positions of the nodes
+// in the generated code
+type generator struct {
+	scope    *scope
+	numTemps int
+
+	marks []mark
+	names []string
+}
+
+// mark - position in the source of the node marked in the generated code
+type mark struct {
+	line   int
+	column int
+	name   int
+}
+
+// markerStart, markerEnd - enclose index of the mark in the generated
+// code, they are removed when the mappings are built. The bytes can't
+// appear in the code otherwise, strings escape control characters
+const (
+	markerStart = '\x00'
+	markerEnd   = '\x01'
+)
+
+// indentation - indentation of the generated code
+const indentation = "  "
+
+// Generate - returns JavaScript of the program and the source map, which
+// refers to the source by the name. The program prints its result
+// (or the runtime error) to the console. Programs using features without
+// JavaScript support are rejected with an error
+func Generate(program *ast.Program, source string) (*Result, error) {
+	// names are always an array in the source map, even if empty
+	g := &generator{names: []string{}}
+
+	main := newScope(nil, 1)
+	g.scope = main
+	lets := g.declare(collectLets(program.Statements))
+
+	if _, err := g.generateStatements(program.Statements, true); err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	out.WriteString("// Code generated by beaver build -emit=js. DO NOT EDIT.\n")
+	out.WriteString("\"use strict\";\n\n")
+	out.WriteString(runtime)
+	out.WriteString("\n$bv.main(() => {\n")
+	out.WriteString(lets)
+	out.WriteString(main.out.String())
+	out.WriteString("});\n")
+
+	code, mappings := g.resolveMarks(out.String())
+
+	return &Result{
+		Code: code,
+		Map: &SourceMap{
+			Version:  3,
+			Sources:  []string{source},
+			Names:    g.names,
+			Mappings: encodeMappings(mappings),
+		},
+	}, nil
+}
+
+// generateStatements - generates statements of the block. In the tail
+// position value of the last expression is returned from the function,
+// otherwise JavaScript expression with the value is returned (empty if
+// the block doesn't end with expression). Statements following return
+// are not generated
+func (g *generator) generateStatements(statements []ast.Statement, tail bool) (string, error) {
+	for idx, s := range statements {
+		last := idx == len(statements)-1
+
+		switch s := s.(type) {
+		case *ast.ExpressionStatement:
+			if last && tail {
+				return "", g.generateReturn(s.Expression)
+			}
+
+			value, err := g.generateExpression(s.Expression)
+			if err != nil {
+				return "", err
+			}
+			if last {
+				return value, nil
+			}
+			if !isLiteral(s.Expression) {
+				g.emit("%s%s;", g.mark(s.Token, ""), value)
+			}
+		case *ast.LetStatement:
+			if err := g.generateLetStatement(s); err != nil {
+				return "", err
+			}
+		case *ast.ReturnStatement:
+			value, err := g.generateExpression(s.ReturnValue)
+			if err != nil {
+				return "", err
+			}
+			g.emit("%sreturn %s;", g.mark(s.Token, ""), value)
+			return "", nil
+		default:
+			return "", fmt.Errorf("%T is not supported by JavaScript backend", s)
+		}
+	}
+
+	if tail {
+		g.emitEmptyReturn()
+	}
+	return "", nil
+}
+
+// emitEmptyReturn - ends the function which doesn't end with expression,
+// such program has no result and such function returns null
+func (g *generator) emitEmptyReturn() {
+	if g.scope.outer == nil {
+		g.emit("return;")
+		return
+	}
+	g.emit("return null;")
+}
+
+// generateReturn - returns value of the expression in the tail position,
+// conditionals with statements in branches become if statements
+// returning from every branch
+func (g *generator) generateReturn(node ast.Expression) error {
+	switch node := node.(type) {
+	case *ast.IfExpression:
+		if needsStatements(node) {
+			return g.generateTailConditional(node.Token, node.Condition, node.Consequence, node.Alternative)
+		}
+	case *ast.ConditionalExpression:
+		if needsStatements(node) {
+			return g.generateTailConditional(node.Token, node.Condition, node.Consequence, node.Alternative)
+		}
+	}
+
+	value, err := g.generateExpression(node)
+	if err != nil {
+		return err
+	}
+	g.emit("return %s;", value)
+	return nil
+}
+
+// generateTailConditional - generates conditional in the tail position
+func (g *generator) generateTailConditional(
+	tok token.Token,
+	condition ast.Expression,
+	consequence ast.Node,
+	alternative ast.Node,
+) error {
+	cond, err := g.generateCondition(condition)
+	if err != nil {
+		return err
+	}
+
+	g.emit("%sif (%s) {", g.mark(tok, ""), cond)
+	if err := g.generateTailBranch(consequence); err != nil {
+		return err
+	}
+	g.emit("} else {")
+	if err := g.generateTailBranch(alternative); err != nil {
+		return err
+	}
+	g.emit("}")
+	return nil
+}
+
+// generateTailBranch - generates block or expression of the conditional
+// in the tail position
+func (g *generator) generateTailBranch(node ast.Node) error {
+	g.scope.indent++
+	bound := g.scope.saveBound()
+	defer func() {
+		g.scope.indent--
+		g.scope.bound = bound
+	}()
+
+	switch node := node.(type) {
+	case *ast.BlockStatement:
+		if node == nil {
+			g.emit("return null;")
+			return nil
+		}
+		_, err := g.generateStatements(node.Statements, true)
+		return err
+	case ast.Expression:
+		return g.generateReturn(node)
+	default:
+		g.emit("return null;")
+		return nil
+	}
+}
+
+// generateLetStatement - assigns the value to the variable of the name
+func (g *generator) generateLetStatement(node *ast.LetStatement) error {
+	ident, ok := node.Pattern.(*ast.Identifier)
+	if !ok {
+		return fmt.Errorf("destructuring is not supported by JavaScript backend")
+	}
+
+	variable, owner, ok := g.scope.resolve(ident.Value)
+	if !ok {
+		return fmt.Errorf("identifier not found: %s", ident.Value)
+	}
+
+	// the function can't be called before it's assigned,
+	// so it refers to itself without the check
+	if _, ok := node.Value.(*ast.FunctionLiteral); ok && owner == g.scope {
+		owner.bound[ident.Value] = true
+	}
+
+	value, err := g.generateExpression(node.Value)
+	if err != nil {
+		return err
+	}
+	g.emit("%s%s%s = %s;", g.mark(node.Token, ""), g.mark(ident.Token, ident.Value), variable, value)
+	owner.bound[ident.Value] = true
+	return nil
+}
+
+// generateExpression - returns JavaScript expression evaluating the
+// expression, conditionals with statements are generated as statements
+// in the order of the evaluator
+func (g *generator) generateExpression(node ast.Expression) (string, error) {
+	value, err := g.generateNode(node)
+	if err != nil {
+		return "", err
+	}
+	return g.mark(nodeToken(node), "") + value, nil
+}
+
+// generateNode - returns JavaScript expression of the node (without the mark)
+func (g *generator) generateNode(node ast.Expression) (string, error) {
+	switch node := node.(type) {
+	case *ast.IntegerLiteral:
+		return strconv.FormatInt(node.Value, 10) + "n", nil
+	case *ast.StringLiteral:
+		return quote(node.Value), nil
+	case *ast.Boolean:
+		return strconv.FormatBool(node.Value), nil
+	case *ast.NullLiteral:
+		return "null", nil
+	case *ast.Identifier:
+		variable, owner, ok := g.scope.resolve(node.Value)
+		if !ok {
+			if builtin, ok := builtins[node.Value]; ok {
+				return g.mark(node.Token, node.Value) + builtin, nil
+			}
+			if _, ok := object.GetBuiltinByName(node.Value); ok {
+				return "", fmt.Errorf("builtin %s is not supported by JavaScript backend", node.Value)
+			}
+			// unknown name fails only when it's evaluated, like in the evaluator
+			return fmt.Sprintf("$bv.load(%sundefined, %s)", g.mark(node.Token, node.Value), quote(node.Value)), nil
+		}
+		name := g.mark(node.Token, node.Value)
+		if owner.bound[node.Value] {
+			return name + variable, nil
+		}
+		return fmt.Sprintf("$bv.load(%s%s, %s)", name, variable, quote(node.Value)), nil
+	case *ast.PrefixExpression:
+		return g.generatePrefixExpression(node)
+	case *ast.InfixExpression:
+		return g.generateInfixExpression(node)
+	case *ast.IfExpression:
+		return g.generateConditional(node.Condition, node.Consequence, node.Alternative)
+	case *ast.ConditionalExpression:
+		return g.generateConditional(node.Condition, node.Consequence, node.Alternative)
+	case *ast.ArrayLiteral:
+		elements, err := g.generateExpressions(node.Elements)
+		if err != nil {
+			return "", err
+		}
+		return "[" + strings.Join(elements, ", ") + "]", nil
+	case *ast.HashLiteral:
+		return g.generateHashLiteral(node)
+	case *ast.FunctionLiteral:
+		return g.generateFunctionLiteral(node)
+	case *ast.CallExpression:
+		if _, ok := node.Function.(*ast.MemberExpression); ok {
+			return "", fmt.Errorf("method calls are not supported by JavaScript backend")
+		}
+		values, err := g.generateExpressions(append([]ast.Expression{node.Function}, node.Arguments...))
+		if err != nil {
+			return "", err
+		}
+		return "$bv.call(" + strings.Join(values, ", ") + ")", nil
+	default:
+		return "", fmt.Errorf("%T is not supported by JavaScript backend", node)
+	}
+}
+
+// generatePrefixExpression - applies prefix operator, negative integer
+// literals are written as is
+func (g *generator) generatePrefixExpression(node *ast.PrefixExpression) (string, error) {
+	switch node.Operator {
+	case "!":
+		if isBoolean(node.Right) {
+			right, err := g.generateOperand(node.Right)
+			return "!" + right, err
+		}
+		right, err := g.generateExpression(node.Right)
+		return "!$bv.truthy(" + right + ")", err
+	case "-":
+		if literal, ok := node.Right.(*ast.IntegerLiteral); ok {
+			return strconv.FormatInt(-literal.Value, 10) + "n", nil
+		}
+		right, err := g.generateExpression(node.Right)
+		return "$bv.neg(" + right + ")", err
+	default:
+		return "", fmt.Errorf("unknown operator %s", node.Operator)
+	}
+}
+
+// infixFunctions - functions of the runtime applying infix operators
+var infixFunctions = map[string]string{
+	"+": "add",
+	"-": "sub",
+	"*": "mul",
+	"/": "div",
+	"<": "lt",
+	">": "gt",
+}
+
+// generateInfixExpression - applies infix operator. Equality of values
+// follows JavaScript strict equality, the right operand of `??` is
+// evaluated only if the left one is null
+func (g *generator) generateInfixExpression(node *ast.InfixExpression) (string, error) {
+	switch node.Operator {
+	case "??":
+		return g.generateNullish(node)
+	case "==", "!=":
+		if needsStatements(node.Right) {
+			values, err := g.generateExpressions([]ast.Expression{node.Left, node.Right})
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("%s %s= %s", values[0], node.Operator, values[1]), nil
+		}
+		left, err := g.generateOperand(node.Left)
+		if err != nil {
+			return "", err
+		}
+		right, err := g.generateOperand(node.Right)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s %s= %s", left, node.Operator, right), nil
+	}
+
+	fn, ok := infixFunctions[node.Operator]
+	if !ok {
+		return "", fmt.Errorf("unknown operator %s", node.Operator)
+	}
+
+	values, err := g.generateExpressions([]ast.Expression{node.Left, node.Right})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("$bv.%s(%s, %s)", fn, values[0], values[1]), nil
+}
+
+// generateNullish - generates `??`, the right operand becomes function
+// called if the left operand is null
+func (g *generator) generateNullish(node *ast.InfixExpression) (string, error) {
+	left, err := g.generateExpression(node.Left)
+	if err != nil {
+		return "", err
+	}
+
+	if !needsStatements(node.Right) {
+		right, err := g.generateExpression(node.Right)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("$bv.coalesce(%s, () => %s)", left, right), nil
+	}
+
+	result := g.newTemp()
+	g.emit("let %s = %s;", result, left)
+	g.emit("if (%s === null) {", result)
+	g.scope.indent++
+	bound := g.scope.saveBound()
+	right, err := g.generateExpression(node.Right)
+	if err != nil {
+		return "", err
+	}
+	g.emit("%s = %s;", result, right)
+	g.scope.bound = bound
+	g.scope.indent--
+	g.emit("}")
+	return result, nil
+}
+
+// generateConditional - generates if/else and ternary expressions,
+// missing alternative produces null. Branches without statements
+// become ternary expression
+func (g *generator) generateConditional(
+	condition ast.Expression,
+	consequence ast.Node,
+	alternative ast.Node,
+) (string, error) {
+	cond, err := g.generateCondition(condition)
+	if err != nil {
+		return "", err
+	}
+
+	if !needsStatements(consequence) && !needsStatements(alternative) {
+		cons, err := g.generateBranchOperand(consequence)
+		if err != nil {
+			return "", err
+		}
+		alt, err := g.generateBranchOperand(alternative)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s ? %s : %s", cond, cons, alt), nil
+	}
+
+	result := g.newTemp()
+	g.emit("let %s;", result)
+	g.emit("if (%s) {", cond)
+	if err := g.generateBranch(result, consequence); err != nil {
+		return "", err
+	}
+	g.emit("} else {")
+	if err := g.generateBranch(result, alternative); err != nil {
+		return "", err
+	}
+	g.emit("}")
+	return result, nil
+}
+
+// generateBranchOperand - returns expression of the branch which
+// has no statements
+func (g *generator) generateBranchOperand(node ast.Node) (string, error) {
+	switch node := node.(type) {
+	case *ast.BlockStatement:
+		if node == nil || len(node.Statements) == 0 {
+			return "null", nil
+		}
+		return g.generateOperand(node.Statements[0].(*ast.ExpressionStatement).Expression)
+	case ast.Expression:
+		return g.generateOperand(node)
+	default:
+		return "null", nil
+	}
+}
+
+// generateBranch - generates block or expression of the conditional
+// and assigns its value to the result
+func (g *generator) generateBranch(result string, node ast.Node) error {
+	g.scope.indent++
+	bound := g.scope.saveBound()
+	defer func() {
+		g.scope.indent--
+		g.scope.bound = bound
+	}()
+
+	value := "null"
+	switch node := node.(type) {
+	case *ast.BlockStatement:
+		if node == nil {
+			break
+		}
+		v, err := g.generateStatements(node.Statements, false)
+		if err != nil {
+			return err
+		}
+		if v != "" {
+			value = v
+		}
+		if len(node.Statements) > 0 {
+			if _, ok := node.Statements[len(node.Statements)-1].(*ast.ReturnStatement); ok {
+				return nil
+			}
+		}
+	case ast.Expression:
+		v, err := g.generateExpression(node)
+		if err != nil {
+			return err
+		}
+		value = v
+	}
+
+	g.emit("%s = %s;", result, value)
+	return nil
+}
+
+// generateCondition - returns JavaScript condition of the expression
+func (g *generator) generateCondition(node ast.Expression) (string, error) {
+	if isBoolean(node) {
+		return g.generateOperand(node)
+	}
+	value, err := g.generateExpression(node)
+	return "$bv.truthy(" + value + ")", err
+}
+
+// generateOperand - returns expression which can be the operand
+// of JavaScript operator, equality and ternary get parentheses
+func (g *generator) generateOperand(node ast.Expression) (string, error) {
+	value, err := g.generateExpression(node)
+	if err != nil {
+		return "", err
+	}
+
+	switch node := node.(type) {
+	case *ast.InfixExpression:
+		if node.Operator == "==" || node.Operator == "!=" {
+			return "(" + value + ")", nil
+		}
+	case *ast.IfExpression, *ast.ConditionalExpression:
+		if !needsStatements(node) {
+			return "(" + value + ")", nil
+		}
+	}
+	return value, nil
+}
+
+// generateHashLiteral - creates Map of the pairs, keys which are not
+// literals are checked by the runtime
+func (g *generator) generateHashLiteral(node *ast.HashLiteral) (string, error) {
+	expressions := []ast.Expression{}
+	for _, pair := range node.Pairs {
+		if pair.Value == nil {
+			return "", fmt.Errorf("spread elements are not supported by JavaScript backend")
+		}
+		expressions = append(expressions, pair.Key, pair.Value)
+	}
+
+	values, err := g.generateExpressions(expressions)
+	if err != nil {
+		return "", err
+	}
+	if len(values) == 0 {
+		return "new Map()", nil
+	}
+
+	pairs := []string{}
+	for i := 0; i < len(values); i += 2 {
+		key := values[i]
+		switch expressions[i].(type) {
+		case *ast.IntegerLiteral, *ast.StringLiteral, *ast.Boolean:
+		default:
+			key = "$bv.key(" + key + ")"
+		}
+		pairs = append(pairs, "["+key+", "+values[i+1]+"]")
+	}
+	return "new Map([" + strings.Join(pairs, ", ") + "])", nil
+}
+
+// generateFunctionLiteral - generates arrow function, bodies which
+// are single expression are written without block
+func (g *generator) generateFunctionLiteral(node *ast.FunctionLiteral) (string, error) {
+	if len(node.Defaults) > 0 || node.Rest != nil {
+		return "", fmt.Errorf("default and rest parameters are not supported by JavaScript backend")
+	}
+
+	outer := g.scope
+	fn := newScope(outer, outer.indent+1)
+	g.scope = fn
+	defer func() { g.scope = outer }()
+
+	names := make([]string, len(node.Parameters))
+	params := make([]string, len(node.Parameters))
+	for idx, p := range node.Parameters {
+		names[idx] = quote(p.Value)
+		params[idx] = g.mark(p.Token, p.Value) + jsName(p.Value)
+		fn.locals[p.Value] = jsName(p.Value)
+		fn.bound[p.Value] = true
+	}
+	header := fmt.Sprintf("$bv.fn([%s], (%s) => ", strings.Join(names, ", "), strings.Join(params, ", "))
+
+	statements := node.Body.Statements
+	if len(statements) == 1 {
+		if s, ok := statements[0].(*ast.ExpressionStatement); ok && !needsStatements(s.Expression) {
+			body, err := g.generateOperand(s.Expression)
+			if err != nil {
+				return "", err
+			}
+			if _, ok := s.Expression.(*ast.FunctionLiteral); !ok {
+				body = g.mark(s.Token, "") + body
+			}
+			return header + body + ")", nil
+		}
+	}
+
+	lets := g.declare(collectLets(statements))
+	if _, err := g.generateStatements(statements, true); err != nil {
+		return "", err
+	}
+
+	return header + "{\n" + lets + fn.out.String() +
+		strings.Repeat(indentation, outer.indent) + "})", nil
+}
+
+// generateExpressions - generates expressions in order. Values of the
+// expressions preceding one with statements are stored in constants
+// (unless they are literals), so they are evaluated first
+func (g *generator) generateExpressions(expressions []ast.Expression) ([]string, error) {
+	values := []string{}
+
+	for idx, e := range expressions {
+		switch e.(type) {
+		case *ast.KeywordArgument:
+			return nil, fmt.Errorf("keyword arguments are not supported by JavaScript backend")
+		case *ast.SpreadElement:
+			return nil, fmt.Errorf("spread elements are not supported by JavaScript backend")
+		}
+
+		if needsStatements(e) {
+			for i, value := range values {
+				if !isLiteral(expressions[i]) {
+					values[i] = g.newTemp()
+					g.emit("const %s = %s;", values[i], value)
+				}
+			}
+		}
+
+		value, err := g.generateExpression(expressions[idx])
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+
+	return values, nil
+}
+
+// declare - adds variables of the bindings to the scope and returns their
+// declaration, the bindings are assigned by let statements
+func (g *generator) declare(names []string) string {
+	variables := []string{}
+	for _, name := range names {
+		if _, ok := g.scope.locals[name]; ok {
+			continue
+		}
+		g.scope.locals[name] = jsName(name)
+		variables = append(variables, jsName(name))
+	}
+
+	if len(variables) == 0 {
+		return ""
+	}
+	return strings.Repeat(indentation, g.scope.indent) + "let " + strings.Join(variables, ", ") + ";\n"
+}
+
+// emit - writes the line of JavaScript code into the current function
+func (g *generator) emit(format string, a ...interface{}) {
+	g.scope.out.WriteString(strings.Repeat(indentation, g.scope.indent))
+	g.scope.out.WriteString(fmt.Sprintf(format, a...))
+	g.scope.out.WriteString("\n")
+}
+
+// newTemp - returns name of the new temporary variable, names of the
+// bindings can't contain `$`, so they don't clash
+func (g *generator) newTemp() string {
+	g.numTemps++
+	return fmt.Sprintf("$t%d", g.numTemps)
+}
+
+// mark - returns marker of the position of the token, named marks refer
+// to the bindings
+func (g *generator) mark(tok token.Token, name string) string {
+	if tok.Line == 0 {
+		return ""
+	}
+
+	m := mark{line: tok.Line - 1, column: tok.Column - 1, name: -1}
+	if name != "" {
+		m.name = g.nameIndex(name)
+	}
+	g.marks = append(g.marks, m)
+
+	return string(markerStart) + strconv.Itoa(len(g.marks)-1) + string(markerEnd)
+}
+
+// nameIndex - returns index of the name in the names of the source map
+func (g *generator) nameIndex(name string) int {
+	for idx, n := range g.names {
+		if n == name {
+			return idx
+		}
+	}
+	g.names = append(g.names, name)
+	return len(g.names) - 1
+}
+
+// resolveMarks - removes marks from the code and returns positions
+// of the marks in the code
+func (g *generator) resolveMarks(code string) (string, []mapping) {
+	var out strings.Builder
+	mappings := []mapping{}
+	line, column := 0, 0
+
+	for i := 0; i < len(code); i++ {
+		switch c := code[i]; c {
+		case markerStart:
+			end := strings.IndexByte(code[i:], markerEnd) + i
+			idx, _ := strconv.Atoi(code[i+1 : end])
+			m := g.marks[idx]
+			mappings = append(mappings, mapping{
+				generatedLine:   line,
+				generatedColumn: column,
+				line:            m.line,
+				column:          m.column,
+				name:            m.name,
+			})
+			i = end
+		case '\n':
+			out.WriteByte(c)
+			line, column = line+1, 0
+		default:
+			out.WriteByte(c)
+			column++
+		}
+	}
+
+	return out.String(), mappings
+}
+
+// newScope - creates scope of the function nested into the outer one
+func newScope(outer *scope, indent int) *scope {
+	return &scope{
+		outer:  outer,
+		locals: map[string]string{},
+		bound:  map[string]bool{},
+		out:    &bytes.Buffer{},
+		indent: indent,
+	}
+}
+
+// resolve - returns JavaScript variable of the binding and the scope
+// which declares it
+func (s *scope) resolve(name string) (string, *scope, bool) {
+	if variable, ok := s.locals[name]; ok {
+		return variable, s, true
+	}
+	if s.outer == nil {
+		return "", nil, false
+	}
+	return s.outer.resolve(name)
+}
+
+// saveBound - returns copy of the bound bindings, bindings assigned
+// in the branch are restored after it
+func (s *scope) saveBound() map[string]bool {
+	bound := map[string]bool{}
+	for name, ok := range s.bound {
+		bound[name] = ok
+	}
+	return bound
+}
+
+// nodeToken - returns token of the expression
+func nodeToken(node ast.Expression) token.Token {
+	switch node := node.(type) {
+	case *ast.IntegerLiteral:
+		return node.Token
+	case *ast.StringLiteral:
+		return node.Token
+	case *ast.Boolean:
+		return node.Token
+	case *ast.NullLiteral:
+		return node.Token
+	case *ast.PrefixExpression:
+		return node.Token
+	case *ast.InfixExpression:
+		return node.Token
+	case *ast.IfExpression:
+		return node.Token
+	case *ast.ConditionalExpression:
+		return node.Token
+	case *ast.ArrayLiteral:
+		return node.Token
+	case *ast.HashLiteral:
+		return node.Token
+	case *ast.FunctionLiteral:
+		return node.Token
+	case *ast.CallExpression:
+		return node.Token
+	}
+	return token.Token{}
+}
+
+// isLiteral - checks if the expression is a literal without side effects
+func isLiteral(node ast.Expression) bool {
+	switch node.(type) {
+	case *ast.IntegerLiteral, *ast.StringLiteral, *ast.Boolean,
+		*ast.NullLiteral, *ast.FunctionLiteral:
+		return true
+	}
+	return false
+}
+
+// isBoolean - checks if the expression always produces boolean,
+// such expressions are used as JavaScript conditions as is
+func isBoolean(node ast.Expression) bool {
+	switch node := node.(type) {
+	case *ast.Boolean:
+		return true
+	case *ast.PrefixExpression:
+		return node.Operator == "!"
+	case *ast.InfixExpression:
+		switch node.Operator {
+		case "==", "!=", "<", ">":
+			return true
+		}
+	}
+	return false
+}
+
+// needsStatements - checks if generated code of the node has statements:
+// conditionals with lets, returns or several statements in branches.
+// Bodies of nested functions are not executed in place
+func needsStatements(node ast.Node) bool {
+	switch node := node.(type) {
+	case *ast.BlockStatement:
+		if node == nil || len(node.Statements) == 0 {
+			return false
+		}
+		if len(node.Statements) > 1 {
+			return true
+		}
+		s, ok := node.Statements[0].(*ast.ExpressionStatement)
+		return !ok || needsStatements(s.Expression)
+	case *ast.IfExpression:
+		return needsStatements(node.Condition) || needsStatements(node.Consequence) ||
+			(node.Alternative != nil && needsStatements(node.Alternative))
+	case *ast.ConditionalExpression:
+		return needsStatements(node.Condition) || needsStatements(node.Consequence) ||
+			needsStatements(node.Alternative)
+	case *ast.InfixExpression:
+		return needsStatements(node.Left) || needsStatements(node.Right)
+	case *ast.PrefixExpression:
+		return needsStatements(node.Right)
+	case *ast.CallExpression:
+		if needsStatements(node.Function) {
+			return true
+		}
+		for _, arg := range node.Arguments {
+			if needsStatements(arg) {
+				return true
+			}
+		}
+	case *ast.ArrayLiteral:
+		for _, e := range node.Elements {
+			if needsStatements(e) {
+				return true
+			}
+		}
+	case *ast.HashLiteral:
+		for _, pair := range node.Pairs {
+			if needsStatements(pair.Key) || needsStatements(pair.Value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// collectLets - returns names bound by let statements of the function
+// body (or the program) including nested blocks, but not nested functions
+func collectLets(statements []ast.Statement) []string {
+	names := []string{}
+	seen := map[string]bool{}
+
+	var walk func(node ast.Node)
+	walk = func(node ast.Node) {
+		switch node := node.(type) {
+		case *ast.LetStatement:
+			if ident, ok := node.Pattern.(*ast.Identifier); ok && !seen[ident.Value] {
+				seen[ident.Value] = true
+				names = append(names, ident.Value)
+			}
+			walk(node.Value)
+		case *ast.ExpressionStatement:
+			walk(node.Expression)
+		case *ast.ReturnStatement:
+			walk(node.ReturnValue)
+		case *ast.BlockStatement:
+			if node == nil {
+				return
+			}
+			for _, s := range node.Statements {
+				walk(s)
+			}
+		case *ast.IfExpression:
+			walk(node.Condition)
+			walk(node.Consequence)
+			walk(node.Alternative)
+		case *ast.ConditionalExpression:
+			walk(node.Condition)
+			walk(node.Consequence)
+			walk(node.Alternative)
+		case *ast.PrefixExpression:
+			walk(node.Right)
+		case *ast.InfixExpression:
+			walk(node.Left)
+			walk(node.Right)
+		case *ast.CallExpression:
+			walk(node.Function)
+			for _, arg := range node.Arguments {
+				walk(arg)
+			}
+		case *ast.ArrayLiteral:
+			for _, e := range node.Elements {
+				walk(e)
+			}
+		case *ast.HashLiteral:
+			for _, pair := range node.Pairs {
+				walk(pair.Key)
+				walk(pair.Value)
+			}
+		}
+	}
+
+	for _, s := range statements {
+		walk(s)
+	}
+	return names
+}
+
+// reserved - words which can't be JavaScript variables, and globals
+// used by the generated code
+var reserved = map[string]bool{
+	"arguments": true, "await": true, "break": true, "case": true, "catch": true,
+	"class": true, "const": true, "continue": true, "debugger": true, "default": true,
+	"delete": true, "do": true, "else": true, "enum": true, "eval": true,
+	"export": true, "extends": true, "false": true, "finally": true, "for": true,
+	"function": true, "if": true, "implements": true, "import": true, "in": true,
+	"instanceof": true, "interface": true, "let": true, "new": true, "null": true,
+	"package": true, "private": true, "protected": true, "public": true, "return": true,
+	"static": true, "super": true, "switch": true, "this": true, "throw": true,
+	"true": true, "try": true, "typeof": true, "var": true, "void": true,
+	"while": true, "with": true, "yield": true, "undefined": true, "NaN": true,
+	"Infinity": true, "Map": true,
+}
+
+// jsName - returns JavaScript variable of the binding, reserved words
+// get `$` suffix which Beaver names can't have
+func jsName(name string) string {
+	if reserved[name] {
+		return name + "$"
+	}
+	return name
+}
+
+// quote - returns JavaScript string literal of the string, control and
+// non-ASCII characters are escaped, so the code is ASCII
+func quote(s string) string {
+	var out strings.Builder
+	out.WriteByte('"')
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == '"' || r == '\\':
+			out.WriteByte('\\')
+			out.WriteRune(r)
+		case r == '\n':
+			out.WriteString(`\n`)
+		case r == utf8.RuneError && size == 1:
+			out.WriteString(fmt.Sprintf(`\x%02x`, s[i]))
+		case r < 0x20 || r >= 0x7f:
+			// characters outside the basic plane are written
+			// as surrogate pairs
+			if r > 0xffff {
+				r -= 0x10000
+				out.WriteString(fmt.Sprintf(`\u%04x\u%04x`, 0xd800+(r>>10), 0xdc00+(r&0x3ff)))
+			} else {
+				out.WriteString(fmt.Sprintf(`\u%04x`, r))
+			}
+		default:
+			out.WriteRune(r)
+		}
+		i += size
+	}
+	out.WriteByte('"')
+	return out.String()
+}
@@ -0,0 +1,347 @@
+package jsgen
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/technoboom/compiler/internal/testutil"
+)
+
+func TestGenerateErrors(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"let [a] = [1];", "destructuring is not supported by JavaScript backend"},
+		{"let f = (a) => a; f(a: 1)", "keyword arguments are not supported by JavaScript backend"},
+		{"[...[1]]", "spread elements are not supported by JavaScript backend"},
+		{"{...{}}", "spread elements are not supported by JavaScript backend"},
+		{`"a".upper()`, "method calls are not supported by JavaScript backend"},
+		{"(a = 1) => a", "default and rest parameters are not supported by JavaScript backend"},
+		{"throw 1", "*ast.ThrowStatement is not supported by JavaScript backend"},
+		{"struct P { x }", "*ast.StructStatement is not supported by JavaScript backend"},
+		{"tag(1)", "builtin tag is not supported by JavaScript backend"},
+	}
+
+	for _, tt := range tests {
+		_, err := Generate(testutil.Parse(tt.input), "input.bv")
+		if err == nil {
+			t.Errorf("expected error for %q, got none", tt.input)
+			continue
+		}
+		if err.Error() != tt.expectedError {
+			t.Errorf("wrong error for %q. want=%q, got=%q",
+				tt.input, tt.expectedError, err.Error())
+		}
+	}
+}
+
+func TestQuote(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"beaver", `"beaver"`},
+		{`a"b\c`, `"a\"b\\c"`},
+		{"multi\nline\t", `"multi\nline\u0009"`},
+		{"b\u00f3br", `"b\u00f3br"`},
+		{"\u2028", `"\u2028"`},
+		{"\U0001f9ab", `"\ud83e\uddab"`},
+		{"\xff", `"\xff"`},
+	}
+
+	for _, tt := range tests {
+		if actual := quote(tt.input); actual != tt.expected {
+			t.Errorf("wrong quote of %q. want=%s, got=%s", tt.input, tt.expected, actual)
+		}
+	}
+}
+
+func TestNames(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"fib", "fib"},
+		{"make_counter", "make_counter"},
+		{"new", "new$"},
+		{"arguments", "arguments$"},
+		{"undefined", "undefined$"},
+		{"Map", "Map$"},
+	}
+
+	for _, tt := range tests {
+		if actual := jsName(tt.name); actual != tt.expected {
+			t.Errorf("wrong name of %s. want=%s, got=%s", tt.name, tt.expected, actual)
+		}
+	}
+}
+
+// TestJSEquivalence - runs generated programs with node and checks that
+// they produce the same results and errors as the evaluator
+func TestJSEquivalence(t *testing.T) {
+	if _, err := exec.LookPath("node"); err != nil {
+		t.Skipf("node is not available: %s", err)
+	}
+
+	inputs := []string{
+		"5 + 5 * 2 - 10 / 5",
+		"-(3 * 3) + 50 / 2",
+		"-7 / 2",
+		"1 < 2 == true",
+		"!5; !!null",
+		"!(1 == 2)",
+		`"beaver"`,
+		"\"multi\nline\"",
+		`"bóbr"`,
+		`"a" == "a"`,
+		"1 == true",
+		"null == null",
+		"[1, \"two\", true, null, [3], {}]",
+		`{"a": 1, 2: "b", true: [3], "a": 4}`,
+		`let k = "a"; {k: 1, "b": k}`,
+		"if (1 > 2) { 10 }",
+		"if (null) { 10 } else { let x = 5; x * 2 }",
+		"[if (0) { 1 } else { 2 }, if (false) { 1 }]",
+		"1 < 2 ? \"yes\" : \"no\"",
+		"(1 < 2 ? 1 : 2) == 1",
+		"null ?? false ?? 3",
+		"null ?? (if (true) { let y = 4; y })",
+		"let a = 5; let b = a; let a = 7; a + b",
+		"let x = 1;",
+		"",
+		"let new = 1; let Map = 2; new + Map",
+		"let identity = (x) => x; identity(5)",
+		"let add = function(x, y) { x + y; }; add(5 + 5, add(5, 5))",
+		"function(x) { x; }(5)",
+		"9; return 2 * 5; 9;",
+		"if (10 > 1) { if (10 > 1) { return 10; } return 1; }",
+		"let f = (x) => { if (x) { return [x] }; [] }; [f(1), f(false)]",
+		"let f = (x) => { if (x) { return 1 } else { return 2 } }; [f(true), f(null)]",
+		"let f = (x) => { let y = x ? 1 : 2; [y, x ?? 3] }; [f(true), f(null)]",
+		"let f = (n) => { let n = n * 2; n }; f(4)",
+		"let f = (n) => { let n = 1; 2 }; f(4)",
+		"let f = (x) => [x, if (x) { let x = 5; x } else { 0 }, x]; f(1)",
+		"let f = () => { let x = 1; let r = [x, (() => { let x = 2; x })(), x]; r }; f()",
+		"let fib = (n) => n < 2 ? n : fib(n - 1) + fib(n - 2); fib(20)",
+		"let newAdder = (a) => (b) => a + b; let addTwo = newAdder(2); addTwo(3)",
+		"let make = (x) => () => x; let a = make(1); let b = make(2); [a(), b()]",
+		"let f = (a) => (b) => (c) => a * 100 + b * 10 + c; f(1)(2)(3)",
+		"let f = () => { let x = 1; let g = () => x; let x = 2; g() }; f()",
+		"let f = () => { let g = () => x; let x = 5; g() }; f()",
+		`let f = () => {
+			let even = (n) => n == 0 ? true : odd(n - 1);
+			let odd = (n) => n == 0 ? false : even(n - 1);
+			[even(10), odd(7)]
+		};
+		f()`,
+		"let g = 10; let f = () => g; let g = 20; f()",
+		"5 + true; 5;",
+		"-true",
+		`"a" - "b"`,
+		"let f = () => { 1 + null }; [1, f()]",
+		"let f = (a) => a; f(1, 2)",
+		"let f = (a, b) => a; f(1)",
+		"1(2)",
+		"{[1]: 2}",
+		"10 / (5 - 5)",
+		"let f = () => { x }; let r = f(); let x = 1;",
+		// unknown names fail only when they are evaluated
+		"a",
+		"let f = () => b; 1",
+		"if (false) { unknown }",
+		"let f = () => [1, b]; puts(1); f()",
+		// output of puts is followed by the result or the error
+		`puts(1, "two", [3], {"a": null}); puts()`,
+		"let f = (x) => { puts(x); x * 2 }; puts(f(1) + f(2))",
+		"puts; let p = puts; [p == puts, p(1)]",
+		"let puts = (x) => x * 2; puts(21)",
+		`puts("before"); -true`,
+	}
+
+	testJSEquivalence(t, inputs)
+}
+
+// TestJSIntegers - checks that integers above 2^53 keep their precision
+// and wrap around on overflow like in the evaluator
+func TestJSIntegers(t *testing.T) {
+	if _, err := exec.LookPath("node"); err != nil {
+		t.Skipf("node is not available: %s", err)
+	}
+
+	inputs := []string{
+		"9007199254740993",
+		"9007199254740992 + 1",
+		"[9007199254740993 == 9007199254740992, 9007199254740993 > 9007199254740992]",
+		"{9007199254740993: 1, 9007199254740992: 2}",
+		"9223372036854775807 + 1",
+		"-9223372036854775807 - 2",
+		"3037000500 * 3037000500",
+		"let min = -9223372036854775807 - 1; [min / -1, -min, min * -1, min / 7]",
+		"let f = (n, k) => k == 0 ? n : f(n * 1000003 - 7, k - 1); f(1, 50)",
+	}
+
+	testJSEquivalence(t, inputs)
+}
+
+// testJSEquivalence - runs generated programs with node and compares
+// their output with results of the evaluator
+func testJSEquivalence(t *testing.T, inputs []string) {
+	dir, err := ioutil.TempDir("", "jsgen")
+	if err != nil {
+		t.Fatalf("cannot create directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, input := range inputs {
+		result, err := Generate(testutil.Parse(input), "input.bv")
+		if err != nil {
+			t.Errorf("generation error for %q: %s", input, err)
+			continue
+		}
+
+		file := filepath.Join(dir, "program.js")
+		if err := ioutil.WriteFile(file, []byte(result.Code), 0644); err != nil {
+			t.Fatalf("cannot write file: %s", err)
+		}
+
+		// errors are printed to stderr
+		out, _ := exec.Command("node", file).CombinedOutput()
+		actual := strings.TrimSuffix(string(out), "\n")
+		if expected := testutil.Evaluate(input); actual != expected {
+			t.Errorf("results differ for %q. evaluator=%q, js=%q\n%s",
+				input, expected, actual, result.Code)
+		}
+	}
+}
+
+func TestSourceMap(t *testing.T) {
+	input := "let add = (a, b) => {\n  a + b\n};\nadd(1, 2)"
+
+	result, err := Generate(testutil.Parse(input), "add.bv")
+	if err != nil {
+		t.Fatalf("generation error: %s", err)
+	}
+
+	var decoded map[string]interface{}
+	data, err := result.Map.JSON()
+	if err != nil {
+		t.Fatalf("cannot encode source map: %s", err)
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("invalid JSON of source map: %s", err)
+	}
+	if decoded["version"] != 3.0 {
+		t.Errorf("wrong version. got=%v", decoded["version"])
+	}
+
+	if strings.ContainsAny(result.Code, "\x00\x01") {
+		t.Fatalf("code contains markers:\n%s", result.Code)
+	}
+
+	tests := []struct {
+		generated string
+		line      int
+		column    int
+		name      string
+	}{
+		{"$bv.add(", 1, 4, ""},
+		{"a, b) =>", 0, 11, "a"},
+		// calls are mapped to their parentheses
+		{"$bv.call(", 3, 3, ""},
+		{"add = $bv.fn", 0, 4, "add"},
+	}
+
+	segments := decodeMappings(t, result.Map.Mappings)
+	lines := strings.Split(result.Code, "\n")
+
+	for _, tt := range tests {
+		line, column := -1, -1
+		for idx, l := range lines {
+			if c := strings.Index(l, tt.generated); c >= 0 {
+				line, column = idx, c
+				break
+			}
+		}
+		if line < 0 {
+			t.Errorf("%q is not in the code:\n%s", tt.generated, result.Code)
+			continue
+		}
+
+		s, ok := segments[[2]int{line, column}]
+		if !ok {
+			t.Errorf("no mapping of %q at %d:%d", tt.generated, line, column)
+			continue
+		}
+		if s.line != tt.line || s.column != tt.column {
+			t.Errorf("wrong position of %q. want=%d:%d, got=%d:%d",
+				tt.generated, tt.line, tt.column, s.line, s.column)
+		}
+		name := ""
+		if s.name >= 0 {
+			name = result.Map.Names[s.name]
+		}
+		if name != tt.name {
+			t.Errorf("wrong name of %q. want=%q, got=%q", tt.generated, tt.name, name)
+		}
+	}
+}
+
+// decodeMappings - decodes mappings of the source map by their
+// generated positions
+func decodeMappings(t *testing.T, mappings string) map[[2]int]mapping {
+	segments := map[[2]int]mapping{}
+	column, line, sourceColumn, name := 0, 0, 0, 0
+
+	for generatedLine, l := range strings.Split(mappings, ";") {
+		column = 0
+		if l == "" {
+			continue
+		}
+		for _, segment := range strings.Split(l, ",") {
+			fields := decodeVLQ(t, segment)
+			if len(fields) != 4 && len(fields) != 5 {
+				t.Fatalf("wrong segment %q", segment)
+			}
+			column += fields[0]
+			line += fields[2]
+			sourceColumn += fields[3]
+			m := mapping{generatedLine, column, line, sourceColumn, -1}
+			if len(fields) == 5 {
+				name += fields[4]
+				m.name = name
+			}
+			segments[[2]int{generatedLine, column}] = m
+		}
+	}
+	return segments
+}
+
+func decodeVLQ(t *testing.T, segment string) []int {
+	values := []int{}
+	value, shift := 0, uint(0)
+
+	for _, c := range segment {
+		digit := strings.IndexRune(base64Digits, c)
+		if digit < 0 {
+			t.Fatalf("wrong digit in %q", segment)
+		}
+		value |= (digit & 31) << shift
+		shift += 5
+		if digit&32 != 0 {
+			continue
+		}
+		if value&1 == 1 {
+			values = append(values, -(value >> 1))
+		} else {
+			values = append(values, value>>1)
+		}
+		value, shift = 0, 0
+	}
+	return values
+}
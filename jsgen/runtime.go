@@ -0,0 +1,111 @@
+package jsgen
+
+// runtime - JavaScript runtime included into every generated program.
+// Integers are BigInts wrapped to 64 bits after every operation (numbers
+// lose precision above 2^53), hashes are Maps, functions keep names of their
+// parameters, builtins are marked as such, operators follow the rules and errors of the evaluator
+const runtime = `const $bv = (() => {
+  class BeaverError extends Error {
+    constructor(kind, message) {
+      super(message);
+      this.kind = kind;
+    }
+  }
+
+  const fail = (kind, message) => {
+    throw new BeaverError(kind, message);
+  };
+
+  const type = (value) => {
+    if (value === null) return "NULL";
+    switch (typeof value) {
+      case "boolean": return "BOOLEAN";
+      case "bigint": return "INTEGER";
+      case "string": return "STRING";
+      case "function": return value.builtin ? "BUILTIN" : "FUNCTION";
+    }
+    return Array.isArray(value) ? "ARRAY" : "HASH";
+  };
+
+  const integers = (operator, left, right) => {
+    if (typeof left === "bigint" && typeof right === "bigint") return;
+    const l = type(left), r = type(right);
+    if (l !== r) fail("TypeError", ` + "`type mismatch: ${l} ${operator} ${r}`" + `);
+    fail("TypeError", ` + "`unknown operator: ${l} ${operator} ${r}`" + `);
+  };
+
+  const inspect = (value) => {
+    switch (type(value)) {
+      case "NULL": return "null";
+      case "ARRAY": return "[" + value.map(inspect).join(", ") + "]";
+      case "HASH":
+        return "{" + Array.from(value, ([k, v]) => inspect(k) + ": " + inspect(v)).join(", ") + "}";
+      case "FUNCTION": return "function(" + value.params.join(", ") + ") { <native> }";
+      case "BUILTIN": return "builtin function";
+      default: return String(value);
+    }
+  };
+
+  // builtins take any number of arguments
+  const builtin = (body) => Object.assign(body, { builtin: true });
+
+  return {
+    fn(params, body) {
+      body.params = params;
+      return body;
+    },
+    call(fn, ...args) {
+      if (typeof fn !== "function") fail("TypeError", ` + "`not a function: ${type(fn)}`" + `);
+      if (fn.builtin) return fn(...args);
+      const want = fn.params.length;
+      if (args.length > want) {
+        fail("ArgumentError", ` + "`wrong number of arguments: want=${want}, got=${args.length}`" + `);
+      }
+      if (args.length < want) fail("ArgumentError", ` + "`missing argument: ${fn.params[args.length]}`" + `);
+      return fn(...args);
+    },
+    load(value, name) {
+      if (value === undefined) fail("NameError", ` + "`identifier not found: ${name}`" + `);
+      return value;
+    },
+    truthy: (value) => value !== null && value !== false,
+    coalesce: (value, otherwise) => value === null ? otherwise() : value,
+    neg(value) {
+      if (typeof value !== "bigint") fail("TypeError", ` + "`unknown operator: -${type(value)}`" + `);
+      return BigInt.asIntN(64, -value);
+    },
+    add: (l, r) => (integers("+", l, r), BigInt.asIntN(64, l + r)),
+    sub: (l, r) => (integers("-", l, r), BigInt.asIntN(64, l - r)),
+    mul: (l, r) => (integers("*", l, r), BigInt.asIntN(64, l * r)),
+    div(l, r) {
+      integers("/", l, r);
+      if (r === 0n) fail("Error", "division by zero");
+      // division of BigInts truncates, only MIN / -1 overflows
+      return BigInt.asIntN(64, l / r);
+    },
+    lt: (l, r) => (integers("<", l, r), l < r),
+    gt: (l, r) => (integers(">", l, r), l > r),
+    key(value) {
+      if (!["bigint", "string", "boolean"].includes(typeof value)) {
+        fail("TypeError", ` + "`unusable as hash key: ${type(value)}`" + `);
+      }
+      return value;
+    },
+    inspect,
+    puts: builtin((...args) => {
+      for (const arg of args) console.log(inspect(arg));
+      return null;
+    }),
+    main(program) {
+      try {
+        const result = program();
+        if (result !== undefined) console.log(inspect(result));
+      } catch (e) {
+        if (!(e instanceof BeaverError)) throw e;
+        console.error(` + "`${e.kind}: ${e.message}`" + `);
+        if (typeof process !== "undefined") process.exitCode = 1;
+      }
+    },
+  };
+})();
+`
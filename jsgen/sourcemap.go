@@ -0,0 +1,115 @@
+package jsgen
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+)
+
+// SourceMap - source map of the generated code in Source Map Revision 3
+// format, it maps positions in the generated code to Beaver sources
+type SourceMap struct {
+	Version int `json:"version"`
+	// name of the generated file
+	File           string   `json:"file,omitempty"`
+	Sources        []string `json:"sources"`
+	SourcesContent []string `json:"sourcesContent,omitempty"`
+	// names of the bindings referred by the mappings
+	Names []string `json:"names"`
+	// VLQ encoded mappings
+	Mappings string `json:"mappings"`
+}
+
+// JSON - returns the source map as JSON
+func (m *SourceMap) JSON() ([]byte, error) {
+	return json.Marshal(m)
+}
+
+// mapping - maps position in the generated code to position in the source,
+// all positions are zero-based
+type mapping struct {
+	generatedLine   int
+	generatedColumn int
+	line            int
+	column          int
+	// index in the names of the source map, -1 if the mapping has no name
+	name int
+}
+
+// base64Digits - digits of VLQ values
+const base64Digits = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+
+// encodeMappings - encodes the mappings of the only source into
+// `mappings` field: lines of the generated code are separated by `;`,
+// segments of the line by `,`, fields of the segments are relative
+// to the previous segment
+func encodeMappings(mappings []mapping) string {
+	sort.SliceStable(mappings, func(i, j int) bool {
+		if mappings[i].generatedLine != mappings[j].generatedLine {
+			return mappings[i].generatedLine < mappings[j].generatedLine
+		}
+		return mappings[i].generatedColumn < mappings[j].generatedColumn
+	})
+
+	// several nodes can start at the same position, the mapping
+	// of the innermost one (added last) is kept
+	unique := []mapping{}
+	for _, m := range mappings {
+		last := len(unique) - 1
+		if last >= 0 && unique[last].generatedLine == m.generatedLine &&
+			unique[last].generatedColumn == m.generatedColumn {
+			unique[last] = m
+			continue
+		}
+		unique = append(unique, m)
+	}
+
+	var out bytes.Buffer
+	line, column, sourceLine, sourceColumn, name := 0, 0, 0, 0, 0
+
+	for idx, m := range unique {
+		if m.generatedLine != line {
+			for ; line < m.generatedLine; line++ {
+				out.WriteByte(';')
+			}
+			column = 0
+		} else if idx > 0 {
+			out.WriteByte(',')
+		}
+
+		encodeVLQ(&out, m.generatedColumn-column)
+		// index of the source, there is only one
+		encodeVLQ(&out, 0)
+		encodeVLQ(&out, m.line-sourceLine)
+		encodeVLQ(&out, m.column-sourceColumn)
+		if m.name >= 0 {
+			encodeVLQ(&out, m.name-name)
+			name = m.name
+		}
+
+		column, sourceLine, sourceColumn = m.generatedColumn, m.line, m.column
+	}
+
+	return out.String()
+}
+
+// encodeVLQ - writes the value as base64 VLQ: sign is the lowest bit,
+// then groups of 5 bits from the lowest, the 6th bit marks continuation
+func encodeVLQ(out *bytes.Buffer, value int) {
+	v := value << 1
+	if value < 0 {
+		v = (-value << 1) | 1
+	}
+
+	for {
+		digit := v & 31
+		v >>= 5
+		if v > 0 {
+			digit |= 32
+		}
+		out.WriteByte(base64Digits[digit])
+		if v == 0 {
+			return
+		}
+	}
+}
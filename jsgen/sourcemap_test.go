@@ -0,0 +1,63 @@
+package jsgen
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/technoboom/compiler/internal/testutil"
+)
+
+func TestEncodeVLQ(t *testing.T) {
+	tests := []struct {
+		value    int
+		expected string
+	}{
+		{0, "A"},
+		{1, "C"},
+		{-1, "D"},
+		{15, "e"},
+		{16, "gB"},
+		{123, "2H"},
+		{-1000, "x+B"},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		encodeVLQ(&out, tt.value)
+		if out.String() != tt.expected {
+			t.Errorf("wrong VLQ of %d. want=%s, got=%s", tt.value, tt.expected, out.String())
+		}
+	}
+}
+
+func TestEncodeMappings(t *testing.T) {
+	mappings := []mapping{
+		{generatedLine: 2, generatedColumn: 4, line: 1, column: 2, name: -1},
+		{generatedLine: 0, generatedColumn: 0, line: 0, column: 0, name: -1},
+		{generatedLine: 0, generatedColumn: 6, line: 0, column: 4, name: -1},
+		// the innermost mapping at the same position is kept
+		{generatedLine: 0, generatedColumn: 6, line: 0, column: 4, name: 0},
+		{generatedLine: 2, generatedColumn: 10, line: 0, column: 0, name: 1},
+	}
+
+	expected := "AAAA,MAAIA;;IACF,MADFC"
+	if actual := encodeMappings(mappings); actual != expected {
+		t.Errorf("wrong mappings. want=%s, got=%s", expected, actual)
+	}
+}
+
+func TestSourceMapWithoutNames(t *testing.T) {
+	result, err := Generate(testutil.Parse("1 + 2"), "sum.bv")
+	if err != nil {
+		t.Fatalf("generation error: %s", err)
+	}
+
+	data, err := result.Map.JSON()
+	if err != nil {
+		t.Fatalf("cannot encode source map: %s", err)
+	}
+	if !strings.Contains(string(data), `"names":[]`) {
+		t.Errorf("names of the source map are not an empty array: %s", data)
+	}
+}
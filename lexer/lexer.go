@@ -8,12 +8,15 @@ type Lexer struct {
 	position     int  // current position in input (points to current char)
 	readPosition int  // current reading position in input (after current char)
 	character    byte // current char after examination
+	line         int  // current line in input (starting from 1)
+	lineStart    int  // position in input where current line starts
 }
 
 // New - creates new lexer with give input
 func New(input string) *Lexer {
 	l := &Lexer{
 		input: input,
+		line:  1,
 	}
 	l.readChar()
 	return l
@@ -23,6 +26,11 @@ func New(input string) *Lexer {
 // shifts readPosition at 1 position forward
 // if the readPosition out of input len, set character to 0
 func (l *Lexer) readChar() {
+	// moving past the line break starts the new line
+	if l.character == '\n' {
+		l.line++
+		l.lineStart = l.readPosition
+	}
 	// check if we not out of input len
 	if l.readPosition >= len(l.input) {
 		l.character = 0
@@ -40,6 +48,9 @@ func (l *Lexer) NextToken() token.Token {
 
 	l.skipWhitespace()
 
+	// position of the first character of the token
+	line, column := l.line, l.position-l.lineStart+1
+
 	switch l.character {
 	case '=':
 		// look ahead on 1 position to check if it's not the ==
@@ -50,6 +61,9 @@ func (l *Lexer) NextToken() token.Token {
 				Type:    token.EQ,
 				Literal: string(character) + string(l.character),
 			}
+		} else if l.pickChar() == '>' {
+			l.readChar()
+			tok = token.Token{Type: token.ARROW, Literal: "=>"}
 		} else {
 			tok = newToken(token.ASSIGN, l.character)
 		}
@@ -63,6 +77,10 @@ func (l *Lexer) NextToken() token.Token {
 		tok = newToken(token.LBRACKET, l.character)
 	case '}':
 		tok = newToken(token.RBRACKET, l.character)
+	case '[':
+		tok = newToken(token.LSQUARE, l.character)
+	case ']':
+		tok = newToken(token.RSQUARE, l.character)
 	case '+':
 		tok = newToken(token.PLUS, l.character)
 	case '-':
@@ -89,8 +107,36 @@ func (l *Lexer) NextToken() token.Token {
 		}
 	case ',':
 		tok = newToken(token.COMMA, l.character)
+	case ':':
+		tok = newToken(token.COLON, l.character)
+	case '?':
+		// look ahead on 1 position to check if it's not the ?? or ?.
+		if l.pickChar() == '?' {
+			l.readChar()
+			tok = token.Token{Type: token.NULLISH, Literal: "??"}
+		} else if l.pickChar() == '.' {
+			l.readChar()
+			tok = token.Token{Type: token.OPTIONAL_DOT, Literal: "?."}
+		} else {
+			tok = newToken(token.QUESTION, l.character)
+		}
+	case '.':
+		// look ahead on 2 positions to check if it's the ...
+		if l.pickChar() == '.' && l.pickCharAt(1) == '.' {
+			l.readChar()
+			l.readChar()
+			tok = token.Token{Type: token.ELLIPSIS, Literal: "..."}
+		} else {
+			tok = newToken(token.DOT, l.character)
+		}
 	case '|':
-		tok = newToken(token.OR, l.character)
+		// look ahead on 1 position to check if it's not the |>
+		if l.pickChar() == '>' {
+			l.readChar()
+			tok = token.Token{Type: token.PIPE, Literal: "|>"}
+		} else {
+			tok = newToken(token.OR, l.character)
+		}
 	case '"':
 		tok.Type = token.STRING
 		tok.Literal = l.readString()
@@ -102,18 +148,21 @@ func (l *Lexer) NextToken() token.Token {
 			// read keyword or identifier
 			tok.Literal = l.readIdentifier()
 			tok.Type = token.LookupIdent(tok.Literal)
+			tok.Line, tok.Column = line, column
 			return tok
 		}
 		if isDigit(l.character) {
 			// read number
 			tok.Type = token.INT
 			tok.Literal = l.readNumber()
+			tok.Line, tok.Column = line, column
 			return tok
 		}
 		// illegal token
 		tok = newToken(token.ILLEGAL, l.character)
 	}
 	l.readChar()
+	tok.Line, tok.Column = line, column
 	return tok
 }
 
@@ -169,6 +218,15 @@ func (l *Lexer) pickChar() byte {
 	return l.input[l.readPosition]
 }
 
+// pickCharAt - picks the character located offset positions after
+// the next one, returns 0 if it's out of input len
+func (l *Lexer) pickCharAt(offset int) byte {
+	if l.readPosition+offset >= len(l.input) {
+		return 0
+	}
+	return l.input[l.readPosition+offset]
+}
+
 // Checks if the character is English letter or underscore
 func isLetter(character byte) bool {
 	return ('a' <= character && character <= 'z') ||
@@ -9,6 +9,10 @@ import (
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "build" {
+		os.Exit(build(os.Args[2:], os.Stderr))
+	}
+
 	currentUser, err := user.Current()
 	if err != nil {
 		panic(err)
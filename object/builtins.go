@@ -0,0 +1,80 @@
+package object
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// Output - writer the puts builtin prints to
+var Output io.Writer = os.Stdout
+
+// Builtins - functions implemented by interpreter available in every
+// environment, compiled code refers to them by index in this list
+var Builtins = []struct {
+	Name    string
+	Builtin *Builtin
+}{
+	{"tag", &Builtin{Fn: builtinTag}},
+	{"implements", &Builtin{Fn: builtinImplements}},
+	{"puts", &Builtin{Fn: builtinPuts}},
+}
+
+// GetBuiltinByName - returns builtin function by its name
+func GetBuiltinByName(name string) (*Builtin, bool) {
+	for _, def := range Builtins {
+		if def.Name == name {
+			return def.Builtin, true
+		}
+	}
+	return nil, false
+}
+
+// builtinTag - returns name of the variant of the enum value
+func builtinTag(args ...Object) Object {
+	if len(args) != 1 {
+		return newError(ARGUMENT_ERROR,
+			"wrong number of arguments: want=1, got=%d", len(args))
+	}
+
+	variant, ok := args[0].(*Variant)
+	if !ok {
+		return newError(TYPE_ERROR,
+			"argument to tag must be enum variant, got %s", args[0].Type())
+	}
+
+	return &String{Value: variant.Definition.Name}
+}
+
+// builtinImplements - checks if the type of the value implements the trait
+func builtinImplements(args ...Object) Object {
+	if len(args) != 2 {
+		return newError(ARGUMENT_ERROR,
+			"wrong number of arguments: want=2, got=%d", len(args))
+	}
+
+	trait, ok := args[1].(*Trait)
+	if !ok {
+		return newError(TYPE_ERROR,
+			"argument to implements must be trait, got %s", args[1].Type())
+	}
+
+	impl := ImplementationOf(args[0])
+	if impl != nil && impl.Implements(trait) {
+		return TRUE
+	}
+	return FALSE
+}
+
+// builtinPuts - prints the arguments on separate lines, returns null
+func builtinPuts(args ...Object) Object {
+	for _, arg := range args {
+		fmt.Fprintln(Output, arg.Inspect())
+	}
+	return NULL
+}
+
+// newError - creates error object of the kind with formatted message
+func newError(kind string, format string, a ...interface{}) *Error {
+	return &Error{Kind: kind, Message: fmt.Sprintf(format, a...)}
+}
@@ -1,38 +1,90 @@
 package object
 
-// NewEnclosedEnvironment - creates new enclosed environment
-func NewEnclosedEnvironment(outer *Environment) *Environment {
-	env := NewEnvironment()
-	env.outer = outer
-	return env
-}
+import "github.com/technoboom/compiler/ast"
 
-// NewEnvironment - creates new environment and returns it
+// NewEnvironment - creates environment of the program, it keeps names
+// of the program variables, so the program can be evaluated in parts
+// (e.g. lines of REPL)
 func NewEnvironment() *Environment {
-	s := make(map[string]Object)
+	return &Environment{scope: ast.NewScope()}
+}
 
-	return &Environment{store: s, outer: nil}
+// NewEnclosedEnvironment - creates new enclosed environment for the variables
+// of the scope assigned by the resolver. Nodes evaluated without resolution
+// have no scope, their environment gets an empty one
+func NewEnclosedEnvironment(outer *Environment, scope *ast.Scope) *Environment {
+	if scope == nil {
+		scope = ast.NewScope()
+	}
+	return &Environment{
+		slots: make([]Object, scope.Len()),
+		outer: outer,
+		scope: scope,
+	}
 }
 
-// Environment - stores variables objects
+// Environment - stores variables objects in slots assigned by the resolver.
+// Slots of variables which aren't bound yet are nil
 type Environment struct {
-	store map[string]Object
+	slots []Object
 	outer *Environment
+	// names of the variables, used for identifiers without location
+	scope *ast.Scope
+}
+
+// Scope - returns the scope of the environment, the scope of the program
+// environment is extended with variables of every evaluated part of the program
+func (e *Environment) Scope() *ast.Scope {
+	return e.scope
+}
+
+// Lookup - returns value of the variable in the location, reports
+// if the variable is bound
+func (e *Environment) Lookup(location ast.Location) (Object, bool) {
+	env := e
+	for depth := 0; depth < location.Depth; depth++ {
+		env = env.outer
+	}
+	return env.get(location.Slot)
+}
+
+// LookupName - returns value of the innermost bound variable with the name.
+// Used for identifiers the resolver left without location: names declared
+// by the later parts of the program and nodes evaluated without resolution
+func (e *Environment) LookupName(name string) (Object, bool) {
+	for env := e; env != nil; env = env.outer {
+		if slot, ok := env.scope.Lookup(name); ok {
+			if obj, ok := env.get(slot); ok {
+				return obj, true
+			}
+		}
+	}
+	return nil, false
 }
 
-// Get - returns variable from environment
-func (e *Environment) Get(name string) (Object, bool) {
-	obj, ok := e.store[name]
-	if !ok && e.outer != nil {
-		// try to find in parent scope
-		obj, ok = e.outer.Get(name)
+// get - returns value of the variable in the slot, reports if it's bound
+func (e *Environment) get(slot int) (Object, bool) {
+	if slot < len(e.slots) {
+		if obj := e.slots[slot]; obj != nil {
+			return obj, true
+		}
 	}
-	return obj, ok
+	return nil, false
 }
 
-// Set - creates variable in environment if not exists
-// otherwise replaces variable value
-func (e *Environment) Set(name string, value Object) Object {
-	e.store[name] = value
+// Set - binds the value to the variable in the slot of the environment
+func (e *Environment) Set(slot int, value Object) Object {
+	if slot >= len(e.slots) {
+		slots := make([]Object, slot+1, 2*slot+2)
+		copy(slots, e.slots)
+		e.slots = slots
+	}
+	e.slots[slot] = value
 	return value
-}
\ No newline at end of file
+}
+
+// SetName - binds the value to the variable with the name in the scope
+// of the environment, declaring the variable if needed
+func (e *Environment) SetName(name string, value Object) Object {
+	return e.Set(e.scope.Declare(name), value)
+}
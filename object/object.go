@@ -3,7 +3,9 @@ package object
 import (
 	"fmt"
 	"github.com/technoboom/compiler/ast"
+	"github.com/technoboom/compiler/code"
 	"bytes"
+	"hash/fnv"
 	"strings"
 )
 
@@ -18,6 +20,23 @@ const (
 	ERROR_OBJ = "ERROR"
 	FUNCTION_OBJ = "FUNCTION"
 	STRING_OBJ = "STRING"
+	ARRAY_OBJ = "ARRAY"
+	HASH_OBJ = "HASH"
+	STRUCT_TYPE_OBJ = "STRUCT_TYPE"
+	ENUM_TYPE_OBJ = "ENUM_TYPE"
+	VARIANT_TYPE_OBJ = "VARIANT_TYPE"
+	BUILTIN_OBJ = "BUILTIN"
+	TRAIT_OBJ = "TRAIT"
+	COMPILED_FUNCTION_OBJ = "COMPILED_FUNCTION"
+	CLOSURE_OBJ = "CLOSURE"
+	CELL_OBJ = "CELL"
+)
+
+// values which exist in single instance, shared by evaluator and vm
+var (
+	NULL = &Null{}
+	TRUE = &Boolean{Value: true}
+	FALSE = &Boolean{Value: false}
 )
 
 // Object - interface for representing types objects
@@ -84,9 +103,25 @@ func (rv *ReturnValue) Inspect() string {
 	return rv.Value.Inspect()
 }
 
+// kinds of errors produced by the interpreter
+const (
+	ERROR          = "Error"
+	TYPE_ERROR     = "TypeError"
+	NAME_ERROR     = "NameError"
+	ARGUMENT_ERROR = "ArgumentError"
+	MATCH_ERROR    = "MatchError"
+)
+
 // Error - structure that stores error messages for error handling
 type Error struct {
 	Message string
+	// kind of the error, e.g. TypeError
+	Kind string
+	// position in the source where the error occurred (0 if unknown)
+	Line   int
+	Column int
+	// the value passed to throw if it's not an error hash
+	Value Object
 }
 
 // Type - returns type of the object
@@ -96,17 +131,57 @@ func (e *Error) Type() ObjectType {
 
 // Inspect - shows value of the object
 func (e *Error) Inspect() string {
-	return e.Message
+	var out bytes.Buffer
+
+	if e.Kind != "" {
+		out.WriteString(e.Kind + ": ")
+	}
+	out.WriteString(e.Message)
+	if e.Line > 0 {
+		out.WriteString(fmt.Sprintf(" (line %d, column %d)", e.Line, e.Column))
+	}
+
+	return out.String()
+}
+
+// Error - implements error interface, so runtime errors of the virtual
+// machine can be returned as Go errors
+func (e *Error) Error() string {
+	return e.Inspect()
+}
+
+// Hash - converts the error into the hash with message, kind (also
+// available as type), line and column keys (and value if the error was
+// thrown with non-error value), this is how errors are seen by catch blocks
+func (e *Error) Hash() *Hash {
+	hash := NewHash()
+
+	hash.Set(&String{Value: "message"}, &String{Value: e.Message})
+	hash.Set(&String{Value: "kind"}, &String{Value: e.Kind})
+	hash.Set(&String{Value: "type"}, &String{Value: e.Kind})
+	hash.Set(&String{Value: "line"}, &Integer{Value: int64(e.Line)})
+	hash.Set(&String{Value: "column"}, &Integer{Value: int64(e.Column)})
+	if e.Value != nil {
+		hash.Set(&String{Value: "value"}, e.Value)
+	}
+
+	return hash
 }
 
 // Function - represents function structure
 type Function struct {
 	// parameters of the function
 	Parameters []*ast.Identifier
+	// default values of parameters, evaluated at call time
+	Defaults map[string]ast.Expression
+	// the rest parameter which collects all extra arguments
+	Rest *ast.Identifier
 	// statements inside block statement of the function
 	Body *ast.BlockStatement
 	// environment variables
 	Env *Environment
+	// parameters and variables of the function, nil if not resolved
+	Scope *ast.Scope
 }
 
 // Type - returns type of the object
@@ -118,15 +193,9 @@ func (f *Function) Type() ObjectType {
 func (f *Function) Inspect() string {
 	var out bytes.Buffer
 
-	params := []string{}
-
-	for _, p := range f.Parameters {
-		params = append(params, p.String())
-	}
-
 	out.WriteString("function")
 	out.WriteString("(")
-	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(ast.ParametersString(f.Parameters, f.Defaults, f.Rest))
 	out.WriteString(") {\n")
 	out.WriteString(f.Body.String())
 	out.WriteString("\n}")
@@ -134,6 +203,79 @@ func (f *Function) Inspect() string {
 	return out.String()
 }
 
+// CompiledFunction - represents function compiled into bytecode
+type CompiledFunction struct {
+	Instructions code.Instructions
+	// number of local bindings including parameters
+	NumLocals    int
+	// names of the parameters, the first locals of the function
+	Parameters   []string
+	// line table and names of the locals (nil if stripped)
+	Debug        *code.DebugInfo
+}
+
+// Type - returns type of the object
+func (cf *CompiledFunction) Type() ObjectType {
+	return COMPILED_FUNCTION_OBJ
+}
+
+// Inspect - shows value of the object
+func (cf *CompiledFunction) Inspect() string {
+	return "function(" + strings.Join(cf.Parameters, ", ") + ") { <compiled> }"
+}
+
+// Closure - compiled function with cells of the variables captured
+// from the enclosing functions
+type Closure struct {
+	Fn   *CompiledFunction
+	Free []*Cell
+}
+
+// Type - returns type of the object
+func (c *Closure) Type() ObjectType {
+	return CLOSURE_OBJ
+}
+
+// Inspect - shows value of the object
+func (c *Closure) Inspect() string {
+	return c.Fn.Inspect()
+}
+
+// Cell - variable captured by the closure. While the function which
+// defines the variable runs, the cell refers to the slot of the variable
+// on the stack, so later bindings are visible to the closure. When the
+// function returns, the cell is closed and keeps the value itself
+type Cell struct {
+	Ref   *Object
+	Value Object
+}
+
+// NewCell - creates open cell referring to the slot
+func NewCell(slot *Object) *Cell {
+	return &Cell{Ref: slot}
+}
+
+// Get - returns current value of the variable
+func (c *Cell) Get() Object {
+	return *c.Ref
+}
+
+// Close - copies value of the variable into the cell
+func (c *Cell) Close() {
+	c.Value = *c.Ref
+	c.Ref = &c.Value
+}
+
+// Type - returns type of the object
+func (c *Cell) Type() ObjectType {
+	return CELL_OBJ
+}
+
+// Inspect - shows value of the object
+func (c *Cell) Inspect() string {
+	return "cell(" + c.Get().Inspect() + ")"
+}
+
 // String - represents string type
 type String struct {
 	Value string
@@ -147,4 +289,343 @@ func (s *String) Type() ObjectType {
 // Inspect - shows value of the object
 func (s *String) Inspect() string {
 	return s.Value
-}
\ No newline at end of file
+}
+
+// Array - represents ordered list of objects
+type Array struct {
+	Elements []Object
+}
+
+// Type - returns type of the object
+func (a *Array) Type() ObjectType {
+	return ARRAY_OBJ
+}
+
+// Inspect - shows value of the object
+func (a *Array) Inspect() string {
+	var out bytes.Buffer
+
+	elements := []string{}
+	for _, e := range a.Elements {
+		elements = append(elements, e.Inspect())
+	}
+
+	out.WriteString("[")
+	out.WriteString(strings.Join(elements, ", "))
+	out.WriteString("]")
+
+	return out.String()
+}
+
+// Implementation - methods of the user declared type and traits
+// implemented by the type
+type Implementation struct {
+	// methods declared with impl blocks
+	Methods map[string]Object
+	Traits  []*Trait
+	// traits of the impl blocks which declared the methods,
+	// methods of the inherent impl blocks aren't there
+	Origins map[string]*Trait
+}
+
+// NewImplementation - creates implementation without methods
+func NewImplementation() Implementation {
+	return Implementation{
+		Methods: make(map[string]Object),
+		Origins: make(map[string]*Trait),
+	}
+}
+
+// Implements - checks if the trait is implemented
+func (i *Implementation) Implements(trait *Trait) bool {
+	for _, t := range i.Traits {
+		if t == trait {
+			return true
+		}
+	}
+	return false
+}
+
+// ImplementationOf - returns methods and traits of the type of the value,
+// returns nil for values of builtin types
+func ImplementationOf(obj Object) *Implementation {
+	switch obj := obj.(type) {
+	case *Struct:
+		return &obj.Definition.Implementation
+	case *Variant:
+		return &obj.Definition.Enum.Implementation
+	default:
+		return nil
+	}
+}
+
+// Trait - represents declared trait: names of methods required from the type
+type Trait struct {
+	Name    string
+	Methods []string
+	// number of parameters of the methods without the receiver
+	Arity map[string]int
+}
+
+// Type - returns type of the object
+func (t *Trait) Type() ObjectType {
+	return TRAIT_OBJ
+}
+
+// Inspect - shows value of the object
+func (t *Trait) Inspect() string {
+	return "trait " + t.Name + " { " + strings.Join(t.Methods, ", ") + " }"
+}
+
+// StructType - represents declared struct type, calling it creates
+// a new instance of the struct
+type StructType struct {
+	Name   string
+	// names of the fields in order of declaration
+	Fields []string
+	Implementation
+}
+
+// Type - returns type of the object
+func (st *StructType) Type() ObjectType {
+	return STRUCT_TYPE_OBJ
+}
+
+// Inspect - shows value of the object
+func (st *StructType) Inspect() string {
+	return "struct " + st.Name + " { " + strings.Join(st.Fields, ", ") + " }"
+}
+
+// Struct - represents instance of the struct type
+type Struct struct {
+	Definition *StructType
+	Fields     map[string]Object
+}
+
+// Type - returns type of the object, each struct type has its own
+// object type named after the struct
+func (s *Struct) Type() ObjectType {
+	return ObjectType(s.Definition.Name)
+}
+
+// Inspect - shows value of the object
+func (s *Struct) Inspect() string {
+	var out bytes.Buffer
+
+	fields := []string{}
+	for _, name := range s.Definition.Fields {
+		fields = append(fields, name+": "+s.Fields[name].Inspect())
+	}
+
+	out.WriteString(s.Definition.Name)
+	out.WriteString("{")
+	out.WriteString(strings.Join(fields, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
+
+// EnumType - represents declared enum (tagged union) type
+type EnumType struct {
+	Name     string
+	// variants in order of declaration
+	Variants []*VariantType
+	// constructors of variants with fields and values of variants
+	// without fields by the name of the variant
+	Members  map[string]Object
+	Implementation
+}
+
+// Type - returns type of the object
+func (et *EnumType) Type() ObjectType {
+	return ENUM_TYPE_OBJ
+}
+
+// Inspect - shows value of the object
+func (et *EnumType) Inspect() string {
+	variants := []string{}
+	for _, v := range et.Variants {
+		variants = append(variants, v.String())
+	}
+
+	return "enum " + et.Name + " { " + strings.Join(variants, ", ") + " }"
+}
+
+// VariantType - represents variant of the enum, calling it creates
+// a new value of the variant
+type VariantType struct {
+	Enum   *EnumType
+	Name   string
+	// names of the fields in order of declaration
+	Fields []string
+}
+
+// Type - returns type of the object
+func (vt *VariantType) Type() ObjectType {
+	return VARIANT_TYPE_OBJ
+}
+
+// Inspect - shows value of the object
+func (vt *VariantType) Inspect() string {
+	return vt.Enum.Name + "." + vt.String()
+}
+
+// String - returns declaration of the variant: <name>(<fields>)
+func (vt *VariantType) String() string {
+	if len(vt.Fields) == 0 {
+		return vt.Name
+	}
+	return vt.Name + "(" + strings.Join(vt.Fields, ", ") + ")"
+}
+
+// Variant - represents value of the enum: tag of the variant and its fields
+type Variant struct {
+	Definition *VariantType
+	// values of the fields in order of declaration
+	Values     []Object
+}
+
+// Type - returns type of the object, each enum has its own
+// object type named after the enum
+func (v *Variant) Type() ObjectType {
+	return ObjectType(v.Definition.Enum.Name)
+}
+
+// Inspect - shows value of the object
+func (v *Variant) Inspect() string {
+	if len(v.Values) == 0 {
+		return v.Definition.Name
+	}
+
+	values := []string{}
+	for _, value := range v.Values {
+		values = append(values, value.Inspect())
+	}
+
+	return v.Definition.Name + "(" + strings.Join(values, ", ") + ")"
+}
+
+// BuiltinFunction - signature of functions implemented by interpreter
+type BuiltinFunction func(args ...Object) Object
+
+// Builtin - represents function implemented by interpreter
+type Builtin struct {
+	Fn BuiltinFunction
+}
+
+// Type - returns type of the object
+func (b *Builtin) Type() ObjectType {
+	return BUILTIN_OBJ
+}
+
+// Inspect - shows value of the object
+func (b *Builtin) Inspect() string {
+	return "builtin function"
+}
+
+// HashKey - key used for storing objects in hashes
+type HashKey struct {
+	Type  ObjectType
+	Value uint64
+}
+
+// Hashable - implemented by objects which can be used as hash keys
+type Hashable interface {
+	HashKey() HashKey
+}
+
+// HashKey - returns key of the object for using it in hashes
+func (i *Integer) HashKey() HashKey {
+	return HashKey{Type: i.Type(), Value: uint64(i.Value)}
+}
+
+// HashKey - returns key of the object for using it in hashes
+func (b *Boolean) HashKey() HashKey {
+	var value uint64
+
+	if b.Value {
+		value = 1
+	}
+
+	return HashKey{Type: b.Type(), Value: value}
+}
+
+// HashKey - returns key of the object for using it in hashes
+func (s *String) HashKey() HashKey {
+	h := fnv.New64a()
+	h.Write([]byte(s.Value))
+
+	return HashKey{Type: s.Type(), Value: h.Sum64()}
+}
+
+// HashPair - key and value stored in the hash
+type HashPair struct {
+	Key   Object
+	Value Object
+}
+
+// Hash - represents hash (dictionary) type, keeps pairs in insertion order
+type Hash struct {
+	pairs map[HashKey]*HashPair
+	keys  []HashKey
+}
+
+// NewHash - creates new empty hash
+func NewHash() *Hash {
+	return &Hash{pairs: make(map[HashKey]*HashPair)}
+}
+
+// Set - stores value by the key, replaces value if key already exists
+func (h *Hash) Set(key Hashable, value Object) {
+	hashKey := key.HashKey()
+	if pair, ok := h.pairs[hashKey]; ok {
+		pair.Value = value
+		return
+	}
+	h.pairs[hashKey] = &HashPair{Key: key.(Object), Value: value}
+	h.keys = append(h.keys, hashKey)
+}
+
+// Get - returns value stored by the key
+func (h *Hash) Get(key Hashable) (Object, bool) {
+	pair, ok := h.pairs[key.HashKey()]
+	if !ok {
+		return nil, false
+	}
+	return pair.Value, true
+}
+
+// Len - returns number of pairs in the hash
+func (h *Hash) Len() int {
+	return len(h.keys)
+}
+
+// Pairs - returns all pairs of the hash in insertion order
+func (h *Hash) Pairs() []*HashPair {
+	pairs := make([]*HashPair, 0, len(h.keys))
+	for _, key := range h.keys {
+		pairs = append(pairs, h.pairs[key])
+	}
+	return pairs
+}
+
+// Type - returns type of the object
+func (h *Hash) Type() ObjectType {
+	return HASH_OBJ
+}
+
+// Inspect - shows value of the object
+func (h *Hash) Inspect() string {
+	var out bytes.Buffer
+
+	pairs := []string{}
+	for _, pair := range h.Pairs() {
+		pairs = append(pairs, pair.Key.Inspect()+": "+pair.Value.Inspect())
+	}
+
+	out.WriteString("{")
+	out.WriteString(strings.Join(pairs, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
@@ -0,0 +1,217 @@
+// Package optimizer - implements optimization passes over bytecode
+// produced by the compiler
+package optimizer
+
+import (
+	"github.com/technoboom/compiler/code"
+	"github.com/technoboom/compiler/compiler"
+	"github.com/technoboom/compiler/object"
+)
+
+// Level - optimization level, every level includes passes of the lower ones
+type Level int
+
+const (
+	// O0 - no optimizations
+	O0 Level = iota
+	// O1 - constant folding and removal of redundant push/pop pairs
+	O1
+	// O2 - collapsing of jump chains and removal of unreachable code
+	O2
+)
+
+// instruction - decoded instruction, jumps refer to the index
+// of the target instruction instead of its offset
+type instruction struct {
+	op       code.Opcode
+	operands []int
+	// source line of the instruction (0 if unknown)
+	line int
+	// index of the instruction the jump leads to, index equal to the number
+	// of instructions is the end of the function
+	target int
+}
+
+// optimizer - keeps constant pool extended with the folded constants
+type optimizer struct {
+	constants []object.Object
+	level     Level
+}
+
+// Optimize - returns optimized copy of the bytecode, compiled functions
+// in the constant pool are optimized too. Optimized program produces
+// the same results and errors as the original one
+func Optimize(bytecode *compiler.Bytecode, level Level) *compiler.Bytecode {
+	if level <= O0 {
+		return bytecode
+	}
+
+	o := &optimizer{
+		constants: append([]object.Object{}, bytecode.Constants...),
+		level:     level,
+	}
+
+	for idx, constant := range bytecode.Constants {
+		fn, ok := constant.(*object.CompiledFunction)
+		if !ok {
+			continue
+		}
+
+		instructions, debug := o.optimize(fn.Instructions, fn.Debug, false)
+		if debug != nil {
+			debug.Locals = fn.Debug.Locals
+			debug.Free = fn.Debug.Free
+		}
+		o.constants[idx] = &object.CompiledFunction{
+			Instructions: instructions,
+			NumLocals:    fn.NumLocals,
+			Parameters:   fn.Parameters,
+			Debug:        debug,
+		}
+	}
+
+	instructions, debug := o.optimize(bytecode.Instructions, bytecode.Debug, true)
+	if debug != nil {
+		debug.Locals = bytecode.Debug.Locals
+	}
+
+	return &compiler.Bytecode{
+		Instructions: instructions,
+		Constants:    o.constants,
+		Debug:        debug,
+	}
+}
+
+// optimize - runs passes over the instructions until none of them
+// changes anything. Values popped by the program are its result,
+// so they are kept in the main program
+func (o *optimizer) optimize(
+	ins code.Instructions,
+	debug *code.DebugInfo,
+	main bool,
+) (code.Instructions, *code.DebugInfo) {
+	list := decode(ins, debug)
+
+	for changed := true; changed; {
+		changed = o.foldConstants(list)
+		if !main {
+			changed = removePushPop(list) || changed
+		}
+		if o.level >= O2 {
+			changed = collapseJumps(list) || changed
+			changed = removeUnreachable(list) || changed
+		}
+		list = compact(list)
+	}
+
+	return encode(list, debug != nil)
+}
+
+// decode - splits instructions into the list and resolves jump offsets
+// into indexes
+func decode(ins code.Instructions, debug *code.DebugInfo) []*instruction {
+	list := []*instruction{}
+	indexes := map[int]int{}
+
+	for offset := 0; offset < len(ins); {
+		def, _ := code.Lookup(ins[offset])
+		operands, read := code.ReadOperands(def, ins[offset+1:])
+
+		current := &instruction{op: code.Opcode(ins[offset]), operands: operands}
+		if debug != nil {
+			current.line = debug.Line(offset)
+		}
+
+		indexes[offset] = len(list)
+		list = append(list, current)
+		offset += 1 + read
+	}
+	indexes[len(ins)] = len(list)
+
+	for _, current := range list {
+		if isJump(current.op) {
+			current.target = indexes[current.operands[0]]
+		}
+	}
+
+	return list
+}
+
+// encode - joins the instructions back, jumps get offsets
+// of their targets
+func encode(list []*instruction, withDebug bool) (code.Instructions, *code.DebugInfo) {
+	offsets := make([]int, len(list)+1)
+	for idx, current := range list {
+		def, _ := code.Lookup(byte(current.op))
+		width := 1
+		for _, w := range def.OperandWidths {
+			width += w
+		}
+		offsets[idx+1] = offsets[idx] + width
+	}
+
+	ins := code.Instructions{}
+	debug := &code.DebugInfo{}
+	for idx, current := range list {
+		if isJump(current.op) {
+			current.operands = []int{offsets[current.target]}
+		}
+
+		debug.AddLine(offsets[idx], current.line)
+		ins = append(ins, code.Make(current.op, current.operands...)...)
+	}
+
+	if !withDebug {
+		return ins, nil
+	}
+	return ins, debug
+}
+
+// compact - drops removed (nil) instructions, targets of the jumps
+// to the removed instructions move to the next remaining one
+func compact(list []*instruction) []*instruction {
+	indexes := make([]int, len(list)+1)
+	result := []*instruction{}
+
+	for idx, current := range list {
+		indexes[idx] = len(result)
+		if current != nil {
+			result = append(result, current)
+		}
+	}
+	indexes[len(list)] = len(result)
+
+	for _, current := range result {
+		if isJump(current.op) {
+			current.target = indexes[current.target]
+		}
+	}
+
+	return result
+}
+
+// jumpTargets - returns indexes of the instructions which
+// remaining jumps lead to
+func jumpTargets(list []*instruction) map[int]bool {
+	targets := map[int]bool{}
+	for _, current := range list {
+		if current != nil && isJump(current.op) {
+			targets[current.target] = true
+		}
+	}
+	return targets
+}
+
+// isJump - checks if operand of the instruction is offset of the jump
+func isJump(op code.Opcode) bool {
+	return op == code.OpJump || op == code.OpJumpNotTruthy || op == code.OpJumpNotNull
+}
+
+// next - returns index of the next remaining instruction after the index
+func next(list []*instruction, idx int) int {
+	idx++
+	for idx < len(list) && list[idx] == nil {
+		idx++
+	}
+	return idx
+}
@@ -0,0 +1,267 @@
+package optimizer
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/technoboom/compiler/code"
+	"github.com/technoboom/compiler/compiler"
+	"github.com/technoboom/compiler/lexer"
+	"github.com/technoboom/compiler/object"
+	"github.com/technoboom/compiler/parser"
+	"github.com/technoboom/compiler/vm"
+)
+
+type optimizerTestCase struct {
+	input                string
+	level                Level
+	expectedInstructions []code.Instructions
+}
+
+func TestConstantFolding(t *testing.T) {
+	tests := []optimizerTestCase{
+		{
+			input: "let x = 1; 2 * 3 + x",
+			level: O1,
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpConstant, 3),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpAdd),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input: "-(1 + 2) * 3 < 0 == !false",
+			level: O1,
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpTrue),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input: `"a" == "a"; 1 != "1"`,
+			level: O1,
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpTrue),
+				code.Make(code.OpPop),
+				code.Make(code.OpTrue),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			// operators failing at runtime are not folded
+			input: "1 / 0; 1 + true",
+			level: O1,
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpDiv),
+				code.Make(code.OpPop),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpTrue),
+				code.Make(code.OpAdd),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input: "null ?? 1; 2 ?? 3",
+			level: O1,
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpPop),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpJump, 13),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:                "2 * 3",
+			level:                O0,
+			expectedInstructions: []code.Instructions{compile(t, "2 * 3").Instructions},
+		},
+	}
+
+	runOptimizerTests(t, tests)
+}
+
+func TestJumpsAndUnreachableCode(t *testing.T) {
+	tests := []optimizerTestCase{
+		{
+			// the condition is folded, the else branch is unreachable
+			input: "if (1 < 2) { 10 } else { 20 }",
+			level: O2,
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input: "if (1 > 2) { 10 } else { 20 }",
+			level: O2,
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 3),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			// jump of the inner conditional leads to the jump of the outer one
+			input: "let x = 1; if (x) { if (x) { 1 } else { 2 } } else { 3 }",
+			level: O2,
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpConstant, 0),
+				// 0003
+				code.Make(code.OpSetGlobal, 0),
+				// 0006
+				code.Make(code.OpGetGlobal, 0),
+				// 0009
+				code.Make(code.OpJumpNotTruthy, 30),
+				// 0012
+				code.Make(code.OpGetGlobal, 0),
+				// 0015
+				code.Make(code.OpJumpNotTruthy, 24),
+				// 0018
+				code.Make(code.OpConstant, 1),
+				// 0021
+				code.Make(code.OpJump, 33),
+				// 0024
+				code.Make(code.OpConstant, 2),
+				// 0027
+				code.Make(code.OpJump, 33),
+				// 0030
+				code.Make(code.OpConstant, 3),
+				// 0033
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input: "return 1; 2; 3",
+			level: O2,
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpReturnValue),
+			},
+		},
+		{
+			input:                "return 1; 2; 3",
+			level:                O1,
+			expectedInstructions: []code.Instructions{compile(t, "return 1; 2; 3").Instructions},
+		},
+	}
+
+	runOptimizerTests(t, tests)
+}
+
+func TestFunctionsOptimization(t *testing.T) {
+	bytecode := Optimize(compile(t, "(a) => { a; 1 + 2; return a * 2; a }"), O2)
+
+	// constants of the body are added before the function
+	fn, ok := bytecode.Constants[3].(*object.CompiledFunction)
+	if !ok {
+		t.Fatalf("constant 3 is not a function: %T", bytecode.Constants[3])
+	}
+
+	expected := []code.Instructions{
+		code.Make(code.OpGetLocal, 0),
+		code.Make(code.OpConstant, 2),
+		code.Make(code.OpMul),
+		code.Make(code.OpReturnValue),
+	}
+	if err := testInstructions(expected, fn.Instructions); err != nil {
+		t.Errorf("wrong function instructions: %s", err)
+	}
+
+	if fn.Debug == nil || len(fn.Debug.Locals) != 1 || fn.Debug.Locals[0] != "a" {
+		t.Errorf("debug info of the function is lost: %+v", fn.Debug)
+	}
+}
+
+// TestDifferential - runs programs with and without optimizations
+// and checks that the results are the same
+func TestDifferential(t *testing.T) {
+	inputs := []string{
+		"2 * 3 + 4 * 5 - 6 / 2",
+		"let x = 7; 2 * 3 + x",
+		"-(5) + -(-5) == 0",
+		"!true == !!false",
+		`"a" == "b"`,
+		"1 / 0 == 1",
+		"1 + true",
+		"if (1 < 2) { 10 } else { 20 }",
+		"if (null) { 10 }",
+		"if (true) { }",
+		"null ?? 1 ?? 2",
+		"false ?? 1",
+		"let x = 1; if (x) { if (x) { 1 } else { 2 } } else { 3 }",
+		"let f = (n) => { if (n > 0) { return 1 } else { return -1 }; 0 }; [f(1), f(-1)]",
+		"let f = (a) => { a; 1 + 2; return a * 2; a }; f(21)",
+		"let fib = (n) => n < 2 ? n : fib(n - 1) + fib(n - 2); fib(15)",
+		"let f = (a) => (b) => a + b * (2 + 3); f(1)(2)",
+		"return 1 + 1; 2",
+		"1; 2; 3",
+		"let f = () => { }; f()",
+		`{"a" == "a": 1 < 2 ? "x" : "y"}`,
+		"let x = true; !x ? 1 : !(1 > 2) ? 2 : 3",
+	}
+
+	for _, input := range inputs {
+		expected := run(t, compile(t, input))
+
+		for _, level := range []Level{O1, O2} {
+			actual := run(t, Optimize(compile(t, input), level))
+			if actual != expected {
+				t.Errorf("results differ for %q at level %d. unoptimized=%q, optimized=%q",
+					input, level, expected, actual)
+			}
+		}
+	}
+}
+
+func runOptimizerTests(t *testing.T, tests []optimizerTestCase) {
+	t.Helper()
+
+	for _, tt := range tests {
+		bytecode := Optimize(compile(t, tt.input), tt.level)
+
+		if err := testInstructions(tt.expectedInstructions, bytecode.Instructions); err != nil {
+			t.Errorf("wrong instructions for %q: %s", tt.input, err)
+		}
+	}
+}
+
+func testInstructions(expected []code.Instructions, actual code.Instructions) error {
+	concatted := code.Instructions{}
+	for _, ins := range expected {
+		concatted = append(concatted, ins...)
+	}
+
+	if concatted.String() != actual.String() {
+		return fmt.Errorf("\nwant=\n%s\ngot =\n%s", concatted, actual)
+	}
+	return nil
+}
+
+func compile(t *testing.T, input string) *compiler.Bytecode {
+	t.Helper()
+
+	l := lexer.New(input)
+	p := parser.New(l)
+
+	comp := compiler.New()
+	if err := comp.Compile(p.ParseProgram()); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	return comp.Bytecode()
+}
+
+// run - returns result or error of the program
+func run(t *testing.T, bytecode *compiler.Bytecode) string {
+	machine := vm.New(bytecode)
+	if err := machine.Run(); err != nil {
+		return err.Error()
+	}
+	return machine.LastPoppedStackElem().Inspect()
+}
@@ -0,0 +1,292 @@
+package optimizer
+
+import (
+	"github.com/technoboom/compiler/code"
+	"github.com/technoboom/compiler/object"
+)
+
+// foldConstants - evaluates operators applied to constants, e.g.
+// `2 * 3 + x` becomes `6 + x`, and conditions known at compile time.
+// Instructions which raise runtime errors are left for the vm
+func (o *optimizer) foldConstants(list []*instruction) bool {
+	changed := false
+	targets := jumpTargets(list)
+
+	for i := range list {
+		if list[i] == nil {
+			continue
+		}
+
+		left, ok := o.constantValue(list[i])
+		if !ok {
+			continue
+		}
+
+		j := next(list, i)
+		if j >= len(list) || targets[j] {
+			continue
+		}
+
+		switch list[j].op {
+		case code.OpMinus, code.OpBang:
+			result, ok := foldPrefix(list[j].op, left)
+			if !ok {
+				continue
+			}
+			list[i] = o.push(result, list[i].line)
+			list[j] = nil
+			changed = true
+		case code.OpJumpNotTruthy:
+			if isTruthy(left) {
+				list[i], list[j] = nil, nil
+			} else {
+				list[i] = nil
+				list[j].op = code.OpJump
+			}
+			changed = true
+		case code.OpJumpNotNull:
+			if left == object.NULL {
+				list[i], list[j] = nil, nil
+			} else {
+				list[j].op = code.OpJump
+			}
+			changed = true
+		default:
+			right, ok := o.constantValue(list[j])
+			if !ok {
+				continue
+			}
+
+			k := next(list, j)
+			if k >= len(list) || targets[k] {
+				continue
+			}
+
+			result, ok := foldInfix(list[k].op, left, right)
+			if !ok {
+				continue
+			}
+			list[i] = o.push(result, list[i].line)
+			list[j], list[k] = nil, nil
+			changed = true
+		}
+	}
+
+	return changed
+}
+
+// constantValue - returns value pushed by the instruction if it's known
+// at compile time
+func (o *optimizer) constantValue(ins *instruction) (object.Object, bool) {
+	switch ins.op {
+	case code.OpConstant:
+		switch constant := o.constants[ins.operands[0]].(type) {
+		case *object.Integer, *object.String:
+			return constant, true
+		}
+	case code.OpTrue:
+		return object.TRUE, true
+	case code.OpFalse:
+		return object.FALSE, true
+	case code.OpNull:
+		return object.NULL, true
+	}
+	return nil, false
+}
+
+// push - returns instruction which pushes the value
+func (o *optimizer) push(value object.Object, line int) *instruction {
+	switch value {
+	case object.TRUE:
+		return &instruction{op: code.OpTrue, line: line}
+	case object.FALSE:
+		return &instruction{op: code.OpFalse, line: line}
+	}
+
+	o.constants = append(o.constants, value)
+	return &instruction{
+		op:       code.OpConstant,
+		operands: []int{len(o.constants) - 1},
+		line:     line,
+	}
+}
+
+// foldPrefix - applies prefix operator to the constant
+func foldPrefix(op code.Opcode, right object.Object) (object.Object, bool) {
+	switch op {
+	case code.OpBang:
+		return nativeBoolToBooleanObject(!isTruthy(right)), true
+	case code.OpMinus:
+		if integer, ok := right.(*object.Integer); ok {
+			return &object.Integer{Value: -integer.Value}, true
+		}
+	}
+	return nil, false
+}
+
+// foldInfix - applies infix operator to the constants following the rules
+// of the vm, operators which would fail are not folded
+func foldInfix(op code.Opcode, left, right object.Object) (object.Object, bool) {
+	leftInt, leftOk := left.(*object.Integer)
+	rightInt, rightOk := right.(*object.Integer)
+
+	if leftOk && rightOk {
+		l, r := leftInt.Value, rightInt.Value
+		switch op {
+		case code.OpAdd:
+			return &object.Integer{Value: l + r}, true
+		case code.OpSub:
+			return &object.Integer{Value: l - r}, true
+		case code.OpMul:
+			return &object.Integer{Value: l * r}, true
+		case code.OpDiv:
+			if r == 0 {
+				return nil, false
+			}
+			return &object.Integer{Value: l / r}, true
+		case code.OpLessThan:
+			return nativeBoolToBooleanObject(l < r), true
+		case code.OpGreaterThan:
+			return nativeBoolToBooleanObject(l > r), true
+		}
+	}
+
+	switch op {
+	case code.OpEqual:
+		return nativeBoolToBooleanObject(constantsEqual(left, right)), true
+	case code.OpNotEqual:
+		return nativeBoolToBooleanObject(!constantsEqual(left, right)), true
+	}
+	return nil, false
+}
+
+// removePushPop - removes instructions which push the value without
+// side effects when the value is popped right away
+func removePushPop(list []*instruction) bool {
+	changed := false
+	targets := jumpTargets(list)
+
+	for i := range list {
+		if list[i] == nil || !isPurePush(list[i].op) {
+			continue
+		}
+
+		j := next(list, i)
+		if j < len(list) && list[j].op == code.OpPop && !targets[j] {
+			list[i], list[j] = nil, nil
+			changed = true
+		}
+	}
+
+	return changed
+}
+
+// isPurePush - checks if the instruction only pushes the value
+func isPurePush(op code.Opcode) bool {
+	switch op {
+	case code.OpConstant, code.OpTrue, code.OpFalse, code.OpNull,
+		code.OpGetGlobal, code.OpGetLocal, code.OpGetFree, code.OpGetBuiltin:
+		return true
+	}
+	return false
+}
+
+// collapseJumps - makes jumps to unconditional jumps lead to the final
+// target, removes jumps to the next instruction
+func collapseJumps(list []*instruction) bool {
+	changed := false
+
+	for i, current := range list {
+		if current == nil || !isJump(current.op) {
+			continue
+		}
+
+		// the number of steps is bounded to stop on cycles
+		for steps := 0; steps < len(list); steps++ {
+			target := current.target
+			for target < len(list) && list[target] == nil {
+				target++
+			}
+			if target >= len(list) || list[target].op != code.OpJump ||
+				list[target].target == current.target {
+				break
+			}
+			current.target = list[target].target
+			changed = true
+		}
+
+		if current.op == code.OpJump && current.target == next(list, i) {
+			list[i] = nil
+			changed = true
+		}
+	}
+
+	return changed
+}
+
+// removeUnreachable - removes instructions following unconditional jumps
+// and returns which are not targets of any jump
+func removeUnreachable(list []*instruction) bool {
+	changed := false
+	targets := jumpTargets(list)
+
+	reachable := true
+	for i, current := range list {
+		// target of the jump may be removed by other passes, then
+		// the jump leads to the next remaining instruction
+		if targets[i] {
+			reachable = true
+		}
+
+		if current == nil {
+			continue
+		}
+
+		if !reachable {
+			list[i] = nil
+			changed = true
+			continue
+		}
+
+		switch current.op {
+		case code.OpJump, code.OpReturnValue, code.OpReturn:
+			reachable = false
+		}
+	}
+
+	return changed
+}
+
+// isTruthy - checks if the value is considered true by conditions
+func isTruthy(obj object.Object) bool {
+	switch obj := obj.(type) {
+	case *object.Boolean:
+		return obj.Value
+	case *object.Null:
+		return false
+	default:
+		return true
+	}
+}
+
+// constantsEqual - compares constants by value
+func constantsEqual(left, right object.Object) bool {
+	switch left := left.(type) {
+	case *object.Integer:
+		r, ok := right.(*object.Integer)
+		return ok && left.Value == r.Value
+	case *object.String:
+		r, ok := right.(*object.String)
+		return ok && left.Value == r.Value
+	default:
+		return left == right
+	}
+}
+
+// nativeBoolToBooleanObject - returns boolean object of the value
+func nativeBoolToBooleanObject(input bool) *object.Boolean {
+	if input {
+		return object.TRUE
+	}
+	return object.FALSE
+}
@@ -13,6 +13,10 @@ import (
 const (
 	_ int = iota
 	LOWEST
+	ASSIGNMENT	// x.y = z
+	TERNARY		// x ? y : z
+	PIPELINE	// x |> f
+	NULLISH		// x ?? y
 	EQUALS 		// ==
 	LESSGREATER	// > or <
 	SUM		// + or -
@@ -31,6 +35,12 @@ var precedences = map[token.Type]int {
 	token.DIVIDE: PRODUCT,
 	token.MULTIPLY: PRODUCT,
 	token.LPAREN: CALL,
+	token.PIPE: PIPELINE,
+	token.QUESTION: TERNARY,
+	token.NULLISH: NULLISH,
+	token.DOT: CALL,
+	token.OPTIONAL_DOT: CALL,
+	token.ASSIGN: ASSIGNMENT,
 }
 
 type (
@@ -67,12 +77,17 @@ func New(l *lexer.Lexer) *Parser {
 
 	p.registerPrefix(token.TRUE, p.parseBoolean)
 	p.registerPrefix(token.FALSE, p.parseBoolean)
+	p.registerPrefix(token.NULL, p.parseNullLiteral)
 
 	p.registerPrefix(token.LPAREN, p.parseGroupedExpression)
 
 	p.registerPrefix(token.IF, p.parseIfExpression)
+	p.registerPrefix(token.MATCH, p.parseMatchExpression)
+	p.registerPrefix(token.TRY, p.parseTryExpression)
 	p.registerPrefix(token.FUNCTION, p.parseFunctionLiteral)
 	p.registerPrefix(token.STRING, p.parseStringLiteral)
+	p.registerPrefix(token.LSQUARE, p.parseArrayLiteral)
+	p.registerPrefix(token.LBRACKET, p.parseHashLiteral)
 
 	// parsing infix expressions ("leds" - "left denotations")
 	p.infixParseFns = make(map[token.Type]infixParseFn)
@@ -86,6 +101,12 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfix(token.GT, p.parseInfixExpression)
 
 	p.registerInfix(token.LPAREN, p.parseCallExpression)
+	p.registerInfix(token.PIPE, p.parsePipelineExpression)
+	p.registerInfix(token.QUESTION, p.parseConditionalExpression)
+	p.registerInfix(token.NULLISH, p.parseInfixExpression)
+	p.registerInfix(token.DOT, p.parseMemberExpression)
+	p.registerInfix(token.OPTIONAL_DOT, p.parseMemberExpression)
+	p.registerInfix(token.ASSIGN, p.parseAssignExpression)
 
 	// read two tokens to ensure that curToken and peekToken are
 	// both set
@@ -123,6 +144,16 @@ func (p *Parser) parseStatement() ast.Statement {
 		return p.parseLetStatement()
 	case token.RETURN:
 		return p.parseReturnStatement()
+	case token.THROW:
+		return p.parseThrowStatement()
+	case token.STRUCT:
+		return p.parseStructStatement()
+	case token.IMPL:
+		return p.parseImplStatement()
+	case token.ENUM:
+		return p.parseEnumStatement()
+	case token.TRAIT:
+		return p.parseTraitStatement()
 	default:
 		return p.parseExpressionStatement()
 	}
@@ -132,11 +163,30 @@ func (p *Parser) parseStatement() ast.Statement {
 func (p *Parser) parseLetStatement() *ast.LetStatement {
 	stmt := &ast.LetStatement{Token: p.curToken}
 
-	if !p.expectPeek(token.IDENT) {
+	p.nextToken()
+
+	// literal patterns can be nested, but the whole let statement
+	// can't be a literal
+	if !p.curTokenIs(token.IDENT) && !p.curTokenIs(token.LSQUARE) &&
+		!p.curTokenIs(token.LBRACKET) {
+		p.patternError()
 		return nil
 	}
 
-	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	stmt.Pattern = p.parsePattern()
+	if stmt.Pattern == nil {
+		return nil
+	}
+
+	seen := map[string]bool{}
+	for _, name := range patternNames(stmt.Pattern, nil) {
+		if seen[name.Value] {
+			msg := fmt.Sprintf("duplicate pattern name: %s", name.Value)
+			p.errors = append(p.errors, msg)
+			return nil
+		}
+		seen[name.Value] = true
+	}
 
 	if !p.expectPeek(token.ASSIGN) {
 		return nil
@@ -153,6 +203,272 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 	return stmt
 }
 
+// parsePattern - parses pattern starting from the current token
+// <identifier>, _, <literal>, [<patterns>], {<hash pattern pairs>}
+// or <variant>(<patterns>)
+func (p *Parser) parsePattern() ast.Pattern {
+	switch p.curToken.Type {
+	case token.IDENT:
+		if p.curToken.Literal == "_" {
+			return &ast.WildcardPattern{Token: p.curToken}
+		}
+		if p.peekTokenIs(token.LPAREN) || p.peekTokenIs(token.DOT) {
+			return p.parseVariantPattern()
+		}
+		return &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	case token.LSQUARE:
+		return p.parseArrayPattern()
+	case token.LBRACKET:
+		return p.parseHashPattern()
+	case token.INT, token.STRING, token.TRUE, token.FALSE, token.NULL:
+		return &ast.LiteralPattern{
+			Token: p.curToken,
+			Value: p.prefixParseFns[p.curToken.Type](),
+		}
+	case token.MINUS:
+		if !p.peekTokenIs(token.INT) {
+			p.patternError()
+			return nil
+		}
+		return &ast.LiteralPattern{
+			Token: p.curToken,
+			Value: p.parsePrefixExpression(),
+		}
+	default:
+		p.patternError()
+		return nil
+	}
+}
+
+// patternNames - appends names bound by the pattern to the list
+func patternNames(pattern ast.Pattern, names []*ast.Identifier) []*ast.Identifier {
+	switch pattern := pattern.(type) {
+	case *ast.Identifier:
+		names = append(names, pattern)
+	case *ast.DefaultPattern:
+		names = patternNames(pattern.Target, names)
+	case *ast.VariantPattern:
+		for _, element := range pattern.Elements {
+			names = patternNames(element, names)
+		}
+	case *ast.ArrayPattern:
+		for _, element := range pattern.Elements {
+			names = patternNames(element, names)
+		}
+		if pattern.Rest != nil {
+			names = append(names, pattern.Rest)
+		}
+	case *ast.HashPattern:
+		for _, pair := range pattern.Pairs {
+			names = patternNames(pair.Value, names)
+		}
+		if pattern.Rest != nil {
+			names = append(names, pattern.Rest)
+		}
+	}
+	return names
+}
+
+// patternError - appends error in the parser when current token can't
+// start a pattern
+func (p *Parser) patternError() {
+	msg := fmt.Sprintf("expected identifier or pattern, got '%s' instead",
+		p.curToken.Type)
+	p.errors = append(p.errors, msg)
+}
+
+// parsePatternElement - parses nested pattern with optional default value
+// <pattern> or <pattern> = <expression>
+func (p *Parser) parsePatternElement() ast.Pattern {
+	pattern := p.parsePattern()
+	if pattern == nil {
+		return nil
+	}
+
+	if !p.peekTokenIs(token.ASSIGN) {
+		return pattern
+	}
+	p.nextToken()
+
+	def := &ast.DefaultPattern{Token: p.curToken, Target: pattern}
+	p.nextToken()
+	def.Default = p.parseExpression(LOWEST)
+	if def.Default == nil {
+		return nil
+	}
+
+	return def
+}
+
+// parsePatternRest - parses ...<identifier> element which must be the last
+// one before the closing token
+func (p *Parser) parsePatternRest(end token.Type) *ast.Identifier {
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	rest := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if !p.peekTokenIs(end) {
+		p.errors = append(p.errors, "rest element must be the last element")
+		return nil
+	}
+	return rest
+}
+
+// parseArrayPattern - parses array destructuring pattern
+// [<pattern>, <pattern> = <default>, ..., ...<rest>]
+func (p *Parser) parseArrayPattern() ast.Pattern {
+	pattern := &ast.ArrayPattern{Token: p.curToken}
+	pattern.Elements = []ast.Pattern{}
+
+	for !p.peekTokenIs(token.RSQUARE) {
+		p.nextToken()
+
+		if p.curTokenIs(token.ELLIPSIS) {
+			pattern.Rest = p.parsePatternRest(token.RSQUARE)
+			if pattern.Rest == nil {
+				return nil
+			}
+			break
+		}
+
+		element := p.parsePatternElement()
+		if element == nil {
+			return nil
+		}
+		pattern.Elements = append(pattern.Elements, element)
+
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+
+	if !p.expectPeek(token.RSQUARE) {
+		return nil
+	}
+
+	return pattern
+}
+
+// parseVariantPattern - parses pattern of the enum variant, the qualified
+// variant may be written without parentheses
+// <variant>(<pattern>, <pattern>, ...)
+// <enum>.<variant>(<pattern>, <pattern>, ...)
+// <enum>.<variant>
+func (p *Parser) parseVariantPattern() ast.Pattern {
+	pattern := &ast.VariantPattern{
+		Token: p.curToken,
+		Name:  &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal},
+	}
+
+	if p.peekTokenIs(token.DOT) {
+		p.nextToken()
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		pattern.Enum = pattern.Name
+		pattern.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		if !p.peekTokenIs(token.LPAREN) {
+			return pattern
+		}
+	}
+	pattern.Elements = []ast.Pattern{}
+
+	p.nextToken()
+	for !p.peekTokenIs(token.RPAREN) {
+		p.nextToken()
+
+		element := p.parsePatternElement()
+		if element == nil {
+			return nil
+		}
+		pattern.Elements = append(pattern.Elements, element)
+
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	return pattern
+}
+
+// parseHashPattern - parses hash destructuring pattern
+// {<key>, <key> = <default>, <key>: <pattern>, ..., ...<rest>}
+func (p *Parser) parseHashPattern() ast.Pattern {
+	pattern := &ast.HashPattern{Token: p.curToken}
+	pattern.Pairs = []*ast.HashPatternPair{}
+
+	for !p.peekTokenIs(token.RBRACKET) {
+		p.nextToken()
+
+		if p.curTokenIs(token.ELLIPSIS) {
+			pattern.Rest = p.parsePatternRest(token.RBRACKET)
+			if pattern.Rest == nil {
+				return nil
+			}
+			break
+		}
+
+		pair := p.parseHashPatternPair()
+		if pair == nil {
+			return nil
+		}
+		pattern.Pairs = append(pattern.Pairs, pair)
+
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+
+	if !p.expectPeek(token.RBRACKET) {
+		return nil
+	}
+
+	return pattern
+}
+
+// parseHashPatternPair - parses single pair of the hash pattern
+func (p *Parser) parseHashPatternPair() *ast.HashPatternPair {
+	if !p.curTokenIs(token.IDENT) && !p.curTokenIs(token.STRING) {
+		msg := fmt.Sprintf("expected hash pattern key, got '%s' instead",
+			p.curToken.Type)
+		p.errors = append(p.errors, msg)
+		return nil
+	}
+
+	pair := &ast.HashPatternPair{
+		Key: &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal},
+	}
+
+	// shorthand {name} binds the value to the variable with the key name
+	if p.curTokenIs(token.IDENT) && !p.peekTokenIs(token.COLON) {
+		pair.Value = p.parsePatternElement()
+		if pair.Value == nil {
+			return nil
+		}
+		return pair
+	}
+
+	if !p.expectPeek(token.COLON) {
+		return nil
+	}
+	p.nextToken()
+
+	pair.Value = p.parsePatternElement()
+	if pair.Value == nil {
+		return nil
+	}
+
+	return pair
+}
+
 // parseReturnStatement - parses return statement
 func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 	stmt := &ast.ReturnStatement{Token: p.curToken}
@@ -169,6 +485,290 @@ func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 	return stmt
 }
 
+// parseThrowStatement - parses throw statement
+func (p *Parser) parseThrowStatement() *ast.ThrowStatement {
+	stmt := &ast.ThrowStatement{Token: p.curToken}
+
+	p.nextToken()
+
+	stmt.Value = p.parseExpression(LOWEST)
+	if stmt.Value == nil {
+		return nil
+	}
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// parseStructStatement - parses struct type declaration
+// struct <name> { <field>, <field>, ... }
+func (p *Parser) parseStructStatement() *ast.StructStatement {
+	stmt := &ast.StructStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if !p.expectPeek(token.LBRACKET) {
+		return nil
+	}
+
+	stmt.Fields = p.parseFieldNames(token.RBRACKET)
+	if stmt.Fields == nil {
+		return nil
+	}
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// parseFieldNames - parses comma separated list of unique field names
+// until the end token, the end token becomes current
+func (p *Parser) parseFieldNames(end token.Type) []*ast.Identifier {
+	fields := []*ast.Identifier{}
+
+	for !p.peekTokenIs(end) {
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+
+		field := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		for _, f := range fields {
+			if f.Value == field.Value {
+				msg := fmt.Sprintf("duplicate field name: %s", field.Value)
+				p.errors = append(p.errors, msg)
+				return nil
+			}
+		}
+		fields = append(fields, field)
+
+		if !p.peekTokenIs(end) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(end) {
+		return nil
+	}
+
+	return fields
+}
+
+// parseEnumStatement - parses enum (tagged union) declaration
+// enum <name> { <variant>(<fields>), <variant>, ... }
+func (p *Parser) parseEnumStatement() *ast.EnumStatement {
+	stmt := &ast.EnumStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if !p.expectPeek(token.LBRACKET) {
+		return nil
+	}
+
+	stmt.Variants = []*ast.EnumVariant{}
+	for !p.peekTokenIs(token.RBRACKET) {
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+
+		variant := &ast.EnumVariant{
+			Name:   &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal},
+			Fields: []*ast.Identifier{},
+		}
+		for _, v := range stmt.Variants {
+			if v.Name.Value == variant.Name.Value {
+				msg := fmt.Sprintf("duplicate variant name: %s", variant.Name.Value)
+				p.errors = append(p.errors, msg)
+				return nil
+			}
+		}
+
+		if p.peekTokenIs(token.LPAREN) {
+			p.nextToken()
+			variant.Fields = p.parseFieldNames(token.RPAREN)
+			if variant.Fields == nil {
+				return nil
+			}
+		}
+		stmt.Variants = append(stmt.Variants, variant)
+
+		if !p.peekTokenIs(token.RBRACKET) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RBRACKET) {
+		return nil
+	}
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// parseImplStatement - parses declaration of methods of the type
+// impl <name> { <method> <method> ... }
+// impl <trait> for <name> { <method> <method> ... }
+func (p *Parser) parseImplStatement() *ast.ImplStatement {
+	stmt := &ast.ImplStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if p.peekTokenIs(token.FOR) {
+		p.nextToken()
+		stmt.Trait = stmt.Name
+
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	}
+
+	if !p.expectPeek(token.LBRACKET) {
+		return nil
+	}
+
+	stmt.Methods = []*ast.MethodDeclaration{}
+	for !p.peekTokenIs(token.RBRACKET) {
+		method := p.parseMethodDeclaration()
+		if method == nil {
+			return nil
+		}
+
+		for _, m := range stmt.Methods {
+			if m.Name.Value == method.Name.Value {
+				msg := fmt.Sprintf("duplicate method name: %s", method.Name.Value)
+				p.errors = append(p.errors, msg)
+				return nil
+			}
+		}
+		stmt.Methods = append(stmt.Methods, method)
+
+		// methods can be separated with semicolons
+		if p.peekTokenIs(token.SEMICOLON) {
+			p.nextToken()
+		}
+	}
+
+	if !p.expectPeek(token.RBRACKET) {
+		return nil
+	}
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// parseTraitStatement - parses trait declaration
+// trait <name> { <method signature>; <method signature>; ... }
+func (p *Parser) parseTraitStatement() *ast.TraitStatement {
+	stmt := &ast.TraitStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if !p.expectPeek(token.LBRACKET) {
+		return nil
+	}
+
+	stmt.Methods = []*ast.TraitMethod{}
+	for !p.peekTokenIs(token.RBRACKET) {
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+
+		method := &ast.TraitMethod{
+			Name: &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal},
+		}
+		for _, m := range stmt.Methods {
+			if m.Name.Value == method.Name.Value {
+				msg := fmt.Sprintf("duplicate method name: %s", method.Name.Value)
+				p.errors = append(p.errors, msg)
+				return nil
+			}
+		}
+
+		if !p.expectPeek(token.LPAREN) {
+			return nil
+		}
+		method.Parameters = p.parseFieldNames(token.RPAREN)
+		if method.Parameters == nil {
+			return nil
+		}
+		stmt.Methods = append(stmt.Methods, method)
+
+		// signatures can be separated with semicolons
+		if p.peekTokenIs(token.SEMICOLON) {
+			p.nextToken()
+		}
+	}
+
+	if !p.expectPeek(token.RBRACKET) {
+		return nil
+	}
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// parseMethodDeclaration - parses single method of impl block
+// <name>(<parameters>) <block statement>
+func (p *Parser) parseMethodDeclaration() *ast.MethodDeclaration {
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+
+	method := &ast.MethodDeclaration{
+		Name: &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal},
+	}
+	lit := &ast.FunctionLiteral{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	if !p.parseFunctionParameters(lit) {
+		return nil
+	}
+
+	if len(lit.Parameters) == 0 {
+		msg := fmt.Sprintf("method %s must have receiver parameter", method.Name.Value)
+		p.errors = append(p.errors, msg)
+		return nil
+	}
+
+	if !p.expectPeek(token.LBRACKET) {
+		return nil
+	}
+
+	lit.Body = p.parseBlockStatement()
+	method.Function = lit
+
+	return method
+}
+
 // parseExpressionStatement - parses expression statements
 func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 	stmt := &ast.ExpressionStatement{Token: p.curToken}
@@ -326,13 +926,46 @@ func (p *Parser) noPrefixParseFnError(t token.Type) {
 	p.errors = append(p.errors, msg)
 }
 
-// parseBoolean - parses boolean expressions, returns ast.Boolean expression
-func (p *Parser) parseBoolean() ast.Expression {
-	return &ast.Boolean{Token: p.curToken, Value: p.curTokenIs(token.TRUE)}
+// parseBoolean - parses boolean expressions, returns ast.Boolean expression
+func (p *Parser) parseBoolean() ast.Expression {
+	return &ast.Boolean{Token: p.curToken, Value: p.curTokenIs(token.TRUE)}
+}
+
+// parseNullLiteral - parses null literal
+func (p *Parser) parseNullLiteral() ast.Expression {
+	return &ast.NullLiteral{Token: p.curToken}
+}
+
+// parseConditionalExpression - parses ternary conditional expression
+// <condition> ? <consequence> : <alternative>
+func (p *Parser) parseConditionalExpression(condition ast.Expression) ast.Expression {
+	expression := &ast.ConditionalExpression{
+		Token:     p.curToken,
+		Condition: condition,
+	}
+
+	p.nextToken()
+	expression.Consequence = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.COLON) {
+		return nil
+	}
+
+	// alternative is parsed with the lowest precedence, so the operator
+	// is right-associative: a ? b : c ? d : e == a ? b : (c ? d : e)
+	p.nextToken()
+	expression.Alternative = p.parseExpression(LOWEST)
+
+	return expression
 }
 
 // parseGroupedExpression - parses grouped expression with parenthesis
+// if parenthesis are followed by '=>' parses arrow function instead
 func (p *Parser) parseGroupedExpression() ast.Expression {
+	if p.isArrowFunctionAhead() {
+		return p.parseArrowFunction()
+	}
+
 	p.nextToken()
 
 	exp := p.parseExpression(LOWEST)
@@ -379,6 +1012,145 @@ func (p *Parser) parseIfExpression() ast.Expression {
 	return expression
 }
 
+// parseMatchExpression - parses pattern matching expression
+// match (<expression>) { <arm>, <arm>, ... }
+func (p *Parser) parseMatchExpression() ast.Expression {
+	expression := &ast.MatchExpression{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	p.nextToken()
+	expression.Subject = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.LBRACKET) {
+		return nil
+	}
+
+	expression.Arms = []*ast.MatchArm{}
+	for !p.peekTokenIs(token.RBRACKET) {
+		p.nextToken()
+
+		arm := p.parseMatchArm()
+		if arm == nil {
+			return nil
+		}
+		expression.Arms = append(expression.Arms, arm)
+
+		// arms are separated by commas, comma is optional after block
+		if p.peekTokenIs(token.COMMA) {
+			p.nextToken()
+		} else if !p.curTokenIs(token.RBRACKET) {
+			break
+		}
+	}
+
+	if !p.expectPeek(token.RBRACKET) {
+		return nil
+	}
+
+	return expression
+}
+
+// parseMatchArm - parses single arm of the match expression
+// <pattern> [if <guard>] => <expression> or <pattern> [if <guard>] => { <body> }
+func (p *Parser) parseMatchArm() *ast.MatchArm {
+	arm := &ast.MatchArm{Pattern: p.parsePattern()}
+	if arm.Pattern == nil {
+		return nil
+	}
+
+	if p.peekTokenIs(token.IF) {
+		p.nextToken()
+		p.nextToken()
+		arm.Guard = p.parseExpression(LOWEST)
+		if arm.Guard == nil {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.ARROW) {
+		return nil
+	}
+
+	if p.peekTokenIs(token.LBRACKET) {
+		p.nextToken()
+		arm.Body = p.parseBlockStatement()
+		return arm
+	}
+
+	p.nextToken()
+
+	// expression body is the only statement of the arm body
+	stmt := &ast.ExpressionStatement{Token: p.curToken}
+	stmt.Expression = p.parseExpression(LOWEST)
+	if stmt.Expression == nil {
+		return nil
+	}
+	arm.Body = &ast.BlockStatement{
+		Token:      stmt.Token,
+		Statements: []ast.Statement{stmt},
+	}
+
+	return arm
+}
+
+// parseTryExpression - parses try expression, at least one of catch
+// and finally clauses is required
+// try { <block> } catch (<identifier>) { <block> } finally { <block> }
+func (p *Parser) parseTryExpression() ast.Expression {
+	expression := &ast.TryExpression{Token: p.curToken}
+
+	if !p.expectPeek(token.LBRACKET) {
+		return nil
+	}
+	expression.Block = p.parseBlockStatement()
+
+	if p.peekTokenIs(token.CATCH) {
+		p.nextToken()
+
+		// the parameter of the catch clause is optional
+		if p.peekTokenIs(token.LPAREN) {
+			p.nextToken()
+			if !p.expectPeek(token.IDENT) {
+				return nil
+			}
+			expression.Parameter = &ast.Identifier{
+				Token: p.curToken,
+				Value: p.curToken.Literal,
+			}
+			if !p.expectPeek(token.RPAREN) {
+				return nil
+			}
+		}
+
+		if !p.expectPeek(token.LBRACKET) {
+			return nil
+		}
+		expression.Catch = p.parseBlockStatement()
+	}
+
+	if p.peekTokenIs(token.FINALLY) {
+		p.nextToken()
+		if !p.expectPeek(token.LBRACKET) {
+			return nil
+		}
+		expression.Finally = p.parseBlockStatement()
+	}
+
+	if expression.Catch == nil && expression.Finally == nil {
+		p.errors = append(p.errors, "try expression requires catch or finally clause")
+		return nil
+	}
+
+	return expression
+}
+
 // parseBlockStatement - parses block statement
 // { <statement> }
 func (p *Parser) parseBlockStatement() *ast.BlockStatement {
@@ -387,7 +1159,7 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 
 	p.nextToken()
 
-	if !p.curTokenIs(token.RBRACKET) {
+	for !p.curTokenIs(token.RBRACKET) && !p.curTokenIs(token.EOF) {
 		stmt := p.parseStatement()
 		if stmt != nil {
 			block.Statements = append(block.Statements, stmt)
@@ -405,7 +1177,9 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 		return nil
 	}
 
-	lit.Parameters = p.parseFunctionParameters()
+	if !p.parseFunctionParameters(lit) {
+		return nil
+	}
 
 	if !p.expectPeek(token.LBRACKET) {
 		return nil
@@ -416,33 +1190,150 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 	return lit
 }
 
-// parseFunctionParameters - parses function parameters
-// (<param 1>, <param2>, <param3>,..., <param N>)
-func (p *Parser) parseFunctionParameters() []*ast.Identifier {
-	identifiers := []*ast.Identifier{}
+// isArrowFunctionAhead - scans tokens after the current '(' up to the matching
+// ')' and checks if it's followed by '=>'. The scanning is performed on a copy
+// of the lexer so the parser state stays untouched
+func (p *Parser) isArrowFunctionAhead() bool {
+	lookahead := *p.l
+	depth := 1
+
+	for tok := p.peekToken; tok.Type != token.EOF; tok = lookahead.NextToken() {
+		switch tok.Type {
+		case token.LPAREN:
+			depth++
+		case token.RPAREN:
+			depth--
+		}
+		if depth == 0 {
+			return lookahead.NextToken().Type == token.ARROW
+		}
+	}
+	return false
+}
+
+// parseArrowFunction - parses arrow function into the function literal
+// (<params>) => <expression> or (<params>) => { <body> }
+func (p *Parser) parseArrowFunction() ast.Expression {
+	lit := &ast.FunctionLiteral{}
+
+	if !p.parseFunctionParameters(lit) {
+		return nil
+	}
+
+	if !p.expectPeek(token.ARROW) {
+		return nil
+	}
+	lit.Token = p.curToken
+
+	if p.peekTokenIs(token.LBRACKET) {
+		p.nextToken()
+		lit.Body = p.parseBlockStatement()
+		return lit
+	}
+
+	p.nextToken()
+
+	// expression body is the only statement of the function body
+	stmt := &ast.ExpressionStatement{Token: p.curToken}
+	stmt.Expression = p.parseExpression(LOWEST)
+	if stmt.Expression == nil {
+		return nil
+	}
+	lit.Body = &ast.BlockStatement{
+		Token:      lit.Token,
+		Statements: []ast.Statement{stmt},
+	}
+
+	return lit
+}
+
+// parseFunctionParameters - parses function parameters into the literal
+// (<param 1>, <param 2> = <default>,..., ...<rest param>)
+// returns false if parameters are malformed
+func (p *Parser) parseFunctionParameters(lit *ast.FunctionLiteral) bool {
+	lit.Parameters = []*ast.Identifier{}
+	lit.Defaults = make(map[string]ast.Expression)
 
 	if p.peekTokenIs(token.RPAREN) {
 		p.nextToken()
-		return identifiers
+		return true
 	}
 
 	p.nextToken()
 
-	identifier := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
-	identifiers = append(identifiers, identifier)
+	if !p.parseFunctionParameter(lit) {
+		return false
+	}
 
 	for p.peekTokenIs(token.COMMA) {
+		if lit.Rest != nil {
+			p.errors = append(p.errors, "rest parameter must be the last parameter")
+			return false
+		}
 		p.nextToken()
 		p.nextToken()
-		identifier := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
-		identifiers = append(identifiers, identifier)
+		if !p.parseFunctionParameter(lit) {
+			return false
+		}
 	}
 
-	if !p.expectPeek(token.RPAREN) {
-		return nil
+	return p.expectPeek(token.RPAREN)
+}
+
+// parseFunctionParameter - parses single function parameter:
+// <name>, <name> = <default expression> or ...<name>
+func (p *Parser) parseFunctionParameter(lit *ast.FunctionLiteral) bool {
+	isRest := p.curTokenIs(token.ELLIPSIS)
+	if isRest && !p.expectPeek(token.IDENT) {
+		return false
+	}
+
+	if !p.curTokenIs(token.IDENT) {
+		msg := fmt.Sprintf("expected parameter name, got '%s' instead",
+			p.curToken.Type)
+		p.errors = append(p.errors, msg)
+		return false
+	}
+
+	identifier := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	if hasParameter(lit, identifier.Value) {
+		msg := fmt.Sprintf("duplicate parameter name: %s", identifier.Value)
+		p.errors = append(p.errors, msg)
+		return false
+	}
+
+	if isRest {
+		lit.Rest = identifier
+		return true
+	}
+
+	lit.Parameters = append(lit.Parameters, identifier)
+
+	if p.peekTokenIs(token.ASSIGN) {
+		p.nextToken()
+		p.nextToken()
+		def := p.parseExpression(LOWEST)
+		if def == nil {
+			return false
+		}
+		lit.Defaults[identifier.Value] = def
 	}
 
-	return identifiers
+	return true
+}
+
+// hasParameter - checks if the function literal already has
+// parameter with the given name
+func hasParameter(lit *ast.FunctionLiteral, name string) bool {
+	if lit.Rest != nil && lit.Rest.Value == name {
+		return true
+	}
+	for _, param := range lit.Parameters {
+		if param.Value == name {
+			return true
+		}
+	}
+	return false
 }
 
 // parseCallExpression - parses call expression
@@ -453,6 +1344,69 @@ func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
 	return exp
 }
 
+// parseMemberExpression - parses access to the field
+// <expression>.<identifier> or <expression>?.<identifier>
+func (p *Parser) parseMemberExpression(object ast.Expression) ast.Expression {
+	exp := &ast.MemberExpression{
+		Token:    p.curToken,
+		Object:   object,
+		Optional: p.curTokenIs(token.OPTIONAL_DOT),
+	}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	exp.Member = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	return exp
+}
+
+// parseAssignExpression - parses assignment to the field, the operator is
+// right-associative: a.x = b.y = 1 == a.x = (b.y = 1)
+func (p *Parser) parseAssignExpression(target ast.Expression) ast.Expression {
+	member, ok := target.(*ast.MemberExpression)
+	if !ok || member.Optional {
+		msg := fmt.Sprintf("invalid assignment target: %s", target.String())
+		p.errors = append(p.errors, msg)
+		return nil
+	}
+
+	exp := &ast.AssignExpression{Token: p.curToken, Target: member}
+
+	p.nextToken()
+	exp.Value = p.parseExpression(LOWEST)
+
+	return exp
+}
+
+// parsePipelineExpression - parses pipeline and desugars it into the call
+// <expression> |> f           => f(<expression>)
+// <expression> |> f(<args>)   => f(<expression>, <args>)
+func (p *Parser) parsePipelineExpression(left ast.Expression) ast.Expression {
+	pipe := p.curToken
+	precedence := p.curPrecedence()
+	p.nextToken()
+
+	right := p.parseExpression(precedence)
+	if right == nil {
+		return nil
+	}
+
+	if call, ok := right.(*ast.CallExpression); ok {
+		return &ast.CallExpression{
+			Token:     call.Token,
+			Function:  call.Function,
+			Arguments: append([]ast.Expression{left}, call.Arguments...),
+		}
+	}
+
+	return &ast.CallExpression{
+		Token:     pipe,
+		Function:  right,
+		Arguments: []ast.Expression{left},
+	}
+}
+
 // parseCallArguments - parses arguments for call
 func (p *Parser) parseCallArguments() []ast.Expression {
 	args := []ast.Expression{}
@@ -464,12 +1418,22 @@ func (p *Parser) parseCallArguments() []ast.Expression {
 
 	p.nextToken()
 
-	args = append(args, p.parseExpression(LOWEST))
+	args = append(args, p.parseCallArgument())
 
 	for p.peekTokenIs(token.COMMA) {
 		p.nextToken()
 		p.nextToken()
-		args = append(args, p.parseExpression(LOWEST))
+		arg := p.parseCallArgument()
+
+		_, isKeyword := arg.(*ast.KeywordArgument)
+		_, prevIsKeyword := args[len(args)-1].(*ast.KeywordArgument)
+		if prevIsKeyword && !isKeyword {
+			p.errors = append(p.errors,
+				"positional argument follows keyword argument")
+			return nil
+		}
+
+		args = append(args, arg)
 	}
 
 	if !p.expectPeek(token.RPAREN) {
@@ -479,8 +1443,139 @@ func (p *Parser) parseCallArguments() []ast.Expression {
 	return args
 }
 
+// parseCallArgument - parses single argument of the call, it's either
+// an expression, a spread array or a keyword argument
+// <expression>, ...<expression> or <name>: <expression>
+func (p *Parser) parseCallArgument() ast.Expression {
+	if p.curTokenIs(token.ELLIPSIS) {
+		return p.parseSpreadElement()
+	}
+
+	if !p.curTokenIs(token.IDENT) || !p.peekTokenIs(token.COLON) {
+		return p.parseExpression(LOWEST)
+	}
+
+	arg := &ast.KeywordArgument{
+		Token: p.curToken,
+		Name:  &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal},
+	}
+
+	p.nextToken()
+	p.nextToken()
+
+	arg.Value = p.parseExpression(LOWEST)
+
+	return arg
+}
+
 // parseStringLiteral - parses string literal expression and returns
 // StringLiteral object
 func (p *Parser) parseStringLiteral() ast.Expression {
 	return &ast.StringLiteral{Token:p.curToken, Value:p.curToken.Literal}
-}
\ No newline at end of file
+}
+
+// parseArrayLiteral - parses array literal
+// [<comma separated expressions>]
+func (p *Parser) parseArrayLiteral() ast.Expression {
+	array := &ast.ArrayLiteral{Token: p.curToken}
+	array.Elements = p.parseExpressionList(token.RSQUARE)
+	if array.Elements == nil {
+		return nil
+	}
+	return array
+}
+
+// parseExpressionList - parses comma separated expressions until the
+// end token
+func (p *Parser) parseExpressionList(end token.Type) []ast.Expression {
+	list := []ast.Expression{}
+
+	if p.peekTokenIs(end) {
+		p.nextToken()
+		return list
+	}
+
+	p.nextToken()
+	list = append(list, p.parseListElement())
+
+	for p.peekTokenIs(token.COMMA) {
+		p.nextToken()
+		p.nextToken()
+		list = append(list, p.parseListElement())
+	}
+
+	if !p.expectPeek(end) {
+		return nil
+	}
+
+	return list
+}
+
+// parseListElement - parses element of the list which is either
+// an expression or a spread array
+// <expression> or ...<expression>
+func (p *Parser) parseListElement() ast.Expression {
+	if p.curTokenIs(token.ELLIPSIS) {
+		return p.parseSpreadElement()
+	}
+	return p.parseExpression(LOWEST)
+}
+
+// parseSpreadElement - parses spread element
+// ...<expression>
+func (p *Parser) parseSpreadElement() ast.Expression {
+	spread := &ast.SpreadElement{Token: p.curToken}
+
+	p.nextToken()
+	spread.Value = p.parseExpression(LOWEST)
+	if spread.Value == nil {
+		return nil
+	}
+
+	return spread
+}
+
+// parseHashLiteral - parses hash literal
+// {<key>: <value>, ...<expression>, <key>: <value>, ...}
+func (p *Parser) parseHashLiteral() ast.Expression {
+	hash := &ast.HashLiteral{Token: p.curToken}
+	hash.Pairs = []*ast.HashPair{}
+
+	for !p.peekTokenIs(token.RBRACKET) {
+		p.nextToken()
+
+		if p.curTokenIs(token.ELLIPSIS) {
+			spread := p.parseSpreadElement()
+			if spread == nil {
+				return nil
+			}
+			hash.Pairs = append(hash.Pairs, &ast.HashPair{Key: spread})
+
+			if !p.peekTokenIs(token.RBRACKET) && !p.expectPeek(token.COMMA) {
+				return nil
+			}
+			continue
+		}
+
+		key := p.parseExpression(LOWEST)
+
+		if !p.expectPeek(token.COLON) {
+			return nil
+		}
+
+		p.nextToken()
+		value := p.parseExpression(LOWEST)
+
+		hash.Pairs = append(hash.Pairs, &ast.HashPair{Key: key, Value: value})
+
+		if !p.peekTokenIs(token.RBRACKET) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RBRACKET) {
+		return nil
+	}
+
+	return hash
+}
@@ -350,6 +350,90 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"add(a + b + c * d / f + g)",
 			"add((((a + b) + ((c * d) / f)) + g))",
 		},
+		{
+			"value |> f",
+			"f(value)",
+		},
+		{
+			"value |> f |> g(extra)",
+			"g(f(value), extra)",
+		},
+		{
+			"a + b |> f(c * d) |> g",
+			"g(f((a + b), (c * d)))",
+		},
+		{
+			"a == b |> f",
+			"f((a == b))",
+		},
+		{
+			"a |> f(b: 1)",
+			"f(a, b: 1)",
+		},
+		{
+			"a ? b : c",
+			"(a ? b : c)",
+		},
+		{
+			"a < b ? a + 1 : b * 2",
+			"((a < b) ? (a + 1) : (b * 2))",
+		},
+		{
+			"a ? b : c ? d : e",
+			"(a ? b : (c ? d : e))",
+		},
+		{
+			"a ? b ? c : d : e",
+			"(a ? (b ? c : d) : e)",
+		},
+		{
+			"a ?? b ?? c",
+			"((a ?? b) ?? c)",
+		},
+		{
+			"a ?? b == c",
+			"(a ?? (b == c))",
+		},
+		{
+			"a ?? b ? c : d ?? e",
+			"((a ?? b) ? c : (d ?? e))",
+		},
+		{
+			"a ?? b |> f",
+			"f((a ?? b))",
+		},
+		{
+			"x |> f ? a : b",
+			"(f(x) ? a : b)",
+		},
+		{
+			"f(a ? b : c, d: e ?? null)",
+			"f((a ? b : c), d: (e ?? null))",
+		},
+		{
+			"a.b.c",
+			"a.b.c",
+		},
+		{
+			"a?.b.c?.d",
+			"a?.b.c?.d",
+		},
+		{
+			"-a?.b ?? c",
+			"((-a?.b) ?? c)",
+		},
+		{
+			"-p.x * f(p).y",
+			"((-p.x) * f(p).y)",
+		},
+		{
+			"a.x = b.y = c + 1",
+			"(a.x = (b.y = (c + 1)))",
+		},
+		{
+			"p.x = a ? b : c",
+			"(p.x = (a ? b : c))",
+		},
 	}
 
 	for _, tt := range tests {
@@ -696,13 +780,19 @@ func testLetStatement(t *testing.T, s ast.Statement, name string) bool {
 		return false
 	}
 
-	if letStmt.Name.Value != name {
-		t.Errorf("letStmt.Name.Value not '%s'. got=%s", name, letStmt.Name.Value)
+	ident, ok := letStmt.Pattern.(*ast.Identifier)
+	if !ok {
+		t.Errorf("letStmt.Pattern not *ast.Identifier. got=%T", letStmt.Pattern)
+		return false
+	}
+
+	if ident.Value != name {
+		t.Errorf("letStmt.Pattern.Value not '%s'. got=%s", name, ident.Value)
 		return false
 	}
 
-	if letStmt.Name.TokenLiteral() != name {
-		t.Errorf("s.Name not '%s'. got=%s", name, letStmt.Name)
+	if ident.TokenLiteral() != name {
+		t.Errorf("s.Pattern not '%s'. got=%s", name, ident)
 		return false
 	}
 
@@ -846,4 +936,1020 @@ func TestStringLiteralExpressions(t *testing.T) {
 		t.Errorf("literal.Value has wrong value, expected=%q, got=%q",
 			"hello, world!", literal.Value)
 	}
-}
\ No newline at end of file
+}
+func TestFunctionDefaultAndRestParameters(t *testing.T) {
+	tests := []struct {
+		input            string
+		expectedParams   []string
+		expectedDefaults map[string]string
+		expectedRest     string
+		expectedString   string
+	}{
+		{
+			input:            "function(a, b = 10) {};",
+			expectedParams:   []string{"a", "b"},
+			expectedDefaults: map[string]string{"b": "10"},
+			expectedString:   "function(a, b = 10)",
+		},
+		{
+			input:            "function(a = 1 + 2, b = a * 2) {};",
+			expectedParams:   []string{"a", "b"},
+			expectedDefaults: map[string]string{"a": "(1 + 2)", "b": "(a * 2)"},
+			expectedString:   "function(a = (1 + 2), b = (a * 2))",
+		},
+		{
+			input:            "function(...rest) {};",
+			expectedParams:   []string{},
+			expectedDefaults: map[string]string{},
+			expectedRest:     "rest",
+			expectedString:   "function(...rest)",
+		},
+		{
+			input:            "function(a, b = 10, ...rest) {};",
+			expectedParams:   []string{"a", "b"},
+			expectedDefaults: map[string]string{"b": "10"},
+			expectedRest:     "rest",
+			expectedString:   "function(a, b = 10, ...rest)",
+		},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		function := stmt.Expression.(*ast.FunctionLiteral)
+
+		if len(function.Parameters) != len(tt.expectedParams) {
+			t.Fatalf("length parameters wrong. want %d, got=%d\n",
+				len(tt.expectedParams), len(function.Parameters))
+		}
+		for i, ident := range tt.expectedParams {
+			testLiteralExpression(t, function.Parameters[i], ident)
+		}
+
+		if len(function.Defaults) != len(tt.expectedDefaults) {
+			t.Fatalf("length defaults wrong. want %d, got=%d\n",
+				len(tt.expectedDefaults), len(function.Defaults))
+		}
+		for name, expected := range tt.expectedDefaults {
+			def, ok := function.Defaults[name]
+			if !ok {
+				t.Fatalf("no default for parameter %q", name)
+			}
+			if def.String() != expected {
+				t.Errorf("default of %q wrong. want=%q, got=%q",
+					name, expected, def.String())
+			}
+		}
+
+		if tt.expectedRest == "" && function.Rest != nil {
+			t.Errorf("function.Rest is not nil. got=%q", function.Rest)
+		}
+		if tt.expectedRest != "" {
+			testLiteralExpression(t, function.Rest, tt.expectedRest)
+		}
+
+		if function.String() != tt.expectedString {
+			t.Errorf("function.String() wrong. want=%q, got=%q",
+				tt.expectedString, function.String())
+		}
+	}
+}
+
+func TestKeywordArgumentParsing(t *testing.T) {
+	input := "f(1, b: 2 * 3, a: x);"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	exp, ok := stmt.Expression.(*ast.CallExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.CallExpression. got=%T",
+			stmt.Expression)
+	}
+
+	if len(exp.Arguments) != 3 {
+		t.Fatalf("wrong length of arguments. got=%d", len(exp.Arguments))
+	}
+
+	testLiteralExpression(t, exp.Arguments[0], 1)
+
+	keywords := []struct {
+		name  string
+		value string
+	}{
+		{"b", "(2 * 3)"},
+		{"a", "x"},
+	}
+	for i, kw := range keywords {
+		arg, ok := exp.Arguments[i+1].(*ast.KeywordArgument)
+		if !ok {
+			t.Fatalf("argument %d is not ast.KeywordArgument. got=%T",
+				i+1, exp.Arguments[i+1])
+		}
+		testIdentifier(t, arg.Name, kw.name)
+		if arg.Value.String() != kw.value {
+			t.Errorf("keyword %q value wrong. want=%q, got=%q",
+				kw.name, kw.value, arg.Value.String())
+		}
+	}
+
+	if exp.String() != "f(1, b: (2 * 3), a: x)" {
+		t.Errorf("exp.String() wrong. got=%q", exp.String())
+	}
+}
+
+func TestFunctionParameterErrors(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"function(...rest, a) {}", "rest parameter must be the last parameter"},
+		{"function(a, a) {}", "duplicate parameter name: a"},
+		{"function(a, ...a) {}", "duplicate parameter name: a"},
+		{"function(1) {}", "expected parameter name, got 'INT' instead"},
+		{"f(a: 1, 2)", "positional argument follows keyword argument"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("expected parser errors for %q, got none", tt.input)
+			continue
+		}
+		if errors[0] != tt.expectedError {
+			t.Errorf("wrong error for %q. want=%q, got=%q",
+				tt.input, tt.expectedError, errors[0])
+		}
+	}
+}
+
+func TestArrowFunctionParsing(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedParams []string
+		expectedBody   string
+		expectedString string
+	}{
+		{
+			input:          "(x) => x * 2",
+			expectedParams: []string{"x"},
+			expectedBody:   "(x * 2)",
+			expectedString: "(x) => (x * 2)",
+		},
+		{
+			input:          "() => 1",
+			expectedParams: []string{},
+			expectedBody:   "1",
+			expectedString: "() => 1",
+		},
+		{
+			input:          "(a, b) => { let c = a + b; return c; }",
+			expectedParams: []string{"a", "b"},
+			expectedBody:   "let c = (a + b);return c;",
+			expectedString: "(a, b) => let c = (a + b);return c;",
+		},
+		{
+			input:          "(a, b = (1 + 2), ...rest) => (a)",
+			expectedParams: []string{"a", "b"},
+			expectedBody:   "a",
+			expectedString: "(a, b = (1 + 2), ...rest) => a",
+		},
+		{
+			input:          "(x) => (y) => x + y",
+			expectedParams: []string{"x"},
+			expectedBody:   "(y) => (x + y)",
+			expectedString: "(x) => (y) => (x + y)",
+		},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statements. got=%d",
+				len(program.Statements))
+		}
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		function, ok := stmt.Expression.(*ast.FunctionLiteral)
+		if !ok {
+			t.Fatalf("stmt.Expression is not ast.FunctionLiteral. got=%T",
+				stmt.Expression)
+		}
+
+		if len(function.Parameters) != len(tt.expectedParams) {
+			t.Fatalf("length parameters wrong. want %d, got=%d\n",
+				len(tt.expectedParams), len(function.Parameters))
+		}
+		for i, ident := range tt.expectedParams {
+			testLiteralExpression(t, function.Parameters[i], ident)
+		}
+
+		if function.Body.String() != tt.expectedBody {
+			t.Errorf("function.Body wrong. want=%q, got=%q",
+				tt.expectedBody, function.Body.String())
+		}
+		if function.String() != tt.expectedString {
+			t.Errorf("function.String() wrong. want=%q, got=%q",
+				tt.expectedString, function.String())
+		}
+	}
+}
+
+func TestArrowFunctionDisambiguation(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"(a + b) * c", "((a + b) * c)"},
+		{"(a) + (b)", "(a + b)"},
+		{"f((x) => x, (y))", "f((x) => x, y)"},
+		{"((x) => x)(5)", "(x) => x(5)"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+
+	// call expression is not a valid parameter of the arrow function
+	input := "(f(a, b)) => 1"
+	l := lexer.New(input)
+	p := New(l)
+	p.ParseProgram()
+	if len(p.Errors()) == 0 {
+		t.Errorf("expected parser errors for %q, got none", input)
+	}
+}
+
+func TestArrayLiteralParsing(t *testing.T) {
+	input := "[1, 2 * 2, a + b]"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	array, ok := stmt.Expression.(*ast.ArrayLiteral)
+	if !ok {
+		t.Fatalf("exp not ast.ArrayLiteral. got=%T", stmt.Expression)
+	}
+
+	if len(array.Elements) != 3 {
+		t.Fatalf("len(array.Elements) not 3. got=%d", len(array.Elements))
+	}
+
+	testIntegerLiteral(t, array.Elements[0], 1)
+	testInfixExpression(t, array.Elements[1], 2, "*", 2)
+	testInfixExpression(t, array.Elements[2], "a", "+", "b")
+}
+
+func TestHashLiteralParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"{}", "{}"},
+		{`{"one": 1, "two": 1 + 1}`, "{one: 1, two: (1 + 1)}"},
+		{`{1: true, false: [], key: "value",}`, "{1: true, false: [], key: value}"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		hash, ok := stmt.Expression.(*ast.HashLiteral)
+		if !ok {
+			t.Fatalf("exp not ast.HashLiteral. got=%T", stmt.Expression)
+		}
+
+		if hash.String() != tt.expected {
+			t.Errorf("hash.String() wrong. want=%q, got=%q",
+				tt.expected, hash.String())
+		}
+	}
+}
+
+func TestDestructuringLetStatements(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedPattern string
+	}{
+		{"let [a, b] = arr;", "[a, b]"},
+		{"let [a, b, ...rest] = arr;", "[a, b, ...rest]"},
+		{"let [a, [b, c], d = 10] = arr;", "[a, [b, c], d = 10]"},
+		{"let [] = arr;", "[]"},
+		{"let {name, age} = person;", "{name, age}"},
+		{"let {name: n, age = 18, ...other} = person;", "{name: n, age = 18, ...other}"},
+		{`let {"full name": name} = person;`, "{full name: name}"},
+		{"let {address: {city}, tags: [first]} = person;", "{address: {city}, tags: [first]}"},
+		{"let [{x, y} = {}, ...points] = arr;", "[{x, y} = {}, ...points]"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statements. got=%d",
+				len(program.Statements))
+		}
+
+		stmt, ok := program.Statements[0].(*ast.LetStatement)
+		if !ok {
+			t.Fatalf("stmt not *ast.LetStatement. got=%T", program.Statements[0])
+		}
+
+		if stmt.Pattern.String() != tt.expectedPattern {
+			t.Errorf("pattern wrong. want=%q, got=%q",
+				tt.expectedPattern, stmt.Pattern.String())
+		}
+	}
+}
+
+func TestDestructuringPatternErrors(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"let [a, ...rest, b] = arr;", "rest element must be the last element"},
+		{"let {...rest, a} = h;", "rest element must be the last element"},
+		{"let [a, *] = arr;", "expected identifier or pattern, got '*' instead"},
+		{"let {1: a} = h;", "expected hash pattern key, got 'INT' instead"},
+		{`let {"a"} = h;`, "expected next token to be ':', got '}' instead"},
+		{"let 5 = x;", "expected identifier or pattern, got 'INT' instead"},
+		{"let [a, a] = arr;", "duplicate pattern name: a"},
+		{"let [a, [b = 1, a]] = arr;", "duplicate pattern name: a"},
+		{"let {a, b: a} = h;", "duplicate pattern name: a"},
+		{"let [a, ...a] = arr;", "duplicate pattern name: a"},
+		{"let {a, ...a} = h;", "duplicate pattern name: a"},
+		{"let Pair(x, x) = p;", "duplicate pattern name: x"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("expected parser errors for %q, got none", tt.input)
+			continue
+		}
+		if errors[0] != tt.expectedError {
+			t.Errorf("wrong error for %q. want=%q, got=%q",
+				tt.input, tt.expectedError, errors[0])
+		}
+	}
+}
+
+func TestMatchExpressionParsing(t *testing.T) {
+	input := `match (value) {
+		0 => "zero",
+		-1 => "minus one",
+		[x, y] => x + y,
+		{kind: "a", size} if size > 10 => { let half = size / 2; half },
+		null => "nothing",
+		n if n < 0 => "negative"
+		, _ => "other",
+	}`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statements. got=%d",
+			len(program.Statements))
+	}
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	exp, ok := stmt.Expression.(*ast.MatchExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.MatchExpression. got=%T",
+			stmt.Expression)
+	}
+
+	testIdentifier(t, exp.Subject, "value")
+
+	expected := []struct {
+		pattern     string
+		patternType string
+		guard       string
+		body        string
+	}{
+		{"0", "*ast.LiteralPattern", "", "zero"},
+		{"(-1)", "*ast.LiteralPattern", "", "minus one"},
+		{"[x, y]", "*ast.ArrayPattern", "", "(x + y)"},
+		{"{kind: a, size}", "*ast.HashPattern", "(size > 10)", "let half = (size / 2);half"},
+		{"null", "*ast.LiteralPattern", "", "nothing"},
+		{"n", "*ast.Identifier", "(n < 0)", "negative"},
+		{"_", "*ast.WildcardPattern", "", "other"},
+	}
+
+	if len(exp.Arms) != len(expected) {
+		t.Fatalf("wrong number of arms. want=%d, got=%d",
+			len(expected), len(exp.Arms))
+	}
+
+	for i, tt := range expected {
+		arm := exp.Arms[i]
+		if arm.Pattern.String() != tt.pattern {
+			t.Errorf("arms[%d] pattern wrong. want=%q, got=%q",
+				i, tt.pattern, arm.Pattern.String())
+		}
+		if fmt.Sprintf("%T", arm.Pattern) != tt.patternType {
+			t.Errorf("arms[%d] pattern type wrong. want=%s, got=%T",
+				i, tt.patternType, arm.Pattern)
+		}
+		if tt.guard == "" && arm.Guard != nil {
+			t.Errorf("arms[%d] guard is not nil. got=%q", i, arm.Guard)
+		}
+		if tt.guard != "" && (arm.Guard == nil || arm.Guard.String() != tt.guard) {
+			t.Errorf("arms[%d] guard wrong. want=%q, got=%v", i, tt.guard, arm.Guard)
+		}
+		if arm.Body.String() != tt.body {
+			t.Errorf("arms[%d] body wrong. want=%q, got=%q",
+				i, tt.body, arm.Body.String())
+		}
+	}
+}
+
+func TestMatchExpressionErrors(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"match x { _ => 1 }", "expected next token to be '(', got 'IDENT' instead"},
+		{"match (x) { 1 -> 2 }", "expected next token to be '=>', got '-' instead"},
+		{"match (x) { 1 => 2 3 => 4 }", "expected next token to be '}', got 'INT' instead"},
+		{"match (x) { * => 2 }", "expected identifier or pattern, got '*' instead"},
+		{"match (x) { -a => 2 }", "expected identifier or pattern, got '-' instead"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("expected parser errors for %q, got none", tt.input)
+			continue
+		}
+		if errors[0] != tt.expectedError {
+			t.Errorf("wrong error for %q. want=%q, got=%q",
+				tt.input, tt.expectedError, errors[0])
+		}
+	}
+}
+
+func TestTryExpressionParsing(t *testing.T) {
+	tests := []struct {
+		input             string
+		expectedParameter string
+		hasCatch          bool
+		hasFinally        bool
+		expectedString    string
+	}{
+		{
+			input:             "try { risky(); } catch (e) { e }",
+			expectedParameter: "e",
+			hasCatch:          true,
+			expectedString:    "try risky() catch(e) e",
+		},
+		{
+			input:          "try { 1 } finally { cleanup() }",
+			hasFinally:     true,
+			expectedString: "try 1 finally cleanup()",
+		},
+		{
+			input:             "try { 1 } catch (err) { 2 } finally { 3 }",
+			expectedParameter: "err",
+			hasCatch:          true,
+			hasFinally:        true,
+			expectedString:    "try 1 catch(err) 2 finally 3",
+		},
+		{
+			input:          "try { 1 } catch { 2 }",
+			hasCatch:       true,
+			expectedString: "try 1 catch 2",
+		},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		exp, ok := stmt.Expression.(*ast.TryExpression)
+		if !ok {
+			t.Fatalf("stmt.Expression is not ast.TryExpression. got=%T",
+				stmt.Expression)
+		}
+
+		if tt.expectedParameter == "" && exp.Parameter != nil {
+			t.Errorf("exp.Parameter is not nil. got=%q", exp.Parameter)
+		}
+		if tt.expectedParameter != "" {
+			testIdentifier(t, exp.Parameter, tt.expectedParameter)
+		}
+		if (exp.Catch != nil) != tt.hasCatch {
+			t.Errorf("exp.Catch wrong. want present=%t, got=%v", tt.hasCatch, exp.Catch)
+		}
+		if (exp.Finally != nil) != tt.hasFinally {
+			t.Errorf("exp.Finally wrong. want present=%t, got=%v", tt.hasFinally, exp.Finally)
+		}
+		if exp.String() != tt.expectedString {
+			t.Errorf("exp.String() wrong. want=%q, got=%q",
+				tt.expectedString, exp.String())
+		}
+	}
+}
+
+func TestThrowStatementParsing(t *testing.T) {
+	input := `throw {"message": "oops", "kind": "MyError"};`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statements. got=%d",
+			len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.ThrowStatement)
+	if !ok {
+		t.Fatalf("stmt not *ast.ThrowStatement. got=%T", program.Statements[0])
+	}
+	if stmt.String() != "throw {message: oops, kind: MyError};" {
+		t.Errorf("stmt.String() wrong. got=%q", stmt.String())
+	}
+}
+
+func TestTryExpressionErrors(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"try { 1 }", "try expression requires catch or finally clause"},
+		{"try 1 catch { 2 }", "expected next token to be '{', got 'INT' instead"},
+		{"try { 1 } catch (1) { 2 }", "expected next token to be 'IDENT', got 'INT' instead"},
+		{"try { 1 } finally 3", "expected next token to be '{', got 'INT' instead"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("expected parser errors for %q, got none", tt.input)
+			continue
+		}
+		if errors[0] != tt.expectedError {
+			t.Errorf("wrong error for %q. want=%q, got=%q",
+				tt.input, tt.expectedError, errors[0])
+		}
+	}
+}
+
+func TestStructStatementParsing(t *testing.T) {
+	input := `struct Point { x, y }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statements. got=%d",
+			len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.StructStatement)
+	if !ok {
+		t.Fatalf("stmt not *ast.StructStatement. got=%T", program.Statements[0])
+	}
+	if stmt.Name.Value != "Point" {
+		t.Errorf("stmt.Name.Value not 'Point'. got=%q", stmt.Name.Value)
+	}
+	if len(stmt.Fields) != 2 {
+		t.Fatalf("stmt.Fields has wrong length. got=%d", len(stmt.Fields))
+	}
+	testIdentifier(t, stmt.Fields[0], "x")
+	testIdentifier(t, stmt.Fields[1], "y")
+}
+
+func TestStructStatementErrors(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"struct { x }", "expected next token to be 'IDENT', got '{' instead"},
+		{"struct Point { x, x }", "duplicate field name: x"},
+		{"struct Point { x y }", "expected next token to be ',', got 'IDENT' instead"},
+		{"p.1", "expected next token to be 'IDENT', got 'INT' instead"},
+		{"a = 1", "invalid assignment target: a"},
+		{"a?.b = 1", "invalid assignment target: a?.b"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("expected parser errors for %q, got none", tt.input)
+			continue
+		}
+		if errors[0] != tt.expectedError {
+			t.Errorf("wrong error for %q. want=%q, got=%q",
+				tt.input, tt.expectedError, errors[0])
+		}
+	}
+}
+
+func TestImplStatementParsing(t *testing.T) {
+	input := `impl Point {
+		norm(self) { self.x * self.x + self.y * self.y };
+		scale(self, k = 2) { Point(self.x * k, self.y * k) }
+	}`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statements. got=%d",
+			len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.ImplStatement)
+	if !ok {
+		t.Fatalf("stmt not *ast.ImplStatement. got=%T", program.Statements[0])
+	}
+	if stmt.Name.Value != "Point" {
+		t.Errorf("stmt.Name.Value not 'Point'. got=%q", stmt.Name.Value)
+	}
+
+	expected := []string{
+		"norm(self)((self.x * self.x) + (self.y * self.y))",
+		"scale(self, k = 2)Point((self.x * k), (self.y * k))",
+	}
+	if len(stmt.Methods) != len(expected) {
+		t.Fatalf("stmt.Methods has wrong length. got=%d", len(stmt.Methods))
+	}
+	for i, method := range stmt.Methods {
+		if method.String() != expected[i] {
+			t.Errorf("method %d wrong. want=%q, got=%q", i, expected[i], method.String())
+		}
+	}
+}
+
+func TestImplStatementTrailingSemicolon(t *testing.T) {
+	input := `impl Point { get(self) { self.x } }; 1`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 2 {
+		t.Fatalf("program.Statements does not contain 2 statements. got=%d",
+			len(program.Statements))
+	}
+	if _, ok := program.Statements[0].(*ast.ImplStatement); !ok {
+		t.Fatalf("stmt not *ast.ImplStatement. got=%T", program.Statements[0])
+	}
+}
+
+func TestMethodCallParsing(t *testing.T) {
+	input := `"abc".upper().len() + arr.push(4, 5).len()`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	expected := "(abc.upper().len() + arr.push(4, 5).len())"
+	if program.String() != expected {
+		t.Errorf("program.String() wrong. want=%q, got=%q", expected, program.String())
+	}
+}
+
+func TestImplStatementErrors(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"impl { }", "expected next token to be 'IDENT', got '{' instead"},
+		{"impl Point { norm() { 1 } }", "method norm must have receiver parameter"},
+		{"impl Point { a(self) { 1 } a(self) { 2 } }", "duplicate method name: a"},
+		{"impl Point { a(self) 1 }", "expected next token to be '{', got 'INT' instead"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("expected parser errors for %q, got none", tt.input)
+			continue
+		}
+		if errors[0] != tt.expectedError {
+			t.Errorf("wrong error for %q. want=%q, got=%q",
+				tt.input, tt.expectedError, errors[0])
+		}
+	}
+}
+
+func TestEnumStatementParsing(t *testing.T) {
+	input := `enum Shape { Circle(r), Rect(w, h), Empty }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statements. got=%d",
+			len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.EnumStatement)
+	if !ok {
+		t.Fatalf("stmt not *ast.EnumStatement. got=%T", program.Statements[0])
+	}
+	if stmt.Name.Value != "Shape" {
+		t.Errorf("stmt.Name.Value not 'Shape'. got=%q", stmt.Name.Value)
+	}
+
+	expected := []struct {
+		name   string
+		fields []string
+	}{
+		{"Circle", []string{"r"}},
+		{"Rect", []string{"w", "h"}},
+		{"Empty", []string{}},
+	}
+	if len(stmt.Variants) != len(expected) {
+		t.Fatalf("stmt.Variants has wrong length. got=%d", len(stmt.Variants))
+	}
+	for i, variant := range stmt.Variants {
+		testIdentifier(t, variant.Name, expected[i].name)
+		if len(variant.Fields) != len(expected[i].fields) {
+			t.Fatalf("variant %s has wrong number of fields. got=%d",
+				expected[i].name, len(variant.Fields))
+		}
+		for j, field := range variant.Fields {
+			testIdentifier(t, field, expected[i].fields[j])
+		}
+	}
+
+	if stmt.String() != input {
+		t.Errorf("stmt.String() wrong. want=%q, got=%q", input, stmt.String())
+	}
+}
+
+func TestVariantPatternParsing(t *testing.T) {
+	input := `match (s) { Circle(r) => r, Rect(w, [h, _] = [1, 2]) => w, Empty() => 0, Empty => 0,
+		Shape.Circle(r) => r, Shape.Empty() => 0, Shape.Empty => 0 }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	exp, ok := stmt.Expression.(*ast.MatchExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression not *ast.MatchExpression. got=%T", stmt.Expression)
+	}
+
+	expected := []string{"Circle(r)", "Rect(w, [h, _] = [1, 2])", "Empty()", "Empty",
+		"Shape.Circle(r)", "Shape.Empty()", "Shape.Empty"}
+	if len(exp.Arms) != len(expected) {
+		t.Fatalf("exp.Arms has wrong length. got=%d", len(exp.Arms))
+	}
+	for i, arm := range exp.Arms {
+		if arm.Pattern.String() != expected[i] {
+			t.Errorf("pattern %d wrong. want=%q, got=%q", i, expected[i], arm.Pattern.String())
+		}
+	}
+}
+
+func TestEnumStatementErrors(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"enum { A }", "expected next token to be 'IDENT', got '{' instead"},
+		{"enum Shape { A, A }", "duplicate variant name: A"},
+		{"enum Shape { A(x, x) }", "duplicate field name: x"},
+		{"enum Shape { A B }", "expected next token to be ',', got 'IDENT' instead"},
+		{"enum Shape { A(1) }", "expected next token to be 'IDENT', got 'INT' instead"},
+		{"let Circle(r s) = c;", "expected next token to be ')', got 'IDENT' instead"},
+		{"let Shape.(r) = c;", "expected next token to be 'IDENT', got '(' instead"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("expected parser errors for %q, got none", tt.input)
+			continue
+		}
+		if errors[0] != tt.expectedError {
+			t.Errorf("wrong error for %q. want=%q, got=%q",
+				tt.input, tt.expectedError, errors[0])
+		}
+	}
+}
+
+func TestTraitStatementParsing(t *testing.T) {
+	input := `trait Shape { area(); perimeter(); scale(k) }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statements. got=%d",
+			len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.TraitStatement)
+	if !ok {
+		t.Fatalf("stmt not *ast.TraitStatement. got=%T", program.Statements[0])
+	}
+	if stmt.Name.Value != "Shape" {
+		t.Errorf("stmt.Name.Value not 'Shape'. got=%q", stmt.Name.Value)
+	}
+	if len(stmt.Methods) != 3 {
+		t.Fatalf("stmt.Methods has wrong length. got=%d", len(stmt.Methods))
+	}
+	if stmt.String() != "trait Shape { area(); perimeter(); scale(k) }" {
+		t.Errorf("stmt.String() wrong. got=%q", stmt.String())
+	}
+}
+
+func TestTraitStatementTrailingSemicolon(t *testing.T) {
+	input := `trait Shape { area() }; 1`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 2 {
+		t.Fatalf("program.Statements does not contain 2 statements. got=%d",
+			len(program.Statements))
+	}
+	if _, ok := program.Statements[0].(*ast.TraitStatement); !ok {
+		t.Fatalf("stmt not *ast.TraitStatement. got=%T", program.Statements[0])
+	}
+}
+
+func TestImplForStatementParsing(t *testing.T) {
+	input := `impl Shape for Circle { area(self) { 3 * self.r * self.r } }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt, ok := program.Statements[0].(*ast.ImplStatement)
+	if !ok {
+		t.Fatalf("stmt not *ast.ImplStatement. got=%T", program.Statements[0])
+	}
+	testIdentifier(t, stmt.Trait, "Shape")
+	testIdentifier(t, stmt.Name, "Circle")
+
+	expected := "impl Shape for Circle { area(self)((3 * self.r) * self.r) }"
+	if stmt.String() != expected {
+		t.Errorf("stmt.String() wrong. want=%q, got=%q", expected, stmt.String())
+	}
+}
+
+func TestTraitStatementErrors(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"trait { area() }", "expected next token to be 'IDENT', got '{' instead"},
+		{"trait Shape { area }", "expected next token to be '(', got '}' instead"},
+		{"trait Shape { area(); area() }", "duplicate method name: area"},
+		{"trait Shape { area() { 1 } }", "expected next token to be 'IDENT', got '{' instead"},
+		{"impl Shape for { }", "expected next token to be 'IDENT', got '{' instead"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("expected parser errors for %q, got none", tt.input)
+			continue
+		}
+		if errors[0] != tt.expectedError {
+			t.Errorf("wrong error for %q. want=%q, got=%q",
+				tt.input, tt.expectedError, errors[0])
+		}
+	}
+}
+
+func TestSpreadElementParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"f(...args)", "f(...args)"},
+		{"f(a, ...rest, b: 1)", "f(a, ...rest, b: 1)"},
+		{"[...a, 1, ...b]", "[...a, 1, ...b]"},
+		{"[...f(x) |> g]", "[...g(f(x))]"},
+		{`{...defaults, "k": v}`, "{...defaults, k: v}"},
+		{`{"a": 1, ...other}`, "{a: 1, ...other}"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("wrong result for %q. want=%q, got=%q",
+				tt.input, tt.expected, program.String())
+		}
+	}
+}
+
+func TestSpreadElementErrors(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"f(b: 1, ...rest)", "positional argument follows keyword argument"},
+		{"[...]", "no prefix parse fn found for ']' prefix"},
+		{"{...a: 1}", "expected next token to be ',', got ':' instead"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("expected parser errors for %q, got none", tt.input)
+			continue
+		}
+		if errors[0] != tt.expectedError {
+			t.Errorf("wrong error for %q. want=%q, got=%q",
+				tt.input, tt.expectedError, errors[0])
+		}
+	}
+}
@@ -9,6 +9,17 @@ var keywords = map[string]Type{
 	"if":       IF,
 	"else":     ELSE,
 	"return":   RETURN,
+	"null":     NULL,
+	"match":    MATCH,
+	"try":      TRY,
+	"catch":    CATCH,
+	"finally":  FINALLY,
+	"throw":    THROW,
+	"struct":   STRUCT,
+	"impl":     IMPL,
+	"enum":     ENUM,
+	"trait":    TRAIT,
+	"for":      FOR,
 }
 
 const (
@@ -30,11 +41,23 @@ const (
 	LBRACKET = "{"
 	// RBRACKET - right (close) bracket
 	RBRACKET = "}"
+	// LSQUARE - left (open) square bracket
+	LSQUARE = "["
+	// RSQUARE - right (close) square bracket
+	RSQUARE = "]"
 
 	// COMMA - comma between operands, declarations, etc.
 	COMMA = ","
 	// SEMICOLON - semicolon between expressions
 	SEMICOLON = ";"
+	// COLON - separates keyword argument name from its value
+	COLON = ":"
+	// DOT - access to the field of the struct
+	DOT = "."
+	// ELLIPSIS - marks rest parameter of the function
+	ELLIPSIS = "..."
+	// ARROW - separates parameters and body of the arrow function
+	ARROW = "=>"
 
 	// PLUS - add/concat operator
 	PLUS = "+"
@@ -54,6 +77,14 @@ const (
 	GT = ">"
 	// OR - OR operator
 	OR = "|"
+	// PIPE - pipeline operator, passes left operand to the right one
+	PIPE = "|>"
+	// QUESTION - ternary conditional operator (cond ? a : b)
+	QUESTION = "?"
+	// NULLISH - null-coalescing operator, returns right operand if left one is null
+	NULLISH = "??"
+	// OPTIONAL_DOT - safe access to the member, null if the object is null
+	OPTIONAL_DOT = "?."
 	// EQ - operator for checking if both operands are equal
 	EQ = "=="
 	// NOTEQ - operator for checking if both operands are not equal
@@ -73,6 +104,28 @@ const (
 	ELSE = "ELSE"
 	// RETURN - return keyword for the function
 	RETURN = "RETURN"
+	// NULL - null value
+	NULL = "NULL"
+	// MATCH - pattern matching expression
+	MATCH = "MATCH"
+	// TRY - starts block which errors can be caught
+	TRY = "TRY"
+	// CATCH - block which handles errors of the try block
+	CATCH = "CATCH"
+	// FINALLY - block which is always executed after try/catch
+	FINALLY = "FINALLY"
+	// THROW - raises an error
+	THROW = "THROW"
+	// STRUCT - struct type declaration
+	STRUCT = "STRUCT"
+	// IMPL - declaration of methods of the type
+	IMPL = "IMPL"
+	// ENUM - enum (tagged union) declaration
+	ENUM = "ENUM"
+	// TRAIT - declaration of methods required from the type
+	TRAIT = "TRAIT"
+	// FOR - marks the type which implements the trait
+	FOR = "FOR"
 
 	// STRING - string data type
 	STRING = "STRING"
@@ -82,9 +135,12 @@ const (
 type Type string
 
 // Token - contains the type and literal of the language token
+// and its position in the source (line and column start from 1)
 type Token struct {
 	Type    Type
 	Literal string
+	Line    int
+	Column  int
 }
 
 // LookupIdent - looks into the keywords map to check if
@@ -0,0 +1,33 @@
+package vm
+
+import (
+	"github.com/technoboom/compiler/code"
+	"github.com/technoboom/compiler/object"
+)
+
+// Frame - call frame of the closure being executed
+type Frame struct {
+	cl *object.Closure
+	// instruction pointer inside the function
+	ip int
+	// position of the stack where locals of the function start
+	basePointer int
+}
+
+// NewFrame - creates frame for the closure call
+func NewFrame(cl *object.Closure, basePointer int) *Frame {
+	return &Frame{cl: cl, ip: -1, basePointer: basePointer}
+}
+
+// Instructions - returns instructions of the function
+func (f *Frame) Instructions() code.Instructions {
+	return f.cl.Fn.Instructions
+}
+
+// debug - returns debug info of the function, empty if it's stripped
+func (f *Frame) debug() *code.DebugInfo {
+	if f.cl.Fn.Debug == nil {
+		return &code.DebugInfo{}
+	}
+	return f.cl.Fn.Debug
+}
@@ -0,0 +1,641 @@
+// Package vm - implements stack-based virtual machine which executes
+// bytecode produced by the compiler
+package vm
+
+import (
+	"fmt"
+
+	"github.com/technoboom/compiler/code"
+	"github.com/technoboom/compiler/compiler"
+	"github.com/technoboom/compiler/object"
+)
+
+// StackSize - maximum number of values on the stack
+const StackSize = 2048
+
+// GlobalsSize - maximum number of global bindings
+const GlobalsSize = 65536
+
+// MaxFrames - maximum depth of function calls
+const MaxFrames = 1024
+
+var (
+	NULL  = object.NULL
+	TRUE  = object.TRUE
+	FALSE = object.FALSE
+)
+
+// unbound - value of the local whose let statement isn't executed yet
+// (e.g. it's in the other branch of if), reading it is a NameError
+var unbound object.Object = &unboundVariable{}
+
+// unboundVariable - type of the unbound marker, it never gets to programs.
+// It isn't zero-sized, so the marker differs from any other pointer
+type unboundVariable struct {
+	_ byte
+}
+
+// Type - returns type of the object
+func (u *unboundVariable) Type() object.ObjectType {
+	return object.NULL_OBJ
+}
+
+// Inspect - shows value of the object
+func (u *unboundVariable) Inspect() string {
+	return "unbound"
+}
+
+// VM - virtual machine executing the bytecode
+type VM struct {
+	constants []object.Object
+
+	stack []object.Object
+	// always points to the next free slot, the top of the stack is stack[sp-1]
+	sp int
+
+	globals []object.Object
+	// names of the globals by their indexes (nil if debug info is stripped)
+	globalNames []string
+
+	frames      []*Frame
+	framesIndex int
+
+	// cells of the locals of the running functions captured by closures
+	openCells []openCell
+
+	// value returned from the program by top-level return statement
+	returned object.Object
+}
+
+// New - creates virtual machine for the bytecode
+func New(bytecode *compiler.Bytecode) *VM {
+	mainFn := &object.CompiledFunction{Instructions: bytecode.Instructions}
+	mainClosure := &object.Closure{Fn: mainFn}
+	mainFrame := NewFrame(mainClosure, 0)
+
+	var globalNames []string
+	if bytecode.Debug != nil {
+		globalNames = bytecode.Debug.Locals
+	}
+
+	frames := make([]*Frame, MaxFrames)
+	frames[0] = mainFrame
+
+	return &VM{
+		constants: bytecode.Constants,
+
+		stack: make([]object.Object, StackSize),
+		sp:    0,
+
+		globals:     make([]object.Object, GlobalsSize),
+		globalNames: globalNames,
+
+		frames:      frames,
+		framesIndex: 1,
+	}
+}
+
+// NewWithGlobalsStore - creates virtual machine which keeps globals of
+// the previous runs (used by REPL)
+func NewWithGlobalsStore(bytecode *compiler.Bytecode, s []object.Object) *VM {
+	vm := New(bytecode)
+	vm.globals = s
+	return vm
+}
+
+// LastPoppedStackElem - returns result of the program: the value of the
+// last expression statement or the value of top-level return statement
+func (vm *VM) LastPoppedStackElem() object.Object {
+	if vm.returned != nil {
+		return vm.returned
+	}
+	return vm.stack[vm.sp]
+}
+
+// Run - executes instructions until the end of the program, runtime errors
+// are returned as *object.Error
+func (vm *VM) Run() error {
+	var ip int
+	var ins code.Instructions
+	var op code.Opcode
+
+	for vm.currentFrame().ip < len(vm.currentFrame().Instructions())-1 {
+		vm.currentFrame().ip++
+
+		ip = vm.currentFrame().ip
+		ins = vm.currentFrame().Instructions()
+		op = code.Opcode(ins[ip])
+
+		var err error
+
+		switch op {
+		case code.OpConstant:
+			constIndex := code.ReadUint16(ins[ip+1:])
+			vm.currentFrame().ip += 2
+			err = vm.push(vm.constants[constIndex])
+
+		case code.OpPop:
+			vm.pop()
+
+		case code.OpAdd, code.OpSub, code.OpMul, code.OpDiv,
+			code.OpEqual, code.OpNotEqual, code.OpGreaterThan, code.OpLessThan:
+			err = vm.executeBinaryOperation(op)
+
+		case code.OpTrue:
+			err = vm.push(TRUE)
+		case code.OpFalse:
+			err = vm.push(FALSE)
+		case code.OpNull:
+			err = vm.push(NULL)
+
+		case code.OpBang:
+			err = vm.executeBangOperator()
+		case code.OpMinus:
+			err = vm.executeMinusOperator()
+
+		case code.OpJump:
+			pos := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip = pos - 1
+		case code.OpJumpNotTruthy:
+			pos := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+
+			condition := vm.pop()
+			if !isTruthy(condition) {
+				vm.currentFrame().ip = pos - 1
+			}
+		case code.OpJumpNotNull:
+			pos := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+
+			if vm.stack[vm.sp-1] != NULL {
+				vm.currentFrame().ip = pos - 1
+			} else {
+				vm.pop()
+			}
+
+		case code.OpSetGlobal:
+			globalIndex := code.ReadUint16(ins[ip+1:])
+			vm.currentFrame().ip += 2
+			vm.globals[globalIndex] = vm.pop()
+			// the assigned value is not a result of the program
+			// ending with let statement
+			vm.stack[vm.sp] = nil
+		case code.OpGetGlobal:
+			globalIndex := code.ReadUint16(ins[ip+1:])
+			vm.currentFrame().ip += 2
+
+			// globals are visible before their let statements
+			if value := vm.globals[globalIndex]; value != nil {
+				err = vm.push(value)
+			} else {
+				err = newError(object.NAME_ERROR, "identifier not found: %s",
+					bindingName(vm.globalNames, int(globalIndex)))
+			}
+
+		case code.OpSetLocal:
+			localIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip++
+
+			frame := vm.currentFrame()
+			vm.stack[frame.basePointer+int(localIndex)] = vm.pop()
+		case code.OpGetLocal:
+			localIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip++
+
+			frame := vm.currentFrame()
+			if value := vm.stack[frame.basePointer+int(localIndex)]; value != unbound {
+				err = vm.push(value)
+			} else {
+				err = newError(object.NAME_ERROR, "identifier not found: %s",
+					bindingName(frame.debug().Locals, int(localIndex)))
+			}
+
+		case code.OpGetBuiltin:
+			builtinIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip++
+			err = vm.push(object.Builtins[builtinIndex].Builtin)
+
+		case code.OpGetFree:
+			freeIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip++
+
+			frame := vm.currentFrame()
+			if value := frame.cl.Free[freeIndex].Get(); value != unbound {
+				err = vm.push(value)
+			} else {
+				err = newError(object.NAME_ERROR, "identifier not found: %s",
+					bindingName(frame.debug().Free, int(freeIndex)))
+			}
+		case code.OpGetLocalCell:
+			localIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip++
+
+			frame := vm.currentFrame()
+			err = vm.push(vm.captureLocal(frame.basePointer + int(localIndex)))
+		case code.OpGetFreeCell:
+			freeIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip++
+			err = vm.push(vm.currentFrame().cl.Free[freeIndex])
+		case code.OpClosure:
+			constIndex := code.ReadUint16(ins[ip+1:])
+			numFree := code.ReadUint8(ins[ip+3:])
+			vm.currentFrame().ip += 3
+			err = vm.pushClosure(int(constIndex), int(numFree))
+
+		case code.OpArray:
+			numElements := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+
+			array := vm.buildArray(vm.sp-numElements, vm.sp)
+			vm.sp = vm.sp - numElements
+			err = vm.push(array)
+		case code.OpHash:
+			numElements := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+
+			var hash object.Object
+			hash, err = vm.buildHash(vm.sp-numElements, vm.sp)
+			if err == nil {
+				vm.sp = vm.sp - numElements
+				err = vm.push(hash)
+			}
+
+		case code.OpCall:
+			numArgs := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip++
+			err = vm.callFunction(int(numArgs))
+
+		case code.OpReturnValue:
+			returnValue := vm.pop()
+
+			// return statement of the program stops the execution
+			if vm.framesIndex == 1 {
+				vm.returned = returnValue
+				return nil
+			}
+
+			frame := vm.popFrame()
+			vm.closeCells(frame.basePointer)
+			vm.sp = frame.basePointer - 1
+			err = vm.push(returnValue)
+		case code.OpReturn:
+			frame := vm.popFrame()
+			vm.closeCells(frame.basePointer)
+			vm.sp = frame.basePointer - 1
+			err = vm.push(NULL)
+
+		default:
+			def, lookupErr := code.Lookup(byte(op))
+			if lookupErr != nil {
+				return lookupErr
+			}
+			return fmt.Errorf("opcode %s is not supported by vm", def.Name)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// currentFrame - returns frame of the function being executed
+func (vm *VM) currentFrame() *Frame {
+	return vm.frames[vm.framesIndex-1]
+}
+
+// pushFrame - enters the function call
+func (vm *VM) pushFrame(f *Frame) error {
+	if vm.framesIndex >= MaxFrames {
+		return newError(object.ERROR, "stack overflow")
+	}
+	vm.frames[vm.framesIndex] = f
+	vm.framesIndex++
+	return nil
+}
+
+// popFrame - leaves the function call
+func (vm *VM) popFrame() *Frame {
+	vm.framesIndex--
+	return vm.frames[vm.framesIndex]
+}
+
+// push - pushes the value on the top of the stack
+func (vm *VM) push(o object.Object) error {
+	if vm.sp >= StackSize {
+		return newError(object.ERROR, "stack overflow")
+	}
+
+	vm.stack[vm.sp] = o
+	vm.sp++
+
+	return nil
+}
+
+// pop - pops the value from the top of the stack, the value stays
+// in the stack until it's overwritten
+func (vm *VM) pop() object.Object {
+	o := vm.stack[vm.sp-1]
+	vm.sp--
+	return o
+}
+
+// callFunction - calls the closure or the builtin function placed
+// on the stack below its arguments
+func (vm *VM) callFunction(numArgs int) error {
+	switch callee := vm.stack[vm.sp-1-numArgs].(type) {
+	case *object.Closure:
+		return vm.callClosure(callee, numArgs)
+	case *object.Builtin:
+		return vm.callBuiltin(callee, numArgs)
+	default:
+		return newError(object.TYPE_ERROR, "not a function: %s", callee.Type())
+	}
+}
+
+// callClosure - enters the closure, the arguments become the first
+// locals of the function
+func (vm *VM) callClosure(cl *object.Closure, numArgs int) error {
+	fn := cl.Fn
+
+	numParams := len(fn.Parameters)
+	if numArgs > numParams {
+		return newError(object.ARGUMENT_ERROR,
+			"wrong number of arguments: want=%d, got=%d", numParams, numArgs)
+	}
+	if numArgs < numParams {
+		return newError(object.ARGUMENT_ERROR,
+			"missing argument: %s", fn.Parameters[numArgs])
+	}
+
+	frame := NewFrame(cl, vm.sp-numArgs)
+	if err := vm.pushFrame(frame); err != nil {
+		return err
+	}
+
+	if frame.basePointer+fn.NumLocals >= StackSize {
+		return newError(object.ERROR, "stack overflow")
+	}
+	// locals declared by let which wasn't executed (e.g. in the other
+	// branch of if) are unbound instead of the values left on the stack
+	for idx := frame.basePointer + numArgs; idx < frame.basePointer+fn.NumLocals; idx++ {
+		vm.stack[idx] = unbound
+	}
+	vm.sp = frame.basePointer + fn.NumLocals
+
+	return nil
+}
+
+// callBuiltin - calls the builtin function and replaces the function
+// and its arguments with the result
+func (vm *VM) callBuiltin(builtin *object.Builtin, numArgs int) error {
+	args := vm.stack[vm.sp-numArgs : vm.sp]
+
+	result := builtin.Fn(args...)
+	vm.sp = vm.sp - numArgs - 1
+
+	if err, ok := result.(*object.Error); ok {
+		return err
+	}
+	return vm.push(result)
+}
+
+// openCell - cell of the local variable and the slot of the variable
+type openCell struct {
+	slot int
+	cell *object.Cell
+}
+
+// captureLocal - returns cell of the local variable in the slot of the
+// stack, closures created in the same call share the cell
+func (vm *VM) captureLocal(slot int) *object.Cell {
+	for _, open := range vm.openCells {
+		if open.slot == slot {
+			return open.cell
+		}
+	}
+
+	cell := object.NewCell(&vm.stack[slot])
+	vm.openCells = append(vm.openCells, openCell{slot: slot, cell: cell})
+	return cell
+}
+
+// closeCells - closes cells of the locals of the returning function,
+// so they keep values after the stack is reused
+func (vm *VM) closeCells(basePointer int) {
+	remaining := vm.openCells[:0]
+	for _, open := range vm.openCells {
+		if open.slot >= basePointer {
+			open.cell.Close()
+		} else {
+			remaining = append(remaining, open)
+		}
+	}
+	vm.openCells = remaining
+}
+
+// pushClosure - pops cells of the captured variables and pushes
+// the closure over the compiled function from the constant pool
+func (vm *VM) pushClosure(constIndex int, numFree int) error {
+	fn, ok := vm.constants[constIndex].(*object.CompiledFunction)
+	if !ok {
+		return fmt.Errorf("not a function: %+v", vm.constants[constIndex])
+	}
+
+	free := make([]*object.Cell, numFree)
+	for i := 0; i < numFree; i++ {
+		free[i] = vm.stack[vm.sp-numFree+i].(*object.Cell)
+	}
+	vm.sp = vm.sp - numFree
+
+	return vm.push(&object.Closure{Fn: fn, Free: free})
+}
+
+// executeBinaryOperation - pops two operands and pushes result of the
+// operator, follows the same rules as the evaluator: integers support
+// all operators, other values can be compared with `==` and `!=` only
+func (vm *VM) executeBinaryOperation(op code.Opcode) error {
+	right := vm.pop()
+	left := vm.pop()
+
+	leftType := left.Type()
+	rightType := right.Type()
+
+	switch {
+	case leftType == object.INTEGER_OBJ && rightType == object.INTEGER_OBJ:
+		return vm.executeIntegerOperation(op, left, right)
+	case op == code.OpEqual:
+		return vm.push(nativeBoolToBooleanObject(objectsEqual(left, right)))
+	case op == code.OpNotEqual:
+		return vm.push(nativeBoolToBooleanObject(!objectsEqual(left, right)))
+	case leftType != rightType:
+		return newError(object.TYPE_ERROR, "type mismatch: %s %s %s",
+			leftType, operators[op], rightType)
+	default:
+		return newError(object.TYPE_ERROR, "unknown operator: %s %s %s",
+			leftType, operators[op], rightType)
+	}
+}
+
+// operators - source representation of binary operators used in errors
+var operators = map[code.Opcode]string{
+	code.OpAdd:         "+",
+	code.OpSub:         "-",
+	code.OpMul:         "*",
+	code.OpDiv:         "/",
+	code.OpEqual:       "==",
+	code.OpNotEqual:    "!=",
+	code.OpGreaterThan: ">",
+	code.OpLessThan:    "<",
+}
+
+// executeIntegerOperation - pushes result of the operator for two integers
+func (vm *VM) executeIntegerOperation(
+	op code.Opcode,
+	left, right object.Object,
+) error {
+	leftValue := left.(*object.Integer).Value
+	rightValue := right.(*object.Integer).Value
+
+	switch op {
+	case code.OpAdd:
+		return vm.push(&object.Integer{Value: leftValue + rightValue})
+	case code.OpSub:
+		return vm.push(&object.Integer{Value: leftValue - rightValue})
+	case code.OpMul:
+		return vm.push(&object.Integer{Value: leftValue * rightValue})
+	case code.OpDiv:
+		if rightValue == 0 {
+			return newError(object.ERROR, "division by zero")
+		}
+		return vm.push(&object.Integer{Value: leftValue / rightValue})
+	case code.OpEqual:
+		return vm.push(nativeBoolToBooleanObject(leftValue == rightValue))
+	case code.OpNotEqual:
+		return vm.push(nativeBoolToBooleanObject(leftValue != rightValue))
+	case code.OpGreaterThan:
+		return vm.push(nativeBoolToBooleanObject(leftValue > rightValue))
+	case code.OpLessThan:
+		return vm.push(nativeBoolToBooleanObject(leftValue < rightValue))
+	default:
+		return newError(object.TYPE_ERROR, "unknown operator: %s %s %s",
+			left.Type(), operators[op], right.Type())
+	}
+}
+
+// executeBangOperator - replaces the top of the stack with its negation
+func (vm *VM) executeBangOperator() error {
+	operand := vm.pop()
+
+	switch operand {
+	case TRUE:
+		return vm.push(FALSE)
+	case FALSE:
+		return vm.push(TRUE)
+	case NULL:
+		return vm.push(TRUE)
+	default:
+		return vm.push(FALSE)
+	}
+}
+
+// executeMinusOperator - negates the integer on the top of the stack
+func (vm *VM) executeMinusOperator() error {
+	operand := vm.pop()
+
+	if operand.Type() != object.INTEGER_OBJ {
+		return newError(object.TYPE_ERROR, "unknown operator: -%s", operand.Type())
+	}
+
+	value := operand.(*object.Integer).Value
+	return vm.push(&object.Integer{Value: -value})
+}
+
+// buildArray - creates array from the values of the stack
+func (vm *VM) buildArray(startIndex, endIndex int) object.Object {
+	elements := make([]object.Object, endIndex-startIndex)
+
+	for i := startIndex; i < endIndex; i++ {
+		elements[i-startIndex] = vm.stack[i]
+	}
+
+	return &object.Array{Elements: elements}
+}
+
+// buildHash - creates hash from keys and values of the stack
+func (vm *VM) buildHash(startIndex, endIndex int) (object.Object, error) {
+	hash := object.NewHash()
+
+	for i := startIndex; i < endIndex; i += 2 {
+		key := vm.stack[i]
+		value := vm.stack[i+1]
+
+		hashKey, ok := key.(object.Hashable)
+		if !ok {
+			return nil, newError(object.TYPE_ERROR, "unusable as hash key: %s", key.Type())
+		}
+
+		hash.Set(hashKey, value)
+	}
+
+	return hash, nil
+}
+
+// bindingName - returns name of the binding by its index, bytecode
+// without debug info has the index only
+func bindingName(names []string, index int) string {
+	if index < len(names) {
+		return names[index]
+	}
+	return fmt.Sprintf("#%d", index)
+}
+
+// newError - creates runtime error of the given kind with given message
+// as formatted string
+func newError(kind string, format string, a ...interface{}) *object.Error {
+	return &object.Error{Kind: kind, Message: fmt.Sprintf(format, a...)}
+}
+
+// nativeBoolToBooleanObject - returns shared boolean object for the value
+func nativeBoolToBooleanObject(input bool) *object.Boolean {
+	if input {
+		return TRUE
+	}
+	return FALSE
+}
+
+// isTruthy - checks if the value is treated as true by conditionals,
+// only false and null are not truthy
+func isTruthy(obj object.Object) bool {
+	switch obj := obj.(type) {
+	case *object.Boolean:
+		return obj.Value
+	case *object.Null:
+		return false
+	default:
+		return true
+	}
+}
+
+// objectsEqual - compares integers, strings and booleans by value,
+// all other values are equal only to themselves
+func objectsEqual(left, right object.Object) bool {
+	if left.Type() != right.Type() {
+		return false
+	}
+
+	switch left := left.(type) {
+	case *object.Integer:
+		return left.Value == right.(*object.Integer).Value
+	case *object.String:
+		return left.Value == right.(*object.String).Value
+	case *object.Boolean:
+		return left.Value == right.(*object.Boolean).Value
+	case *object.Null:
+		return true
+	default:
+		return left == right
+	}
+}
@@ -0,0 +1,360 @@
+package vm
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/technoboom/compiler/compiler"
+	"github.com/technoboom/compiler/evaluator"
+	"github.com/technoboom/compiler/internal/testutil"
+	"github.com/technoboom/compiler/object"
+)
+
+type vmTestCase struct {
+	input    string
+	expected string
+}
+
+func TestIntegerArithmetic(t *testing.T) {
+	tests := []vmTestCase{
+		{"1", "1"},
+		{"1 + 2", "3"},
+		{"1 - 2", "-1"},
+		{"4 / 2 * 3", "6"},
+		{"5 * (2 + 10)", "60"},
+		{"-5 + 10", "5"},
+		{"-(3 * 3) + 50 / 2", "16"},
+	}
+
+	runVMTests(t, tests)
+}
+
+func TestBooleanExpressions(t *testing.T) {
+	tests := []vmTestCase{
+		{"true", "true"},
+		{"1 < 2", "true"},
+		{"1 > 2", "false"},
+		{"1 == 1", "true"},
+		{"1 != 1", "false"},
+		{"true == false", "false"},
+		{"(1 < 2) == true", "true"},
+		{`"a" == "a"`, "true"},
+		{`"a" != 1`, "true"},
+		{"null == null", "true"},
+		{"!true", "false"},
+		{"!!5", "true"},
+		{"!null", "true"},
+	}
+
+	runVMTests(t, tests)
+}
+
+func TestConditionals(t *testing.T) {
+	tests := []vmTestCase{
+		{"if (true) { 10 }", "10"},
+		{"if (false) { 10 }", "null"},
+		{"if (1 > 2) { 10 } else { 20 }", "20"},
+		{"if (null) { 10 } else { 20 }", "20"},
+		{"if ((if (false) { 10 })) { 10 } else { 20 }", "20"},
+		{"if (true) { }", "null"},
+		{"1 < 2 ? 1 : 2", "1"},
+		{"null ?? 5", "5"},
+		{"3 ?? 5", "3"},
+		{"false ?? 5", "false"},
+	}
+
+	runVMTests(t, tests)
+}
+
+func TestGlobalLetStatements(t *testing.T) {
+	tests := []vmTestCase{
+		{"let one = 1; one", "1"},
+		{"let one = 1; let two = one + one; one + two", "3"},
+		{"let a = 1; let a = a + 1; a", "2"},
+		{"let a = 1; if (true) { let a = 5; }; a", "5"},
+		// globals are visible before their let statements
+		{"let f = () => x; let x = 7; f()", "7"},
+		{`
+		let isEven = (n) => n == 0 ? true : isOdd(n - 1);
+		let isOdd = (n) => n == 0 ? false : isEven(n - 1);
+		[isEven(10), isOdd(7), isEven(3)]
+		`, "[true, true, false]"},
+		// locals are visible after their let statements
+		{"let x = 1; let f = () => { let x = x + 1; x }; [f(), x]", "[2, 1]"},
+		{"let x = 1; let f = () => { let y = x; let x = 2; [y, x] }; f()", "[1, 2]"},
+	}
+
+	runVMTests(t, tests)
+}
+
+func TestArrayAndHashLiterals(t *testing.T) {
+	tests := []vmTestCase{
+		{"[]", "[]"},
+		{"[1, 2 * 3, \"a\"]", "[1, 6, a]"},
+		{"{}", "{}"},
+		{`{"a": 1, 2: [3], true: {}}`, "{a: 1, 2: [3], true: {}}"},
+	}
+
+	runVMTests(t, tests)
+}
+
+func TestFunctionCalls(t *testing.T) {
+	tests := []vmTestCase{
+		{"let f = function() { 5 + 10; }; f();", "15"},
+		{"let f = () => { return 1; 2 }; f()", "1"},
+		{"let f = () => { }; f()", "null"},
+		{"let f = (a, b) => { let c = a + b; c * 2 }; f(1, 2)", "6"},
+		{"let a = 10; let f = (b) => a + b; f(5)", "15"},
+		{"let first = () => 1; let second = () => first() + 1; second()", "2"},
+		{"let f = (x) => { if (x > 10) { return 1; } 0 }; [f(11), f(5)]", "[1, 0]"},
+		{"let f = (a) => a; f(f)(3)", "3"},
+		{"return 10; 9;", "10"},
+		{"if (10 > 1) { if (10 > 1) { return 10; } return 1; }", "10"},
+		{"let fib = (n) => n < 2 ? n : fib(n - 1) + fib(n - 2); fib(15)", "610"},
+		{"let f = (c) => { if (c) { let x = 1; } x }; f(true)", "1"},
+	}
+
+	runVMTests(t, tests)
+}
+
+func TestClosures(t *testing.T) {
+	tests := []vmTestCase{
+		{"let newAdder = (a) => (b) => a + b; let addTwo = newAdder(2); addTwo(3)", "5"},
+		{"let make = (x) => () => x; let a = make(1); let b = make(2); [a(), b()]", "[1, 2]"},
+		{"let f = (a) => (b) => (c) => a * 100 + b * 10 + c; f(1)(2)(3)", "123"},
+		{`
+		let f = (a) => {
+			let b = a + 1;
+			let g = () => { let c = b + 1; () => a + b + c };
+			g()
+		};
+		f(1)()
+		`, "6"},
+		// closures see bindings made after their creation
+		{`
+		let counter = () => {
+			let n = 0;
+			let get = () => n;
+			let n = n + 1;
+			let n = n + 1;
+			get
+		};
+		counter()()
+		`, "2"},
+		// closures created in the same call share the variable
+		{`
+		let f = () => {
+			let x = 1;
+			let a = () => x;
+			let b = () => () => x;
+			let x = 2;
+			[a(), b()()]
+		};
+		f()
+		`, "[2, 2]"},
+		// local recursive functions
+		{`
+		let wrapper = () => {
+			let fib = (n) => n < 2 ? n : fib(n - 1) + fib(n - 2);
+			fib(10)
+		};
+		wrapper()
+		`, "55"},
+	}
+
+	runVMTests(t, tests)
+}
+
+func TestBuiltinFunctions(t *testing.T) {
+	tests := []vmTestCase{
+		{"let f = tag; f == tag", "true"},
+		{"let tag = (x) => x; tag(1)", "1"},
+	}
+
+	runVMTests(t, tests)
+}
+
+func TestRuntimeErrors(t *testing.T) {
+	tests := []vmTestCase{
+		{"5 + true;", "TypeError: type mismatch: INTEGER + BOOLEAN"},
+		{"5 + true; 5;", "TypeError: type mismatch: INTEGER + BOOLEAN"},
+		{"-true", "TypeError: unknown operator: -BOOLEAN"},
+		{"10 / (5 - 5)", "Error: division by zero"},
+		{"true + false;", "TypeError: unknown operator: BOOLEAN + BOOLEAN"},
+		{`"a" + "b"`, "TypeError: unknown operator: STRING + STRING"},
+		{"if (10 > 1) { true + false; }", "TypeError: unknown operator: BOOLEAN + BOOLEAN"},
+		{"{[1]: 2}", "TypeError: unusable as hash key: ARRAY"},
+		{"1()", "TypeError: not a function: INTEGER"},
+		{"let f = (a) => a; f(1, 2)", "ArgumentError: wrong number of arguments: want=1, got=2"},
+		{"let f = (a, b) => a; f(1)", "ArgumentError: missing argument: b"},
+		{"let f = () => f(); f()", "Error: stack overflow"},
+		{"tag(1)", "TypeError: argument to tag must be enum variant, got INTEGER"},
+		{"implements(1)", "ArgumentError: wrong number of arguments: want=2, got=1"},
+		{"let a = a", "NameError: identifier not found: a"},
+		{"unknown", "NameError: identifier not found: unknown"},
+		{"let f = () => { let a = a; a }; f()", "NameError: identifier not found: a"},
+		{"let f = () => x; f(); let x = 1", "NameError: identifier not found: x"},
+		// locals of let which wasn't executed don't see stale values
+		{"let g = (a, b) => a + b; let f = (c) => { if (c) { let x = 1; } x }; [g(2, 3), f(false)]",
+			"NameError: identifier not found: x"},
+		{"let f = (a) => { if (a) { let y = 3 }; y }; f(false)", "NameError: identifier not found: y"},
+		{"let f = () => { let g = () => y; let r = g(); let y = 1; r }; f()",
+			"NameError: identifier not found: y"},
+	}
+
+	for _, tt := range tests {
+		vm, err := runVM(tt.input)
+		if err == nil {
+			t.Errorf("expected VM error for %q, got result %s",
+				tt.input, vm.LastPoppedStackElem().Inspect())
+			continue
+		}
+
+		if err.Error() != tt.expected {
+			t.Errorf("wrong VM error for %q. want=%q, got=%q",
+				tt.input, tt.expected, err.Error())
+		}
+	}
+}
+
+// TestDecodedBytecode - runs bytecode which went through the file format
+func TestDecodedBytecode(t *testing.T) {
+	input := `
+	let newAdder = (a) => (b) => a + b;
+	let fib = (n) => n < 2 ? n : fib(n - 1) + fib(n - 2);
+	[newAdder(2)(3), fib(10), "beaver", {"k": null}]
+	`
+	expected := "[5, 55, beaver, {k: null}]"
+
+	comp := compiler.New()
+	if err := comp.Compile(testutil.Parse(input)); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	var buf bytes.Buffer
+	if err := compiler.Encode(&buf, comp.Bytecode()); err != nil {
+		t.Fatalf("encoding error: %s", err)
+	}
+	bytecode, err := compiler.Decode(&buf)
+	if err != nil {
+		t.Fatalf("decoding error: %s", err)
+	}
+
+	vm := New(bytecode)
+	if err := vm.Run(); err != nil {
+		t.Fatalf("vm error: %s", err)
+	}
+	if result := vm.LastPoppedStackElem().Inspect(); result != expected {
+		t.Errorf("wrong result. want=%q, got=%q", expected, result)
+	}
+}
+
+// TestEvaluatorEquivalence - runs programs through the evaluator and the VM
+// and checks that results are the same
+func TestEvaluatorEquivalence(t *testing.T) {
+	inputs := []string{
+		"5 + 5 + 5 + 5 - 10",
+		"2 * (5 + 10)",
+		"(5 + 10 * 2 + 15 / 3) * 2 + -10",
+		"1 < 2 == true",
+		"!!true",
+		"if (1 < 2) { 10 } else { 20 }",
+		"if (1 > 2) { 10 }",
+		"let a = 5; let b = a; let c = a + b + 5; c;",
+		"let identity = function(x) { x; }; identity(5);",
+		"let double = function(x) { x * 2; }; double(5);",
+		"let add = function(x, y) { x + y; }; add(5 + 5, add(5, 5));",
+		"function(x) { x; }(5)",
+		"9; return 2 * 5; 9;",
+		"if (10 > 1) { if (10 > 1) { return 10; } return 1; }",
+		"let f = function(x) { if (x) { return 1 }; 2 }; [f(true), f(false)]",
+		"let max = (a, b) => a > b ? a : b; max(3, 7)",
+		"let x = null; x ?? 1",
+		`[1, "two", true, null, [3], {"k": 4}]`,
+		"5 + true; 5;",
+		"-true",
+		`{[1]: 2}`,
+		"let f = (a) => a; f()",
+		"let newAdder = (a) => (b) => a + b; newAdder(2)(3)",
+		"let f = () => { let x = 1; let g = () => x; let x = 2; g() }; f()",
+		"let f = () => { let x = 1; let g = () => x; g }; let x = 5; f()()",
+		"let f = () => { let loop = (n) => n == 0 ? 0 : loop(n - 1); loop(5) }; f()",
+		"let make = (x) => () => x; [make(1)(), make(2)()]",
+		"tag(1)",
+	}
+
+	for _, input := range inputs {
+		expected := testutil.Evaluate(input)
+
+		vm, err := runVM(input)
+		var actual string
+		if err != nil {
+			actual = err.Error()
+		} else {
+			actual = vm.LastPoppedStackElem().Inspect()
+		}
+
+		if actual != expected {
+			t.Errorf("results differ for %q. evaluator=%q, vm=%q", input, expected, actual)
+		}
+	}
+}
+
+func runVMTests(t *testing.T, tests []vmTestCase) {
+	t.Helper()
+
+	for _, tt := range tests {
+		vm, err := runVM(tt.input)
+		if err != nil {
+			t.Fatalf("vm error for %q: %s", tt.input, err)
+		}
+
+		result := vm.LastPoppedStackElem()
+		if result.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. want=%q, got=%q",
+				tt.input, tt.expected, result.Inspect())
+		}
+	}
+}
+
+func runVM(input string) (*VM, error) {
+	comp := compiler.New()
+	if err := comp.Compile(testutil.Parse(input)); err != nil {
+		return nil, err
+	}
+
+	vm := New(comp.Bytecode())
+	return vm, vm.Run()
+}
+
+const fibProgram = `
+let fib = function(n) {
+	if (n < 2) { return n; }
+	fib(n - 1) + fib(n - 2);
+};
+fib(25);
+`
+
+func BenchmarkFibonacciVM(b *testing.B) {
+	program := testutil.Parse(fibProgram)
+
+	for i := 0; i < b.N; i++ {
+		comp := compiler.New()
+		if err := comp.Compile(program); err != nil {
+			b.Fatalf("compiler error: %s", err)
+		}
+
+		vm := New(comp.Bytecode())
+		if err := vm.Run(); err != nil {
+			b.Fatalf("vm error: %s", err)
+		}
+	}
+}
+
+func BenchmarkFibonacciEvaluator(b *testing.B) {
+	program := testutil.Parse(fibProgram)
+
+	for i := 0; i < b.N; i++ {
+		evaluator.Eval(program, object.NewEnvironment())
+	}
+}